@@ -3,13 +3,17 @@ package main
 
 import (
 	"log"
+	"net/http"
 	"os"
 	"context"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
 	"go-discussion-app/config"
+	"go-discussion-app/internal/activity"
+	"go-discussion-app/internal/audit"
 	"go-discussion-app/internal/auth"
 	"go-discussion-app/internal/comment"
 	"go-discussion-app/internal/discussion"
@@ -19,6 +23,12 @@ import (
 	"go-discussion-app/internal/tag"
 	"go-discussion-app/internal/user"
 	"go-discussion-app/db"
+	"go-discussion-app/models"
+	"go-discussion-app/pkg/jwtutil"
+	"go-discussion-app/pkg/logger"
+	"go-discussion-app/pkg/mailer"
+	"go-discussion-app/pkg/pagination"
+	"go-discussion-app/pkg/slowquery"
 )
 
 func main() {
@@ -27,12 +37,18 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	logger.Infof("effective config: %s", cfg.SafeString())
+	slowquery.SetThreshold(cfg.SlowQueryThreshold)
+	mailer.SetRateLimit(cfg.MailRatePerSecond, cfg.MailRateBurst)
+	pagination.SetMaxOffset(cfg.MaxPageOffset)
+	jwtutil.SetRefreshExpiry(time.Duration(cfg.JWTRefreshExpiryMins) * time.Minute)
 
 	dbConn, err := db.InitPostgres(context.Background())
 	if err != nil {
 		log.Fatalf("Failed to connect to DB: %v", err)
 	}
 	defer dbConn.Close()
+	audit.SetStore(audit.NewRepository(dbConn))
 
 	// Set Gin mode
 	if os.Getenv("GIN_MODE") == "release" {
@@ -40,6 +56,20 @@ func main() {
 	}
 
 	router := gin.Default()
+	// Trailing slashes are trimmed before routing (see TrimTrailingSlash
+	// below) rather than answered with a 301/307 redirect
+	router.RedirectTrailingSlash = false
+
+	// ClientIP believes X-Forwarded-For only from GATEWAY_TRUSTED_PROXIES, so
+	// per-IP limits see the real client behind the proxy while anyone else
+	// is counted by their own address.
+	trustedProxies := make([]string, len(cfg.GatewayTrustedProxies))
+	for i, p := range cfg.GatewayTrustedProxies {
+		trustedProxies[i] = p.String()
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid GATEWAY_TRUSTED_PROXIES: %v", err)
+	}
 
 	// CORS middleware (allow all for now; restrict in prod)
 	router.Use(cors.Default())
@@ -48,22 +78,45 @@ func main() {
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
+	// Health routes are registered before the concurrency limit so they only
+	// pick up the middlewares above: a busy instance must still answer its
+	// liveness probe rather than be restarted for it.
+	health.RegisterRoutes(router, dbConn, cfg)
+
+	if cfg.MaxConcurrentRequests > 0 {
+		router.Use(middleware.ConcurrencyLimit(cfg.MaxConcurrentRequests))
+	}
+	if cfg.CompressionEnabled {
+		router.Use(middleware.Gzip(cfg.CompressionMinSize))
+	}
+
+	// Unknown paths get a JSON 404 like every other error
+	router.NoRoute(middleware.NotFound())
+
 	// Public routes
-        auth.RegisterRoutes(router, dbConn)
-	health.RegisterRoutes(router, dbConn)
+        auth.RegisterRoutes(router, dbConn, cfg)
+	subscription.RegisterPublicRoutes(router, dbConn, cfg)
 
 	// Protected routes group (JWT middleware)
 	protected := router.Group("/")
-	protected.Use(middleware.JWTAuth())
+	protected.Use(middleware.JWTAuth(dbConn, cfg), middleware.RequireActive(dbConn))
 
-	user.RegisterRoutes(protected, dbConn)
-	discussion.RegisterRoutes(protected, dbConn)
-	comment.RegisterRoutes(protected, dbConn)
-	subscription.RegisterRoutes(protected, dbConn)
+	user.RegisterRoutes(protected, dbConn, cfg, auth.RequireRole(models.RoleAdmin))
+	discussion.RegisterRoutes(protected, dbConn, cfg, comment.NewService(comment.NewRepository(dbConn), cfg))
+	comment.RegisterRoutes(protected, dbConn, cfg)
+	subscription.RegisterRoutes(protected, dbConn, cfg)
 	tag.RegisterRoutes(protected, dbConn)
+	activity.RegisterRoutes(protected, dbConn, cfg)
+	audit.RegisterRoutes(protected, dbConn, auth.RequireRole(models.RoleAdmin))
+
+	// Background jobs
+	if cfg.StaleDiscussionAge > 0 {
+		staleSvc := discussion.NewService(discussion.NewRepository(dbConn), tag.NewRepository(dbConn), cfg)
+		go discussion.SweepStale(context.Background(), staleSvc, cfg.StaleDiscussionAge, cfg.StaleCheckInterval)
+	}
 
 	// Start server
-	if err := router.Run(":" + cfg.Port); err != nil {
+	if err := http.ListenAndServe(":"+cfg.Port, middleware.TrimTrailingSlash(router)); err != nil {
 		log.Fatalf("Failed to run server: %v", err)
 	}
 }
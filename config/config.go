@@ -4,11 +4,125 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"go-discussion-app/pkg/featureflags"
+)
+
+// MinJWTSecretLength is the shortest JWT_SECRET accepted. Tokens are signed
+// with HS256, whose key should be at least as long as the 256-bit hash output.
+const MinJWTSecretLength = 32
+
+// Comment orders and discussion sorts accepted by DEFAULT_COMMENT_ORDER,
+// DEFAULT_DISCUSSION_SORT and the matching ?order= / ?sort= query parameters.
+const (
+	CommentOrderOldest = "oldest"
+	CommentOrderNewest = "newest"
+
+	DiscussionSortNewest        = "newest"
+	DiscussionSortOldest        = "oldest"
+	DiscussionSortMostCommented = "most_commented"
+	DiscussionSortMostViewed    = "most_viewed"
+	DiscussionSortSubscribers   = "subscribers"
+	// DiscussionSortRecent and DiscussionSortPopular are the original names
+	// of newest and most_commented, still accepted.
+	DiscussionSortRecent  = "recent"
+	DiscussionSortPopular = "popular"
+)
+
+// DiscussionSorts lists the discussion sorts in the order they are documented.
+var DiscussionSorts = []string{DiscussionSortNewest, DiscussionSortOldest, DiscussionSortMostCommented, DiscussionSortMostViewed, DiscussionSortSubscribers}
+
+// DefaultDiscussionLanguages is the DISCUSSION_LANGUAGES allowlist used when
+// the variable is unset: ISO 639-1 codes of widely used languages.
+var DefaultDiscussionLanguages = []string{"ar", "de", "en", "es", "fr", "hi", "it", "ja", "ko", "nl", "pl", "pt", "ru", "tr", "uk", "zh"}
+
+// What happens to a user's discussions and comments when they delete their
+// account, selected by DELETED_USER_CONTENT.
+const (
+	DeletedContentCascade  = "cascade"  // delete the content along with the user
+	DeletedContentReassign = "reassign" // hand the content to the "deleted user" placeholder
+)
+
+// How the readiness check treats SMTP, selected by HEALTH_CHECK_SMTP.
+const (
+	HealthSMTPOff      = "off"      // not checked
+	HealthSMTPOptional = "optional" // reported in checks but never fails readiness
+	HealthSMTPRequired = "required" // an unreachable server fails readiness
 )
 
+// ValidCommentOrder reports whether s is a supported comment order.
+func ValidCommentOrder(s string) bool {
+	return s == CommentOrderOldest || s == CommentOrderNewest
+}
+
+// ValidDiscussionSort reports whether s is a supported discussion sort.
+func ValidDiscussionSort(s string) bool {
+	if s == DiscussionSortRecent || s == DiscussionSortPopular {
+		return true
+	}
+	for _, v := range DiscussionSorts {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidDeletedContentMode reports whether s is a supported DELETED_USER_CONTENT value.
+func ValidDeletedContentMode(s string) bool {
+	return s == DeletedContentCascade || s == DeletedContentReassign
+}
+
+// ValidHealthSMTPMode reports whether s is a supported HEALTH_CHECK_SMTP value.
+func ValidHealthSMTPMode(s string) bool {
+	return s == HealthSMTPOff || s == HealthSMTPOptional || s == HealthSMTPRequired
+}
+
+// ParseTrustedProxies parses a comma-separated list of IP addresses and
+// CIDR ranges; a bare address stands for itself alone.
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if strings.Contains(part, "/") {
+			p, err := netip.ParsePrefix(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", part)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP %q", part)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// ParseAge parses a duration that may also be given in whole days ("90d"),
+// which time.ParseDuration does not accept.
+func ParseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
 // Config holds every configurable setting for the application.
 // You can add or remove fields as needed (e.g. Redis settings, API keys, etc.).
 type Config struct {
@@ -17,6 +131,8 @@ type Config struct {
 	ReadTimeout    time.Duration // e.g. 5 * time.Second
 	WriteTimeout   time.Duration // e.g. 10 * time.Second
 	ShutdownPeriod time.Duration // graceful shutdown timeout
+	MaxConcurrentRequests int    // in-flight requests beyond this get 503 (0 disables)
+	BaseURL        string        // public address of the app, for links in emails (no trailing slash)
 
 	// POSTGRES
 	DBHost     string
@@ -28,7 +144,13 @@ type Config struct {
 
 	// JWT
 	JWTSecret     string
-	JWTExpiryMins int // how long (in minutes) a token remains valid
+	JWTExpiryMins        int // how long (in minutes) a token remains valid
+	JWTRefreshExpiryMins int // how long (in minutes) a refresh token remains valid
+
+	// GATEWAY (off unless TRUST_GATEWAY_HEADERS=true)
+	TrustGatewayHeaders   bool           // take the user id from GatewayUserHeader on requests from GatewayTrustedProxies
+	GatewayUserHeader     string         // e.g. "X-User-ID"
+	GatewayTrustedProxies []netip.Prefix // peers allowed to set GatewayUserHeader and X-Forwarded-For; required when trusting headers
 
 	// SMTP (Mailer)
 	SMTPHost     string
@@ -36,11 +158,70 @@ type Config struct {
 	SMTPUsername string
 	SMTPPassword string
 	FromEmail    string
+	MailRatePerSecond float64 // max emails sent per second across the process (0 disables)
+	MailRateBurst     int     // emails that may go out back to back before pacing starts
 
 	// LOGGING
 	LogLevel  string // e.g. "debug" / "info" / "warn" / "error"
 	LogFormat string // "text" or "json"
 
+	// DATABASE DIAGNOSTICS
+	SlowQueryThreshold time.Duration // queries slower than this are logged at Warn (0 disables)
+
+	// REGISTRATION
+	AllowedEmailDomains []string // lower-cased; empty allows every domain
+
+	// RATE LIMITS
+	DiscussionRateLimit  int           // max discussions a user may create per window (0 disables)
+	DiscussionRateWindow time.Duration // e.g. 1 * time.Hour
+	CommentCooldown      time.Duration // min gap between one user's comments (0 disables)
+	LoginMaxFailures     int           // failed logins per email or IP before a lockout (0 disables)
+	LoginFailureWindow   time.Duration // failures are counted, and a lockout lasts, this long
+
+	// SUBSCRIPTIONS
+	MaxSubscriptionsPerEmail int           // max discussions one email may subscribe to (0 disables)
+	AnonSubscribeRateLimit   int           // max anonymous subscribe requests per IP per window (0 disables)
+	AnonSubscribeRateWindow  time.Duration // e.g. 1 * time.Hour
+	NotifyJobTimeout         time.Duration // an async notify job is cancelled after this long
+	NotifyMaxRecipients      int           // subscribers one notify job mails before handing off to the next (0 disables)
+
+	// TAG LIMITS
+	MaxTagsPerDiscussion int // max tags one discussion may carry (0 disables)
+	MaxDiscussionsPerTag int // max discussions one tag may be attached to (0 disables)
+
+	// SEARCH
+	SearchMaxResults     int // page size cap for GET /discussions/search
+	SearchMinQueryLength int // shorter queries are rejected with 400
+
+	// COMPRESSION
+	CompressionEnabled bool // gzip responses for clients that accept it
+	CompressionMinSize int  // bodies smaller than this many bytes are sent uncompressed
+
+	// LIST DEFAULTS (used when the client sends no ?order= / ?sort=)
+	DefaultCommentOrder   string // "oldest" or "newest"
+	DefaultDiscussionSort string // one of DiscussionSorts, or "recent" / "popular"
+	MaxPageOffset         int    // ?offset= above this is rejected with 400 (0 disables)
+
+	// ACCOUNT DELETION
+	DeletedContentMode string // "cascade" or "reassign"
+
+	// PASSWORD RESET
+	PasswordResetTTL time.Duration // how long an emailed reset token stays valid
+
+	// STALE DISCUSSIONS
+	StaleDiscussionAge time.Duration // published discussions idle this long are archived (0 disables)
+	StaleCheckInterval time.Duration // how often the stale sweep runs
+
+	// HEALTH
+	HealthSMTPMode    string        // HealthSMTPOff, HealthSMTPOptional or HealthSMTPRequired
+	HealthSMTPTimeout time.Duration // how long the readiness check waits to connect to SMTP
+
+	// LANGUAGES
+	DiscussionLanguages []string // ISO 639-1 codes a discussion's language may be set to
+
+	// FEATURES
+	Features featureflags.Flags // FEATURE_* toggles; disabled features' routes are not registered
+
 	// Any other integrations you might need, for example:
 	// RedisAddress  string
 	// RedisPassword string
@@ -68,6 +249,16 @@ func LoadConfig() (*Config, error) {
 	if err != nil || shutdownPeriod <= 0 {
 		shutdownPeriod = 15 * time.Second
 	}
+	maxConcurrent := 0
+	if v := os.Getenv("MAX_CONCURRENT_REQUESTS"); v != "" {
+		if n, parseErr := strconv.Atoi(v); parseErr == nil && n >= 0 {
+			maxConcurrent = n
+		}
+	}
+	baseURL := strings.TrimRight(os.Getenv("APP_BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + strings.TrimPrefix(port, ":")
+	}
 
 	// 2) POSTGRES (required)
 	dbHost := os.Getenv("DB_HOST")
@@ -88,6 +279,10 @@ func LoadConfig() (*Config, error) {
 	if jwtSecret == "" {
 		return nil, fmt.Errorf("JWT_SECRET must be set")
 	}
+	if len(jwtSecret) < MinJWTSecretLength {
+		return nil, fmt.Errorf("JWT_SECRET must be at least %d bytes for HS256 (got %d); generate one with e.g. `openssl rand -hex 32`",
+			MinJWTSecretLength, len(jwtSecret))
+	}
 	jwtExpiryStr := os.Getenv("JWT_EXPIRES_IN") // in minutes
 	jwtExpiry := 60                              // default 60 minutes
 	if jwtExpiryStr != "" {
@@ -95,6 +290,12 @@ func LoadConfig() (*Config, error) {
 			jwtExpiry = m
 		}
 	}
+	jwtRefreshExpiry := 30 * 24 * 60 // default 30 days
+	if v := os.Getenv("JWT_REFRESH_EXPIRES_IN"); v != "" {
+		if m, parseErr := strconv.Atoi(v); parseErr == nil && m > 0 {
+			jwtRefreshExpiry = m
+		}
+	}
 
 	// 4) SMTP / MAILER (optional, but if you plan to send mail, require these)
 	smtpHost := os.Getenv("SMTP_HOST")
@@ -120,11 +321,246 @@ func LoadConfig() (*Config, error) {
 		logFmt = "text"
 	}
 
+	slowQuery := 200 * time.Millisecond
+	if v := os.Getenv("SLOW_QUERY_THRESHOLD"); v != "" {
+		if d, parseErr := time.ParseDuration(v); parseErr == nil && d >= 0 {
+			slowQuery = d
+		}
+	}
+
+	// 6) REGISTRATION (optional): comma-separated list of allowed email domains
+	var allowedDomains []string
+	for _, d := range strings.Split(os.Getenv("ALLOWED_EMAIL_DOMAINS"), ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			allowedDomains = append(allowedDomains, d)
+		}
+	}
+
+	// 7) RATE LIMITS (optional with sensible defaults)
+	discRateLimit := 10
+	if v := os.Getenv("DISCUSSION_RATE_LIMIT"); v != "" {
+		if n, parseErr := strconv.Atoi(v); parseErr == nil && n >= 0 {
+			discRateLimit = n
+		}
+	}
+	discRateWindow, err := time.ParseDuration(os.Getenv("DISCUSSION_RATE_WINDOW"))
+	if err != nil || discRateWindow <= 0 {
+		discRateWindow = time.Hour
+	}
+	commentCooldown := 10 * time.Second
+	if v := os.Getenv("COMMENT_COOLDOWN"); v != "" {
+		if d, parseErr := time.ParseDuration(v); parseErr == nil && d >= 0 {
+			commentCooldown = d
+		}
+	}
+	maxSubs := 200
+	if v := os.Getenv("MAX_SUBSCRIPTIONS_PER_EMAIL"); v != "" {
+		if n, parseErr := strconv.Atoi(v); parseErr == nil && n >= 0 {
+			maxSubs = n
+		}
+	}
+	loginMaxFailures := 5
+	if v := os.Getenv("LOGIN_MAX_FAILURES"); v != "" {
+		if n, parseErr := strconv.Atoi(v); parseErr == nil && n >= 0 {
+			loginMaxFailures = n
+		}
+	}
+	loginFailureWindow, err := time.ParseDuration(os.Getenv("LOGIN_FAILURE_WINDOW"))
+	if err != nil || loginFailureWindow <= 0 {
+		loginFailureWindow = 15 * time.Minute
+	}
+	anonSubLimit := 5
+	if v := os.Getenv("ANON_SUBSCRIBE_RATE_LIMIT"); v != "" {
+		if n, parseErr := strconv.Atoi(v); parseErr == nil && n >= 0 {
+			anonSubLimit = n
+		}
+	}
+	anonSubWindow, err := time.ParseDuration(os.Getenv("ANON_SUBSCRIBE_RATE_WINDOW"))
+	if err != nil || anonSubWindow <= 0 {
+		anonSubWindow = time.Hour
+	}
+
+	// 8) SEARCH (optional with sensible defaults)
+	searchMax := 50
+	if v := os.Getenv("SEARCH_MAX_RESULTS"); v != "" {
+		if n, parseErr := strconv.Atoi(v); parseErr == nil && n > 0 {
+			searchMax = n
+		}
+	}
+	searchMinLen := 2
+	if v := os.Getenv("SEARCH_MIN_QUERY_LENGTH"); v != "" {
+		if n, parseErr := strconv.Atoi(v); parseErr == nil && n > 0 {
+			searchMinLen = n
+		}
+	}
+
+	// 9) COMPRESSION (optional, on by default)
+	compression := true
+	if v := os.Getenv("COMPRESSION_ENABLED"); v != "" {
+		if b, parseErr := strconv.ParseBool(v); parseErr == nil {
+			compression = b
+		}
+	}
+	compressionMin := 1024
+	if v := os.Getenv("COMPRESSION_MIN_SIZE"); v != "" {
+		if n, parseErr := strconv.Atoi(v); parseErr == nil && n >= 0 {
+			compressionMin = n
+		}
+	}
+
+	// 10) LIST DEFAULTS (optional, rejected at startup when unknown)
+	commentOrder := CommentOrderOldest
+	if v := os.Getenv("DEFAULT_COMMENT_ORDER"); v != "" {
+		if !ValidCommentOrder(v) {
+			return nil, fmt.Errorf("DEFAULT_COMMENT_ORDER must be %q or %q, got %q", CommentOrderOldest, CommentOrderNewest, v)
+		}
+		commentOrder = v
+	}
+	discussionSort := DiscussionSortRecent
+	if v := os.Getenv("DEFAULT_DISCUSSION_SORT"); v != "" {
+		if !ValidDiscussionSort(v) {
+			return nil, fmt.Errorf("DEFAULT_DISCUSSION_SORT must be one of %s, got %q", strings.Join(DiscussionSorts, ", "), v)
+		}
+		discussionSort = v
+	}
+	maxPageOffset := 10000
+	if v := os.Getenv("MAX_PAGE_OFFSET"); v != "" {
+		if n, parseErr := strconv.Atoi(v); parseErr == nil && n >= 0 {
+			maxPageOffset = n
+		}
+	}
+
+	// 11) ACCOUNT DELETION (optional, rejected at startup when unknown)
+	deletedContent := DeletedContentCascade
+	if v := os.Getenv("DELETED_USER_CONTENT"); v != "" {
+		if !ValidDeletedContentMode(v) {
+			return nil, fmt.Errorf("DELETED_USER_CONTENT must be %q or %q, got %q", DeletedContentCascade, DeletedContentReassign, v)
+		}
+		deletedContent = v
+	}
+
+	// 12) FEATURES (optional, all on by default, rejected at startup when not a boolean)
+	features, err := featureflags.FromEnv(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	// 13) PASSWORD RESET (optional with sensible defaults)
+	resetTTL, err := time.ParseDuration(os.Getenv("PASSWORD_RESET_TTL"))
+	if err != nil || resetTTL <= 0 {
+		resetTTL = time.Hour
+	}
+
+	// 14) STALE DISCUSSIONS (optional, sweep disabled by default)
+	var staleAge time.Duration
+	if v := os.Getenv("STALE_DISCUSSION_AGE"); v != "" {
+		if d, parseErr := ParseAge(v); parseErr == nil && d >= 0 {
+			staleAge = d
+		}
+	}
+	staleInterval, err := time.ParseDuration(os.Getenv("STALE_CHECK_INTERVAL"))
+	if err != nil || staleInterval <= 0 {
+		staleInterval = time.Hour
+	}
+
+	// 15) MAIL RATE (optional, unlimited by default)
+	var mailRate float64
+	if v := os.Getenv("MAIL_RATE_PER_SECOND"); v != "" {
+		if r, parseErr := strconv.ParseFloat(v, 64); parseErr == nil && r >= 0 {
+			mailRate = r
+		}
+	}
+	mailBurst, err := strconv.Atoi(os.Getenv("MAIL_RATE_BURST"))
+	if err != nil || mailBurst < 1 {
+		mailBurst = 1
+	}
+
+	// 16) NOTIFY JOBS
+	notifyTimeout, err := time.ParseDuration(os.Getenv("NOTIFY_JOB_TIMEOUT"))
+	if err != nil || notifyTimeout <= 0 {
+		notifyTimeout = 10 * time.Minute
+	}
+	notifyMaxRecipients := 1000
+	if v := os.Getenv("NOTIFY_MAX_RECIPIENTS"); v != "" {
+		if n, parseErr := strconv.Atoi(v); parseErr == nil && n >= 0 {
+			notifyMaxRecipients = n
+		}
+	}
+
+	// 17) TAG LIMITS (optional; the per-tag cap is off by default)
+	maxTagsPerDiscussion := 10
+	if v := os.Getenv("MAX_TAGS_PER_DISCUSSION"); v != "" {
+		if n, parseErr := strconv.Atoi(v); parseErr == nil && n >= 0 {
+			maxTagsPerDiscussion = n
+		}
+	}
+	var maxDiscussionsPerTag int
+	if v := os.Getenv("MAX_DISCUSSIONS_PER_TAG"); v != "" {
+		if n, parseErr := strconv.Atoi(v); parseErr == nil && n >= 0 {
+			maxDiscussionsPerTag = n
+		}
+	}
+
+	// 18) HEALTH (optional; SMTP is not checked unless asked for)
+	healthSMTP := HealthSMTPOff
+	if v := os.Getenv("HEALTH_CHECK_SMTP"); v != "" {
+		if !ValidHealthSMTPMode(v) {
+			return nil, fmt.Errorf("HEALTH_CHECK_SMTP must be %q, %q or %q, got %q", HealthSMTPOff, HealthSMTPOptional, HealthSMTPRequired, v)
+		}
+		if v != HealthSMTPOff && (smtpHost == "" || smtpPort == "") {
+			return nil, fmt.Errorf("HEALTH_CHECK_SMTP=%s needs SMTP_HOST and SMTP_PORT", v)
+		}
+		healthSMTP = v
+	}
+	healthSMTPTimeout, err := time.ParseDuration(os.Getenv("HEALTH_CHECK_SMTP_TIMEOUT"))
+	if err != nil || healthSMTPTimeout <= 0 {
+		healthSMTPTimeout = 2 * time.Second
+	}
+
+	// 19) GATEWAY (optional, off by default; when on, the trusted proxies are required)
+	var trustGateway bool
+	if v := os.Getenv("TRUST_GATEWAY_HEADERS"); v != "" {
+		b, parseErr := strconv.ParseBool(v)
+		if parseErr != nil {
+			return nil, fmt.Errorf("TRUST_GATEWAY_HEADERS must be a boolean, got %q", v)
+		}
+		trustGateway = b
+	}
+	gatewayHeader := os.Getenv("GATEWAY_USER_HEADER")
+	if gatewayHeader == "" {
+		gatewayHeader = "X-User-ID"
+	}
+	gatewayProxies, err := ParseTrustedProxies(os.Getenv("GATEWAY_TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, fmt.Errorf("GATEWAY_TRUSTED_PROXIES: %w", err)
+	}
+	if trustGateway && len(gatewayProxies) == 0 {
+		return nil, fmt.Errorf("TRUST_GATEWAY_HEADERS=true needs GATEWAY_TRUSTED_PROXIES")
+	}
+
+	// 20) LANGUAGES (optional; codes must be two lower-case letters)
+	languages := DefaultDiscussionLanguages
+	if v := os.Getenv("DISCUSSION_LANGUAGES"); v != "" {
+		languages = nil
+		for _, code := range strings.Split(v, ",") {
+			code = strings.ToLower(strings.TrimSpace(code))
+			if code == "" {
+				continue
+			}
+			if !isLanguageCode(code) {
+				return nil, fmt.Errorf("DISCUSSION_LANGUAGES: %q is not a two-letter ISO 639-1 code", code)
+			}
+			languages = append(languages, code)
+		}
+	}
+
 	cfg := &Config{
 		Port:           port,
 		ReadTimeout:    readTO,
 		WriteTimeout:   writeTO,
 		ShutdownPeriod: shutdownPeriod,
+		MaxConcurrentRequests: maxConcurrent,
+		BaseURL:        baseURL,
 
 		DBHost:     dbHost,
 		DBPort:     dbPort,
@@ -134,17 +570,135 @@ func LoadConfig() (*Config, error) {
 		DBSSLMode:  dbSSL,
 
 		JWTSecret:     jwtSecret,
-		JWTExpiryMins: jwtExpiry,
+		JWTExpiryMins:        jwtExpiry,
+		JWTRefreshExpiryMins: jwtRefreshExpiry,
+
+		TrustGatewayHeaders:   trustGateway,
+		GatewayUserHeader:     gatewayHeader,
+		GatewayTrustedProxies: gatewayProxies,
 
 		SMTPHost:     smtpHost,
 		SMTPPort:     smtpPort,
 		SMTPUsername: smtpUser,
 		SMTPPassword: smtpPass,
 		FromEmail:    fromEmail,
+		MailRatePerSecond: mailRate,
+		MailRateBurst:     mailBurst,
 
 		LogLevel:  logLvl,
 		LogFormat: logFmt,
+
+		SlowQueryThreshold: slowQuery,
+
+		AllowedEmailDomains: allowedDomains,
+
+		DiscussionRateLimit:  discRateLimit,
+		DiscussionRateWindow: discRateWindow,
+		CommentCooldown:      commentCooldown,
+		LoginMaxFailures:     loginMaxFailures,
+		LoginFailureWindow:   loginFailureWindow,
+
+		MaxSubscriptionsPerEmail: maxSubs,
+		AnonSubscribeRateLimit:   anonSubLimit,
+		AnonSubscribeRateWindow:  anonSubWindow,
+		NotifyJobTimeout:         notifyTimeout,
+		NotifyMaxRecipients:      notifyMaxRecipients,
+
+		MaxTagsPerDiscussion: maxTagsPerDiscussion,
+		MaxDiscussionsPerTag: maxDiscussionsPerTag,
+
+		SearchMaxResults:     searchMax,
+		SearchMinQueryLength: searchMinLen,
+
+		DefaultCommentOrder:   commentOrder,
+		DefaultDiscussionSort: discussionSort,
+		MaxPageOffset:         maxPageOffset,
+
+		DeletedContentMode: deletedContent,
+
+		PasswordResetTTL: resetTTL,
+
+		StaleDiscussionAge: staleAge,
+		StaleCheckInterval: staleInterval,
+
+		HealthSMTPMode:    healthSMTP,
+		HealthSMTPTimeout: healthSMTPTimeout,
+
+		DiscussionLanguages: languages,
+
+		Features: features,
+
+		CompressionEnabled: compression,
+		CompressionMinSize: compressionMin,
 	}
 
 	return cfg, nil
 }
+
+// isLanguageCode reports whether s has the shape of an ISO 639-1 code.
+func isLanguageCode(s string) bool {
+	return len(s) == 2 && s[0] >= 'a' && s[0] <= 'z' && s[1] >= 'a' && s[1] <= 'z'
+}
+
+// LanguageAllowed reports whether code is in DiscussionLanguages.
+func (c *Config) LanguageAllowed(code string) bool {
+	for _, l := range c.DiscussionLanguages {
+		if l == code {
+			return true
+		}
+	}
+	return false
+}
+
+// redacted replaces secret values in SafeString output.
+const redacted = "[redacted]"
+
+// SMTPConfigured reports whether enough SMTP settings are present to send mail.
+func (c *Config) SMTPConfigured() bool {
+	return c.SMTPHost != "" && c.SMTPPort != "" && c.FromEmail != ""
+}
+
+// SafeString summarises the effective configuration for logging at startup.
+// Passwords and secrets are never included, only whether they are set.
+func (c *Config) SafeString() string {
+	secret := func(v string) string {
+		if v == "" {
+			return "<unset>"
+		}
+		return redacted
+	}
+	return fmt.Sprintf(
+		"port=%s base_url=%s read_timeout=%s write_timeout=%s max_concurrent_requests=%d "+
+			"db_host=%s db_port=%s db_name=%s db_user=%s db_password=%s db_sslmode=%s "+
+			"jwt_secret=%s jwt_expiry_mins=%d jwt_refresh_expiry_mins=%d "+
+			"trust_gateway_headers=%t gateway_user_header=%s gateway_trusted_proxies=%v "+
+			"smtp_configured=%t smtp_host=%s smtp_password=%s mail_rate_per_second=%g mail_rate_burst=%d "+
+			"log_level=%s log_format=%s slow_query_threshold=%s "+
+			"allowed_email_domains=%v "+
+			"discussion_rate_limit=%d discussion_rate_window=%s comment_cooldown=%s "+
+			"login_max_failures=%d login_failure_window=%s max_subscriptions_per_email=%d "+
+			"anon_subscribe_rate_limit=%d anon_subscribe_rate_window=%s notify_job_timeout=%s notify_max_recipients=%d "+
+			"max_tags_per_discussion=%d max_discussions_per_tag=%d "+
+			"search_max_results=%d search_min_query_length=%d "+
+			"default_comment_order=%s default_discussion_sort=%s max_page_offset=%d "+
+			"deleted_user_content=%s password_reset_ttl=%s stale_discussion_age=%s stale_check_interval=%s "+
+			"health_check_smtp=%s health_check_smtp_timeout=%s discussion_languages=%v disabled_features=%s "+
+			"compression_enabled=%t compression_min_size=%d",
+		c.Port, c.BaseURL, c.ReadTimeout, c.WriteTimeout, c.MaxConcurrentRequests,
+		c.DBHost, c.DBPort, c.DBName, c.DBUser, secret(c.DBPassword), c.DBSSLMode,
+		secret(c.JWTSecret), c.JWTExpiryMins, c.JWTRefreshExpiryMins,
+		c.TrustGatewayHeaders, c.GatewayUserHeader, c.GatewayTrustedProxies,
+		c.SMTPConfigured(), c.SMTPHost, secret(c.SMTPPassword), c.MailRatePerSecond, c.MailRateBurst,
+		c.LogLevel, c.LogFormat, c.SlowQueryThreshold,
+		c.AllowedEmailDomains,
+		c.DiscussionRateLimit, c.DiscussionRateWindow, c.CommentCooldown,
+		c.LoginMaxFailures, c.LoginFailureWindow, c.MaxSubscriptionsPerEmail,
+		c.AnonSubscribeRateLimit, c.AnonSubscribeRateWindow, c.NotifyJobTimeout, c.NotifyMaxRecipients,
+		c.MaxTagsPerDiscussion, c.MaxDiscussionsPerTag,
+		c.SearchMaxResults, c.SearchMinQueryLength,
+		c.DefaultCommentOrder, c.DefaultDiscussionSort, c.MaxPageOffset,
+		c.DeletedContentMode, c.PasswordResetTTL, c.StaleDiscussionAge, c.StaleCheckInterval,
+		c.HealthSMTPMode, c.HealthSMTPTimeout, c.DiscussionLanguages, c.Features,
+		c.CompressionEnabled, c.CompressionMinSize,
+	)
+}
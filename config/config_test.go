@@ -0,0 +1,497 @@
+package config
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/pkg/featureflags"
+)
+
+func TestSafeString_RedactsSecrets(t *testing.T) {
+	cfg := &Config{
+		Port:         "8080",
+		DBHost:       "db.internal",
+		DBName:       "discussions",
+		DBUser:       "app",
+		DBPassword:   "db-pass-123",
+		JWTSecret:    "jwt-secret-456",
+		SMTPHost:     "smtp.example.com",
+		SMTPPort:     "587",
+		SMTPUsername: "mailer",
+		SMTPPassword: "smtp-pass-789",
+		FromEmail:    "noreply@example.com",
+		LogLevel:     "info",
+
+		DiscussionRateWindow: time.Hour,
+	}
+
+	s := cfg.SafeString()
+	for _, secret := range []string{"db-pass-123", "jwt-secret-456", "smtp-pass-789"} {
+		assert.NotContains(t, s, secret)
+	}
+	assert.Equal(t, 3, strings.Count(s, redacted))
+	assert.Contains(t, s, "port=8080")
+	assert.Contains(t, s, "db_host=db.internal")
+	assert.Contains(t, s, "db_name=discussions")
+	assert.Contains(t, s, "log_level=info")
+	assert.Contains(t, s, "smtp_configured=true")
+}
+
+func TestSafeString_UnsetSecrets(t *testing.T) {
+	s := (&Config{}).SafeString()
+	assert.NotContains(t, s, redacted)
+	assert.Contains(t, s, "jwt_secret=<unset>")
+	assert.Contains(t, s, "smtp_configured=false")
+}
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_PORT", "5432")
+	t.Setenv("DB_USER", "app")
+	t.Setenv("DB_NAME", "discussions")
+}
+
+func TestLoadConfig_RejectsShortJWTSecret(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", "abc")
+
+	cfg, err := LoadConfig()
+	assert.Nil(t, cfg)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "at least 32 bytes")
+	}
+}
+
+func TestLoadConfig_AcceptsLongJWTSecret(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("x", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	if assert.NotNil(t, cfg) {
+		assert.Len(t, cfg.JWTSecret, MinJWTSecretLength)
+	}
+}
+
+func TestLoadConfig_AllowedEmailDomains(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+	t.Setenv("ALLOWED_EMAIL_DOMAINS", " Corp.Example, ,partner.example ")
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"corp.example", "partner.example"}, cfg.AllowedEmailDomains)
+
+	t.Setenv("ALLOWED_EMAIL_DOMAINS", "")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.AllowedEmailDomains)
+}
+
+func TestLoadConfig_Compression(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.CompressionEnabled)
+	assert.Equal(t, 1024, cfg.CompressionMinSize)
+
+	t.Setenv("COMPRESSION_ENABLED", "false")
+	t.Setenv("COMPRESSION_MIN_SIZE", "4096")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.False(t, cfg.CompressionEnabled)
+	assert.Equal(t, 4096, cfg.CompressionMinSize)
+}
+
+func TestLoadConfig_SearchLimits(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 50, cfg.SearchMaxResults)
+	assert.Equal(t, 2, cfg.SearchMinQueryLength)
+
+	t.Setenv("SEARCH_MAX_RESULTS", "10")
+	t.Setenv("SEARCH_MIN_QUERY_LENGTH", "0") // ignored, must be positive
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, cfg.SearchMaxResults)
+	assert.Equal(t, 2, cfg.SearchMinQueryLength)
+}
+
+func TestLoadConfig_ListDefaults(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, CommentOrderOldest, cfg.DefaultCommentOrder)
+	assert.Equal(t, DiscussionSortRecent, cfg.DefaultDiscussionSort)
+
+	t.Setenv("DEFAULT_COMMENT_ORDER", "newest")
+	t.Setenv("DEFAULT_DISCUSSION_SORT", "popular")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, CommentOrderNewest, cfg.DefaultCommentOrder)
+	assert.Equal(t, DiscussionSortPopular, cfg.DefaultDiscussionSort)
+
+	t.Setenv("DEFAULT_DISCUSSION_SORT", "hot")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "DEFAULT_DISCUSSION_SORT")
+}
+
+func TestLoadConfig_DeletedContentMode(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, DeletedContentCascade, cfg.DeletedContentMode)
+
+	t.Setenv("DELETED_USER_CONTENT", "reassign")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, DeletedContentReassign, cfg.DeletedContentMode)
+
+	t.Setenv("DELETED_USER_CONTENT", "archive")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "DELETED_USER_CONTENT")
+}
+
+func TestLoadConfig_AnonSubscribeRateLimit(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, cfg.AnonSubscribeRateLimit)
+	assert.Equal(t, time.Hour, cfg.AnonSubscribeRateWindow)
+
+	t.Setenv("ANON_SUBSCRIBE_RATE_LIMIT", "0")
+	t.Setenv("ANON_SUBSCRIBE_RATE_WINDOW", "10m")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cfg.AnonSubscribeRateLimit)
+	assert.Equal(t, 10*time.Minute, cfg.AnonSubscribeRateWindow)
+}
+
+func TestLoadConfig_FeatureFlags(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.Features.Enabled(featureflags.Search))
+
+	t.Setenv("FEATURE_SEARCH", "false")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.False(t, cfg.Features.Enabled(featureflags.Search))
+	assert.True(t, cfg.Features.Enabled(featureflags.Activity))
+	assert.Contains(t, cfg.SafeString(), "disabled_features=search")
+
+	t.Setenv("FEATURE_SEARCH", "nope")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "FEATURE_SEARCH")
+}
+
+func TestLoadConfig_PasswordResetTTL(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, cfg.PasswordResetTTL)
+
+	t.Setenv("PASSWORD_RESET_TTL", "15m")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, cfg.PasswordResetTTL)
+}
+
+func TestLoadConfig_StaleDiscussions(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), cfg.StaleDiscussionAge)
+	assert.Equal(t, time.Hour, cfg.StaleCheckInterval)
+
+	t.Setenv("STALE_DISCUSSION_AGE", "90d")
+	t.Setenv("STALE_CHECK_INTERVAL", "6h")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 90*24*time.Hour, cfg.StaleDiscussionAge)
+	assert.Equal(t, 6*time.Hour, cfg.StaleCheckInterval)
+}
+
+func TestLoadConfig_MailRate(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, cfg.MailRatePerSecond)
+	assert.Equal(t, 1, cfg.MailRateBurst)
+
+	t.Setenv("MAIL_RATE_PER_SECOND", "2.5")
+	t.Setenv("MAIL_RATE_BURST", "10")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 2.5, cfg.MailRatePerSecond)
+	assert.Equal(t, 10, cfg.MailRateBurst)
+
+	t.Setenv("MAIL_RATE_PER_SECOND", "-1")
+	t.Setenv("MAIL_RATE_BURST", "0")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, cfg.MailRatePerSecond)
+	assert.Equal(t, 1, cfg.MailRateBurst)
+}
+
+func TestLoadConfig_NotifyJobTimeout(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, cfg.NotifyJobTimeout)
+
+	t.Setenv("NOTIFY_JOB_TIMEOUT", "90s")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, cfg.NotifyJobTimeout)
+}
+
+func TestLoadConfig_TagLimits(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, cfg.MaxTagsPerDiscussion)
+	assert.Equal(t, 0, cfg.MaxDiscussionsPerTag)
+
+	t.Setenv("MAX_TAGS_PER_DISCUSSION", "0")
+	t.Setenv("MAX_DISCUSSIONS_PER_TAG", "500")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cfg.MaxTagsPerDiscussion)
+	assert.Equal(t, 500, cfg.MaxDiscussionsPerTag)
+
+	// garbage falls back to the defaults
+	t.Setenv("MAX_TAGS_PER_DISCUSSION", "-1")
+	t.Setenv("MAX_DISCUSSIONS_PER_TAG", "lots")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, cfg.MaxTagsPerDiscussion)
+	assert.Equal(t, 0, cfg.MaxDiscussionsPerTag)
+}
+
+func TestParseAge(t *testing.T) {
+	d, err := ParseAge("30d")
+	assert.NoError(t, err)
+	assert.Equal(t, 30*24*time.Hour, d)
+
+	d, err = ParseAge("36h")
+	assert.NoError(t, err)
+	assert.Equal(t, 36*time.Hour, d)
+
+	for _, bad := range []string{"", "d", "1.5d", "soon"} {
+		_, err = ParseAge(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_MaxConcurrentRequests(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cfg.MaxConcurrentRequests)
+
+	t.Setenv("MAX_CONCURRENT_REQUESTS", "64")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 64, cfg.MaxConcurrentRequests)
+}
+
+func TestLoadConfig_LoginLimit(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, cfg.LoginMaxFailures)
+	assert.Equal(t, 15*time.Minute, cfg.LoginFailureWindow)
+
+	t.Setenv("LOGIN_MAX_FAILURES", "0")
+	t.Setenv("LOGIN_FAILURE_WINDOW", "1h")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cfg.LoginMaxFailures)
+	assert.Equal(t, time.Hour, cfg.LoginFailureWindow)
+
+	// garbage falls back to the defaults
+	t.Setenv("LOGIN_MAX_FAILURES", "many")
+	t.Setenv("LOGIN_FAILURE_WINDOW", "-5m")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, cfg.LoginMaxFailures)
+	assert.Equal(t, 15*time.Minute, cfg.LoginFailureWindow)
+}
+
+func TestLoadConfig_NotifyMaxRecipients(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, cfg.NotifyMaxRecipients)
+
+	t.Setenv("NOTIFY_MAX_RECIPIENTS", "250")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 250, cfg.NotifyMaxRecipients)
+
+	t.Setenv("NOTIFY_MAX_RECIPIENTS", "0")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cfg.NotifyMaxRecipients)
+
+	t.Setenv("NOTIFY_MAX_RECIPIENTS", "-3")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, cfg.NotifyMaxRecipients)
+}
+
+func TestLoadConfig_HealthCheckSMTP(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+	t.Setenv("SMTP_HOST", "")
+	t.Setenv("SMTP_PORT", "")
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, HealthSMTPOff, cfg.HealthSMTPMode)
+	assert.Equal(t, 2*time.Second, cfg.HealthSMTPTimeout)
+
+	// checking a server that was never configured is a startup error
+	t.Setenv("HEALTH_CHECK_SMTP", HealthSMTPRequired)
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "SMTP_HOST")
+
+	t.Setenv("SMTP_HOST", "mail.example.com")
+	t.Setenv("SMTP_PORT", "587")
+	t.Setenv("HEALTH_CHECK_SMTP_TIMEOUT", "500ms")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, HealthSMTPRequired, cfg.HealthSMTPMode)
+	assert.Equal(t, 500*time.Millisecond, cfg.HealthSMTPTimeout)
+
+	t.Setenv("HEALTH_CHECK_SMTP", "true")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "HEALTH_CHECK_SMTP")
+}
+
+func TestLoadConfig_GatewayHeaders(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.False(t, cfg.TrustGatewayHeaders)
+	assert.Equal(t, "X-User-ID", cfg.GatewayUserHeader)
+
+	// trusting headers from anyone is refused
+	t.Setenv("TRUST_GATEWAY_HEADERS", "true")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "GATEWAY_TRUSTED_PROXIES")
+
+	t.Setenv("GATEWAY_TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.7")
+	t.Setenv("GATEWAY_USER_HEADER", "X-Auth-User")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.TrustGatewayHeaders)
+	assert.Equal(t, "X-Auth-User", cfg.GatewayUserHeader)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.7/32")}, cfg.GatewayTrustedProxies)
+
+	t.Setenv("GATEWAY_TRUSTED_PROXIES", "10.0.0.0/33")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "invalid CIDR")
+
+	t.Setenv("GATEWAY_TRUSTED_PROXIES", "10.0.0.1")
+	t.Setenv("TRUST_GATEWAY_HEADERS", "yes please")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "TRUST_GATEWAY_HEADERS")
+}
+
+func TestLoadConfig_BaseURL(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+	t.Setenv("SERVER_PORT", "9090")
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:9090", cfg.BaseURL)
+
+	t.Setenv("APP_BASE_URL", "https://forum.example.com/")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://forum.example.com", cfg.BaseURL)
+}
+
+func TestLoadConfig_MaxPageOffset(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 10000, cfg.MaxPageOffset)
+
+	t.Setenv("MAX_PAGE_OFFSET", "500")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 500, cfg.MaxPageOffset)
+
+	t.Setenv("MAX_PAGE_OFFSET", "0")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cfg.MaxPageOffset)
+
+	t.Setenv("MAX_PAGE_OFFSET", "-1") // ignored
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 10000, cfg.MaxPageOffset)
+}
+
+func TestLoadConfig_DiscussionLanguages(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretLength))
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultDiscussionLanguages, cfg.DiscussionLanguages)
+	assert.True(t, cfg.LanguageAllowed("en"))
+
+	t.Setenv("DISCUSSION_LANGUAGES", " EN, de ,,fr")
+	cfg, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"en", "de", "fr"}, cfg.DiscussionLanguages)
+	assert.False(t, cfg.LanguageAllowed("ja"))
+
+	for _, v := range []string{"english", "e1", "e"} {
+		t.Setenv("DISCUSSION_LANGUAGES", v)
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, "DISCUSSION_LANGUAGES", v)
+	}
+}
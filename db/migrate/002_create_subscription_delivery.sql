@@ -0,0 +1,10 @@
+-- db/migrate/002_create_subscription_delivery.sql
+
+-- Tracks consecutive delivery failures per subscriber email (bounce tracking).
+-- Rows are cleared on the next successful delivery.
+CREATE TABLE IF NOT EXISTS subscription_delivery (
+    email           VARCHAR(255) PRIMARY KEY,
+    failure_count   INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    last_failed_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
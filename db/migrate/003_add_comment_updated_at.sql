@@ -0,0 +1,4 @@
+-- db/migrate/003_add_comment_updated_at.sql
+
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
@@ -0,0 +1,4 @@
+-- db/migrate/004_add_user_role.sql
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'member';
@@ -0,0 +1,16 @@
+-- db/migrate/005_create_discussion_revisions.sql
+
+-- One row per edit of a discussion, holding the state before and after the edit.
+CREATE TABLE IF NOT EXISTS discussion_revisions (
+    id                SERIAL PRIMARY KEY,
+    discussion_id     INTEGER NOT NULL REFERENCES discussions(id) ON DELETE CASCADE,
+    edited_by         INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    previous_title    VARCHAR(255) NOT NULL,
+    previous_content  TEXT NOT NULL,
+    title             VARCHAR(255) NOT NULL,
+    content           TEXT NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_discussion_revisions_edited_by
+    ON discussion_revisions(edited_by, created_at);
@@ -0,0 +1,5 @@
+-- db/migrate/007_add_user_is_banned.sql
+
+-- Banned users keep their data but can no longer use the API.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS is_banned BOOLEAN NOT NULL DEFAULT FALSE;
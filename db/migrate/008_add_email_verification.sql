@@ -0,0 +1,12 @@
+-- db/migrate/008_add_email_verification.sql
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+-- At most one pending verification token per user; only its hash is stored.
+CREATE TABLE IF NOT EXISTS email_verifications (
+    user_id     INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash  CHAR(64) NOT NULL UNIQUE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    sent_at     TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,9 @@
+-- db/migrate/009_add_discussion_status.sql
+
+-- Drafts are visible only to their author; archived discussions are kept but
+-- no longer listed by default. Existing rows become published.
+ALTER TABLE discussions
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published'
+        CHECK (status IN ('draft', 'published', 'archived'));
+
+CREATE INDEX IF NOT EXISTS idx_discussions_status ON discussions (status, created_at DESC);
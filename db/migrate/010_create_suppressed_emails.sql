@@ -0,0 +1,9 @@
+-- db/migrate/010_create_suppressed_emails.sql
+
+-- Emails that must never be subscribed or mailed again (complaints,
+-- compliance requests). Stored lower-cased.
+CREATE TABLE IF NOT EXISTS suppressed_emails (
+    email       VARCHAR(255) PRIMARY KEY,
+    reason      TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
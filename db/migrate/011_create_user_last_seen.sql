@@ -0,0 +1,8 @@
+-- db/migrate/011_create_user_last_seen.sql
+
+-- One row per user: when they last marked discussions as seen.
+-- GET /discussions?unseen=true lists what changed after seen_at.
+CREATE TABLE IF NOT EXISTS user_last_seen (
+    user_id  INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    seen_at  TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,14 @@
+-- db/migrate/012_create_audit_log.sql
+
+-- Append-only trail of security-sensitive actions (logins, bans, deletes, ...).
+-- actor_id is kept as a plain integer so entries survive the actor's deletion.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id          SERIAL PRIMARY KEY,
+    actor_id    INTEGER NOT NULL,
+    action      VARCHAR(64) NOT NULL,
+    target      VARCHAR(255) NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, created_at);
@@ -0,0 +1,8 @@
+-- db/migrate/013_add_deleted_user_placeholder.sql
+
+-- Placeholder account that receives a user's discussions and comments when
+-- DELETED_USER_CONTENT=reassign. The password hash is not a valid bcrypt
+-- hash and the account is banned, so nobody can log in as it.
+INSERT INTO users (username, email, password_hash, full_name, role, is_banned)
+VALUES ('deleted-user', 'deleted-user@invalid', '!', 'Deleted user', 'member', TRUE)
+ON CONFLICT (username) DO NOTHING;
@@ -0,0 +1,20 @@
+-- db/migrate/014_add_subscription_confirmation.sql
+
+-- Anonymous subscriptions start unconfirmed and only receive notifications
+-- once the emailed confirm link is followed. Existing rows stay active.
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS confirmed BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Hash of the pending confirm token; cleared once confirmed.
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS confirm_token_hash CHAR(64) UNIQUE;
+
+-- One row per anonymous subscribe request, for per-IP rate limiting.
+CREATE TABLE IF NOT EXISTS anonymous_subscribe_requests (
+    id            SERIAL PRIMARY KEY,
+    ip            VARCHAR(45) NOT NULL,
+    requested_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_anonymous_subscribe_requests_ip
+    ON anonymous_subscribe_requests(ip, requested_at);
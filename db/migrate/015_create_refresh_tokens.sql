@@ -0,0 +1,13 @@
+-- db/migrate/015_create_refresh_tokens.sql
+
+-- Refresh tokens issued at login. token_id is the token's jti claim; deleting
+-- the row (on logout) revokes the token even before it expires.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id          SERIAL PRIMARY KEY,
+    user_id     INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_id    CHAR(32) NOT NULL UNIQUE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
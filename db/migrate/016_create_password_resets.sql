@@ -0,0 +1,11 @@
+-- db/migrate/016_create_password_resets.sql
+
+-- At most one pending password-reset token per user; requesting another
+-- replaces it. Only the token's hash is stored and the row is deleted once
+-- the token is used.
+CREATE TABLE IF NOT EXISTS password_resets (
+    user_id     INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash  CHAR(64) NOT NULL UNIQUE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
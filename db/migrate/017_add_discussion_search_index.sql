@@ -0,0 +1,6 @@
+-- db/migrate/017_add_discussion_search_index.sql
+
+-- Backs GET /discussions/search. The expression must match searchDocument in
+-- internal/discussion/repository.go or the planner will not use the index.
+CREATE INDEX IF NOT EXISTS idx_discussions_search
+    ON discussions USING GIN (to_tsvector('english', title || ' ' || content));
@@ -0,0 +1,8 @@
+-- db/migrate/018_add_comment_parent.sql
+
+-- A reply points at the comment it answers; top-level comments have no parent.
+-- Deleting a comment keeps its replies, which move up to the top level.
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES comments(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id);
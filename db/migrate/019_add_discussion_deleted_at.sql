@@ -0,0 +1,7 @@
+-- db/migrate/019_add_discussion_deleted_at.sql
+
+-- DELETE /discussions/:id now soft-deletes by setting deleted_at, so posts and
+-- their comments survive and an admin can restore them. Reads filter on
+-- deleted_at IS NULL.
+ALTER TABLE discussions
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
@@ -0,0 +1,5 @@
+-- db/migrate/020_add_discussion_view_count.sql
+
+-- Incremented each time GET /discussions/:id serves the discussion.
+ALTER TABLE discussions
+    ADD COLUMN IF NOT EXISTS view_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+-- db/migrate/021_add_comment_deleted_at.sql
+
+-- DELETE /users/:id/comments soft-deletes a user's comments by setting
+-- deleted_at. Comment reads filter on deleted_at IS NULL.
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
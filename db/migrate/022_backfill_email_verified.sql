@@ -0,0 +1,7 @@
+-- db/migrate/022_backfill_email_verified.sql
+
+-- Login now requires a verified email. Accounts that existed before that
+-- rule are treated as verified so they are not locked out.
+UPDATE users
+SET email_verified_at = created_at
+WHERE email_verified_at IS NULL;
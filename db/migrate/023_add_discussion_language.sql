@@ -0,0 +1,9 @@
+-- db/migrate/023_add_discussion_language.sql
+
+-- ISO 639-1 code of the language a discussion is written in; '' when the
+-- author did not say. GET /discussions?lang= filters on it.
+ALTER TABLE discussions
+    ADD COLUMN IF NOT EXISTS language VARCHAR(2) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_discussions_language
+    ON discussions (language) WHERE language <> '';
@@ -0,0 +1,13 @@
+-- db/migrate/024_create_tag_subscriptions.sql
+
+-- Follows of a tag. Attaching the tag to a discussion subscribes each
+-- follower to that discussion, so notifications reach them through the
+-- ordinary subscriptions table.
+CREATE TABLE IF NOT EXISTS tag_subscriptions (
+    id              SERIAL PRIMARY KEY,
+    tag_id          INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    user_id         INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    email           VARCHAR(255) NOT NULL,
+    subscribed_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tag_id, email)
+);
@@ -0,0 +1,9 @@
+-- db/migrate/025_add_edited_at.sql
+
+-- When a discussion's title or content, or a comment's content, was last
+-- changed by an edit; NULL if it never was. Clients show "(edited)" from it.
+ALTER TABLE discussions
+    ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
+
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
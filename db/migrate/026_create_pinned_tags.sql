@@ -0,0 +1,13 @@
+-- db/migrate/026_create_pinned_tags.sql
+
+-- Discussions a moderator pinned within one tag's listing. A pin hangs off
+-- the discussion's tag row, so removing the tag from the discussion (or
+-- deleting either) drops the pin with it.
+CREATE TABLE IF NOT EXISTS pinned_tags (
+    discussion_id   INTEGER NOT NULL,
+    tag_id          INTEGER NOT NULL,
+    pinned_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tag_id, discussion_id),
+    FOREIGN KEY (discussion_id, tag_id)
+        REFERENCES discussion_tags (discussion_id, tag_id) ON DELETE CASCADE
+);
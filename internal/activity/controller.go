@@ -0,0 +1,45 @@
+// controller.go
+package activity
+
+import (
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+    "go-discussion-app/internal/auth"
+    "go-discussion-app/pkg/httperr"
+    "go-discussion-app/pkg/logger"
+    "go-discussion-app/pkg/pagination"
+)
+
+type Controller struct {
+    svc *Service
+}
+
+func NewController(svc *Service) *Controller {
+    return &Controller{svc: svc}
+}
+
+// GET /me/activity?limit=&offset=
+func (ctr *Controller) Feed(c *gin.Context) {
+    userID, ok := auth.GetUserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+        return
+    }
+    page, err := pagination.FromQuery(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    items, err := ctr.svc.Feed(c.Request.Context(), userID, page.Limit, page.Offset)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("activity feed error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load activity"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"data": items, "limit": page.Limit, "offset": page.Offset})
+}
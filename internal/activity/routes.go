@@ -0,0 +1,22 @@
+// routes.go
+package activity
+
+import (
+    "database/sql"
+
+    "github.com/gin-gonic/gin"
+    "go-discussion-app/config"
+    "go-discussion-app/internal/comment"
+    "go-discussion-app/internal/discussion"
+    "go-discussion-app/pkg/featureflags"
+)
+
+// RegisterRoutes mounts GET /me/activity under the protected group unless
+// FEATURE_ACTIVITY is off.
+func RegisterRoutes(rg *gin.RouterGroup, db *sql.DB, cfg *config.Config) {
+    if !cfg.Features.Enabled(featureflags.Activity) {
+        return
+    }
+    svc := NewService(discussion.NewRepository(db), comment.NewRepository(db))
+    rg.GET("/me/activity", NewController(svc).Feed)
+}
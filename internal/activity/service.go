@@ -0,0 +1,87 @@
+// service.go
+package activity
+
+import (
+    "context"
+    "sort"
+    "time"
+
+    "go-discussion-app/models"
+)
+
+// Item types in the feed.
+const (
+    TypeDiscussion = "discussion" // a discussion the user started
+    TypeComment    = "comment"    // a comment the user wrote
+    TypeReply      = "reply"      // someone else's comment on one of the user's discussions
+)
+
+// Item is one entry of GET /me/activity. Exactly one of Discussion and
+// Comment is set, depending on Type.
+type Item struct {
+    Type       string             `json:"type"`
+    At         time.Time          `json:"at"`
+    Discussion *models.Discussion `json:"discussion,omitempty"`
+    Comment    *models.Comment    `json:"comment,omitempty"`
+}
+
+// DiscussionSource is satisfied by discussion.Repository.
+type DiscussionSource interface {
+    ListRecentByUser(ctx context.Context, userID, limit int) ([]models.Discussion, error)
+}
+
+// CommentSource is satisfied by comment.Repository.
+type CommentSource interface {
+    ListRecentByUser(ctx context.Context, userID, limit int) ([]models.Comment, error)
+    ListRecentRepliesTo(ctx context.Context, ownerID, limit int) ([]models.Comment, error)
+}
+
+type Service struct {
+    discussions DiscussionSource
+    comments    CommentSource
+}
+
+func NewService(discussions DiscussionSource, comments CommentSource) *Service {
+    return &Service{discussions: discussions, comments: comments}
+}
+
+// Feed returns one page of userID's activity, newest first. Each source is
+// asked for its newest offset+limit rows, which is enough to fill the
+// requested window of the merged feed.
+func (s *Service) Feed(ctx context.Context, userID, limit, offset int) ([]Item, error) {
+    n := offset + limit
+
+    ds, err := s.discussions.ListRecentByUser(ctx, userID, n)
+    if err != nil {
+        return nil, err
+    }
+    cs, err := s.comments.ListRecentByUser(ctx, userID, n)
+    if err != nil {
+        return nil, err
+    }
+    rs, err := s.comments.ListRecentRepliesTo(ctx, userID, n)
+    if err != nil {
+        return nil, err
+    }
+
+    items := make([]Item, 0, len(ds)+len(cs)+len(rs))
+    for i := range ds {
+        items = append(items, Item{Type: TypeDiscussion, At: ds[i].CreatedAt, Discussion: &ds[i]})
+    }
+    for i := range cs {
+        items = append(items, Item{Type: TypeComment, At: cs[i].CreatedAt, Comment: &cs[i]})
+    }
+    for i := range rs {
+        items = append(items, Item{Type: TypeReply, At: rs[i].CreatedAt, Comment: &rs[i]})
+    }
+    sort.SliceStable(items, func(i, j int) bool { return items[i].At.After(items[j].At) })
+
+    if offset >= len(items) {
+        return []Item{}, nil
+    }
+    items = items[offset:]
+    if len(items) > limit {
+        items = items[:limit]
+    }
+    return items, nil
+}
@@ -0,0 +1,105 @@
+package activity
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/models"
+)
+
+type fakeDiscussions struct {
+	ds       []models.Discussion
+	gotLimit int
+}
+
+func (f *fakeDiscussions) ListRecentByUser(ctx context.Context, userID, limit int) ([]models.Discussion, error) {
+	f.gotLimit = limit
+	return f.ds, nil
+}
+
+type fakeComments struct {
+	own, replies []models.Comment
+}
+
+func (f *fakeComments) ListRecentByUser(ctx context.Context, userID, limit int) ([]models.Comment, error) {
+	return f.own, nil
+}
+
+func (f *fakeComments) ListRecentRepliesTo(ctx context.Context, ownerID, limit int) ([]models.Comment, error) {
+	return f.replies, nil
+}
+
+func newFixture() (*fakeDiscussions, *fakeComments) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(m int) time.Time { return base.Add(time.Duration(m) * time.Minute) }
+	ds := &fakeDiscussions{ds: []models.Discussion{
+		{ID: 1, UserID: 7, CreatedAt: at(50)},
+		{ID: 2, UserID: 7, CreatedAt: at(10)},
+	}}
+	cs := &fakeComments{
+		own:     []models.Comment{{ID: 10, UserID: 7, CreatedAt: at(40)}, {ID: 11, UserID: 7, CreatedAt: at(5)}},
+		replies: []models.Comment{{ID: 20, UserID: 8, DiscussionID: 1, CreatedAt: at(60)}, {ID: 21, UserID: 9, CreatedAt: at(20)}},
+	}
+	return ds, cs
+}
+
+func TestFeed_MergesSourcesNewestFirst(t *testing.T) {
+	ds, cs := newFixture()
+	svc := NewService(ds, cs)
+
+	items, err := svc.Feed(context.Background(), 7, 20, 0)
+	assert.NoError(t, err)
+	if !assert.Len(t, items, 6) {
+		return
+	}
+
+	var types []string
+	for i, it := range items {
+		types = append(types, it.Type)
+		if i > 0 {
+			assert.False(t, it.At.After(items[i-1].At), "items must be newest first")
+		}
+	}
+	assert.Equal(t, []string{TypeReply, TypeDiscussion, TypeComment, TypeReply, TypeDiscussion, TypeComment}, types)
+	assert.Equal(t, 20, items[0].Comment.ID)
+	assert.Equal(t, 1, items[1].Discussion.ID)
+	assert.Nil(t, items[1].Comment)
+	assert.Equal(t, 20, ds.gotLimit)
+}
+
+func TestFeed_Paginates(t *testing.T) {
+	ds, cs := newFixture()
+	svc := NewService(ds, cs)
+
+	items, err := svc.Feed(context.Background(), 7, 2, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, 10, items[0].Comment.ID)
+		assert.Equal(t, 21, items[1].Comment.ID)
+	}
+	// each source must cover offset+limit rows
+	assert.Equal(t, 4, ds.gotLimit)
+
+	items, err = svc.Feed(context.Background(), 7, 20, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+	assert.NotNil(t, items)
+}
+
+func TestFeedHandler_RequiresUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ds, cs := newFixture()
+	router := gin.New()
+	router.GET("/me/activity", NewController(NewService(ds, cs)).Feed)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/me/activity", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,61 @@
+// audit.go
+package audit
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "go-discussion-app/models"
+    "go-discussion-app/pkg/logger"
+)
+
+// Actions recorded in the audit log.
+const (
+    ActionLogin             = "login"
+    ActionUserBan           = "user.ban"
+    ActionUserUnban         = "user.unban"
+    ActionUserDelete        = "user.delete"
+    ActionDiscussionDelete  = "discussion.delete"
+    ActionDiscussionRestore = "discussion.restore"
+)
+
+// Filter narrows List. Zero values match everything.
+type Filter struct {
+    ActorID int
+    Action  string
+}
+
+// Store persists audit entries.
+type Store interface {
+    Insert(ctx context.Context, e *models.AuditEntry) error
+    List(ctx context.Context, f Filter, limit, offset int) ([]models.AuditEntry, error)
+}
+
+// store receives every Record call; nil (the default) discards them.
+var store Store
+
+// SetStore installs the Store used by Record and returns the previous one.
+// main.go wires the database-backed store at startup.
+func SetStore(s Store) Store {
+    prev := store
+    store = s
+    return prev
+}
+
+// Record appends an entry to the audit log. Failures are logged rather than
+// returned so that auditing never blocks the action being audited.
+func Record(ctx context.Context, actorID int, action, target string) {
+    if store == nil {
+        return
+    }
+    e := &models.AuditEntry{ActorID: actorID, Action: action, Target: target, CreatedAt: time.Now().UTC()}
+    if err := store.Insert(ctx, e); err != nil {
+        logger.Errorf("audit record %s by %d on %s failed: %v", action, actorID, target, err)
+    }
+}
+
+// Target formats a target reference such as "user:42".
+func Target(kind string, id int) string {
+    return fmt.Sprintf("%s:%d", kind, id)
+}
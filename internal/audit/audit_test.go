@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/models"
+)
+
+type recordingStore struct {
+	entries []models.AuditEntry
+	err     error
+}
+
+func (s *recordingStore) Insert(ctx context.Context, e *models.AuditEntry) error {
+	s.entries = append(s.entries, *e)
+	return s.err
+}
+
+func (s *recordingStore) List(ctx context.Context, f Filter, limit, offset int) ([]models.AuditEntry, error) {
+	return s.entries, s.err
+}
+
+func TestRecord_WritesToStore(t *testing.T) {
+	rs := &recordingStore{}
+	prev := SetStore(rs)
+	t.Cleanup(func() { SetStore(prev) })
+
+	Record(context.Background(), 3, ActionUserBan, Target("user", 9))
+
+	if assert.Len(t, rs.entries, 1) {
+		e := rs.entries[0]
+		assert.Equal(t, 3, e.ActorID)
+		assert.Equal(t, "user.ban", e.Action)
+		assert.Equal(t, "user:9", e.Target)
+		assert.False(t, e.CreatedAt.IsZero())
+	}
+}
+
+func TestRecord_NoStoreOrFailingStoreIsHarmless(t *testing.T) {
+	prev := SetStore(nil)
+	t.Cleanup(func() { SetStore(prev) })
+	Record(context.Background(), 1, ActionLogin, "user:1")
+
+	SetStore(&recordingStore{err: errors.New("db down")})
+	Record(context.Background(), 1, ActionLogin, "user:1")
+}
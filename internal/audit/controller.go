@@ -0,0 +1,51 @@
+// controller.go
+package audit
+
+import (
+    "net/http"
+    "strconv"
+
+    "github.com/gin-gonic/gin"
+    "go-discussion-app/pkg/httperr"
+    "go-discussion-app/pkg/logger"
+    "go-discussion-app/pkg/pagination"
+)
+
+type Controller struct {
+    store Store
+}
+
+func NewController(store Store) *Controller {
+    return &Controller{store: store}
+}
+
+// GET /admin/audit?actor_id=&action=&limit=&offset= (admin only)
+func (ctr *Controller) List(c *gin.Context) {
+    var f Filter
+    if s := c.Query("actor_id"); s != "" {
+        id, err := strconv.Atoi(s)
+        if err != nil || id <= 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "actor_id must be a positive integer"})
+            return
+        }
+        f.ActorID = id
+    }
+    f.Action = c.Query("action")
+
+    page, err := pagination.FromQuery(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    entries, err := ctr.store.List(c.Request.Context(), f, page.Limit, page.Offset)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("list audit log error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"data": entries, "limit": page.Limit, "offset": page.Offset})
+}
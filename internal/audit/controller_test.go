@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/models"
+)
+
+type filterCapturingStore struct {
+	recordingStore
+	filter        Filter
+	limit, offset int
+}
+
+func (s *filterCapturingStore) List(ctx context.Context, f Filter, limit, offset int) ([]models.AuditEntry, error) {
+	s.filter, s.limit, s.offset = f, limit, offset
+	return []models.AuditEntry{}, nil
+}
+
+func performAuditRequest(store Store, path string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/audit", NewController(store).List)
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestListAudit_PassesFilters(t *testing.T) {
+	store := &filterCapturingStore{}
+
+	w := performAuditRequest(store, "/admin/audit?actor_id=4&action=login&limit=5&offset=10")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, Filter{ActorID: 4, Action: "login"}, store.filter)
+	assert.Equal(t, 5, store.limit)
+	assert.Equal(t, 10, store.offset)
+	assert.JSONEq(t, `{"data":[],"limit":5,"offset":10}`, w.Body.String())
+}
+
+func TestListAudit_InvalidActor(t *testing.T) {
+	for _, q := range []string{"?actor_id=abc", "?actor_id=-1", "?limit=x"} {
+		w := performAuditRequest(&filterCapturingStore{}, "/admin/audit"+q)
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+}
@@ -0,0 +1,54 @@
+// repository.go
+package audit
+
+import (
+    "context"
+    "database/sql"
+
+    "go-discussion-app/models"
+    "go-discussion-app/pkg/slowquery"
+)
+
+type repo struct {
+    db *slowquery.DB
+}
+
+// NewRepository constructs a Store backed by the audit_log table.
+func NewRepository(db *sql.DB) Store {
+    return &repo{db: slowquery.Wrap(db)}
+}
+
+func (r *repo) Insert(ctx context.Context, e *models.AuditEntry) error {
+    const q = `
+      INSERT INTO audit_log (actor_id, action, target, created_at)
+      VALUES ($1, $2, $3, $4)
+      RETURNING id;
+    `
+    return r.db.QueryRowContext(ctx, q, e.ActorID, e.Action, e.Target, e.CreatedAt).Scan(&e.ID)
+}
+
+// List returns entries matching f, newest first.
+func (r *repo) List(ctx context.Context, f Filter, limit, offset int) ([]models.AuditEntry, error) {
+    const q = `
+      SELECT id, actor_id, action, target, created_at
+      FROM audit_log
+      WHERE ($1 = 0 OR actor_id = $1) AND ($2 = '' OR action = $2)
+      ORDER BY created_at DESC, id DESC
+      LIMIT $3 OFFSET $4;
+    `
+    rows, err := r.db.QueryContext(ctx, q, f.ActorID, f.Action, limit, offset)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    entries := []models.AuditEntry{}
+    for rows.Next() {
+        var e models.AuditEntry
+        if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.Target, &e.CreatedAt); err != nil {
+            return nil, err
+        }
+        entries = append(entries, e)
+    }
+    return entries, rows.Err()
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/models"
+)
+
+func newMockRepo(t *testing.T) (Store, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewRepository(db), mock
+}
+
+func TestInsert_WritesAllFields(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`INSERT INTO audit_log \(actor_id, action, target, created_at\)`).
+		WithArgs(4, ActionDiscussionDelete, "discussion:7", at).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+
+	e := &models.AuditEntry{ActorID: 4, Action: ActionDiscussionDelete, Target: "discussion:7", CreatedAt: at}
+	assert.NoError(t, repo.Insert(context.Background(), e))
+	assert.Equal(t, 11, e.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestList_FiltersAndPages(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now()
+
+	mock.ExpectQuery(`WHERE \(\$1 = 0 OR actor_id = \$1\) AND \(\$2 = '' OR action = \$2\)\s+ORDER BY created_at DESC, id DESC\s+LIMIT \$3 OFFSET \$4`).
+		WithArgs(4, ActionLogin, 20, 40).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor_id", "action", "target", "created_at"}).
+			AddRow(2, 4, ActionLogin, "user:4", now))
+	mock.ExpectQuery(`FROM audit_log`).
+		WithArgs(0, "", 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor_id", "action", "target", "created_at"}))
+
+	entries, err := repo.List(context.Background(), Filter{ActorID: 4, Action: ActionLogin}, 20, 40)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	entries, err = repo.List(context.Background(), Filter{}, 20, 0)
+	assert.NoError(t, err)
+	assert.NotNil(t, entries)
+	assert.Empty(t, entries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,15 @@
+// routes.go
+package audit
+
+import (
+    "database/sql"
+
+    "github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes mounts GET /admin/audit. requireAdmin is passed in because
+// the auth package records logins here and so cannot be imported.
+func RegisterRoutes(rg *gin.RouterGroup, db *sql.DB, requireAdmin gin.HandlerFunc) {
+    ctr := NewController(NewRepository(db))
+    rg.GET("/admin/audit", requireAdmin, ctr.List)
+}
@@ -0,0 +1,66 @@
+// blacklist.go
+package auth
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+// TokenBlacklist records access tokens, by their jti claim, that were
+// revoked before they expire. Entries only need to outlive the token, so a
+// store with per-key TTLs such as Redis can stand in for the in-memory one.
+type TokenBlacklist interface {
+    // Add revokes jti until expiresAt.
+    Add(ctx context.Context, jti string, expiresAt time.Time) error
+    // Contains reports whether jti is revoked.
+    Contains(ctx context.Context, jti string) (bool, error)
+}
+
+// blacklist is checked by AuthMiddleware on every JWT and filled by logout.
+var blacklist TokenBlacklist = NewMemoryBlacklist()
+
+// SetBlacklist installs the TokenBlacklist used by AuthMiddleware and
+// Logout and returns the previous one. It is process-wide, like the
+// in-memory default, so every instance needs a shared store to honour a
+// logout made on another.
+func SetBlacklist(b TokenBlacklist) TokenBlacklist {
+    prev := blacklist
+    blacklist = b
+    return prev
+}
+
+// MemoryBlacklist is a TokenBlacklist held in process memory. Expired
+// entries are dropped as new ones are added.
+type MemoryBlacklist struct {
+    mu      sync.Mutex
+    entries map[string]time.Time
+
+    now func() time.Time
+}
+
+func NewMemoryBlacklist() *MemoryBlacklist {
+    return &MemoryBlacklist{entries: make(map[string]time.Time), now: time.Now}
+}
+
+func (b *MemoryBlacklist) Add(ctx context.Context, jti string, expiresAt time.Time) error {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    now := b.now()
+    for id, exp := range b.entries {
+        if !now.Before(exp) {
+            delete(b.entries, id)
+        }
+    }
+    if now.Before(expiresAt) {
+        b.entries[jti] = expiresAt
+    }
+    return nil
+}
+
+func (b *MemoryBlacklist) Contains(ctx context.Context, jti string) (bool, error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    exp, ok := b.entries[jti]
+    return ok && b.now().Before(exp), nil
+}
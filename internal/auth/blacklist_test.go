@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBlacklist_ForgetsExpiredTokens(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	b := NewMemoryBlacklist()
+	b.now = func() time.Time { return now }
+
+	assert.NoError(t, b.Add(ctx, "a", now.Add(time.Hour)))
+	assert.NoError(t, b.Add(ctx, "b", now.Add(time.Minute)))
+	assert.NoError(t, b.Add(ctx, "gone", now.Add(-time.Second))) // already expired: not stored
+
+	ok, err := b.Contains(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, _ = b.Contains(ctx, "gone")
+	assert.False(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	ok, _ = b.Contains(ctx, "b")
+	assert.False(t, ok, "the entry lapses with the token")
+
+	assert.NoError(t, b.Add(ctx, "c", now.Add(time.Hour)))
+	assert.Len(t, b.entries, 2, "expired entries are dropped on Add")
+}
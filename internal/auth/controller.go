@@ -2,9 +2,16 @@
 package auth
 
 import (
+    "errors"
+    "io"
+    "math"
     "net/http"
+    "strconv"
+    "strings"
 
     "github.com/gin-gonic/gin"
+    "go-discussion-app/internal/user"
+    "go-discussion-app/pkg/httperr"
     "go-discussion-app/pkg/logger"
 )
 
@@ -25,9 +32,13 @@ func (ctr *AuthController) RegisterHandler(c *gin.Context) {
     }
     id, err := ctr.svc.Register(c.Request.Context(), &dto)
     if err != nil {
-        if err == ErrUserExists {
+        if errors.Is(err, ErrValidation) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        } else if err == ErrUserExists {
             c.JSON(http.StatusConflict, gin.H{"error": "email already in use"})
-        } else {
+        } else if err == ErrEmailDomainBlocked {
+            c.JSON(http.StatusForbidden, gin.H{"error": "registration is not open to this email domain"})
+        } else if !httperr.Abort(c, err) {
             logger.Errorf("register error: %v", err)
             c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
         }
@@ -43,15 +54,305 @@ func (ctr *AuthController) LoginHandler(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
         return
     }
-    token, err := ctr.svc.Login(c.Request.Context(), &dto)
+    pair, err := ctr.svc.Login(c.Request.Context(), &dto, c.ClientIP())
     if err != nil {
-        if err == ErrInvalidCredentials {
+        var locked *LoginLockedError
+        if errors.Is(err, ErrValidation) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        } else if errors.As(err, &locked) {
+            c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+            c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed login attempts, try again later"})
+        } else if err == ErrInvalidCredentials {
             c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong email or password"})
-        } else {
+        } else if err == ErrAccountBanned {
+            c.JSON(http.StatusForbidden, gin.H{"error": "account is banned"})
+        } else if err == ErrEmailNotVerified {
+            c.JSON(http.StatusForbidden, gin.H{"error": "email address not verified, check your inbox"})
+        } else if !httperr.Abort(c, err) {
             logger.Errorf("login error: %v", err)
             c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
         }
         return
     }
+    c.JSON(http.StatusOK, pair)
+}
+
+// RefreshHandler handles POST /auth/refresh.
+func (ctr *AuthController) RefreshHandler(c *gin.Context) {
+    var dto RefreshDTO
+    if err := c.ShouldBindJSON(&dto); err != nil || dto.Validate() != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+        return
+    }
+    token, err := ctr.svc.Refresh(c.Request.Context(), dto.RefreshToken)
+    if err != nil {
+        if err == ErrInvalidRefreshToken {
+            c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+        } else if err == ErrAccountBanned {
+            c.JSON(http.StatusForbidden, gin.H{"error": "account is banned"})
+        } else if !httperr.Abort(c, err) {
+            logger.Errorf("refresh error: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        }
+        return
+    }
     c.JSON(http.StatusOK, gin.H{"token": token})
 }
+
+// LogoutHandler handles POST /auth/logout. The access token in the
+// Authorization header is blacklisted until it expires and the refresh_token
+// in the body is revoked; either may be left out, but not both.
+func (ctr *AuthController) LogoutHandler(c *gin.Context) {
+    var dto RefreshDTO
+    if err := c.ShouldBindJSON(&dto); err != nil && !errors.Is(err, io.EOF) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+        return
+    }
+    access := bearerToken(c)
+    if strings.HasPrefix(access, APITokenPrefix) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "API tokens are revoked with DELETE /auth/tokens/:id"})
+        return
+    }
+    if access == "" && dto.Validate() != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "a bearer access token or refresh_token is required"})
+        return
+    }
+
+    if access != "" {
+        if err := ctr.svc.RevokeAccessToken(c.Request.Context(), access); err != nil {
+            ctr.logoutError(c, err)
+            return
+        }
+    }
+    if dto.RefreshToken != "" {
+        if err := ctr.svc.Logout(c.Request.Context(), dto.RefreshToken); err != nil {
+            ctr.logoutError(c, err)
+            return
+        }
+    }
+    c.Status(http.StatusNoContent)
+}
+
+func (ctr *AuthController) logoutError(c *gin.Context, err error) {
+    if err == ErrInvalidRefreshToken || err == ErrInvalidAccessToken {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+    } else if !httperr.Abort(c, err) {
+        logger.Errorf("logout error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+    }
+}
+
+type TokenController struct {
+    svc *TokenService
+}
+
+func NewTokenController(svc *TokenService) *TokenController {
+    return &TokenController{svc: svc}
+}
+
+// Create handles POST /auth/tokens. The raw token is only ever returned here.
+func (ctr *TokenController) Create(c *gin.Context) {
+    userID, ok := GetUserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+        return
+    }
+    var dto CreateTokenDTO
+    if err := c.ShouldBindJSON(&dto); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+        return
+    }
+    if err := dto.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    t, raw, err := ctr.svc.Create(c.Request.Context(), userID, &dto)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("create api token error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        return
+    }
+    c.JSON(http.StatusCreated, gin.H{
+        "id":         t.ID,
+        "name":       t.Name,
+        "token":      raw,
+        "created_at": t.CreatedAt,
+    })
+}
+
+// List handles GET /auth/tokens.
+func (ctr *TokenController) List(c *gin.Context) {
+    userID, ok := GetUserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+        return
+    }
+    tokens, err := ctr.svc.List(c.Request.Context(), userID)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("list api tokens error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        return
+    }
+    c.JSON(http.StatusOK, tokens)
+}
+
+// Revoke handles DELETE /auth/tokens/:id.
+func (ctr *TokenController) Revoke(c *gin.Context) {
+    userID, ok := GetUserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+        return
+    }
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+        return
+    }
+    if err := ctr.svc.Revoke(c.Request.Context(), id, userID); err != nil {
+        if err == ErrTokenNotFound {
+            c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+        } else if !httperr.Abort(c, err) {
+            logger.Errorf("revoke api token error: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        }
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
+
+type VerificationController struct {
+    svc *VerificationService
+}
+
+func NewVerificationController(svc *VerificationService) *VerificationController {
+    return &VerificationController{svc: svc}
+}
+
+// Resend handles POST /auth/resend-verification for the current user.
+func (ctr *VerificationController) Resend(c *gin.Context) {
+    userID, ok := GetUserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+        return
+    }
+    if err := ctr.svc.Resend(c.Request.Context(), userID); err != nil {
+        switch err {
+        case ErrAlreadyVerified:
+            c.JSON(http.StatusBadRequest, gin.H{"error": "email already verified"})
+        case ErrVerificationResendTooSoon:
+            c.JSON(http.StatusTooManyRequests, gin.H{"error": "verification email sent recently, try again later"})
+        case user.ErrUserNotFound:
+            c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+        default:
+            if httperr.Abort(c, err) {
+                return
+            }
+            logger.Errorf("resend verification error: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        }
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "verification email sent"})
+}
+
+// ResendTo handles POST /auth/verify/resend for users who cannot log in
+// yet. The reply is the same whether or not a mail was sent.
+func (ctr *VerificationController) ResendTo(c *gin.Context) {
+    var dto ResendVerificationDTO
+    if err := c.ShouldBindJSON(&dto); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+        return
+    }
+    if err := dto.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := ctr.svc.ResendTo(c.Request.Context(), dto.Email); err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("resend verification error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "if that email is registered and unverified, a verification email has been sent"})
+}
+
+// Verify handles GET /auth/verify?token=...
+func (ctr *VerificationController) Verify(c *gin.Context) {
+    token := c.Query("token")
+    if token == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+        return
+    }
+    if err := ctr.svc.Verify(c.Request.Context(), token); err != nil {
+        if err == ErrInvalidVerificationToken {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired token"})
+        } else if !httperr.Abort(c, err) {
+            logger.Errorf("verify email error: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        }
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+type PasswordResetController struct {
+    svc *PasswordResetService
+}
+
+func NewPasswordResetController(svc *PasswordResetService) *PasswordResetController {
+    return &PasswordResetController{svc: svc}
+}
+
+// Forgot handles POST /auth/forgot-password. The reply is the same whether
+// or not the email is registered.
+func (ctr *PasswordResetController) Forgot(c *gin.Context) {
+    var dto ForgotPasswordDTO
+    if err := c.ShouldBindJSON(&dto); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+        return
+    }
+    if err := dto.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := ctr.svc.Request(c.Request.Context(), dto.Email); err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("forgot password error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "if that email is registered, a reset link has been sent"})
+}
+
+// Reset handles POST /auth/reset-password.
+func (ctr *PasswordResetController) Reset(c *gin.Context) {
+    var dto ResetPasswordDTO
+    if err := c.ShouldBindJSON(&dto); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+        return
+    }
+    if err := dto.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := ctr.svc.Reset(c.Request.Context(), &dto); err != nil {
+        if err == ErrInvalidResetToken {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired token"})
+        } else if !httperr.Abort(c, err) {
+            logger.Errorf("reset password error: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        }
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "password updated"})
+}
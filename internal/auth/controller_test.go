@@ -5,18 +5,23 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/crypto/bcrypt"
 
+	"go-discussion-app/config"
+	"go-discussion-app/internal/audit"
 	"go-discussion-app/internal/user"
 	"go-discussion-app/models"
 	"go-discussion-app/pkg/jwtutil"
@@ -65,11 +70,59 @@ func (m *MockUserRepository) Delete(ctx context.Context, id int) (sql.Result, er
 	return args.Get(0).(sql.Result), args.Error(1)
 }
 
+func (m *MockUserRepository) DeleteReassigningContent(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetBanned(ctx context.Context, id int, banned bool) (bool, error) {
+	args := m.Called(ctx, id, banned)
+	return args.Bool(0), args.Error(1)
+}
+
+// memRefreshRepo is an in-memory RefreshTokenRepository.
+type memRefreshRepo struct {
+	tokens map[string]memRefreshToken
+}
+
+type memRefreshToken struct {
+	userID    int
+	expiresAt time.Time
+}
+
+func newMemRefreshRepo() *memRefreshRepo {
+	return &memRefreshRepo{tokens: map[string]memRefreshToken{}}
+}
+
+func (r *memRefreshRepo) Create(ctx context.Context, userID int, tokenID string, expiresAt time.Time) error {
+	r.tokens[tokenID] = memRefreshToken{userID: userID, expiresAt: expiresAt}
+	return nil
+}
+
+func (r *memRefreshRepo) Active(ctx context.Context, tokenID string, userID int, now time.Time) (bool, error) {
+	t, ok := r.tokens[tokenID]
+	return ok && t.userID == userID && t.expiresAt.After(now), nil
+}
+
+func (r *memRefreshRepo) Delete(ctx context.Context, tokenID string) (bool, error) {
+	_, ok := r.tokens[tokenID]
+	delete(r.tokens, tokenID)
+	return ok, nil
+}
+
 // Helper function to set up the Gin router with controller routes
 func setupTestRouter(mockUserRepo user.UserRepository) *gin.Engine {
+	return setupTestRouterWithConfig(mockUserRepo, nil)
+}
+
+func setupTestRouterWithConfig(mockUserRepo user.UserRepository, cfg *config.Config) *gin.Engine {
+	return setupTestRouterWithRefresh(mockUserRepo, newMemRefreshRepo(), cfg)
+}
+
+func setupTestRouterWithRefresh(mockUserRepo user.UserRepository, refreshRepo RefreshTokenRepository, cfg *config.Config) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New() // Use gin.New() for a blank router in tests
-	authService := NewService(mockUserRepo)
+	authService := NewService(mockUserRepo, refreshRepo, nil, cfg)
 	authController := NewController(authService)
 
 	// Group for /auth routes
@@ -77,6 +130,8 @@ func setupTestRouter(mockUserRepo user.UserRepository) *gin.Engine {
 	{
 		authGroup.POST("/register", authController.RegisterHandler)
 		authGroup.POST("/login", authController.LoginHandler)
+		authGroup.POST("/refresh", authController.RefreshHandler)
+		authGroup.POST("/logout", authController.LogoutHandler)
 	}
 
 	// Dummy protected route for middleware testing
@@ -88,6 +143,9 @@ func setupTestRouter(mockUserRepo user.UserRepository) *gin.Engine {
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "welcome", "userID": userID})
 	})
+	router.GET("/admin", JWTAuthMiddleware(), RequireRole(models.RoleAdmin), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "welcome"})
+	})
 	return router
 }
 
@@ -108,6 +166,13 @@ func performRequest(r http.Handler, method, path string, body interface{}) *http
 	return w
 }
 
+// verifiedAt marks a fixture user as having confirmed their email, which
+// login requires.
+func verifiedAt() *time.Time {
+	t := time.Now().UTC().Add(-time.Hour)
+	return &t
+}
+
 func TestRegister_Success(t *testing.T) {
 	mockUserRepo := new(MockUserRepository)
 	router := setupTestRouter(mockUserRepo)
@@ -133,6 +198,61 @@ func TestRegister_Success(t *testing.T) {
 	mockUserRepo.AssertExpectations(t)
 }
 
+func TestRegister_PasswordTooLongForBcrypt(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouter(mockUserRepo)
+	dto := RegisterDTO{Username: "alice", Email: "alice@example.com", Password: "a1" + strings.Repeat("x", MaxPasswordBytes-1)}
+
+	w := performRequest(router, "POST", "/auth/register", dto)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "password must be at most 72 bytes")
+	mockUserRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestRegister_AllowedEmailDomains(t *testing.T) {
+	cfg := &config.Config{AllowedEmailDomains: []string{"corp.example", "partner.example"}}
+
+	t.Run("allowed domain", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		router := setupTestRouterWithConfig(mockUserRepo, cfg)
+		dto := RegisterDTO{Username: "alice", Email: "alice@Corp.Example", Password: "password123"}
+
+		// the domain check ignores case, and the address is stored lower-cased
+		mockUserRepo.On("GetByEmail", mock.Anything, "alice@corp.example").Return(nil, nil)
+		mockUserRepo.On("Create", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+			return u.Email == "alice@corp.example"
+		})).Return(7, nil)
+
+		w := performRequest(router, "POST", "/auth/register", dto)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("disallowed domain", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		router := setupTestRouterWithConfig(mockUserRepo, cfg)
+		dto := RegisterDTO{Username: "mallory", Email: "mallory@gmail.com", Password: "password123"}
+
+		w := performRequest(router, "POST", "/auth/register", dto)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockUserRepo.AssertNotCalled(t, "GetByEmail", mock.Anything, mock.Anything)
+		mockUserRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unset allows all", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		router := setupTestRouterWithConfig(mockUserRepo, &config.Config{})
+		dto := RegisterDTO{Username: "bob", Email: "bob@anywhere.example", Password: "password123"}
+
+		mockUserRepo.On("GetByEmail", mock.Anything, dto.Email).Return(nil, nil)
+		mockUserRepo.On("Create", mock.Anything, mock.Anything).Return(8, nil)
+
+		w := performRequest(router, "POST", "/auth/register", dto)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
 func TestRegister_UserExists(t *testing.T) {
 	mockUserRepo := new(MockUserRepository)
 	router := setupTestRouter(mockUserRepo)
@@ -156,6 +276,20 @@ func TestRegister_UserExists(t *testing.T) {
 	mockUserRepo.AssertExpectations(t)
 }
 
+func TestRegister_RepositoryErrorIsServerError(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouter(mockUserRepo)
+	registerDTO := RegisterDTO{Username: "dbdown", Email: "dbdown@example.com", Password: "password123"}
+
+	mockUserRepo.On("GetByEmail", mock.Anything, registerDTO.Email).Return(nil, assert.AnError)
+
+	w := performRequest(router, "POST", "/auth/register", registerDTO)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var response map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "server error", response["error"])
+}
+
 func TestRegister_InvalidInput_ServiceValidationFailure(t *testing.T) {
 	mockUserRepo := new(MockUserRepository)
 	router := setupTestRouter(mockUserRepo)
@@ -166,23 +300,103 @@ func TestRegister_InvalidInput_ServiceValidationFailure(t *testing.T) {
 		Password: "password123",
 	}
 
-	// GetByEmail should not be called if service validation fails first.
-	// However, the current service calls GetByEmail *before* userRepo.Create, but *after* dto.Validate().
-	// If dto.Validate() fails, GetByEmail and Create are not called.
+	// Register validates the DTO before any lookup, so neither GetByEmail
+	// nor Create is called.
 
 	w := performRequest(router, "POST", "/auth/register", registerDTO)
 
-	// As per controller logic, service errors (other than ErrUserExists) result in 500.
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	// ErrValidation is reported to the client as 400 with the reason.
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 	var response map[string]string
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, "server error", response["error"]) // Controller's generic message
+	assert.Equal(t, "username is required", response["error"])
 	// Ensure no DB calls were made for this validation failure path
 	mockUserRepo.AssertNotCalled(t, "GetByEmail")
 	mockUserRepo.AssertNotCalled(t, "Create")
 }
 
+func TestRegister_ProfileTextLimits(t *testing.T) {
+	cases := []struct {
+		name     string
+		fullName string
+		bio      string
+		wantCode int
+		wantErr  string
+	}{
+		{"at limits", strings.Repeat("n", user.MaxFullNameLength), strings.Repeat("b", user.MaxBioLength), http.StatusCreated, ""},
+		{"full_name over", strings.Repeat("n", user.MaxFullNameLength+1), "", http.StatusBadRequest, "full_name must be at most 100 characters (got 101)"},
+		{"bio over", "", strings.Repeat("b", user.MaxBioLength+1), http.StatusBadRequest, "bio must be at most 1000 characters (got 1001)"},
+		{"whitespace not counted", "  " + strings.Repeat("n", user.MaxFullNameLength) + "  ", "", http.StatusCreated, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			router := setupTestRouter(mockUserRepo)
+			mockUserRepo.On("GetByEmail", mock.Anything, "limits@example.com").Return(nil, nil)
+			mockUserRepo.On("Create", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+				return u.FullName == strings.TrimSpace(tc.fullName)
+			})).Return(1, nil)
+
+			w := performRequest(router, "POST", "/auth/register", RegisterDTO{
+				Username: "limits", Email: "limits@example.com", Password: "password123",
+				FullName: tc.fullName, Bio: tc.bio,
+			})
+			assert.Equal(t, tc.wantCode, w.Code)
+			if tc.wantErr != "" {
+				var resp map[string]string
+				json.Unmarshal(w.Body.Bytes(), &resp)
+				assert.Equal(t, tc.wantErr, resp["error"])
+				mockUserRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestRegister_EmailAndPasswordRules(t *testing.T) {
+	cases := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  string
+	}{
+		{"valid", "rules@example.com", "password123", ""},
+		{"email trimmed", "  rules@example.com ", "password123", ""},
+		{"email without at", "abc", "password123", "email must be a valid address like name@example.com"},
+		{"email without domain", "rules@", "password123", "email must be a valid address like name@example.com"},
+		{"email with display name", "Rules <rules@example.com>", "password123", "email must be a valid address like name@example.com"},
+		{"password too short", "rules@example.com", "abc1", "password must be at least 8 characters"},
+		{"password single char", "rules@example.com", "1", "password must be at least 8 characters"},
+		{"password no digit", "rules@example.com", "passwordonly", "password must contain at least one letter and one digit"},
+		{"password no letter", "rules@example.com", "12345678", "password must contain at least one letter and one digit"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			router := setupTestRouter(mockUserRepo)
+			mockUserRepo.On("GetByEmail", mock.Anything, "rules@example.com").Return(nil, nil)
+			mockUserRepo.On("Create", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+				return u.Email == "rules@example.com"
+			})).Return(1, nil)
+
+			w := performRequest(router, "POST", "/auth/register", RegisterDTO{
+				Username: "rules", Email: tc.email, Password: tc.password,
+			})
+			if tc.wantErr == "" {
+				assert.Equal(t, http.StatusCreated, w.Code)
+				mockUserRepo.AssertExpectations(t)
+				return
+			}
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var resp map[string]string
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			assert.Equal(t, tc.wantErr, resp["error"])
+			mockUserRepo.AssertNotCalled(t, "GetByEmail", mock.Anything, mock.Anything)
+			mockUserRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
 func TestRegister_InvalidInput_BindingFailure(t *testing.T) {
 	mockUserRepo := new(MockUserRepository)
 	router := setupTestRouter(mockUserRepo)
@@ -214,9 +428,10 @@ func TestLogin_Success(t *testing.T) {
 	// Password hash for "password123"
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(loginDTO.Password), bcrypt.DefaultCost)
 	expectedUser := &models.User{
-		ID:           1,
-		Email:        loginDTO.Email,
-		PasswordHash: string(hashedPassword),
+		ID:              1,
+		Email:           loginDTO.Email,
+		PasswordHash:    string(hashedPassword),
+		EmailVerifiedAt: verifiedAt(),
 	}
 
 	mockUserRepo.On("GetByEmail", mock.Anything, loginDTO.Email).Return(expectedUser, nil)
@@ -237,6 +452,196 @@ func TestLogin_Success(t *testing.T) {
 	mockUserRepo.AssertExpectations(t)
 }
 
+// loginForRefresh logs user 1 in and returns the issued token pair.
+func loginForRefresh(t *testing.T, router http.Handler, mockUserRepo *MockUserRepository) TokenPair {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	mockUserRepo.On("GetByEmail", mock.Anything, "test@example.com").
+		Return(&models.User{ID: 1, Email: "test@example.com", Role: models.RoleMember, PasswordHash: string(hashedPassword), EmailVerifiedAt: verifiedAt()}, nil)
+
+	w := performRequest(router, "POST", "/auth/login", LoginDTO{Email: "test@example.com", Password: "password123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var pair TokenPair
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pair))
+	return pair
+}
+
+func TestLogin_IssuesRefreshToken(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	refreshRepo := newMemRefreshRepo()
+	router := setupTestRouterWithRefresh(mockUserRepo, refreshRepo, nil)
+
+	pair := loginForRefresh(t, router, mockUserRepo)
+	assert.NotEmpty(t, pair.RefreshToken)
+	assert.Len(t, refreshRepo.tokens, 1)
+
+	// a refresh token is not an access token
+	_, err := jwtutil.ValidateToken(pair.RefreshToken)
+	assert.ErrorIs(t, err, jwtutil.ErrTokenInvalid)
+}
+
+func TestRefresh_ReturnsNewAccessToken(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouter(mockUserRepo)
+	pair := loginForRefresh(t, router, mockUserRepo)
+	mockUserRepo.On("GetByID", mock.Anything, 1).Return(&models.User{ID: 1, Role: models.RoleAdmin}, nil)
+
+	w := performRequest(router, "POST", "/auth/refresh", RefreshDTO{RefreshToken: pair.RefreshToken})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	claims, err := jwtutil.ValidateToken(resp["token"])
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, claims.UserID)
+		// the role is re-read, not copied from the refresh token
+		assert.Equal(t, models.RoleAdmin, claims.Role)
+	}
+}
+
+func TestRefresh_RevokedByLogout(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouter(mockUserRepo)
+	pair := loginForRefresh(t, router, mockUserRepo)
+
+	w := performRequest(router, "POST", "/auth/logout", RefreshDTO{RefreshToken: pair.RefreshToken})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = performRequest(router, "POST", "/auth/refresh", RefreshDTO{RefreshToken: pair.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockUserRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+// useBlacklist gives the test a fresh in-memory token blacklist.
+func useBlacklist(t *testing.T) *MemoryBlacklist {
+	b := NewMemoryBlacklist()
+	prev := SetBlacklist(b)
+	t.Cleanup(func() { SetBlacklist(prev) })
+	return b
+}
+
+func TestLogout_RevokesAccessToken(t *testing.T) {
+	useBlacklist(t)
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouter(mockUserRepo)
+	pair := loginForRefresh(t, router, mockUserRepo)
+	other, err := jwtutil.GenerateToken(1)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, performAuthedRequest(router, "GET", "/protected", pair.AccessToken, nil).Code)
+
+	w := performAuthedRequest(router, "POST", "/auth/logout", pair.AccessToken, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = performAuthedRequest(router, "GET", "/protected", pair.AccessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	var respData map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &respData))
+	assert.Equal(t, "token revoked", respData["error"])
+
+	// Other tokens of the same user are unaffected, and so is the refresh token.
+	assert.Equal(t, http.StatusOK, performAuthedRequest(router, "GET", "/protected", other, nil).Code)
+	mockUserRepo.On("GetByID", mock.Anything, 1).Return(&models.User{ID: 1, Role: models.RoleMember}, nil)
+	assert.Equal(t, http.StatusOK, performRequest(router, "POST", "/auth/refresh", RefreshDTO{RefreshToken: pair.RefreshToken}).Code)
+}
+
+func TestLogout_RevokesBothTokens(t *testing.T) {
+	useBlacklist(t)
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouter(mockUserRepo)
+	pair := loginForRefresh(t, router, mockUserRepo)
+
+	w := performAuthedRequest(router, "POST", "/auth/logout", pair.AccessToken, RefreshDTO{RefreshToken: pair.RefreshToken})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	assert.Equal(t, http.StatusUnauthorized, performAuthedRequest(router, "GET", "/protected", pair.AccessToken, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, performRequest(router, "POST", "/auth/refresh", RefreshDTO{RefreshToken: pair.RefreshToken}).Code)
+}
+
+func TestLogout_BadRequests(t *testing.T) {
+	useBlacklist(t)
+	router := setupTestRouter(new(MockUserRepository))
+
+	// nothing to revoke
+	w := performRequest(router, "POST", "/auth/logout", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// a garbage access token
+	w = performAuthedRequest(router, "POST", "/auth/logout", "not-a-jwt", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// API tokens have their own revocation endpoint
+	w = performAuthedRequest(router, "POST", "/auth/logout", APITokenPrefix+"abc", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRefresh_RejectsExpiredAndAccessTokens(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouter(mockUserRepo)
+	pair := loginForRefresh(t, router, mockUserRepo)
+
+	// an access token cannot be used to refresh
+	w := performRequest(router, "POST", "/auth/refresh", RefreshDTO{RefreshToken: pair.AccessToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// an expired refresh token
+	expired := jwtutil.JWTClaims{
+		UserID:   1,
+		TokenUse: "refresh",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "0123456789abcdef0123456789abcdef",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+	}
+	raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, expired).SignedString([]byte(os.Getenv("JWT_SECRET")))
+	assert.NoError(t, err)
+	w = performRequest(router, "POST", "/auth/refresh", RefreshDTO{RefreshToken: raw})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "POST", "/auth/refresh", RefreshDTO{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// auditEntries captures audit.Record calls for the duration of the test.
+type auditEntries struct{ entries []models.AuditEntry }
+
+func (a *auditEntries) Insert(ctx context.Context, e *models.AuditEntry) error {
+	a.entries = append(a.entries, *e)
+	return nil
+}
+
+func (a *auditEntries) List(ctx context.Context, f audit.Filter, limit, offset int) ([]models.AuditEntry, error) {
+	return a.entries, nil
+}
+
+func captureAudit(t *testing.T) *auditEntries {
+	a := &auditEntries{}
+	prev := audit.SetStore(a)
+	t.Cleanup(func() { audit.SetStore(prev) })
+	return a
+}
+
+func TestLogin_RecordsAudit(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouter(mockUserRepo)
+	recorded := captureAudit(t)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	mockUserRepo.On("GetByEmail", mock.Anything, "test@example.com").
+		Return(&models.User{ID: 5, Email: "test@example.com", PasswordHash: string(hashedPassword), EmailVerifiedAt: verifiedAt()}, nil)
+	mockUserRepo.On("GetByEmail", mock.Anything, "test2@example.com").Return(nil, nil)
+
+	w := performRequest(router, "POST", "/auth/login", LoginDTO{Email: "test@example.com", Password: "password123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "POST", "/auth/login", LoginDTO{Email: "test2@example.com", Password: "password123"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// only the successful login is recorded
+	if assert.Len(t, recorded.entries, 1) {
+		assert.Equal(t, 5, recorded.entries[0].ActorID)
+		assert.Equal(t, audit.ActionLogin, recorded.entries[0].Action)
+		assert.Equal(t, "user:5", recorded.entries[0].Target)
+	}
+}
+
 func TestLogin_InvalidCredentials_UserNotFound(t *testing.T) {
 	mockUserRepo := new(MockUserRepository)
 	router := setupTestRouter(mockUserRepo)
@@ -274,6 +679,19 @@ func TestLogin_InvalidCredentials_WrongPassword(t *testing.T) {
 	mockUserRepo.AssertExpectations(t)
 }
 
+func TestLogin_BannedUser(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouter(mockUserRepo)
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	mockUserRepo.On("GetByEmail", mock.Anything, "banned@example.com").
+		Return(&models.User{ID: 3, Email: "banned@example.com", PasswordHash: string(hashed), IsBanned: true}, nil)
+
+	w := performRequest(router, "POST", "/auth/login", LoginDTO{Email: "banned@example.com", Password: "password123"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockUserRepo.AssertExpectations(t)
+}
+
 func TestLogin_InvalidInput_BindingFailure(t *testing.T) {
     mockUserRepo := new(MockUserRepository)
     router := setupTestRouter(mockUserRepo)
@@ -298,17 +716,143 @@ func TestLogin_InvalidInput_ServiceValidationFailure(t *testing.T) {
     router := setupTestRouter(mockUserRepo)
     loginDTO := LoginDTO{Password: "password123"} // Missing Email
 
-    // Service validation (dto.Validate()) will fail.
-    // Controller returns 500 for such service errors.
+    // Service validation (dto.Validate()) fails with ErrValidation,
+    // which the controller reports as 400 with the reason.
     w := performRequest(router, "POST", "/auth/login", loginDTO)
-    assert.Equal(t, http.StatusInternalServerError, w.Code)
+    assert.Equal(t, http.StatusBadRequest, w.Code)
 	var respData map[string]string
 	err := json.Unmarshal(w.Body.Bytes(), &respData)
 	assert.NoError(t, err)
-    assert.Equal(t, "server error", respData["error"])
+    assert.Equal(t, "email is required", respData["error"])
     mockUserRepo.AssertNotCalled(t, "GetByEmail")
 }
 
+func TestLogin_RepositoryErrorIsServerError(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouter(mockUserRepo)
+	loginDTO := LoginDTO{Email: "test@example.com", Password: "password123"}
+
+	mockUserRepo.On("GetByEmail", mock.Anything, loginDTO.Email).Return(nil, assert.AnError)
+
+	w := performRequest(router, "POST", "/auth/login", loginDTO)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var respData map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &respData))
+	assert.Equal(t, "server error", respData["error"])
+}
+
+func loginLimitConfig(max int) *config.Config {
+	return &config.Config{LoginMaxFailures: max, LoginFailureWindow: 15 * time.Minute}
+}
+
+func TestLogin_LockedOutAfterFailures(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouterWithConfig(mockUserRepo, loginLimitConfig(3))
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	mockUserRepo.On("GetByEmail", mock.Anything, "test@example.com").
+		Return(&models.User{ID: 1, Email: "test@example.com", PasswordHash: string(hashed), EmailVerifiedAt: verifiedAt()}, nil)
+
+	for i := 0; i < 3; i++ {
+		w := performRequest(router, "POST", "/auth/login", LoginDTO{Email: "test@example.com", Password: "wrongpass1"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "attempt %d", i+1)
+	}
+
+	// Locked out now, even with the right password.
+	w := performRequest(router, "POST", "/auth/login", LoginDTO{Email: "Test@Example.com", Password: "password123"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	retry, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.True(t, retry > 0 && retry <= 900, "Retry-After %d", retry)
+	var respData map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &respData))
+	assert.Equal(t, "too many failed login attempts, try again later", respData["error"])
+	mockUserRepo.AssertNumberOfCalls(t, "GetByEmail", 3)
+}
+
+func TestLogin_LockoutCountsPerIP(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouterWithConfig(mockUserRepo, loginLimitConfig(3))
+	mockUserRepo.On("GetByEmail", mock.Anything, mock.Anything).Return(nil, nil)
+
+	login := func(email, addr string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(LoginDTO{Email: email, Password: "password123"})
+		req := httptest.NewRequest("POST", "/auth/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		assert.Equal(t, http.StatusUnauthorized, login(email, "203.0.113.7:5000").Code)
+	}
+	w := login("d@example.com", "203.0.113.7:5001")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	// Another address may still try the same email.
+	assert.Equal(t, http.StatusUnauthorized, login("d@example.com", "198.51.100.2:5000").Code)
+}
+
+func TestLogin_LockoutTrustsForwardedForOnlyFromProxy(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouterWithConfig(mockUserRepo, loginLimitConfig(3))
+	assert.NoError(t, router.SetTrustedProxies([]string{"10.0.0.1"}))
+	mockUserRepo.On("GetByEmail", mock.Anything, mock.Anything).Return(nil, nil)
+
+	login := func(i int, peer, forwarded string) int {
+		body, _ := json.Marshal(LoginDTO{Email: "u" + strconv.Itoa(i) + "@example.com", Password: "password123"})
+		req := httptest.NewRequest("POST", "/auth/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwarded)
+		req.RemoteAddr = peer + ":5000"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Through the trusted proxy each client is counted on its own, so the
+	// proxy's address never locks everyone out.
+	for i, client := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4"} {
+		assert.Equal(t, http.StatusUnauthorized, login(i, "10.0.0.1", client))
+	}
+
+	var code int
+	for i, forged := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4"} {
+		code = login(10+i, "203.0.113.7", forged)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, code, "a forged X-Forwarded-For must not reset the count")
+}
+
+func TestLogin_SuccessResetsFailures(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouterWithConfig(mockUserRepo, loginLimitConfig(3))
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	mockUserRepo.On("GetByEmail", mock.Anything, "test@example.com").
+		Return(&models.User{ID: 1, Email: "test@example.com", PasswordHash: string(hashed), EmailVerifiedAt: verifiedAt()}, nil)
+
+	wrong := LoginDTO{Email: "test@example.com", Password: "wrongpass1"}
+	right := LoginDTO{Email: "test@example.com", Password: "password123"}
+	for _, dto := range []LoginDTO{wrong, wrong, right} {
+		performRequest(router, "POST", "/auth/login", dto)
+	}
+	// Without the reset the next failure would be the third and lock the email.
+	assert.Equal(t, http.StatusUnauthorized, performRequest(router, "POST", "/auth/login", wrong).Code)
+	assert.Equal(t, http.StatusOK, performRequest(router, "POST", "/auth/login", right).Code)
+}
+
+func TestLogin_NoLimitWhenDisabled(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupTestRouterWithConfig(mockUserRepo, loginLimitConfig(0))
+	mockUserRepo.On("GetByEmail", mock.Anything, "test@example.com").Return(nil, nil)
+
+	for i := 0; i < 10; i++ {
+		w := performRequest(router, "POST", "/auth/login", LoginDTO{Email: "test@example.com", Password: "password123"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+}
 
 func TestAuthMiddleware_ValidToken(t *testing.T) {
 	mockUserRepo := new(MockUserRepository) // Not used by middleware directly but setup needs it
@@ -330,6 +874,25 @@ func TestAuthMiddleware_ValidToken(t *testing.T) {
 	assert.Equal(t, float64(123), respData["userID"]) // JSON numbers are float64
 }
 
+func TestRequireRole(t *testing.T) {
+	router := setupTestRouter(new(MockUserRepository))
+
+	for role, want := range map[string]int{
+		models.RoleAdmin:  http.StatusOK,
+		models.RoleMember: http.StatusForbidden,
+		"":                http.StatusForbidden,
+	} {
+		token, err := jwtutil.GenerateTokenWithRole(1, role)
+		assert.NoError(t, err)
+
+		req, _ := http.NewRequest("GET", "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, "role %q", role)
+	}
+}
+
 func TestAuthMiddleware_InvalidToken_NoToken(t *testing.T) {
 	mockUserRepo := new(MockUserRepository)
 	router := setupTestRouter(mockUserRepo)
@@ -427,3 +990,628 @@ func TestAuthMiddleware_ExpiredToken(t *testing.T) {
     // A comprehensive test of `jwtutil.ExtractUserID` itself should verify it returns this error.
     t.Skip("Skipping direct expired token test: requires jwtutil to generate expired tokens or time mocking.")
 }
+
+// --- API token Tests ---
+
+// fakeTokenRepository keeps tokens in memory so a token created through the
+// API can be used and revoked in the same test.
+type fakeTokenRepository struct {
+	tokens map[int]models.APIToken
+	nextID int
+}
+
+func newFakeTokenRepository() *fakeTokenRepository {
+	return &fakeTokenRepository{tokens: map[int]models.APIToken{}}
+}
+
+func (f *fakeTokenRepository) Create(ctx context.Context, t *models.APIToken) (int, error) {
+	f.nextID++
+	t.ID = f.nextID
+	f.tokens[t.ID] = *t
+	return t.ID, nil
+}
+
+func (f *fakeTokenRepository) ListByUser(ctx context.Context, userID int) ([]models.APIToken, error) {
+	var out []models.APIToken
+	for _, t := range f.tokens {
+		if t.UserID == userID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeTokenRepository) Delete(ctx context.Context, id, userID int) (bool, error) {
+	t, ok := f.tokens[id]
+	if !ok || t.UserID != userID {
+		return false, nil
+	}
+	delete(f.tokens, id)
+	return true, nil
+}
+
+func (f *fakeTokenRepository) FindUserByHash(ctx context.Context, hash string) (*models.User, error) {
+	for _, t := range f.tokens {
+		if t.TokenHash == hash {
+			return &models.User{ID: t.UserID, Role: models.RoleMember}, nil
+		}
+	}
+	return nil, nil
+}
+
+func setupTokenTestRouter(repo TokenRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	tokenController := NewTokenController(NewTokenService(repo))
+
+	tokens := router.Group("/auth/tokens", AuthMiddleware(repo))
+	tokens.POST("", tokenController.Create)
+	tokens.GET("", tokenController.List)
+	tokens.DELETE("/:id", tokenController.Revoke)
+
+	router.GET("/protected", AuthMiddleware(repo), func(c *gin.Context) {
+		userID, _ := GetUserID(c)
+		c.JSON(http.StatusOK, gin.H{"userID": userID})
+	})
+	return router
+}
+
+func performAuthedRequest(r http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateAPIToken_Success(t *testing.T) {
+	repo := newFakeTokenRepository()
+	router := setupTokenTestRouter(repo)
+	jwtToken, _ := jwtutil.GenerateToken(7)
+
+	w := performAuthedRequest(router, "POST", "/auth/tokens", jwtToken, CreateTokenDTO{Name: "ci"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	raw, _ := resp["token"].(string)
+	assert.True(t, strings.HasPrefix(raw, APITokenPrefix))
+	assert.Equal(t, "ci", resp["name"])
+
+	stored := repo.tokens[1]
+	assert.Equal(t, 7, stored.UserID)
+	assert.Equal(t, hashToken(raw), stored.TokenHash)
+	assert.NotEqual(t, raw, stored.TokenHash, "raw token must not be stored")
+
+	// listing exposes metadata only
+	w = performAuthedRequest(router, "GET", "/auth/tokens", jwtToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), raw)
+	assert.NotContains(t, w.Body.String(), stored.TokenHash)
+}
+
+func TestAPITokens_BannedUserRejected(t *testing.T) {
+	repo := newFakeTokenRepository()
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("GetByID", mock.Anything, 7).Return(&models.User{ID: 7, IsBanned: true}, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	tokenController := NewTokenController(NewTokenService(repo))
+	tokens := router.Group("/auth/tokens", AuthMiddleware(repo), RequireActive(mockUserRepo))
+	tokens.POST("", tokenController.Create)
+	tokens.GET("", tokenController.List)
+	tokens.DELETE("/:id", tokenController.Revoke)
+	jwtToken, _ := jwtutil.GenerateToken(7)
+
+	w := performAuthedRequest(router, "POST", "/auth/tokens", jwtToken, CreateTokenDTO{Name: "ci"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, repo.tokens)
+	w = performAuthedRequest(router, "GET", "/auth/tokens", jwtToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performAuthedRequest(router, "DELETE", "/auth/tokens/1", jwtToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCreateAPIToken_MissingName(t *testing.T) {
+	router := setupTokenTestRouter(newFakeTokenRepository())
+	jwtToken, _ := jwtutil.GenerateToken(7)
+
+	w := performAuthedRequest(router, "POST", "/auth/tokens", jwtToken, CreateTokenDTO{Name: "  "})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAPIToken_AuthenticatesAndRevokes(t *testing.T) {
+	repo := newFakeTokenRepository()
+	router := setupTokenTestRouter(repo)
+	jwtToken, _ := jwtutil.GenerateToken(7)
+
+	w := performAuthedRequest(router, "POST", "/auth/tokens", jwtToken, CreateTokenDTO{Name: "ci"})
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	raw := created["token"].(string)
+
+	w = performAuthedRequest(router, "GET", "/protected", raw, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"userID":7}`, w.Body.String())
+
+	// another user cannot revoke it
+	otherToken, _ := jwtutil.GenerateToken(8)
+	w = performAuthedRequest(router, "DELETE", "/auth/tokens/1", otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performAuthedRequest(router, "DELETE", "/auth/tokens/1", jwtToken, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = performAuthedRequest(router, "GET", "/protected", raw, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAPIToken_UnknownToken(t *testing.T) {
+	router := setupTokenTestRouter(newFakeTokenRepository())
+
+	w := performAuthedRequest(router, "GET", "/protected", APITokenPrefix+"deadbeef", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// --- Email verification Tests ---
+
+// fakeVerificationRepository keeps a single pending token per user in memory.
+type fakeVerificationRepository struct {
+	hashes  map[int]string
+	sentAt  map[int]time.Time
+	expires map[int]time.Time
+}
+
+func newFakeVerificationRepository() *fakeVerificationRepository {
+	return &fakeVerificationRepository{hashes: map[int]string{}, sentAt: map[int]time.Time{}, expires: map[int]time.Time{}}
+}
+
+func (f *fakeVerificationRepository) LastSentAt(ctx context.Context, userID int) (time.Time, error) {
+	return f.sentAt[userID], nil
+}
+
+func (f *fakeVerificationRepository) Save(ctx context.Context, userID int, tokenHash string, expiresAt, sentAt time.Time) error {
+	f.hashes[userID] = tokenHash
+	f.sentAt[userID] = sentAt
+	f.expires[userID] = expiresAt
+	return nil
+}
+
+func (f *fakeVerificationRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (int, error) {
+	for id, h := range f.hashes {
+		if h == tokenHash && f.expires[id].After(now) {
+			delete(f.hashes, id)
+			delete(f.sentAt, id)
+			return id, nil
+		}
+	}
+	return 0, nil
+}
+
+// stubSendMail replaces the mail transport for the duration of the test and
+// records what would have been sent. Background sends run inline.
+func stubSendMail(t *testing.T) *[]string {
+	var bodies []string
+	orig, origGo := sendMail, goMail
+	sendMail = func(to []string, subject, body string) error {
+		bodies = append(bodies, to[0]+"|"+body)
+		return nil
+	}
+	goMail = func(f func()) { f() }
+	t.Cleanup(func() { sendMail, goMail = orig, origGo })
+	return &bodies
+}
+
+func setupVerificationTestRouter(userRepo user.UserRepository, repo VerificationRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ctr := NewVerificationController(NewVerificationService(userRepo, repo, nil))
+	router.POST("/auth/resend-verification", JWTAuthMiddleware(), ctr.Resend)
+	router.GET("/auth/verify", ctr.Verify)
+	return router
+}
+
+func TestResendVerification_Success(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	repo := newFakeVerificationRepository()
+	router := setupVerificationTestRouter(mockUserRepo, repo)
+	sent := stubSendMail(t)
+	token, _ := jwtutil.GenerateToken(4)
+
+	mockUserRepo.On("GetByID", mock.Anything, 4).Return(&models.User{ID: 4, Email: "new@example.com"}, nil)
+
+	w := performAuthedRequest(router, "POST", "/auth/resend-verification", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Len(t, *sent, 1) {
+		assert.True(t, strings.HasPrefix((*sent)[0], "new@example.com|"))
+	}
+	assert.NotEmpty(t, repo.hashes[4])
+
+	// the emailed token verifies the address
+	raw := emailedToken((*sent)[0])
+	w = performRequest(router, "GET", "/auth/verify?token="+raw, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "GET", "/auth/verify?token="+raw, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "tokens are single use")
+}
+
+func TestResendVerification_AlreadyVerified(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupVerificationTestRouter(mockUserRepo, newFakeVerificationRepository())
+	sent := stubSendMail(t)
+	token, _ := jwtutil.GenerateToken(4)
+	verifiedAt := time.Now().UTC()
+
+	mockUserRepo.On("GetByID", mock.Anything, 4).Return(&models.User{ID: 4, Email: "a@example.com", EmailVerifiedAt: &verifiedAt}, nil)
+
+	w := performAuthedRequest(router, "POST", "/auth/resend-verification", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, *sent)
+}
+
+func TestResendVerification_RateLimited(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	repo := newFakeVerificationRepository()
+	router := setupVerificationTestRouter(mockUserRepo, repo)
+	sent := stubSendMail(t)
+	token, _ := jwtutil.GenerateToken(4)
+
+	mockUserRepo.On("GetByID", mock.Anything, 4).Return(&models.User{ID: 4, Email: "a@example.com"}, nil)
+
+	w := performAuthedRequest(router, "POST", "/auth/resend-verification", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performAuthedRequest(router, "POST", "/auth/resend-verification", token, nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Len(t, *sent, 1)
+
+	// once the interval has passed a new email goes out
+	repo.sentAt[4] = time.Now().UTC().Add(-VerificationResendInterval - time.Second)
+	w = performAuthedRequest(router, "POST", "/auth/resend-verification", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, *sent, 2)
+}
+
+// emailedToken pulls the raw token out of the link in a body recorded by
+// stubSendMail.
+func emailedToken(body string) string {
+	for _, field := range strings.Fields(body) {
+		if u, err := url.Parse(field); err == nil && u.Query().Has("token") {
+			return u.Query().Get("token")
+		}
+	}
+	return ""
+}
+
+func TestRegister_VerifyThenLogin(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	repo := newFakeVerificationRepository()
+	sent := stubSendMail(t)
+	verifySvc := NewVerificationService(mockUserRepo, repo, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	authCtr := NewController(NewService(mockUserRepo, newMemRefreshRepo(), verifySvc, nil))
+	verifyCtr := NewVerificationController(verifySvc)
+	router.POST("/auth/register", authCtr.RegisterHandler)
+	router.POST("/auth/login", authCtr.LoginHandler)
+	router.GET("/auth/verify", verifyCtr.Verify)
+
+	dto := RegisterDTO{Username: "newbie", Email: "newbie@example.com", Password: "password123"}
+	mockUserRepo.On("GetByEmail", mock.Anything, dto.Email).Return(nil, nil).Once()
+	mockUserRepo.On("Create", mock.Anything, mock.Anything).Return(9, nil)
+
+	w := performRequest(router, "POST", "/auth/register", dto)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	if !assert.Len(t, *sent, 1) {
+		return
+	}
+	assert.True(t, strings.HasPrefix((*sent)[0], "newbie@example.com|"))
+	assert.NotEmpty(t, repo.hashes[9])
+
+	// not verified yet: login is refused
+	hashed, _ := bcrypt.GenerateFromPassword([]byte(dto.Password), bcrypt.DefaultCost)
+	stored := &models.User{ID: 9, Email: dto.Email, PasswordHash: string(hashed)}
+	mockUserRepo.On("GetByEmail", mock.Anything, dto.Email).Return(stored, nil).Once()
+	w = performRequest(router, "POST", "/auth/login", LoginDTO{Email: dto.Email, Password: dto.Password})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var resp map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "email address not verified, check your inbox", resp["error"])
+
+	w = performRequest(router, "GET", "/auth/verify?token="+emailedToken((*sent)[0]), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// the repository now reports the address as verified
+	verified := *stored
+	verified.EmailVerifiedAt = verifiedAt()
+	mockUserRepo.On("GetByEmail", mock.Anything, dto.Email).Return(&verified, nil).Once()
+	w = performRequest(router, "POST", "/auth/login", LoginDTO{Email: dto.Email, Password: dto.Password})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRegister_MailFailureStillCreatesAccount(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	repo := newFakeVerificationRepository()
+	orig := sendMail
+	sendMail = func(to []string, subject, body string) error { return assert.AnError }
+	t.Cleanup(func() { sendMail = orig })
+
+	svc := NewService(mockUserRepo, newMemRefreshRepo(), NewVerificationService(mockUserRepo, repo, nil), nil)
+	dto := &RegisterDTO{Username: "newbie", Email: "newbie@example.com", Password: "password123"}
+	mockUserRepo.On("GetByEmail", mock.Anything, dto.Email).Return(nil, nil)
+	mockUserRepo.On("Create", mock.Anything, mock.Anything).Return(9, nil)
+
+	id, err := svc.Register(context.Background(), dto)
+	assert.NoError(t, err)
+	assert.Equal(t, 9, id)
+}
+
+func TestVerify_ExpiredTokenRejected(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	repo := newFakeVerificationRepository()
+	router := setupVerificationTestRouter(mockUserRepo, repo)
+	sent := stubSendMail(t)
+	token, _ := jwtutil.GenerateToken(4)
+
+	mockUserRepo.On("GetByID", mock.Anything, 4).Return(&models.User{ID: 4, Email: "late@example.com"}, nil)
+	w := performAuthedRequest(router, "POST", "/auth/resend-verification", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	repo.expires[4] = time.Now().UTC().Add(-time.Minute)
+	w = performRequest(router, "GET", "/auth/verify?token="+emailedToken((*sent)[0]), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestResendVerificationByEmail(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	repo := newFakeVerificationRepository()
+	sent := stubSendMail(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/verify/resend", NewVerificationController(NewVerificationService(mockUserRepo, repo, nil)).ResendTo)
+
+	mockUserRepo.On("GetByEmail", mock.Anything, "ghost@example.com").Return(nil, nil)
+	mockUserRepo.On("GetByEmail", mock.Anything, "done@example.com").
+		Return(&models.User{ID: 2, Email: "done@example.com", EmailVerifiedAt: verifiedAt()}, nil)
+	mockUserRepo.On("GetByEmail", mock.Anything, "pending@example.com").
+		Return(&models.User{ID: 3, Email: "pending@example.com"}, nil)
+
+	// unknown, verified and pending addresses all get the same reply
+	var bodies []string
+	for _, email := range []string{"ghost@example.com", "done@example.com", "pending@example.com", "pending@example.com"} {
+		w := performRequest(router, "POST", "/auth/verify/resend", ResendVerificationDTO{Email: email})
+		assert.Equal(t, http.StatusOK, w.Code, email)
+		bodies = append(bodies, w.Body.String())
+	}
+	for _, b := range bodies[1:] {
+		assert.Equal(t, bodies[0], b)
+	}
+	// only the pending address is mailed, and the repeat is rate limited
+	if assert.Len(t, *sent, 1) {
+		assert.True(t, strings.HasPrefix((*sent)[0], "pending@example.com|"))
+	}
+
+	w := performRequest(router, "POST", "/auth/verify/resend", ResendVerificationDTO{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// --- Password reset Tests ---
+
+type fakeReset struct {
+	userID    int
+	expiresAt time.Time
+	createdAt time.Time
+}
+
+// fakePasswordResetRepository keeps a single pending token per user in memory.
+// Consume records the new password hash and, when refresh is set, revokes
+// the owner's refresh tokens there.
+type fakePasswordResetRepository struct {
+	byHash    map[string]fakeReset
+	passwords map[int]string
+	refresh   *memRefreshRepo
+}
+
+func newFakePasswordResetRepository() *fakePasswordResetRepository {
+	return &fakePasswordResetRepository{byHash: map[string]fakeReset{}, passwords: map[int]string{}}
+}
+
+func (f *fakePasswordResetRepository) Save(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	for h, r := range f.byHash {
+		if r.userID == userID {
+			delete(f.byHash, h)
+		}
+	}
+	f.byHash[tokenHash] = fakeReset{userID: userID, expiresAt: expiresAt, createdAt: time.Now().UTC()}
+	return nil
+}
+
+func (f *fakePasswordResetRepository) LastSentAt(ctx context.Context, userID int) (time.Time, error) {
+	for _, r := range f.byHash {
+		if r.userID == userID {
+			return r.createdAt, nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+func (f *fakePasswordResetRepository) Consume(ctx context.Context, tokenHash, passwordHash string, now time.Time) (int, error) {
+	r, ok := f.byHash[tokenHash]
+	if !ok || !r.expiresAt.After(now) {
+		return 0, nil
+	}
+	delete(f.byHash, tokenHash)
+	f.passwords[r.userID] = passwordHash
+	if f.refresh != nil {
+		for id, t := range f.refresh.tokens {
+			if t.userID == r.userID {
+				delete(f.refresh.tokens, id)
+			}
+		}
+	}
+	return r.userID, nil
+}
+
+func setupPasswordResetTestRouter(userRepo user.UserRepository, repo PasswordResetRepository, cfg *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ctr := NewPasswordResetController(NewPasswordResetService(userRepo, repo, cfg))
+	router.POST("/auth/forgot-password", ctr.Forgot)
+	router.POST("/auth/reset-password", ctr.Reset)
+	return router
+}
+
+// sentToken pulls the raw token out of an email recorded by stubSendMail.
+func sentToken(body string) string {
+	return strings.Fields(body[strings.Index(body, "token:")+len("token:"):])[0]
+}
+
+func TestForgotPassword_UnknownEmailStillOK(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	router := setupPasswordResetTestRouter(mockUserRepo, newFakePasswordResetRepository(), nil)
+	sent := stubSendMail(t)
+
+	mockUserRepo.On("GetByEmail", mock.Anything, "nobody@example.com").Return(nil, nil)
+	mockUserRepo.On("GetByEmail", mock.Anything, "someone@example.com").Return(&models.User{ID: 3, Email: "someone@example.com"}, nil)
+
+	unknown := performRequest(router, "POST", "/auth/forgot-password", ForgotPasswordDTO{Email: "nobody@example.com"})
+	known := performRequest(router, "POST", "/auth/forgot-password", ForgotPasswordDTO{Email: "someone@example.com"})
+
+	assert.Equal(t, http.StatusOK, unknown.Code)
+	assert.Equal(t, known.Code, unknown.Code)
+	assert.Equal(t, known.Body.String(), unknown.Body.String(), "responses must not reveal which emails exist")
+	assert.Len(t, *sent, 1)
+}
+
+func TestForgotPassword_ThrottledPerEmail(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	repo := newFakePasswordResetRepository()
+	router := setupPasswordResetTestRouter(mockUserRepo, repo, nil)
+	sent := stubSendMail(t)
+
+	mockUserRepo.On("GetByEmail", mock.Anything, "someone@example.com").Return(&models.User{ID: 3, Email: "someone@example.com"}, nil)
+
+	for i := 0; i < 3; i++ {
+		w := performRequest(router, "POST", "/auth/forgot-password", ForgotPasswordDTO{Email: "someone@example.com"})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.Len(t, *sent, 1, "repeats within the resend interval are not mailed")
+
+	// once the interval has passed another email goes out
+	for h, r := range repo.byHash {
+		r.createdAt = r.createdAt.Add(-PasswordResetResendInterval)
+		repo.byHash[h] = r
+	}
+	performRequest(router, "POST", "/auth/forgot-password", ForgotPasswordDTO{Email: "someone@example.com"})
+	assert.Len(t, *sent, 2)
+}
+
+func TestResetPassword_Success(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	repo := newFakePasswordResetRepository()
+	router := setupPasswordResetTestRouter(mockUserRepo, repo, nil)
+	sent := stubSendMail(t)
+	u := &models.User{ID: 3, Email: "someone@example.com", PasswordHash: "old"}
+
+	mockUserRepo.On("GetByEmail", mock.Anything, "someone@example.com").Return(u, nil)
+
+	w := performRequest(router, "POST", "/auth/forgot-password", ForgotPasswordDTO{Email: "someone@example.com"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	if !assert.Len(t, *sent, 1) {
+		return
+	}
+	raw := sentToken((*sent)[0])
+
+	w = performRequest(router, "POST", "/auth/reset-password", ResetPasswordDTO{Token: raw, NewPassword: "n3w-secret"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(repo.passwords[3]), []byte("n3w-secret")))
+
+	w = performRequest(router, "POST", "/auth/reset-password", ResetPasswordDTO{Token: raw, NewPassword: "an0ther-secret"})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "tokens are single use")
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(repo.passwords[3]), []byte("n3w-secret")))
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestResetPassword_RevokesRefreshTokens(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	refreshRepo := newMemRefreshRepo()
+	router := setupTestRouterWithRefresh(mockUserRepo, refreshRepo, nil)
+	resetRepo := newFakePasswordResetRepository()
+	resetRepo.refresh = refreshRepo
+	resetCtr := NewPasswordResetController(NewPasswordResetService(mockUserRepo, resetRepo, nil))
+	router.POST("/auth/forgot-password", resetCtr.Forgot)
+	router.POST("/auth/reset-password", resetCtr.Reset)
+	sent := stubSendMail(t)
+
+	pair := loginForRefresh(t, router, mockUserRepo)
+
+	performRequest(router, "POST", "/auth/forgot-password", ForgotPasswordDTO{Email: "test@example.com"})
+	if !assert.Len(t, *sent, 1) {
+		return
+	}
+	w := performRequest(router, "POST", "/auth/reset-password", ResetPasswordDTO{Token: sentToken((*sent)[0]), NewPassword: "n3w-secret"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "POST", "/auth/refresh", RefreshDTO{RefreshToken: pair.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "a refresh token issued before the reset must not survive it")
+}
+
+func TestResetPassword_ExpiredToken(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	repo := newFakePasswordResetRepository()
+	router := setupPasswordResetTestRouter(mockUserRepo, repo, &config.Config{PasswordResetTTL: time.Minute})
+	sent := stubSendMail(t)
+
+	mockUserRepo.On("GetByEmail", mock.Anything, "someone@example.com").Return(&models.User{ID: 3, Email: "someone@example.com"}, nil)
+
+	performRequest(router, "POST", "/auth/forgot-password", ForgotPasswordDTO{Email: "someone@example.com"})
+	if !assert.Len(t, *sent, 1) {
+		return
+	}
+	for h, r := range repo.byHash {
+		assert.WithinDuration(t, time.Now().Add(time.Minute), r.expiresAt, 5*time.Second)
+		r.expiresAt = time.Now().Add(-time.Second)
+		repo.byHash[h] = r
+	}
+
+	w := performRequest(router, "POST", "/auth/reset-password", ResetPasswordDTO{Token: sentToken((*sent)[0]), NewPassword: "n3w-secret"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, repo.passwords)
+}
+
+func TestResetPassword_MissingFields(t *testing.T) {
+	router := setupPasswordResetTestRouter(new(MockUserRepository), newFakePasswordResetRepository(), nil)
+
+	w := performRequest(router, "POST", "/auth/reset-password", ResetPasswordDTO{Token: "abc"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "new_password is required")
+}
+
+func TestResetPassword_WeakPasswordRejected(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	repo := newFakePasswordResetRepository()
+	router := setupPasswordResetTestRouter(mockUserRepo, repo, nil)
+	sent := stubSendMail(t)
+
+	mockUserRepo.On("GetByEmail", mock.Anything, "someone@example.com").Return(&models.User{ID: 3, Email: "someone@example.com"}, nil)
+
+	performRequest(router, "POST", "/auth/forgot-password", ForgotPasswordDTO{Email: "someone@example.com"})
+	if !assert.Len(t, *sent, 1) {
+		return
+	}
+	raw := sentToken((*sent)[0])
+
+	for _, pw := range []string{"short1", "lettersonly"} {
+		w := performRequest(router, "POST", "/auth/reset-password", ResetPasswordDTO{Token: raw, NewPassword: pw})
+		assert.Equal(t, http.StatusBadRequest, w.Code, pw)
+		assert.Contains(t, w.Body.String(), "password must", pw)
+	}
+	assert.Len(t, repo.byHash, 1, "a rejected password must not use up the token")
+	assert.Empty(t, repo.passwords)
+}
@@ -1,7 +1,15 @@
 // dto.go 
 package auth
 
-import "errors"
+import (
+    "errors"
+    "fmt"
+    "net/mail"
+    "strings"
+    "unicode"
+
+    "go-discussion-app/internal/user"
+)
 
 // RegisterDTO is the payload for POST /auth/register
 type RegisterDTO struct {
@@ -12,16 +20,57 @@ type RegisterDTO struct {
     Bio      string `json:"bio,omitempty"`
 }
 
+// MinPasswordLength is the shortest password accepted at registration and
+// password reset.
+const MinPasswordLength = 8
+
+// MaxPasswordBytes is the longest password bcrypt can hash.
+const MaxPasswordBytes = 72
+
+// Validate trims username and email and lower-cases the email, then checks
+// that email is a bare address and that the password meets the policy.
 func (dto *RegisterDTO) Validate() error {
+    dto.Username = strings.TrimSpace(dto.Username)
+    dto.Email = strings.ToLower(strings.TrimSpace(dto.Email))
     if dto.Username == "" {
         return errors.New("username is required")
     }
     if dto.Email == "" {
         return errors.New("email is required")
     }
+    if addr, err := mail.ParseAddress(dto.Email); err != nil || addr.Address != dto.Email {
+        return errors.New("email must be a valid address like name@example.com")
+    }
     if dto.Password == "" {
         return errors.New("password is required")
     }
+    if err := validatePassword(dto.Password); err != nil {
+        return err
+    }
+    return user.ValidateProfileText(&dto.FullName, &dto.Bio)
+}
+
+// validatePassword requires MinPasswordLength characters with at least one
+// letter and one digit, and at most MaxPasswordBytes bytes.
+func validatePassword(pw string) error {
+    if len([]rune(pw)) < MinPasswordLength {
+        return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+    }
+    if len(pw) > MaxPasswordBytes {
+        return fmt.Errorf("password must be at most %d bytes", MaxPasswordBytes)
+    }
+    var letter, digit bool
+    for _, r := range pw {
+        switch {
+        case unicode.IsLetter(r):
+            letter = true
+        case unicode.IsDigit(r):
+            digit = true
+        }
+    }
+    if !letter || !digit {
+        return errors.New("password must contain at least one letter and one digit")
+    }
     return nil
 }
 
@@ -40,3 +89,71 @@ func (dto *LoginDTO) Validate() error {
     }
     return nil
 }
+
+// RefreshDTO is the payload for POST /auth/refresh and POST /auth/logout
+type RefreshDTO struct {
+    RefreshToken string `json:"refresh_token"`
+}
+
+func (dto *RefreshDTO) Validate() error {
+    if dto.RefreshToken == "" {
+        return errors.New("refresh_token is required")
+    }
+    return nil
+}
+
+// ForgotPasswordDTO is the payload for POST /auth/forgot-password
+type ForgotPasswordDTO struct {
+    Email string `json:"email"`
+}
+
+func (dto *ForgotPasswordDTO) Validate() error {
+    if strings.TrimSpace(dto.Email) == "" {
+        return errors.New("email is required")
+    }
+    return nil
+}
+
+// ResendVerificationDTO is the payload for POST /auth/verify/resend
+type ResendVerificationDTO struct {
+    Email string `json:"email"`
+}
+
+func (dto *ResendVerificationDTO) Validate() error {
+    if strings.TrimSpace(dto.Email) == "" {
+        return errors.New("email is required")
+    }
+    return nil
+}
+
+// ResetPasswordDTO is the payload for POST /auth/reset-password
+type ResetPasswordDTO struct {
+    Token       string `json:"token"`
+    NewPassword string `json:"new_password"`
+}
+
+func (dto *ResetPasswordDTO) Validate() error {
+    if dto.Token == "" {
+        return errors.New("token is required")
+    }
+    if dto.NewPassword == "" {
+        return errors.New("new_password is required")
+    }
+    return validatePassword(dto.NewPassword)
+}
+
+// CreateTokenDTO is the payload for POST /auth/tokens
+type CreateTokenDTO struct {
+    Name string `json:"name"`
+}
+
+func (dto *CreateTokenDTO) Validate() error {
+    name := strings.TrimSpace(dto.Name)
+    if name == "" {
+        return errors.New("name is required")
+    }
+    if len(name) > 100 {
+        return errors.New("name must be at most 100 characters")
+    }
+    return nil
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"go-discussion-app/models"
+	"go-discussion-app/pkg/jwtutil"
+)
+
+var gatewayProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+// setupGatewayRouter serves GET /whoami behind the gateway middleware,
+// answering with the user id and role the request was authenticated as.
+func setupGatewayRouter(users *MockUserRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GatewayMiddleware(JWTAuthMiddleware(), users, "X-User-ID", gatewayProxies))
+	r.GET("/whoami", func(c *gin.Context) {
+		uid, _ := GetUserID(c)
+		role, _ := GetRole(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": uid, "role": role, "header": c.GetHeader("X-User-ID")})
+	})
+	return r
+}
+
+func gatewayRequest(r http.Handler, remoteAddr, userHeader, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.RemoteAddr = remoteAddr
+	if userHeader != "" {
+		req.Header.Set("X-User-ID", userHeader)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGateway_TrustedProxySetsUserFromHeader(t *testing.T) {
+	users := new(MockUserRepository)
+	r := setupGatewayRouter(users)
+	users.On("GetByID", mock.Anything, 42).Return(&models.User{ID: 42, Role: models.RoleAdmin}, nil)
+
+	w := gatewayRequest(r, "10.1.2.3:4567", "42", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":42,"role":"admin","header":"42"}`, w.Body.String())
+	users.AssertExpectations(t)
+}
+
+func TestGateway_TrustedProxyRejectsBadOrUnknownUser(t *testing.T) {
+	users := new(MockUserRepository)
+	r := setupGatewayRouter(users)
+	users.On("GetByID", mock.Anything, 99).Return(nil, nil)
+
+	for _, h := range []string{"abc", "0", "99"} {
+		w := gatewayRequest(r, "10.1.2.3:4567", h, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, h)
+	}
+}
+
+func TestGateway_UntrustedPeerHeaderIsIgnored(t *testing.T) {
+	users := new(MockUserRepository)
+	r := setupGatewayRouter(users)
+
+	// no token: the header alone must not authenticate
+	w := gatewayRequest(r, "203.0.113.9:4567", "42", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// with a token the request authenticates as the token's user, and the
+	// spoofed header does not reach the handler
+	token, err := jwtutil.GenerateToken(7)
+	assert.NoError(t, err)
+	w = gatewayRequest(r, "203.0.113.9:4567", "42", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":7,"role":"","header":""}`, w.Body.String())
+	users.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+func TestGateway_TrustedProxyWithoutHeaderFallsBackToToken(t *testing.T) {
+	users := new(MockUserRepository)
+	r := setupGatewayRouter(users)
+
+	w := gatewayRequest(r, "10.1.2.3:4567", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	token, err := jwtutil.GenerateToken(7)
+	assert.NoError(t, err)
+	w = gatewayRequest(r, "10.1.2.3:4567", "", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	users.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+func TestFromTrustedPeer_IPv4MappedIPv6(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "[::ffff:10.0.0.5]:80"
+	assert.True(t, fromTrustedPeer(req, gatewayProxies))
+	req.RemoteAddr = "[2001:db8::1]:80"
+	assert.False(t, fromTrustedPeer(req, gatewayProxies))
+}
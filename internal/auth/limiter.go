@@ -0,0 +1,100 @@
+// limiter.go
+package auth
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+// LoginLimiter counts failed logins per key (an email or an IP) so that
+// Login can lock a key out after too many. It is an interface so a shared
+// store such as Redis can replace the in-memory one when the app runs on
+// more than one instance.
+type LoginLimiter interface {
+    // RetryAfter reports how long key stays locked out; 0 means it may try.
+    RetryAfter(ctx context.Context, key string) (time.Duration, error)
+    // Fail records one failed attempt for key.
+    Fail(ctx context.Context, key string) error
+    // Reset forgets key's failed attempts.
+    Reset(ctx context.Context, key string) error
+}
+
+// failures is one key's count within its current window.
+type failures struct {
+    count int
+    start time.Time
+}
+
+// MemoryLoginLimiter is a LoginLimiter held in process memory. The window
+// opens at a key's first failure; once max failures land in it the key is
+// locked out until the window closes. That is INCR plus EXPIRE in Redis.
+type MemoryLoginLimiter struct {
+    mu        sync.Mutex
+    max       int
+    window    time.Duration
+    keys      map[string]*failures
+    lastSweep time.Time
+
+    now func() time.Time
+}
+
+// NewMemoryLoginLimiter allows max failures per key within window.
+func NewMemoryLoginLimiter(max int, window time.Duration) *MemoryLoginLimiter {
+    return &MemoryLoginLimiter{max: max, window: window, keys: make(map[string]*failures), now: time.Now}
+}
+
+// current returns key's live entry, dropping it if its window has closed.
+// The caller holds l.mu.
+func (l *MemoryLoginLimiter) current(key string, now time.Time) *failures {
+    f := l.keys[key]
+    if f != nil && !now.Before(f.start.Add(l.window)) {
+        delete(l.keys, key)
+        return nil
+    }
+    return f
+}
+
+func (l *MemoryLoginLimiter) RetryAfter(ctx context.Context, key string) (time.Duration, error) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    now := l.now()
+    f := l.current(key, now)
+    if f == nil || f.count < l.max {
+        return 0, nil
+    }
+    return f.start.Add(l.window).Sub(now), nil
+}
+
+func (l *MemoryLoginLimiter) Fail(ctx context.Context, key string) error {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    now := l.now()
+    l.sweep(now)
+    f := l.current(key, now)
+    if f == nil {
+        f = &failures{start: now}
+        l.keys[key] = f
+    }
+    f.count++
+    return nil
+}
+
+func (l *MemoryLoginLimiter) Reset(ctx context.Context, key string) error {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    delete(l.keys, key)
+    return nil
+}
+
+// sweep drops expired keys at most once per window, so addresses that fail
+// once and never come back do not pile up. The caller holds l.mu.
+func (l *MemoryLoginLimiter) sweep(now time.Time) {
+    if now.Sub(l.lastSweep) < l.window {
+        return
+    }
+    l.lastSweep = now
+    for key := range l.keys {
+        l.current(key, now)
+    }
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLoginLimiter_LocksUntilWindowCloses(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	l := NewMemoryLoginLimiter(2, 10*time.Minute)
+	l.now = func() time.Time { return now }
+
+	assert.NoError(t, l.Fail(ctx, "ip:1.2.3.4"))
+	now = now.Add(time.Minute)
+	assert.NoError(t, l.Fail(ctx, "ip:1.2.3.4"))
+
+	wait, err := l.RetryAfter(ctx, "ip:1.2.3.4")
+	assert.NoError(t, err)
+	assert.Equal(t, 9*time.Minute, wait) // the window opened at the first failure
+	wait, _ = l.RetryAfter(ctx, "ip:5.6.7.8")
+	assert.Zero(t, wait)
+
+	now = now.Add(9 * time.Minute)
+	wait, _ = l.RetryAfter(ctx, "ip:1.2.3.4")
+	assert.Zero(t, wait)
+
+	// A fresh window starts with the next failure.
+	assert.NoError(t, l.Fail(ctx, "ip:1.2.3.4"))
+	wait, _ = l.RetryAfter(ctx, "ip:1.2.3.4")
+	assert.Zero(t, wait)
+}
+
+func TestMemoryLoginLimiter_ResetAndSweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	l := NewMemoryLoginLimiter(1, time.Minute)
+	l.now = func() time.Time { return now }
+
+	assert.NoError(t, l.Fail(ctx, "email:a@example.com"))
+	assert.NoError(t, l.Reset(ctx, "email:a@example.com"))
+	wait, _ := l.RetryAfter(ctx, "email:a@example.com")
+	assert.Zero(t, wait)
+
+	assert.NoError(t, l.Fail(ctx, "email:b@example.com"))
+	now = now.Add(2 * time.Minute)
+	assert.NoError(t, l.Fail(ctx, "email:c@example.com"))
+	assert.Len(t, l.keys, 1, "expired keys are swept")
+}
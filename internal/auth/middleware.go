@@ -6,11 +6,21 @@ import (
     "strings"
 
     "github.com/gin-gonic/gin"
+    "go-discussion-app/internal/user"
     "go-discussion-app/pkg/jwtutil"
+    "go-discussion-app/pkg/logger"
 )
 
-// JWTAuthMiddleware enforces “Bearer <token>” and sets “userID” in context.
+// JWTAuthMiddleware enforces “Bearer <token>” and sets “userID” and “role” in context.
+// Only JWTs are accepted; use AuthMiddleware to also accept API tokens.
 func JWTAuthMiddleware() gin.HandlerFunc {
+    return AuthMiddleware(nil)
+}
+
+// AuthMiddleware is JWTAuthMiddleware that additionally accepts API tokens
+// (those starting with APITokenPrefix) by looking up their hash in tokens.
+// JWTs revoked by logout are rejected with 401.
+func AuthMiddleware(tokens TokenRepository) gin.HandlerFunc {
     return func(c *gin.Context) {
         auth := c.GetHeader("Authorization")
         parts := strings.SplitN(auth, " ", 2)
@@ -19,7 +29,25 @@ func JWTAuthMiddleware() gin.HandlerFunc {
             c.Abort()
             return
         }
-        uid, err := jwtutil.ExtractUserID(parts[1])
+        if tokens != nil && strings.HasPrefix(parts[1], APITokenPrefix) {
+            u, err := tokens.FindUserByHash(c.Request.Context(), hashToken(parts[1]))
+            if err != nil {
+                logger.Errorf("api token lookup error: %v", err)
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+                c.Abort()
+                return
+            }
+            if u == nil {
+                c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+                c.Abort()
+                return
+            }
+            c.Set("userID", u.ID)
+            c.Set("role", u.Role)
+            c.Next()
+            return
+        }
+        claims, err := jwtutil.ValidateToken(parts[1])
         if err != nil {
             if err == jwtutil.ErrTokenExpired {
                 c.JSON(http.StatusUnauthorized, gin.H{"error": "token expired"})
@@ -29,7 +57,76 @@ func JWTAuthMiddleware() gin.HandlerFunc {
             c.Abort()
             return
         }
-        c.Set("userID", uid)
+        if claims.ID != "" {
+            revoked, err := blacklist.Contains(c.Request.Context(), claims.ID)
+            if err != nil {
+                logger.Errorf("token blacklist lookup error: %v", err)
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+                c.Abort()
+                return
+            }
+            if revoked {
+                c.JSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+                c.Abort()
+                return
+            }
+        }
+        c.Set("userID", claims.UserID)
+        c.Set("role", claims.Role)
+        c.Next()
+    }
+}
+
+// bearerToken returns the token from an "Authorization: Bearer <token>"
+// header, or "" if there is none.
+func bearerToken(c *gin.Context) string {
+    parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+    if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+        return ""
+    }
+    return strings.TrimSpace(parts[1])
+}
+
+// RequireRole rejects requests whose token does not carry the given role.
+// It must run after JWTAuthMiddleware.
+func RequireRole(role string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if r, _ := GetRole(c); r != role {
+            c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+            c.Abort()
+            return
+        }
+        c.Next()
+    }
+}
+
+// RequireActive rejects users that were banned (403) or deleted (401) after
+// their token was issued. It must run after JWTAuthMiddleware.
+func RequireActive(users user.UserRepository) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        uid, ok := GetUserID(c)
+        if !ok {
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+            c.Abort()
+            return
+        }
+        u, err := users.GetByID(c.Request.Context(), uid)
+        if err != nil {
+            logger.Errorf("active user lookup error: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+            c.Abort()
+            return
+        }
+        if u == nil {
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+            c.Abort()
+            return
+        }
+        if u.IsBanned {
+            c.JSON(http.StatusForbidden, gin.H{"error": "account is banned"})
+            c.Abort()
+            return
+        }
         c.Next()
     }
 }
@@ -43,3 +140,13 @@ func GetUserID(c *gin.Context) (int, bool) {
     uid, ok := raw.(int)
     return uid, ok
 }
+
+// GetRole retrieves the authenticated user’s role from context.
+func GetRole(c *gin.Context) (string, bool) {
+    raw, exists := c.Get("role")
+    if !exists {
+        return "", false
+    }
+    role, ok := raw.(string)
+    return role, ok
+}
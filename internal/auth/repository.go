@@ -0,0 +1,280 @@
+// repository.go 
+package auth
+
+import (
+    "context"
+    "database/sql"
+    "time"
+
+    "go-discussion-app/models"
+    "go-discussion-app/pkg/slowquery"
+)
+
+// TokenRepository persists API tokens.
+type TokenRepository interface {
+    Create(ctx context.Context, t *models.APIToken) (int, error)
+    ListByUser(ctx context.Context, userID int) ([]models.APIToken, error)
+    Delete(ctx context.Context, id, userID int) (bool, error)
+    // FindUserByHash returns the owner (ID and role only) of the token with
+    // the given hash and marks it as used, or nil if no such token exists.
+    FindUserByHash(ctx context.Context, hash string) (*models.User, error)
+}
+
+type tokenRepo struct {
+    db *slowquery.DB
+}
+
+func NewTokenRepository(db *sql.DB) TokenRepository {
+    return &tokenRepo{db: slowquery.Wrap(db)}
+}
+
+func (r *tokenRepo) Create(ctx context.Context, t *models.APIToken) (int, error) {
+    const q = `
+      INSERT INTO api_tokens (user_id, name, token_hash, created_at)
+      VALUES ($1,$2,$3,$4)
+      RETURNING id;`
+    var id int
+    err := r.db.QueryRowContext(ctx, q, t.UserID, t.Name, t.TokenHash, t.CreatedAt).Scan(&id)
+    return id, err
+}
+
+func (r *tokenRepo) ListByUser(ctx context.Context, userID int) ([]models.APIToken, error) {
+    const q = `
+      SELECT id, user_id, name, created_at, last_used_at
+      FROM api_tokens
+      WHERE user_id = $1
+      ORDER BY created_at DESC, id DESC;`
+    rows, err := r.db.QueryContext(ctx, q, userID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var out []models.APIToken
+    for rows.Next() {
+        var t models.APIToken
+        var lastUsed sql.NullTime
+        if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt, &lastUsed); err != nil {
+            return nil, err
+        }
+        if lastUsed.Valid {
+            t.LastUsedAt = &lastUsed.Time
+        }
+        out = append(out, t)
+    }
+    return out, rows.Err()
+}
+
+func (r *tokenRepo) Delete(ctx context.Context, id, userID int) (bool, error) {
+    res, err := r.db.ExecContext(ctx,
+        `DELETE FROM api_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+    if err != nil {
+        return false, err
+    }
+    n, err := res.RowsAffected()
+    return n > 0, err
+}
+
+func (r *tokenRepo) FindUserByHash(ctx context.Context, hash string) (*models.User, error) {
+    const q = `
+      UPDATE api_tokens t
+      SET last_used_at = NOW()
+      FROM users u
+      WHERE t.token_hash = $1 AND u.id = t.user_id
+      RETURNING u.id, u.role;`
+    u := &models.User{}
+    err := r.db.QueryRowContext(ctx, q, hash).Scan(&u.ID, &u.Role)
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    return u, nil
+}
+
+// VerificationRepository stores the pending email-verification token of
+// each user.
+type VerificationRepository interface {
+    // LastSentAt returns when a verification email was last sent to userID,
+    // or the zero time if none is pending.
+    LastSentAt(ctx context.Context, userID int) (time.Time, error)
+    // Save replaces userID's pending token.
+    Save(ctx context.Context, userID int, tokenHash string, expiresAt, sentAt time.Time) error
+    // Consume deletes the unexpired token with the given hash, marks its
+    // owner verified at now and returns the owner's ID, or 0 if none matched.
+    Consume(ctx context.Context, tokenHash string, now time.Time) (int, error)
+}
+
+type verificationRepo struct {
+    db *slowquery.DB
+}
+
+func NewVerificationRepository(db *sql.DB) VerificationRepository {
+    return &verificationRepo{db: slowquery.Wrap(db)}
+}
+
+func (r *verificationRepo) LastSentAt(ctx context.Context, userID int) (time.Time, error) {
+    var t time.Time
+    err := r.db.QueryRowContext(ctx,
+        `SELECT sent_at FROM email_verifications WHERE user_id = $1`, userID).Scan(&t)
+    if err == sql.ErrNoRows {
+        return time.Time{}, nil
+    }
+    return t, err
+}
+
+func (r *verificationRepo) Save(ctx context.Context, userID int, tokenHash string, expiresAt, sentAt time.Time) error {
+    const q = `
+      INSERT INTO email_verifications (user_id, token_hash, expires_at, sent_at)
+      VALUES ($1,$2,$3,$4)
+      ON CONFLICT (user_id) DO UPDATE
+      SET token_hash = EXCLUDED.token_hash,
+          expires_at = EXCLUDED.expires_at,
+          sent_at    = EXCLUDED.sent_at;`
+    _, err := r.db.ExecContext(ctx, q, userID, tokenHash, expiresAt, sentAt)
+    return err
+}
+
+func (r *verificationRepo) Consume(ctx context.Context, tokenHash string, now time.Time) (int, error) {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    var userID int
+    err = tx.QueryRowContext(ctx,
+        `DELETE FROM email_verifications WHERE token_hash = $1 AND expires_at > $2 RETURNING user_id`,
+        tokenHash, now).Scan(&userID)
+    if err == sql.ErrNoRows {
+        return 0, nil
+    }
+    if err != nil {
+        return 0, err
+    }
+    if _, err := tx.ExecContext(ctx,
+        `UPDATE users SET email_verified_at = $1 WHERE id = $2`, now, userID); err != nil {
+        return 0, err
+    }
+    return userID, tx.Commit()
+}
+
+// PasswordResetRepository stores the pending password-reset token of each
+// user.
+type PasswordResetRepository interface {
+    // LastSentAt returns when userID's pending token was issued, or the zero
+    // time if none is pending.
+    LastSentAt(ctx context.Context, userID int) (time.Time, error)
+    // Save replaces userID's pending token.
+    Save(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error
+    // Consume deletes the unexpired token with the given hash, sets its
+    // owner's password to passwordHash, revokes all of the owner's refresh
+    // and API tokens and returns the owner's ID, or 0 if none matched.
+    Consume(ctx context.Context, tokenHash, passwordHash string, now time.Time) (int, error)
+}
+
+type passwordResetRepo struct {
+    db *slowquery.DB
+}
+
+func NewPasswordResetRepository(db *sql.DB) PasswordResetRepository {
+    return &passwordResetRepo{db: slowquery.Wrap(db)}
+}
+
+func (r *passwordResetRepo) LastSentAt(ctx context.Context, userID int) (time.Time, error) {
+    var t time.Time
+    err := r.db.QueryRowContext(ctx,
+        `SELECT created_at FROM password_resets WHERE user_id = $1`, userID).Scan(&t)
+    if err == sql.ErrNoRows {
+        return time.Time{}, nil
+    }
+    return t, err
+}
+
+func (r *passwordResetRepo) Save(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+    const q = `
+      INSERT INTO password_resets (user_id, token_hash, expires_at)
+      VALUES ($1,$2,$3)
+      ON CONFLICT (user_id) DO UPDATE
+      SET token_hash = EXCLUDED.token_hash,
+          expires_at = EXCLUDED.expires_at,
+          created_at = NOW();`
+    _, err := r.db.ExecContext(ctx, q, userID, tokenHash, expiresAt)
+    return err
+}
+
+// Consume does all its writes in one transaction, so no session that
+// predates the new password survives it.
+func (r *passwordResetRepo) Consume(ctx context.Context, tokenHash, passwordHash string, now time.Time) (int, error) {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    var userID int
+    err = tx.QueryRowContext(ctx,
+        `DELETE FROM password_resets WHERE token_hash = $1 AND expires_at > $2 RETURNING user_id`,
+        tokenHash, now).Scan(&userID)
+    if err == sql.ErrNoRows {
+        return 0, nil
+    }
+    if err != nil {
+        return 0, err
+    }
+    if _, err := tx.ExecContext(ctx,
+        `UPDATE users SET password_hash = $1, updated_at = $2 WHERE id = $3`, passwordHash, now, userID); err != nil {
+        return 0, err
+    }
+    if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1`, userID); err != nil {
+        return 0, err
+    }
+    if _, err := tx.ExecContext(ctx, `DELETE FROM api_tokens WHERE user_id = $1`, userID); err != nil {
+        return 0, err
+    }
+    return userID, tx.Commit()
+}
+
+// RefreshTokenRepository tracks issued refresh tokens by their jti so they
+// can be revoked.
+type RefreshTokenRepository interface {
+    Create(ctx context.Context, userID int, tokenID string, expiresAt time.Time) error
+    // Active reports whether tokenID belongs to userID, is unrevoked and
+    // has not expired by now.
+    Active(ctx context.Context, tokenID string, userID int, now time.Time) (bool, error)
+    // Delete revokes tokenID and reports whether it existed.
+    Delete(ctx context.Context, tokenID string) (bool, error)
+}
+
+type refreshTokenRepo struct {
+    db *slowquery.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) RefreshTokenRepository {
+    return &refreshTokenRepo{db: slowquery.Wrap(db)}
+}
+
+func (r *refreshTokenRepo) Create(ctx context.Context, userID int, tokenID string, expiresAt time.Time) error {
+    _, err := r.db.ExecContext(ctx,
+        `INSERT INTO refresh_tokens (user_id, token_id, expires_at) VALUES ($1,$2,$3)`,
+        userID, tokenID, expiresAt)
+    return err
+}
+
+func (r *refreshTokenRepo) Active(ctx context.Context, tokenID string, userID int, now time.Time) (bool, error) {
+    var ok bool
+    err := r.db.QueryRowContext(ctx,
+        `SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE token_id = $1 AND user_id = $2 AND expires_at > $3)`,
+        tokenID, userID, now).Scan(&ok)
+    return ok, err
+}
+
+func (r *refreshTokenRepo) Delete(ctx context.Context, tokenID string) (bool, error) {
+    res, err := r.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE token_id = $1`, tokenID)
+    if err != nil {
+        return false, err
+    }
+    n, err := res.RowsAffected()
+    return n > 0, err
+}
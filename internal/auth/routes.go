@@ -5,17 +5,37 @@ import (
     "database/sql"
 
     "github.com/gin-gonic/gin"
+    "go-discussion-app/config"
     "go-discussion-app/internal/user"
 )
 
-// RegisterRoutes mounts /auth/register and /auth/login.
+// RegisterRoutes mounts /auth/register, /auth/login, /auth/refresh,
+// /auth/logout, email verification, password reset and the /auth/tokens API.
 // Pass router, DB connection, and the JWT secret (if you want to use it in middleware).
-func RegisterRoutes(router *gin.Engine, dbConn *sql.DB) {
+func RegisterRoutes(router *gin.Engine, dbConn *sql.DB, cfg *config.Config) {
     userRepo := user.NewRepository(dbConn)
-    svc := NewService(userRepo)
+    verifySvc := NewVerificationService(userRepo, NewVerificationRepository(dbConn), cfg)
+    svc := NewService(userRepo, NewRefreshTokenRepository(dbConn), verifySvc, cfg)
     ctr := NewController(svc)
 
+    tokenRepo := NewTokenRepository(dbConn)
+    tokenCtr := NewTokenController(NewTokenService(tokenRepo))
+    verifyCtr := NewVerificationController(verifySvc)
+    resetCtr := NewPasswordResetController(NewPasswordResetService(userRepo, NewPasswordResetRepository(dbConn), cfg))
+
     grp := router.Group("/auth")
     grp.POST("/register", ctr.RegisterHandler)
     grp.POST("/login", ctr.LoginHandler)
+    grp.POST("/refresh", ctr.RefreshHandler)
+    grp.POST("/logout", ctr.LogoutHandler)
+    grp.GET("/verify", verifyCtr.Verify)
+    grp.POST("/resend-verification", AuthMiddleware(tokenRepo), verifyCtr.Resend)
+    grp.POST("/verify/resend", verifyCtr.ResendTo)
+    grp.POST("/forgot-password", resetCtr.Forgot)
+    grp.POST("/reset-password", resetCtr.Reset)
+
+    tokens := grp.Group("/tokens", AuthMiddleware(tokenRepo), RequireActive(userRepo))
+    tokens.POST("", tokenCtr.Create)
+    tokens.GET("", tokenCtr.List)
+    tokens.DELETE("/:id", tokenCtr.Revoke)
 }
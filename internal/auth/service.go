@@ -3,32 +3,145 @@ package auth
 
 import (
     "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
     "errors"
+    "net/url"
+    "strings"
     "time"
 
     "golang.org/x/crypto/bcrypt"
 
+    "go-discussion-app/config"
+    "go-discussion-app/internal/audit"
     "go-discussion-app/internal/user"
     "go-discussion-app/models"
     "go-discussion-app/pkg/jwtutil"
+    "go-discussion-app/pkg/logger"
+    "go-discussion-app/pkg/mailer"
 )
 
 var (
-    ErrUserExists         = errors.New("user with that email already exists")
-    ErrInvalidCredentials = errors.New("invalid email or password")
+    ErrUserExists          = errors.New("user with that email already exists")
+    ErrInvalidCredentials  = errors.New("invalid email or password")
+    ErrTokenNotFound       = errors.New("api token not found")
+    ErrAccountBanned       = errors.New("account is banned")
+    ErrEmailNotVerified    = errors.New("email address not verified")
+    ErrEmailDomainBlocked  = errors.New("email domain is not allowed")
+    ErrInvalidRefreshToken = errors.New("invalid, expired or revoked refresh token")
+    ErrInvalidAccessToken  = errors.New("invalid or expired access token")
+
+    ErrAlreadyVerified           = errors.New("email already verified")
+    ErrVerificationResendTooSoon = errors.New("verification email sent too recently")
+    ErrInvalidVerificationToken  = errors.New("invalid or expired verification token")
+
+    ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+    // ErrValidation matches, via errors.Is, the errors Register and Login
+    // return when the DTO fails Validate. Their message is the reason.
+    ErrValidation = errors.New("invalid input")
+    // ErrTooManyLoginAttempts matches, via errors.Is, the *LoginLockedError
+    // Login returns while an email or IP is locked out.
+    ErrTooManyLoginAttempts = errors.New("too many failed login attempts")
 )
 
+// invalidInput wraps a DTO validation failure so that it matches
+// ErrValidation while keeping the original message.
+type invalidInput struct{ err error }
+
+func (e invalidInput) Error() string        { return e.err.Error() }
+func (e invalidInput) Unwrap() error        { return e.err }
+func (e invalidInput) Is(target error) bool { return target == ErrValidation }
+
+// LoginLockedError tells the caller when it may try logging in again.
+type LoginLockedError struct {
+    RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string        { return ErrTooManyLoginAttempts.Error() }
+func (e *LoginLockedError) Is(target error) bool { return target == ErrTooManyLoginAttempts }
+
+const (
+    // VerificationTokenTTL is how long an emailed verification token stays valid.
+    VerificationTokenTTL = 24 * time.Hour
+    // VerificationResendInterval is the minimum gap between two verification
+    // emails to the same user.
+    VerificationResendInterval = 5 * time.Minute
+    // PasswordResetResendInterval is the minimum gap between two password
+    // reset emails to the same user.
+    PasswordResetResendInterval = 5 * time.Minute
+)
+
+// sendMail is the mail transport used for verification and password-reset
+// emails; tests swap it out.
+var sendMail = mailer.SendMail
+
+// goMail runs f in the background, so a reply does not wait on the mail
+// server; tests run f inline.
+var goMail = func(f func()) { go f() }
+
+// APITokenPrefix marks a bearer token as an API token rather than a JWT.
+const APITokenPrefix = "dga_"
+
+// TokenPair is what a successful login returns.
+type TokenPair struct {
+    AccessToken  string `json:"token"`
+    RefreshToken string `json:"refresh_token"`
+}
+
 type AuthService struct {
-    userRepo user.UserRepository
+    userRepo    user.UserRepository
+    refreshRepo RefreshTokenRepository
+    verify      *VerificationService
+    limiter     LoginLimiter
+    cfg         *config.Config
+}
+
+// NewService builds the AuthService. verify emails new users their
+// verification token; with a nil verify none is sent. Failed logins are
+// limited in memory per cfg.LoginMaxFailures and cfg.LoginFailureWindow.
+func NewService(uRepo user.UserRepository, refreshRepo RefreshTokenRepository, verify *VerificationService, cfg *config.Config) *AuthService {
+    if cfg == nil {
+        cfg = &config.Config{}
+    }
+    s := &AuthService{userRepo: uRepo, refreshRepo: refreshRepo, verify: verify, cfg: cfg}
+    if cfg.LoginMaxFailures > 0 {
+        s.limiter = NewMemoryLoginLimiter(cfg.LoginMaxFailures, cfg.LoginFailureWindow)
+    }
+    return s
+}
+
+// SetLoginLimiter replaces the failed-login limiter; nil disables it.
+func (s *AuthService) SetLoginLimiter(l LoginLimiter) {
+    s.limiter = l
 }
 
-func NewService(uRepo user.UserRepository) *AuthService {
-    return &AuthService{userRepo: uRepo}
+// domainAllowed reports whether email's domain is in cfg.AllowedEmailDomains.
+// An empty list allows every domain.
+func (s *AuthService) domainAllowed(email string) bool {
+    if len(s.cfg.AllowedEmailDomains) == 0 {
+        return true
+    }
+    at := strings.LastIndex(email, "@")
+    if at < 0 {
+        return false
+    }
+    domain := strings.ToLower(email[at+1:])
+    for _, d := range s.cfg.AllowedEmailDomains {
+        if domain == d {
+            return true
+        }
+    }
+    return false
 }
 
 func (s *AuthService) Register(ctx context.Context, dto *RegisterDTO) (int, error) {
     if err := dto.Validate(); err != nil {
-        return 0, err
+        return 0, invalidInput{err}
+    }
+    if !s.domainAllowed(dto.Email) {
+        return 0, ErrEmailDomainBlocked
     }
 
     if existing, err := s.userRepo.GetByEmail(ctx, dto.Email); err != nil {
@@ -49,27 +162,403 @@ func (s *AuthService) Register(ctx context.Context, dto *RegisterDTO) (int, erro
         PasswordHash: string(hashed),
         FullName:     dto.FullName,
         Bio:          dto.Bio,
+        Role:         models.RoleMember,
         CreatedAt:    now,
         UpdatedAt:    now,
     }
-    return s.userRepo.Create(ctx, u)
+    id, err := s.userRepo.Create(ctx, u)
+    if err != nil {
+        return 0, err
+    }
+    u.ID = id
+
+    // The account exists either way; a lost email can be re-requested
+    // through POST /auth/verify/resend.
+    if s.verify != nil {
+        if err := s.verify.send(ctx, u, now); err != nil {
+            logger.Warnf("verification email for user %d not sent: %v", id, err)
+        }
+    }
+    return id, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, dto *LoginDTO) (string, error) {
+// Login checks the credentials and issues an access token plus a refresh
+// token, which is recorded so Logout can revoke it. Wrong credentials count
+// against both the email and ip; once either is locked out Login returns a
+// *LoginLockedError without checking the password.
+func (s *AuthService) Login(ctx context.Context, dto *LoginDTO, ip string) (*TokenPair, error) {
     if err := dto.Validate(); err != nil {
-        return "", err
+        return nil, invalidInput{err}
+    }
+    emailKey := "email:" + strings.ToLower(dto.Email)
+    keys := []string{emailKey}
+    if ip != "" {
+        keys = append(keys, "ip:"+ip)
+    }
+    if err := s.checkLockout(ctx, keys); err != nil {
+        return nil, err
     }
 
     u, err := s.userRepo.GetByEmail(ctx, dto.Email)
     if err != nil {
-        return "", err
+        return nil, err
     }
     if u == nil {
-        return "", ErrInvalidCredentials
+        s.loginFailed(ctx, keys)
+        return nil, ErrInvalidCredentials
     }
     if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(dto.Password)); err != nil {
-        return "", ErrInvalidCredentials
+        s.loginFailed(ctx, keys)
+        return nil, ErrInvalidCredentials
+    }
+    // Only the email is cleared: resetting the ip too would let one client
+    // wipe its count by logging into its own account between guesses.
+    if s.limiter != nil {
+        if err := s.limiter.Reset(ctx, emailKey); err != nil {
+            logger.Warnf("login limiter reset %s: %v", emailKey, err)
+        }
+    }
+    if u.IsBanned {
+        return nil, ErrAccountBanned
+    }
+    if u.EmailVerifiedAt == nil {
+        return nil, ErrEmailNotVerified
+    }
+
+    token, err := jwtutil.GenerateTokenWithRole(u.ID, u.Role)
+    if err != nil {
+        return nil, err
+    }
+    refresh, tokenID, expiresAt, err := jwtutil.GenerateRefreshToken(u.ID)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.refreshRepo.Create(ctx, u.ID, tokenID, expiresAt); err != nil {
+        return nil, err
+    }
+    audit.Record(ctx, u.ID, audit.ActionLogin, audit.Target("user", u.ID))
+    return &TokenPair{AccessToken: token, RefreshToken: refresh}, nil
+}
+
+// checkLockout returns a *LoginLockedError for the longest lockout among
+// keys. Limiter errors are logged and let the attempt through, so a limiter
+// outage does not stop everyone logging in.
+func (s *AuthService) checkLockout(ctx context.Context, keys []string) error {
+    if s.limiter == nil {
+        return nil
+    }
+    var wait time.Duration
+    for _, key := range keys {
+        d, err := s.limiter.RetryAfter(ctx, key)
+        if err != nil {
+            logger.Warnf("login limiter check %s: %v", key, err)
+            continue
+        }
+        if d > wait {
+            wait = d
+        }
+    }
+    if wait > 0 {
+        return &LoginLockedError{RetryAfter: wait}
+    }
+    return nil
+}
+
+// loginFailed counts a wrong-credentials attempt against every key.
+func (s *AuthService) loginFailed(ctx context.Context, keys []string) {
+    if s.limiter == nil {
+        return
+    }
+    for _, key := range keys {
+        if err := s.limiter.Fail(ctx, key); err != nil {
+            logger.Warnf("login limiter fail %s: %v", key, err)
+        }
+    }
+}
+
+// Refresh exchanges a valid, unrevoked refresh token for a new access token
+// carrying the user's current role.
+func (s *AuthService) Refresh(ctx context.Context, raw string) (string, error) {
+    claims, err := jwtutil.ValidateRefreshToken(raw)
+    if err != nil {
+        return "", ErrInvalidRefreshToken
+    }
+    ok, err := s.refreshRepo.Active(ctx, claims.ID, claims.UserID, time.Now().UTC())
+    if err != nil {
+        return "", err
+    }
+    if !ok {
+        return "", ErrInvalidRefreshToken
+    }
+    u, err := s.userRepo.GetByID(ctx, claims.UserID)
+    if err != nil {
+        return "", err
+    }
+    if u == nil {
+        return "", ErrInvalidRefreshToken
+    }
+    if u.IsBanned {
+        return "", ErrAccountBanned
+    }
+    return jwtutil.GenerateTokenWithRole(u.ID, u.Role)
+}
+
+// RevokeAccessToken blacklists the access token raw until it expires, so
+// AuthMiddleware rejects it from now on. Tokens issued without a jti cannot
+// be revoked and are left to expire.
+func (s *AuthService) RevokeAccessToken(ctx context.Context, raw string) error {
+    claims, err := jwtutil.ValidateToken(raw)
+    if err != nil {
+        return ErrInvalidAccessToken
+    }
+    if claims.ID == "" || claims.ExpiresAt == nil {
+        return nil
+    }
+    return blacklist.Add(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+// Logout revokes the refresh token. Revoking one that is already gone is
+// not an error.
+func (s *AuthService) Logout(ctx context.Context, raw string) error {
+    claims, err := jwtutil.ValidateRefreshToken(raw)
+    if err != nil {
+        return ErrInvalidRefreshToken
+    }
+    _, err = s.refreshRepo.Delete(ctx, claims.ID)
+    return err
+}
+
+// TokenService manages long-lived API tokens.
+type TokenService struct {
+    repo TokenRepository
+}
+
+func NewTokenService(repo TokenRepository) *TokenService {
+    return &TokenService{repo: repo}
+}
+
+// Create issues a new token for userID. The raw token is returned only here;
+// just its hash is stored.
+func (s *TokenService) Create(ctx context.Context, userID int, dto *CreateTokenDTO) (*models.APIToken, string, error) {
+    if err := dto.Validate(); err != nil {
+        return nil, "", err
+    }
+
+    secret, err := randomToken()
+    if err != nil {
+        return nil, "", err
+    }
+    raw := APITokenPrefix + secret
+
+    t := &models.APIToken{
+        UserID:    userID,
+        Name:      strings.TrimSpace(dto.Name),
+        TokenHash: hashToken(raw),
+        CreatedAt: time.Now().UTC(),
+    }
+    id, err := s.repo.Create(ctx, t)
+    if err != nil {
+        return nil, "", err
+    }
+    t.ID = id
+    return t, raw, nil
+}
+
+func (s *TokenService) List(ctx context.Context, userID int) ([]models.APIToken, error) {
+    return s.repo.ListByUser(ctx, userID)
+}
+
+// Revoke deletes one of userID's tokens. Tokens owned by someone else are
+// reported as ErrTokenNotFound.
+func (s *TokenService) Revoke(ctx context.Context, id, userID int) error {
+    ok, err := s.repo.Delete(ctx, id, userID)
+    if err != nil {
+        return err
+    }
+    if !ok {
+        return ErrTokenNotFound
+    }
+    return nil
+}
+
+// randomToken returns 32 random bytes, hex encoded.
+func randomToken() (string, error) {
+    buf := make([]byte, 32)
+    if _, err := rand.Read(buf); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(buf), nil
+}
+
+func hashToken(raw string) string {
+    sum := sha256.Sum256([]byte(raw))
+    return hex.EncodeToString(sum[:])
+}
+
+// VerificationService issues and redeems email-verification tokens.
+type VerificationService struct {
+    users user.UserRepository
+    repo  VerificationRepository
+    cfg   *config.Config
+}
+
+// NewVerificationService links emailed tokens to cfg.BaseURL.
+func NewVerificationService(users user.UserRepository, repo VerificationRepository, cfg *config.Config) *VerificationService {
+    if cfg == nil {
+        cfg = &config.Config{}
     }
+    return &VerificationService{users: users, repo: repo, cfg: cfg}
+}
 
-    return jwtutil.GenerateToken(u.ID)
+// Resend emails userID a fresh verification token, replacing any pending one.
+func (s *VerificationService) Resend(ctx context.Context, userID int) error {
+    u, err := s.users.GetByID(ctx, userID)
+    if err != nil {
+        return err
+    }
+    if u == nil {
+        return user.ErrUserNotFound
+    }
+    return s.resend(ctx, u)
+}
+
+// ResendTo is Resend for a user who cannot log in yet, looked up by email.
+// Unknown, already verified and recently mailed addresses are skipped
+// without error, so the reply does not reveal which emails are registered.
+func (s *VerificationService) ResendTo(ctx context.Context, email string) error {
+    u, err := s.users.GetByEmail(ctx, strings.TrimSpace(email))
+    if err != nil || u == nil {
+        return err
+    }
+    err = s.resend(ctx, u)
+    if err == ErrVerificationResendTooSoon || err == ErrAlreadyVerified {
+        return nil
+    }
+    return err
+}
+
+func (s *VerificationService) resend(ctx context.Context, u *models.User) error {
+    if u.EmailVerifiedAt != nil {
+        return ErrAlreadyVerified
+    }
+    now := time.Now().UTC()
+    last, err := s.repo.LastSentAt(ctx, u.ID)
+    if err != nil {
+        return err
+    }
+    if !last.IsZero() && now.Sub(last) < VerificationResendInterval {
+        return ErrVerificationResendTooSoon
+    }
+    return s.send(ctx, u, now)
+}
+
+// send stores a fresh token for u, replacing any pending one, and emails it.
+func (s *VerificationService) send(ctx context.Context, u *models.User, now time.Time) error {
+    raw, err := randomToken()
+    if err != nil {
+        return err
+    }
+    if err := s.repo.Save(ctx, u.ID, hashToken(raw), now.Add(VerificationTokenTTL), now); err != nil {
+        return err
+    }
+    link := s.cfg.BaseURL + "/auth/verify?" + url.Values{"token": {raw}}.Encode()
+    body := "Confirm your email address within 24 hours by opening this link:\n\n" + link
+    return sendMail([]string{u.Email}, "Verify your email address", body)
+}
+
+// Verify redeems a token sent by Resend.
+func (s *VerificationService) Verify(ctx context.Context, raw string) error {
+    id, err := s.repo.Consume(ctx, hashToken(raw), time.Now().UTC())
+    if err != nil {
+        return err
+    }
+    if id == 0 {
+        return ErrInvalidVerificationToken
+    }
+    return nil
+}
+
+// PasswordResetService issues and redeems password-reset tokens.
+type PasswordResetService struct {
+    users user.UserRepository
+    repo  PasswordResetRepository
+    cfg   *config.Config
+}
+
+func NewPasswordResetService(users user.UserRepository, repo PasswordResetRepository, cfg *config.Config) *PasswordResetService {
+    if cfg == nil {
+        cfg = &config.Config{}
+    }
+    return &PasswordResetService{users: users, repo: repo, cfg: cfg}
+}
+
+// ttl falls back to an hour when PasswordResetTTL is unset.
+func (s *PasswordResetService) ttl() time.Duration {
+    if s.cfg.PasswordResetTTL > 0 {
+        return s.cfg.PasswordResetTTL
+    }
+    return time.Hour
+}
+
+// Request emails a reset token to email if it belongs to an account and was
+// not sent one in the last PasswordResetResendInterval. Unknown and recently
+// mailed addresses are skipped without error, and the email goes out in the
+// background, so neither the response nor its timing reveals which emails
+// are registered.
+func (s *PasswordResetService) Request(ctx context.Context, email string) error {
+    u, err := s.users.GetByEmail(ctx, strings.TrimSpace(email))
+    if err != nil {
+        return err
+    }
+    if u == nil {
+        return nil
+    }
+    now := time.Now().UTC()
+    last, err := s.repo.LastSentAt(ctx, u.ID)
+    if err != nil {
+        return err
+    }
+    if !last.IsZero() && now.Sub(last) < PasswordResetResendInterval {
+        return nil
+    }
+
+    raw, err := randomToken()
+    if err != nil {
+        return err
+    }
+    ttl := s.ttl()
+    if err := s.repo.Save(ctx, u.ID, hashToken(raw), now.Add(ttl)); err != nil {
+        return err
+    }
+    body := "Someone asked to reset the password for this account. If it was you, use this token:\n\n" + raw +
+        "\n\nSubmit it with your new password to POST /auth/reset-password within " + ttl.String() +
+        ". If you did not ask for a reset you can ignore this email."
+    goMail(func() {
+        if err := sendMail([]string{u.Email}, "Reset your password", body); err != nil {
+            logger.Errorf("password reset email to user %d failed: %v", u.ID, err)
+        }
+    })
+    return nil
+}
+
+// Reset redeems a token sent by Request and sets the owner's new password.
+// Redeeming deletes the token, so it cannot be used twice, and signs the
+// owner out everywhere: their refresh and API tokens are revoked along with
+// the password change.
+func (s *PasswordResetService) Reset(ctx context.Context, dto *ResetPasswordDTO) error {
+    if err := dto.Validate(); err != nil {
+        return err
+    }
+    hashed, err := bcrypt.GenerateFromPassword([]byte(dto.NewPassword), bcrypt.DefaultCost)
+    if err != nil {
+        return err
+    }
+    userID, err := s.repo.Consume(ctx, hashToken(dto.Token), string(hashed), time.Now().UTC())
+    if err != nil {
+        return err
+    }
+    if userID == 0 {
+        return ErrInvalidResetToken
+    }
+    return nil
 }
@@ -2,20 +2,36 @@
 package comment
 
 import (
+    "context"
     "net/http"
     "strconv"
 
     "github.com/gin-gonic/gin"
+    "go-discussion-app/config"
+    "go-discussion-app/pkg/httperr"
     "go-discussion-app/pkg/logger"
+    "go-discussion-app/pkg/pagination"
     "go-discussion-app/internal/auth"
+    "go-discussion-app/models"
 )
 
+// DefaultPageSize is how many comments GET /discussions/:id/comments returns
+// when the client sends no ?limit=.
+const DefaultPageSize = 50
+
+// DiscussionLookup is the slice of discussion.Service the comment
+// controller needs to resolve a comment's parent.
+type DiscussionLookup interface {
+    GetByID(ctx context.Context, id int) (*models.Discussion, error)
+}
+
 type Controller struct {
-    svc Service
+    svc         Service
+    discussions DiscussionLookup
 }
 
-func NewController(svc Service) *Controller {
-    return &Controller{svc: svc}
+func NewController(svc Service, discussions DiscussionLookup) *Controller {
+    return &Controller{svc: svc, discussions: discussions}
 }
 
 // POST /discussions/:id/comments
@@ -46,8 +62,23 @@ func (ctr *Controller) Create(c *gin.Context) {
     }
 
     // Call service
-    commentID, err := ctr.svc.AddComment(c.Request.Context(), discID, userID, dto.Content)
+    commentID, err := ctr.svc.AddComment(c.Request.Context(), discID, userID, dto.Content, dto.ParentID)
+    if err == ErrCommentCooldown {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "you are commenting too fast, try again shortly"})
+        return
+    }
+    if err == ErrInvalidParent {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err == ErrDiscussionArchived {
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+        return
+    }
     if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
         logger.Errorf("failed to add comment: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "could not add comment"})
         return
@@ -56,7 +87,7 @@ func (ctr *Controller) Create(c *gin.Context) {
     c.JSON(http.StatusCreated, gin.H{"id": commentID})
 }
 
-// GET /discussions/:id/comments
+// GET /discussions/:id/comments?order=oldest|newest&limit=&offset=
 func (ctr *Controller) List(c *gin.Context) {
     discID, err := strconv.Atoi(c.Param("id"))
     if err != nil {
@@ -64,12 +95,301 @@ func (ctr *Controller) List(c *gin.Context) {
         return
     }
 
-    comments, err := ctr.svc.GetComments(c.Request.Context(), discID)
+    order := c.Query("order")
+    if order != "" && !config.ValidCommentOrder(order) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "order must be oldest or newest"})
+        return
+    }
+
+    page, err := pagination.FromQueryDefault(c, DefaultPageSize)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    comments, total, err := ctr.svc.GetComments(c.Request.Context(), discID, order, page.Limit, page.Offset)
     if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
         logger.Errorf("failed to list comments: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch comments"})
         return
     }
 
+    // next_cursor is the offset of the following page, null on the last one.
+    c.JSON(http.StatusOK, gin.H{
+        "data":        comments,
+        "limit":       page.Limit,
+        "offset":      page.Offset,
+        "total":       total,
+        "next_cursor": page.NextOffset(len(comments), total),
+    })
+}
+
+// GET /discussions/:id/comments/tree
+func (ctr *Controller) Tree(c *gin.Context) {
+    discID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid discussion ID"})
+        return
+    }
+
+    tree, err := ctr.svc.GetCommentTree(c.Request.Context(), discID)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("failed to build comment tree: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch comments"})
+        return
+    }
+
+    c.JSON(http.StatusOK, tree)
+}
+
+// GET /comments?ids=1,2,3
+func (ctr *Controller) BatchGet(c *gin.Context) {
+    ids, err := parseIDs(c.Query("ids"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    comments, err := ctr.svc.GetCommentsByIDs(c.Request.Context(), ids)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("failed to fetch comments by ids: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch comments"})
+        return
+    }
+
     c.JSON(http.StatusOK, comments)
 }
+
+// GET /comments/:id/discussion
+func (ctr *Controller) GetDiscussion(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid comment ID"})
+        return
+    }
+
+    cm, err := ctr.svc.GetComment(c.Request.Context(), id)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("failed to fetch comment: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch discussion"})
+        return
+    }
+    if cm == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+        return
+    }
+
+    d, err := ctr.discussions.GetByID(c.Request.Context(), cm.DiscussionID)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("failed to fetch parent discussion: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch discussion"})
+        return
+    }
+    viewerID, _ := auth.GetUserID(c)
+    if d == nil || (d.Status == models.StatusDraft && d.UserID != viewerID) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "discussion not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, d)
+}
+
+// PATCH /comments/:id
+func (ctr *Controller) Patch(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid comment ID"})
+        return
+    }
+
+    var dto PatchCommentDTO
+    if err := c.ShouldBindJSON(&dto); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+        return
+    }
+    if err := dto.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if !ctr.authorizeOwner(c, 0, id) {
+        return
+    }
+
+    updated, err := ctr.svc.UpdateContent(c.Request.Context(), id, dto.Content)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("failed to update comment: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update comment"})
+        return
+    }
+    if updated == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, updated)
+}
+
+// PUT /discussions/:id/comments/:commentId
+func (ctr *Controller) Update(c *gin.Context) {
+    discussionID, id, ok := parseCommentPath(c)
+    if !ok {
+        return
+    }
+
+    var dto PatchCommentDTO
+    if err := c.ShouldBindJSON(&dto); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+        return
+    }
+    if err := dto.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if !ctr.authorizeOwner(c, discussionID, id) {
+        return
+    }
+
+    updated, err := ctr.svc.UpdateContent(c.Request.Context(), id, dto.Content)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("failed to update comment: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update comment"})
+        return
+    }
+    if updated == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+        return
+    }
+    c.JSON(http.StatusOK, updated)
+}
+
+// DELETE /discussions/:id/comments/:commentId
+func (ctr *Controller) Delete(c *gin.Context) {
+    discussionID, id, ok := parseCommentPath(c)
+    if !ok {
+        return
+    }
+    if !ctr.authorizeOwner(c, discussionID, id) {
+        return
+    }
+    if err := ctr.svc.DeleteComment(c.Request.Context(), id); err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("failed to delete comment: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not delete comment"})
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
+
+// DELETE /users/:id/comments?ids=1,2,3 (self only)
+func (ctr *Controller) BulkDelete(c *gin.Context) {
+    userID, ok := auth.GetUserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+        return
+    }
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+        return
+    }
+    if id != userID {
+        c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+        return
+    }
+    ids, err := parseIDs(c.Query("ids"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    deleted, err := ctr.svc.DeleteOwnComments(c.Request.Context(), userID, ids)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("failed to bulk delete comments: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not delete comments"})
+        return
+    }
+
+    done := make(map[int]bool, len(deleted))
+    for _, id := range deleted {
+        done[id] = true
+    }
+    skipped := []int{}
+    for _, id := range ids {
+        if !done[id] {
+            skipped = append(skipped, id)
+        }
+    }
+    c.JSON(http.StatusOK, gin.H{"deleted": deleted, "skipped": skipped})
+}
+
+// parseCommentPath reads :id and :commentId, writing 400 if either is not a
+// number.
+func parseCommentPath(c *gin.Context) (discussionID, commentID int, ok bool) {
+    discussionID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid discussion ID"})
+        return 0, 0, false
+    }
+    commentID, err = strconv.Atoi(c.Param("commentId"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid comment ID"})
+        return 0, 0, false
+    }
+    return discussionID, commentID, true
+}
+
+// authorizeOwner checks that the caller wrote comment id. A non-zero
+// discussionID must also match the comment's discussion. Otherwise it writes
+// 401, 404 or 403 (500 for lookup errors) and reports false.
+func (ctr *Controller) authorizeOwner(c *gin.Context, discussionID, id int) bool {
+    userID, ok := auth.GetUserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+        return false
+    }
+    existing, err := ctr.svc.GetComment(c.Request.Context(), id)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return false
+        }
+        logger.Errorf("failed to fetch comment: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch comment"})
+        return false
+    }
+    if existing == nil || (discussionID != 0 && existing.DiscussionID != discussionID) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+        return false
+    }
+    if existing.UserID != userID {
+        c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+        return false
+    }
+    return true
+}
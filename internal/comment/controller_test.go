@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -24,19 +25,85 @@ type MockCommentService struct {
 	mock.Mock
 }
 
-func (m *MockCommentService) AddComment(ctx context.Context, discussionID, userID int, content string) (int, error) {
-	args := m.Called(ctx, discussionID, userID, content)
+func (m *MockCommentService) AddComment(ctx context.Context, discussionID, userID int, content string, parentID *int) (int, error) {
+	args := m.Called(ctx, discussionID, userID, content, parentID)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *MockCommentService) GetComments(ctx context.Context, discussionID int) ([]models.Comment, error) {
+func (m *MockCommentService) GetCommentTree(ctx context.Context, discussionID int) ([]*CommentNode, error) {
 	args := m.Called(ctx, discussionID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
+	return args.Get(0).([]*CommentNode), args.Error(1)
+}
+
+func (m *MockCommentService) GetComments(ctx context.Context, discussionID int, order string, limit, offset int) ([]models.Comment, int, error) {
+	args := m.Called(ctx, discussionID, order, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]models.Comment), args.Int(1), args.Error(2)
+}
+
+func (m *MockCommentService) ListPage(ctx context.Context, discussionID, limit, offset int) ([]models.Comment, int, error) {
+	args := m.Called(ctx, discussionID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]models.Comment), args.Int(1), args.Error(2)
+}
+
+func (m *MockCommentService) GetComment(ctx context.Context, id int) (*models.Comment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockCommentService) GetCommentsByIDs(ctx context.Context, ids []int) ([]models.Comment, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).([]models.Comment), args.Error(1)
 }
 
+func (m *MockCommentService) UpdateContent(ctx context.Context, id int, content string) (*models.Comment, error) {
+	args := m.Called(ctx, id, content)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockCommentService) DeleteComment(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockCommentService) DeleteOwnComments(ctx context.Context, userID int, ids []int) ([]int, error) {
+	args := m.Called(ctx, userID, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+// MockDiscussionLookup is a mock implementation of DiscussionLookup
+type MockDiscussionLookup struct {
+	mock.Mock
+}
+
+func (m *MockDiscussionLookup) GetByID(ctx context.Context, id int) (*models.Discussion, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Discussion), args.Error(1)
+}
+
 // Helper to generate a JWT token for testing
 func generateTestTokenComment(userID int) string {
 	token, err := jwtutil.GenerateToken(userID)
@@ -48,9 +115,13 @@ func generateTestTokenComment(userID int) string {
 
 // Helper to set up the Gin router with CommentController and middleware
 func setupCommentTestRouter(mockService Service) *gin.Engine {
+	return setupCommentTestRouterWithDiscussions(mockService, new(MockDiscussionLookup))
+}
+
+func setupCommentTestRouterWithDiscussions(mockService Service, discussions DiscussionLookup) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-	commentController := NewController(mockService)
+	commentController := NewController(mockService, discussions)
 
 	// Apply JWT middleware to the group where comment routes are defined
 	// This assumes that these routes are intended to be protected.
@@ -61,6 +132,13 @@ func setupCommentTestRouter(mockService Service) *gin.Engine {
 		// The :id here is discussionID
 		authedRoutes.POST("/discussions/:id/comments", commentController.Create)
 		authedRoutes.GET("/discussions/:id/comments", commentController.List)
+		authedRoutes.GET("/discussions/:id/comments/tree", commentController.Tree)
+		authedRoutes.GET("/comments", commentController.BatchGet)
+		authedRoutes.PATCH("/comments/:id", commentController.Patch)
+		authedRoutes.PUT("/discussions/:id/comments/:commentId", commentController.Update)
+		authedRoutes.DELETE("/discussions/:id/comments/:commentId", commentController.Delete)
+		authedRoutes.GET("/comments/:id/discussion", commentController.GetDiscussion)
+		authedRoutes.DELETE("/users/:id/comments", commentController.BulkDelete)
 	}
 	return router
 }
@@ -95,7 +173,7 @@ func TestCreateComment_Success(t *testing.T) {
 	dto := CreateCommentDTO{Content: "This is a test comment."}
 	expectedCommentID := 123
 
-	mockService.On("AddComment", mock.Anything, discussionID, actingUserID, dto.Content).Return(expectedCommentID, nil)
+	mockService.On("AddComment", mock.Anything, discussionID, actingUserID, dto.Content, (*int)(nil)).Return(expectedCommentID, nil)
 
 	w := performCommentRequest(router, "POST", fmt.Sprintf("/discussions/%d/comments", discussionID), token, dto)
 
@@ -117,7 +195,7 @@ func TestCreateComment_Unauthorized_NoToken(t *testing.T) {
 
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 	// Service should not be called
-	mockService.AssertNotCalled(t, "AddComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockService.AssertNotCalled(t, "AddComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestCreateComment_InvalidDiscussionID_Format(t *testing.T) {
@@ -177,7 +255,7 @@ func TestCreateComment_ServiceError(t *testing.T) {
 	token := generateTestTokenComment(actingUserID)
 	dto := CreateCommentDTO{Content: "Valid comment"}
 
-	mockService.On("AddComment", mock.Anything, discussionID, actingUserID, dto.Content).Return(0, assert.AnError)
+	mockService.On("AddComment", mock.Anything, discussionID, actingUserID, dto.Content, (*int)(nil)).Return(0, assert.AnError)
 
 	w := performCommentRequest(router, "POST", fmt.Sprintf("/discussions/%d/comments", discussionID), token, dto)
 
@@ -201,16 +279,25 @@ func TestListComments_Success(t *testing.T) {
 		{ID: 1, DiscussionID: discussionID, UserID: 1, Content: "Comment 1"},
 		{ID: 2, DiscussionID: discussionID, UserID: 2, Content: "Comment 2"},
 	}
-	mockService.On("GetComments", mock.Anything, discussionID).Return(expectedComments, nil)
+	mockService.On("GetComments", mock.Anything, discussionID, "", DefaultPageSize, 0).Return(expectedComments, 2, nil)
 
 	w := performCommentRequest(router, "GET", fmt.Sprintf("/discussions/%d/comments", discussionID), token, nil)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	var comments []models.Comment
-	err := json.Unmarshal(w.Body.Bytes(), &comments)
+	var resp struct {
+		Data       []models.Comment `json:"data"`
+		Limit      int              `json:"limit"`
+		Offset     int              `json:"offset"`
+		Total      int              `json:"total"`
+		NextCursor *int             `json:"next_cursor"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
 	assert.NoError(t, err)
-	assert.Len(t, comments, 2)
-	assert.Equal(t, expectedComments[0].Content, comments[0].Content)
+	assert.Len(t, resp.Data, 2)
+	assert.Equal(t, expectedComments[0].Content, resp.Data[0].Content)
+	assert.Equal(t, DefaultPageSize, resp.Limit)
+	assert.Equal(t, 2, resp.Total)
+	assert.Nil(t, resp.NextCursor)
 	mockService.AssertExpectations(t)
 }
 
@@ -221,15 +308,50 @@ func TestListComments_Success_NoComments(t *testing.T) {
 	token := generateTestTokenComment(1)
 	expectedComments := []models.Comment{} // Empty slice
 
-	mockService.On("GetComments", mock.Anything, discussionID).Return(expectedComments, nil)
+	mockService.On("GetComments", mock.Anything, discussionID, "", DefaultPageSize, 0).Return(expectedComments, 0, nil)
 
 	w := performCommentRequest(router, "GET", fmt.Sprintf("/discussions/%d/comments", discussionID), token, nil)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	var comments []models.Comment
-	err := json.Unmarshal(w.Body.Bytes(), &comments)
-	assert.NoError(t, err)
-	assert.Len(t, comments, 0)
+	assert.JSONEq(t, `{"data":[],"limit":50,"offset":0,"total":0,"next_cursor":null}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestListComments_OrderParam(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	mockService.On("GetComments", mock.Anything, 10, "newest", DefaultPageSize, 0).Return([]models.Comment{}, 0, nil)
+
+	w := performCommentRequest(router, "GET", "/discussions/10/comments?order=newest", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performCommentRequest(router, "GET", "/discussions/10/comments?order=sideways", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestListComments_Paging(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	page := []models.Comment{{ID: 11, DiscussionID: 10}, {ID: 12, DiscussionID: 10}}
+	mockService.On("GetComments", mock.Anything, 10, "", 2, 4).Return(page, 9, nil)
+
+	w := performCommentRequest(router, "GET", "/discussions/10/comments?limit=2&offset=4", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(2), resp["limit"])
+	assert.Equal(t, float64(4), resp["offset"])
+	assert.Equal(t, float64(9), resp["total"])
+	assert.Equal(t, float64(6), resp["next_cursor"])
+
+	for _, q := range []string{"limit=-1", "offset=-5", "limit=abc"} {
+		w = performCommentRequest(router, "GET", "/discussions/10/comments?"+q, token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
 	mockService.AssertExpectations(t)
 }
 
@@ -253,7 +375,7 @@ func TestListComments_ServiceError(t *testing.T) {
 	discussionID := 10
 	token := generateTestTokenComment(1)
 
-	mockService.On("GetComments", mock.Anything, discussionID).Return(nil, assert.AnError)
+	mockService.On("GetComments", mock.Anything, discussionID, "", DefaultPageSize, 0).Return(nil, 0, assert.AnError)
 
 	w := performCommentRequest(router, "GET", fmt.Sprintf("/discussions/%d/comments", discussionID), token, nil)
 
@@ -278,7 +400,461 @@ func TestListComments_Unauthorized_NoToken(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 }
 
+// --- Patch Comment Tests (PATCH /comments/:id) ---
+
+func TestPatchComment_Success(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	actingUserID := 1
+	commentID := 5
+	token := generateTestTokenComment(actingUserID)
+
+	existing := &models.Comment{ID: commentID, DiscussionID: 10, UserID: actingUserID, Content: "old"}
+	updated := &models.Comment{ID: commentID, DiscussionID: 10, UserID: actingUserID, Content: "fixed typo"}
+	mockService.On("GetComment", mock.Anything, commentID).Return(existing, nil)
+	mockService.On("UpdateContent", mock.Anything, commentID, "fixed typo").Return(updated, nil)
+
+	w := performCommentRequest(router, "PATCH", fmt.Sprintf("/comments/%d", commentID), token, PatchCommentDTO{Content: "  fixed typo  "})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp models.Comment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "fixed typo", resp.Content)
+	mockService.AssertExpectations(t)
+}
+
+func TestPatchComment_EmptyContent(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	w := performCommentRequest(router, "PATCH", "/comments/5", token, PatchCommentDTO{Content: "   "})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "content is required", resp["error"])
+	mockService.AssertNotCalled(t, "UpdateContent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPatchComment_TooLong(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	long := make([]byte, MaxContentLength+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+	w := performCommentRequest(router, "PATCH", "/comments/5", token, PatchCommentDTO{Content: string(long)})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPatchComment_Forbidden_NotAuthor(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	commentID := 5
+	token := generateTestTokenComment(2)
+
+	existing := &models.Comment{ID: commentID, DiscussionID: 10, UserID: 1, Content: "old"}
+	mockService.On("GetComment", mock.Anything, commentID).Return(existing, nil)
+
+	w := performCommentRequest(router, "PATCH", fmt.Sprintf("/comments/%d", commentID), token, PatchCommentDTO{Content: "hijack"})
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "UpdateContent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPatchComment_NotFound(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	mockService.On("GetComment", mock.Anything, 5).Return(nil, nil)
+
+	w := performCommentRequest(router, "PATCH", "/comments/5", token, PatchCommentDTO{Content: "x"})
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+// --- Update / Delete Comment Tests (/discussions/:id/comments/:commentId) ---
+
+func TestUpdateComment_Success(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	existing := &models.Comment{ID: 5, DiscussionID: 10, UserID: 1, Content: "teh"}
+	updated := &models.Comment{ID: 5, DiscussionID: 10, UserID: 1, Content: "the"}
+	mockService.On("GetComment", mock.Anything, 5).Return(existing, nil)
+	mockService.On("UpdateContent", mock.Anything, 5, "the").Return(updated, nil)
+
+	w := performCommentRequest(router, "PUT", "/discussions/10/comments/5", token, PatchCommentDTO{Content: "the"})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp models.Comment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "the", resp.Content)
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateComment_Forbidden_NotAuthor(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(2)
+
+	mockService.On("GetComment", mock.Anything, 5).Return(&models.Comment{ID: 5, DiscussionID: 10, UserID: 1}, nil)
+
+	w := performCommentRequest(router, "PUT", "/discussions/10/comments/5", token, PatchCommentDTO{Content: "hijack"})
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error":"forbidden"}`, w.Body.String())
+	mockService.AssertNotCalled(t, "UpdateContent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateComment_WrongDiscussion(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	mockService.On("GetComment", mock.Anything, 5).Return(&models.Comment{ID: 5, DiscussionID: 10, UserID: 1}, nil)
+
+	w := performCommentRequest(router, "PUT", "/discussions/11/comments/5", token, PatchCommentDTO{Content: "x"})
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertNotCalled(t, "UpdateContent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateComment_InvalidPayload(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	w := performCommentRequest(router, "PUT", "/discussions/10/comments/5", token, PatchCommentDTO{Content: " "})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performCommentRequest(router, "PUT", "/discussions/10/comments/abc", token, PatchCommentDTO{Content: "x"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "GetComment", mock.Anything, mock.Anything)
+}
+
+func TestDeleteComment_Success(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	mockService.On("GetComment", mock.Anything, 5).Return(&models.Comment{ID: 5, DiscussionID: 10, UserID: 1}, nil)
+	mockService.On("DeleteComment", mock.Anything, 5).Return(nil)
+
+	w := performCommentRequest(router, "DELETE", "/discussions/10/comments/5", token, nil)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteComment_Forbidden_NotAuthor(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(2)
+
+	mockService.On("GetComment", mock.Anything, 5).Return(&models.Comment{ID: 5, DiscussionID: 10, UserID: 1}, nil)
+
+	w := performCommentRequest(router, "DELETE", "/discussions/10/comments/5", token, nil)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "DeleteComment", mock.Anything, mock.Anything)
+}
+
+func TestDeleteComment_NotFound(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	mockService.On("GetComment", mock.Anything, 5).Return(nil, nil)
+
+	w := performCommentRequest(router, "DELETE", "/discussions/10/comments/5", token, nil)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertNotCalled(t, "DeleteComment", mock.Anything, mock.Anything)
+}
+
+func TestDeleteComment_ServiceError(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	mockService.On("GetComment", mock.Anything, 5).Return(&models.Comment{ID: 5, DiscussionID: 10, UserID: 1}, nil)
+	mockService.On("DeleteComment", mock.Anything, 5).Return(assert.AnError)
+
+	w := performCommentRequest(router, "DELETE", "/discussions/10/comments/5", token, nil)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestCreateComment_Cooldown(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+	dto := CreateCommentDTO{Content: "too soon"}
+
+	mockService.On("AddComment", mock.Anything, 10, 1, dto.Content, (*int)(nil)).Return(0, ErrCommentCooldown)
+
+	w := performCommentRequest(router, "POST", "/discussions/10/comments", token, dto)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateComment_ArchivedDiscussion(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+	dto := CreateCommentDTO{Content: "still open?"}
+
+	mockService.On("AddComment", mock.Anything, 10, 1, dto.Content, (*int)(nil)).Return(0, ErrDiscussionArchived)
+
+	w := performCommentRequest(router, "POST", "/discussions/10/comments", token, dto)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+// --- Batch Get Tests (GET /comments?ids=) ---
+
+func TestBatchGetComments_Success(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	comments := []models.Comment{{ID: 3}, {ID: 1}}
+	mockService.On("GetCommentsByIDs", mock.Anything, []int{3, 1, 2}).Return(comments, nil)
+
+	w := performCommentRequest(router, "GET", "/comments?ids=3,1,2,3", token, nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp []models.Comment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp[0].ID)
+	assert.Equal(t, 1, resp[1].ID)
+	mockService.AssertExpectations(t)
+}
+
+func TestBatchGetComments_InvalidIDs(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	for _, q := range []string{"", "?ids=", "?ids=1,abc", "?ids=0"} {
+		w := performCommentRequest(router, "GET", "/comments"+q, token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	mockService.AssertNotCalled(t, "GetCommentsByIDs", mock.Anything, mock.Anything)
+}
+
+func TestBatchGetComments_TooManyIDs(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	ids := make([]string, MaxBatchIDs+1)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i + 1)
+	}
+	w := performCommentRequest(router, "GET", "/comments?ids="+strings.Join(ids, ","), token, nil)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "GetCommentsByIDs", mock.Anything, mock.Anything)
+
+	// exactly the cap is accepted
+	mockService.On("GetCommentsByIDs", mock.Anything, mock.Anything).Return([]models.Comment{}, nil)
+	w = performCommentRequest(router, "GET", "/comments?ids="+strings.Join(ids[:MaxBatchIDs], ","), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// --- Bulk Delete Tests (DELETE /users/:id/comments?ids=) ---
+
+func TestBulkDeleteComments_ReportsDeletedAndSkipped(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	mockService.On("DeleteOwnComments", mock.Anything, 1, []int{4, 7, 9}).Return([]int{4, 9}, nil)
+
+	w := performCommentRequest(router, "DELETE", "/users/1/comments?ids=4,7,9", token, nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string][]int
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []int{4, 9}, resp["deleted"])
+	assert.Equal(t, []int{7}, resp["skipped"])
+	mockService.AssertExpectations(t)
+}
+
+func TestBulkDeleteComments_OtherUserForbidden(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(2)
+
+	w := performCommentRequest(router, "DELETE", "/users/1/comments?ids=4", token, nil)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "DeleteOwnComments", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBulkDeleteComments_InvalidIDs(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	for _, q := range []string{"", "?ids=", "?ids=1,abc", "?ids=-3"} {
+		w := performCommentRequest(router, "DELETE", "/users/1/comments"+q, token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	mockService.AssertNotCalled(t, "DeleteOwnComments", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBulkDeleteComments_ServiceError(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	token := generateTestTokenComment(1)
+
+	mockService.On("DeleteOwnComments", mock.Anything, 1, []int{4}).Return(nil, assert.AnError)
+
+	w := performCommentRequest(router, "DELETE", "/users/1/comments?ids=4", token, nil)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+// --- Parent Discussion Tests (GET /comments/:id/discussion) ---
+
+func TestGetCommentDiscussion_Success(t *testing.T) {
+	mockService := new(MockCommentService)
+	discussions := new(MockDiscussionLookup)
+	router := setupCommentTestRouterWithDiscussions(mockService, discussions)
+	token := generateTestTokenComment(1)
+
+	mockService.On("GetComment", mock.Anything, 5).Return(&models.Comment{ID: 5, DiscussionID: 10}, nil)
+	discussions.On("GetByID", mock.Anything, 10).Return(&models.Discussion{ID: 10, Title: "parent"}, nil)
+
+	w := performCommentRequest(router, "GET", "/comments/5/discussion", token, nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var d models.Discussion
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+	assert.Equal(t, 10, d.ID)
+	mockService.AssertExpectations(t)
+	discussions.AssertExpectations(t)
+}
+
+func TestGetCommentDiscussion_CommentNotFound(t *testing.T) {
+	mockService := new(MockCommentService)
+	discussions := new(MockDiscussionLookup)
+	router := setupCommentTestRouterWithDiscussions(mockService, discussions)
+	token := generateTestTokenComment(1)
+
+	mockService.On("GetComment", mock.Anything, 5).Return(nil, nil)
+
+	w := performCommentRequest(router, "GET", "/comments/5/discussion", token, nil)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	discussions.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+func TestGetCommentDiscussion_DiscussionNotFound(t *testing.T) {
+	mockService := new(MockCommentService)
+	discussions := new(MockDiscussionLookup)
+	router := setupCommentTestRouterWithDiscussions(mockService, discussions)
+	token := generateTestTokenComment(1)
+
+	mockService.On("GetComment", mock.Anything, 5).Return(&models.Comment{ID: 5, DiscussionID: 10}, nil)
+	discussions.On("GetByID", mock.Anything, 10).Return(nil, nil)
+
+	w := performCommentRequest(router, "GET", "/comments/5/discussion", token, nil)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	discussions.AssertExpectations(t)
+}
+
 // Note: Tests for Update and Delete are not included as these functionalities
 // are not present in the provided CommentController or CommentService.
 // If they were, tests similar to those in user/controller_test.go or discussion/controller_test.go
 // for Update/Delete (including AuthZ checks for author) would be added here.
+
+// --- Threaded replies ---
+
+func TestCreateComment_Reply(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	parent := 3
+
+	mockService.On("AddComment", mock.Anything, 10, 1, "agreed", &parent).Return(7, nil)
+
+	w := performCommentRequest(router, "POST", "/discussions/10/comments", generateTestTokenComment(1),
+		CreateCommentDTO{Content: "agreed", ParentID: &parent})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateComment_ParentInOtherDiscussion(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	parent := 3
+
+	mockService.On("AddComment", mock.Anything, 10, 1, "agreed", &parent).Return(0, ErrInvalidParent)
+
+	w := performCommentRequest(router, "POST", "/discussions/10/comments", generateTestTokenComment(1),
+		CreateCommentDTO{Content: "agreed", ParentID: &parent})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), ErrInvalidParent.Error())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateComment_NonPositiveParent(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+
+	w := performCommentRequest(router, "POST", "/discussions/10/comments", generateTestTokenComment(1),
+		map[string]interface{}{"content": "agreed", "parent_id": 0})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "AddComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCommentTree_NestedResponse(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+	one := 1
+	tree := buildTree([]models.Comment{
+		{ID: 1, DiscussionID: 10, Content: "root"},
+		{ID: 2, DiscussionID: 10, ParentID: &one, Content: "reply"},
+	})
+	mockService.On("GetCommentTree", mock.Anything, 10).Return(tree, nil)
+
+	w := performCommentRequest(router, "GET", "/discussions/10/comments/tree", generateTestTokenComment(1), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got []struct {
+		ID       int  `json:"id"`
+		ParentID *int `json:"parent_id"`
+		Replies  []struct {
+			ID       int           `json:"id"`
+			ParentID *int          `json:"parent_id"`
+			Replies  []interface{} `json:"replies"`
+		} `json:"replies"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	if assert.Len(t, got, 1) && assert.Len(t, got[0].Replies, 1) {
+		assert.Nil(t, got[0].ParentID)
+		assert.Equal(t, 2, got[0].Replies[0].ID)
+		assert.Equal(t, 1, *got[0].Replies[0].ParentID)
+		assert.NotNil(t, got[0].Replies[0].Replies, "leaf replies encode as []")
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestCommentTree_InvalidDiscussionID(t *testing.T) {
+	mockService := new(MockCommentService)
+	router := setupCommentTestRouter(mockService)
+
+	w := performCommentRequest(router, "GET", "/discussions/abc/comments/tree", generateTestTokenComment(1), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "GetCommentTree", mock.Anything, mock.Anything)
+}
@@ -1,16 +1,107 @@
 package comment
 
-import "errors"
+import (
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
 
-// CreateCommentDTO binds the JSON body for creating a comment.
+    "go-discussion-app/models"
+)
+
+// MaxContentLength caps the size of a comment body.
+const MaxContentLength = 10000
+
+// MaxBatchIDs caps how many ids GET /comments?ids= and
+// DELETE /users/:id/comments?ids= accept at once.
+const MaxBatchIDs = 100
+
+// parseIDs parses a comma-separated list of positive comment ids,
+// dropping duplicates while keeping the first-seen order.
+func parseIDs(raw string) ([]int, error) {
+    if strings.TrimSpace(raw) == "" {
+        return nil, errors.New("ids is required")
+    }
+    parts := strings.Split(raw, ",")
+    seen := make(map[int]bool, len(parts))
+    ids := make([]int, 0, len(parts))
+    for _, p := range parts {
+        id, err := strconv.Atoi(strings.TrimSpace(p))
+        if err != nil || id <= 0 {
+            return nil, fmt.Errorf("invalid comment id %q", p)
+        }
+        if seen[id] {
+            continue
+        }
+        seen[id] = true
+        ids = append(ids, id)
+    }
+    if len(ids) > MaxBatchIDs {
+        return nil, fmt.Errorf("at most %d ids may be requested", MaxBatchIDs)
+    }
+    return ids, nil
+}
+
+// CreateCommentDTO binds the JSON body for creating a comment. ParentID,
+// when set, makes the comment a reply to another comment in the same discussion.
 type CreateCommentDTO struct {
-    Content string `json:"content"`
+    Content  string `json:"content"`
+    ParentID *int   `json:"parent_id,omitempty"`
 }
 
-// Validate ensures the content is not empty.
+// Validate ensures the content is not empty and any parent_id is a valid id.
 func (dto *CreateCommentDTO) Validate() error {
     if dto.Content == "" {
         return errors.New("content is required")
     }
+    if dto.ParentID != nil && *dto.ParentID <= 0 {
+        return errors.New("parent_id must be a positive integer")
+    }
+    return nil
+}
+
+// PatchCommentDTO binds the JSON body for PATCH /comments/:id and
+// PUT /discussions/:id/comments/:commentId.
+type PatchCommentDTO struct {
+    Content string `json:"content"`
+}
+
+// Validate trims the content and ensures it is non-empty and within MaxContentLength.
+func (dto *PatchCommentDTO) Validate() error {
+    dto.Content = strings.TrimSpace(dto.Content)
+    if dto.Content == "" {
+        return errors.New("content is required")
+    }
+    if len([]rune(dto.Content)) > MaxContentLength {
+        return errors.New("content is too long")
+    }
     return nil
 }
+
+// CommentNode is a comment together with its replies, oldest first, as
+// returned by GET /discussions/:id/comments/tree.
+type CommentNode struct {
+    models.Comment
+    Replies []*CommentNode `json:"replies"`
+}
+
+// buildTree nests comments by ParentID, keeping their order at every level.
+// A comment whose parent is not in the list is treated as top-level.
+func buildTree(comments []models.Comment) []*CommentNode {
+    nodes := make(map[int]*CommentNode, len(comments))
+    for _, c := range comments {
+        nodes[c.ID] = &CommentNode{Comment: c, Replies: []*CommentNode{}}
+    }
+    roots := []*CommentNode{}
+    for _, c := range comments {
+        n := nodes[c.ID]
+        if c.ParentID != nil {
+            if parent, ok := nodes[*c.ParentID]; ok && parent != n {
+                parent.Replies = append(parent.Replies, n)
+                continue
+            }
+        }
+        roots = append(roots, n)
+    }
+    return roots
+}
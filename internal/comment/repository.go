@@ -4,44 +4,229 @@ package comment
 import (
     "context"
     "database/sql"
+    "fmt"
+    "time"
+
+    "github.com/lib/pq"
 
     "go-discussion-app/models"
+    "go-discussion-app/pkg/slowquery"
 )
 
 type Repository interface {
     Create(ctx context.Context, c *models.Comment) (int, error)
-    ListByDiscussion(ctx context.Context, discussionID int) ([]models.Comment, error)
+    ListByDiscussion(ctx context.Context, discussionID int, newestFirst bool, limit, offset int) ([]models.Comment, error)
+    CountByDiscussion(ctx context.Context, discussionID int) (int, error)
+    GetByID(ctx context.Context, id int) (*models.Comment, error)
+    GetByIDs(ctx context.Context, ids []int) ([]models.Comment, error)
+    UpdateContent(ctx context.Context, id int, content string, updatedAt time.Time, editedAt *time.Time) error
+    Delete(ctx context.Context, id int, at time.Time) error
+    SoftDeleteOwned(ctx context.Context, userID int, ids []int, at time.Time) ([]int, error)
+    LastCreatedByUser(ctx context.Context, userID int) (time.Time, error)
+    ListRecentByUser(ctx context.Context, userID, limit int) ([]models.Comment, error)
+    ListRecentRepliesTo(ctx context.Context, ownerID, limit int) ([]models.Comment, error)
 }
 
 type repository struct {
-    db *sql.DB
+    db *slowquery.DB
 }
 
 func NewRepository(db *sql.DB) Repository {
-    return &repository{db: db}
+    return &repository{db: slowquery.Wrap(db)}
 }
 
+// Create inserts c and returns its id, or ErrDiscussionArchived if its
+// discussion has been archived. The check is part of the insert, so a
+// comment racing the stale sweep cannot land on a closed discussion.
 func (r *repository) Create(ctx context.Context, c *models.Comment) (int, error) {
     const q = `
-      INSERT INTO comments (discussion_id, user_id, content, created_at)
-      VALUES ($1, $2, $3, $4)
+      INSERT INTO comments (discussion_id, user_id, parent_id, content, created_at, updated_at)
+      SELECT $1, $2, $3, $4, $5, $6
+      WHERE NOT EXISTS (SELECT 1 FROM discussions WHERE id = $1 AND status = $7)
       RETURNING id;
     `
     var id int
     err := r.db.QueryRowContext(ctx, q,
-        c.DiscussionID, c.UserID, c.Content, c.CreatedAt,
+        c.DiscussionID, c.UserID, c.ParentID, c.Content, c.CreatedAt, c.UpdatedAt, models.StatusArchived,
     ).Scan(&id)
+    if err == sql.ErrNoRows {
+        return 0, ErrDiscussionArchived
+    }
     return id, err
 }
 
-func (r *repository) ListByDiscussion(ctx context.Context, discussionID int) ([]models.Comment, error) {
-    const q = `
-      SELECT id, discussion_id, user_id, content, created_at
+// ListByDiscussion returns a discussion's comments, oldest first unless
+// newestFirst is set, skipping offset and returning at most limit of them.
+// A limit of 0 returns every comment, as the tree view needs.
+func (r *repository) ListByDiscussion(ctx context.Context, discussionID int, newestFirst bool, limit, offset int) ([]models.Comment, error) {
+    dir := "ASC"
+    if newestFirst {
+        dir = "DESC"
+    }
+    q := fmt.Sprintf(`
+      SELECT id, discussion_id, user_id, parent_id, content, created_at, updated_at, edited_at
       FROM comments
-      WHERE discussion_id = $1
-      ORDER BY created_at ASC;
+      WHERE discussion_id = $1 AND deleted_at IS NULL
+      ORDER BY created_at %s, id %s`, dir, dir)
+    args := []interface{}{discussionID}
+    if limit > 0 {
+        q += `
+      LIMIT $2 OFFSET $3`
+        args = append(args, limit, offset)
+    }
+    rows, err := r.db.QueryContext(ctx, q, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    comments := []models.Comment{}
+    for rows.Next() {
+        var c models.Comment
+        if err := rows.Scan(&c.ID, &c.DiscussionID, &c.UserID, &c.ParentID, &c.Content, &c.CreatedAt, &c.UpdatedAt, &c.EditedAt); err != nil {
+            return nil, err
+        }
+        comments = append(comments, c)
+    }
+    return comments, rows.Err()
+}
+
+func (r *repository) CountByDiscussion(ctx context.Context, discussionID int) (int, error) {
+    var n int
+    err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM comments WHERE discussion_id = $1 AND deleted_at IS NULL;`, discussionID).Scan(&n)
+    return n, err
+}
+
+// GetByID returns the comment with id, or nil, nil when it does not exist
+// or has been deleted.
+func (r *repository) GetByID(ctx context.Context, id int) (*models.Comment, error) {
+    const q = `
+      SELECT id, discussion_id, user_id, parent_id, content, created_at, updated_at, edited_at
+      FROM comments WHERE id = $1 AND deleted_at IS NULL;
+    `
+    var c models.Comment
+    err := r.db.QueryRowContext(ctx, q, id).Scan(
+        &c.ID, &c.DiscussionID, &c.UserID, &c.ParentID, &c.Content, &c.CreatedAt, &c.UpdatedAt, &c.EditedAt,
+    )
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &c, nil
+}
+
+// GetByIDs returns the comments whose id is in ids, in no particular order.
+// Ids that do not exist are simply absent from the result.
+func (r *repository) GetByIDs(ctx context.Context, ids []int) ([]models.Comment, error) {
+    const q = `
+      SELECT id, discussion_id, user_id, parent_id, content, created_at, updated_at, edited_at
+      FROM comments WHERE id = ANY($1) AND deleted_at IS NULL;
+    `
+    rows, err := r.db.QueryContext(ctx, q, pq.Array(ids))
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var comments []models.Comment
+    for rows.Next() {
+        var c models.Comment
+        if err := rows.Scan(&c.ID, &c.DiscussionID, &c.UserID, &c.ParentID, &c.Content, &c.CreatedAt, &c.UpdatedAt, &c.EditedAt); err != nil {
+            return nil, err
+        }
+        comments = append(comments, c)
+    }
+    return comments, rows.Err()
+}
+
+func (r *repository) UpdateContent(ctx context.Context, id int, content string, updatedAt time.Time, editedAt *time.Time) error {
+    const q = `UPDATE comments SET content = $1, updated_at = $2, edited_at = $3 WHERE id = $4;`
+    _, err := r.db.ExecContext(ctx, q, content, updatedAt, editedAt, id)
+    return err
+}
+
+// Delete marks comment id as deleted at at, like SoftDeleteOwned, and turns
+// its replies into top-level comments.
+func (r *repository) Delete(ctx context.Context, id int, at time.Time) error {
+    const q = `
+      WITH deleted AS (
+        UPDATE comments SET deleted_at = $1
+        WHERE id = $2 AND deleted_at IS NULL
+        RETURNING id
+      )
+      UPDATE comments SET parent_id = NULL WHERE parent_id IN (SELECT id FROM deleted);
+    `
+    _, err := r.db.ExecContext(ctx, q, at, id)
+    return err
+}
+
+// SoftDeleteOwned marks those of ids that userID wrote as deleted at at and
+// returns the ids it changed. Comments owned by someone else, already
+// deleted or missing are left alone.
+func (r *repository) SoftDeleteOwned(ctx context.Context, userID int, ids []int, at time.Time) ([]int, error) {
+    const q = `
+      UPDATE comments SET deleted_at = $1
+      WHERE id = ANY($2) AND user_id = $3 AND deleted_at IS NULL
+      RETURNING id;
+    `
+    rows, err := r.db.QueryContext(ctx, q, at, pq.Array(ids), userID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var deleted []int
+    for rows.Next() {
+        var id int
+        if err := rows.Scan(&id); err != nil {
+            return nil, err
+        }
+        deleted = append(deleted, id)
+    }
+    return deleted, rows.Err()
+}
+
+// LastCreatedByUser returns when userID last commented, or the zero time if
+// never. Deleted comments still count, so deleting one does not lift the
+// cooldown.
+func (r *repository) LastCreatedByUser(ctx context.Context, userID int) (time.Time, error) {
+    const q = `SELECT MAX(created_at) FROM comments WHERE user_id = $1;`
+    var t sql.NullTime
+    if err := r.db.QueryRowContext(ctx, q, userID).Scan(&t); err != nil {
+        return time.Time{}, err
+    }
+    return t.Time, nil
+}
+
+// ListRecentByUser returns the newest limit comments written by userID.
+func (r *repository) ListRecentByUser(ctx context.Context, userID, limit int) ([]models.Comment, error) {
+    const q = `
+      SELECT id, discussion_id, user_id, parent_id, content, created_at, updated_at, edited_at
+      FROM comments WHERE user_id = $1 AND deleted_at IS NULL
+      ORDER BY created_at DESC, id DESC
+      LIMIT $2;
     `
-    rows, err := r.db.QueryContext(ctx, q, discussionID)
+    return r.queryComments(ctx, q, userID, limit)
+}
+
+// ListRecentRepliesTo returns the newest limit comments other users left on
+// discussions owned by ownerID.
+func (r *repository) ListRecentRepliesTo(ctx context.Context, ownerID, limit int) ([]models.Comment, error) {
+    const q = `
+      SELECT c.id, c.discussion_id, c.user_id, c.parent_id, c.content, c.created_at, c.updated_at, c.edited_at
+      FROM comments c
+      JOIN discussions d ON d.id = c.discussion_id
+      WHERE d.user_id = $1 AND c.user_id <> $1 AND c.deleted_at IS NULL
+      ORDER BY c.created_at DESC, c.id DESC
+      LIMIT $2;
+    `
+    return r.queryComments(ctx, q, ownerID, limit)
+}
+
+func (r *repository) queryComments(ctx context.Context, q string, args ...interface{}) ([]models.Comment, error) {
+    rows, err := r.db.QueryContext(ctx, q, args...)
     if err != nil {
         return nil, err
     }
@@ -50,7 +235,7 @@ func (r *repository) ListByDiscussion(ctx context.Context, discussionID int) ([]
     var comments []models.Comment
     for rows.Next() {
         var c models.Comment
-        if err := rows.Scan(&c.ID, &c.DiscussionID, &c.UserID, &c.Content, &c.CreatedAt); err != nil {
+        if err := rows.Scan(&c.ID, &c.DiscussionID, &c.UserID, &c.ParentID, &c.Content, &c.CreatedAt, &c.UpdatedAt, &c.EditedAt); err != nil {
             return nil, err
         }
         comments = append(comments, c)
@@ -5,13 +5,24 @@ import (
     "database/sql"
 
     "github.com/gin-gonic/gin"
+    "go-discussion-app/config"
+    "go-discussion-app/internal/discussion"
+    "go-discussion-app/internal/tag"
 )
 
-func RegisterRoutes(rg *gin.RouterGroup, db *sql.DB) {
+func RegisterRoutes(rg *gin.RouterGroup, db *sql.DB, cfg *config.Config) {
     repo := NewRepository(db)
-    svc := NewService(repo)
-    ctr := NewController(svc)
+    svc := NewService(repo, cfg)
+    discSvc := discussion.NewService(discussion.NewRepository(db), tag.NewRepository(db), cfg)
+    ctr := NewController(svc, discSvc)
 
     rg.POST("/discussions/:id/comments", ctr.Create)
     rg.GET("/discussions/:id/comments", ctr.List)
+    rg.GET("/discussions/:id/comments/tree", ctr.Tree)
+    rg.PUT("/discussions/:id/comments/:commentId", ctr.Update)
+    rg.DELETE("/discussions/:id/comments/:commentId", ctr.Delete)
+    rg.GET("/comments", ctr.BatchGet)
+    rg.PATCH("/comments/:id", ctr.Patch)
+    rg.GET("/comments/:id/discussion", ctr.GetDiscussion)
+    rg.DELETE("/users/:id/comments", ctr.BulkDelete)
 }
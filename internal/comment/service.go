@@ -3,34 +3,186 @@ package comment
 
 import (
     "context"
+    "errors"
     "time"
 
+    "go-discussion-app/config"
     "go-discussion-app/models"
 )
 
+// ErrCommentCooldown is returned when a user comments again before
+// cfg.CommentCooldown has passed since their previous comment.
+var ErrCommentCooldown = errors.New("commenting too fast")
+
+// ErrInvalidParent is returned when a reply's parent_id names a comment that
+// does not exist or belongs to another discussion.
+var ErrInvalidParent = errors.New("parent comment not found in this discussion")
+
+// ErrDiscussionArchived is returned when commenting on an archived
+// discussion, including one closed by the stale sweep.
+var ErrDiscussionArchived = errors.New("discussion is archived")
+
 type Service interface {
-    AddComment(ctx context.Context, discussionID, userID int, content string) (int, error)
-    GetComments(ctx context.Context, discussionID int) ([]models.Comment, error)
+    AddComment(ctx context.Context, discussionID, userID int, content string, parentID *int) (int, error)
+    GetComments(ctx context.Context, discussionID int, order string, limit, offset int) ([]models.Comment, int, error)
+    GetCommentTree(ctx context.Context, discussionID int) ([]*CommentNode, error)
+    ListPage(ctx context.Context, discussionID, limit, offset int) ([]models.Comment, int, error)
+    GetComment(ctx context.Context, id int) (*models.Comment, error)
+    GetCommentsByIDs(ctx context.Context, ids []int) ([]models.Comment, error)
+    UpdateContent(ctx context.Context, id int, content string) (*models.Comment, error)
+    DeleteComment(ctx context.Context, id int) error
+    DeleteOwnComments(ctx context.Context, userID int, ids []int) ([]int, error)
 }
 
 type service struct {
     repo Repository
+    cfg  *config.Config
 }
 
-func NewService(repo Repository) Service {
-    return &service{repo: repo}
+func NewService(repo Repository, cfg *config.Config) Service {
+    if cfg == nil {
+        cfg = &config.Config{}
+    }
+    return &service{repo: repo, cfg: cfg}
 }
 
-func (s *service) AddComment(ctx context.Context, discussionID, userID int, content string) (int, error) {
+// AddComment stores a comment, or a reply when parentID is set. A parent
+// outside discussionID yields ErrInvalidParent, and an archived discussion
+// ErrDiscussionArchived.
+func (s *service) AddComment(ctx context.Context, discussionID, userID int, content string, parentID *int) (int, error) {
+    now := time.Now().UTC()
+    if s.cfg.CommentCooldown > 0 {
+        last, err := s.repo.LastCreatedByUser(ctx, userID)
+        if err != nil {
+            return 0, err
+        }
+        if !last.IsZero() && now.Sub(last) < s.cfg.CommentCooldown {
+            return 0, ErrCommentCooldown
+        }
+    }
+    if parentID != nil {
+        parent, err := s.repo.GetByID(ctx, *parentID)
+        if err != nil {
+            return 0, err
+        }
+        if parent == nil || parent.DiscussionID != discussionID {
+            return 0, ErrInvalidParent
+        }
+    }
     comment := &models.Comment{
         DiscussionID: discussionID,
         UserID:       userID,
+        ParentID:     parentID,
         Content:      content,
-        CreatedAt:    time.Now().UTC(),
+        CreatedAt:    now,
+        UpdatedAt:    now,
     }
     return s.repo.Create(ctx, comment)
 }
 
-func (s *service) GetComments(ctx context.Context, discussionID int) ([]models.Comment, error) {
-    return s.repo.ListByDiscussion(ctx, discussionID)
+// GetComments returns one page of a discussion's comments in order
+// ("oldest" or "newest"), along with the discussion's total comment count;
+// an empty order falls back to cfg.DefaultCommentOrder. No comments yields
+// an empty slice rather than nil so the response encodes as [].
+func (s *service) GetComments(ctx context.Context, discussionID int, order string, limit, offset int) ([]models.Comment, int, error) {
+    if order == "" {
+        order = s.cfg.DefaultCommentOrder
+    }
+    total, err := s.repo.CountByDiscussion(ctx, discussionID)
+    if err != nil {
+        return nil, 0, err
+    }
+    comments, err := s.repo.ListByDiscussion(ctx, discussionID, order == config.CommentOrderNewest, limit, offset)
+    if err != nil {
+        return nil, 0, err
+    }
+    if comments == nil {
+        comments = []models.Comment{}
+    }
+    return comments, total, nil
+}
+
+// GetCommentTree returns a discussion's comments nested under the comments
+// they reply to, oldest first at every level.
+func (s *service) GetCommentTree(ctx context.Context, discussionID int) ([]*CommentNode, error) {
+    comments, err := s.repo.ListByDiscussion(ctx, discussionID, false, 0, 0)
+    if err != nil {
+        return nil, err
+    }
+    return buildTree(comments), nil
+}
+
+// ListPage returns one page of a discussion's comments, oldest first
+// whatever the configured default, along with the discussion's total
+// comment count.
+func (s *service) ListPage(ctx context.Context, discussionID, limit, offset int) ([]models.Comment, int, error) {
+    return s.GetComments(ctx, discussionID, config.CommentOrderOldest, limit, offset)
+}
+
+func (s *service) GetComment(ctx context.Context, id int) (*models.Comment, error) {
+    return s.repo.GetByID(ctx, id)
+}
+
+// GetCommentsByIDs returns the comments in the order of ids, skipping any
+// that do not exist.
+func (s *service) GetCommentsByIDs(ctx context.Context, ids []int) ([]models.Comment, error) {
+    found, err := s.repo.GetByIDs(ctx, ids)
+    if err != nil {
+        return nil, err
+    }
+    byID := make(map[int]models.Comment, len(found))
+    for _, c := range found {
+        byID[c.ID] = c
+    }
+    ordered := make([]models.Comment, 0, len(found))
+    for _, id := range ids {
+        if c, ok := byID[id]; ok {
+            ordered = append(ordered, c)
+        }
+    }
+    return ordered, nil
+}
+
+// UpdateContent replaces only the comment's content and bumps UpdatedAt,
+// and EditedAt too when the content actually differs.
+// Returns nil, nil if the comment does not exist.
+func (s *service) UpdateContent(ctx context.Context, id int, content string) (*models.Comment, error) {
+    c, err := s.repo.GetByID(ctx, id)
+    if err != nil || c == nil {
+        return nil, err
+    }
+    c.UpdatedAt = time.Now().UTC()
+    if content != c.Content {
+        edited := c.UpdatedAt
+        c.EditedAt = &edited
+    }
+    c.Content = content
+    if err := s.repo.UpdateContent(ctx, id, c.Content, c.UpdatedAt, c.EditedAt); err != nil {
+        return nil, err
+    }
+    return c, nil
+}
+
+func (s *service) DeleteComment(ctx context.Context, id int) error {
+    return s.repo.Delete(ctx, id, time.Now().UTC())
+}
+
+// DeleteOwnComments soft-deletes those of ids that userID wrote and returns
+// them in request order. The rest are skipped without error.
+func (s *service) DeleteOwnComments(ctx context.Context, userID int, ids []int) ([]int, error) {
+    changed, err := s.repo.SoftDeleteOwned(ctx, userID, ids, time.Now().UTC())
+    if err != nil {
+        return nil, err
+    }
+    done := make(map[int]bool, len(changed))
+    for _, id := range changed {
+        done[id] = true
+    }
+    deleted := []int{}
+    for _, id := range ids {
+        if done[id] {
+            deleted = append(deleted, id)
+        }
+    }
+    return deleted, nil
 }
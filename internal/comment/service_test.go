@@ -0,0 +1,413 @@
+package comment
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/config"
+	"go-discussion-app/models"
+)
+
+func newMockRepo(t *testing.T) (Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewRepository(db), mock
+}
+
+func TestGetCommentsByIDs_KeepsRequestedOrderAndSkipsMissing(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+	now := time.Now()
+
+	// the database returns rows in its own order and knows nothing of id 9
+	cols := []string{"id", "discussion_id", "user_id", "parent_id", "content", "created_at", "updated_at", "edited_at"}
+	mock.ExpectQuery(`SELECT id, discussion_id, user_id, parent_id, content, created_at, updated_at, edited_at\s+FROM comments WHERE id = ANY\(\$1\)`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow(1, 10, 2, nil, "first", now, now, nil).
+			AddRow(5, 11, 2, nil, "fifth", now, now, nil).
+			AddRow(3, 10, 4, nil, "third", now, now, nil))
+
+	got, err := svc.GetCommentsByIDs(context.Background(), []int{5, 9, 1, 3})
+
+	assert.NoError(t, err)
+	if assert.Len(t, got, 3) {
+		assert.Equal(t, 5, got[0].ID)
+		assert.Equal(t, 1, got[1].ID)
+		assert.Equal(t, 3, got[2].ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetComment_Found(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+	now := time.Now()
+	parent := 3
+
+	cols := []string{"id", "discussion_id", "user_id", "parent_id", "content", "created_at", "updated_at", "edited_at"}
+	mock.ExpectQuery(`SELECT id, discussion_id, user_id, parent_id, content, created_at, updated_at, edited_at\s+FROM comments WHERE id = \$1 AND deleted_at IS NULL`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(7, 10, 2, parent, "hello", now, now, nil))
+
+	got, err := svc.GetComment(context.Background(), 7)
+	assert.NoError(t, err)
+	if assert.NotNil(t, got) {
+		assert.Equal(t, 7, got.ID)
+		assert.Equal(t, 10, got.DiscussionID)
+		assert.Equal(t, 2, got.UserID)
+		assert.Equal(t, &parent, got.ParentID)
+		assert.Equal(t, "hello", got.Content)
+		assert.Nil(t, got.EditedAt)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateContent_SetsEditedAtWhenContentChanges(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+	now := time.Now().UTC().Add(-time.Hour)
+	cols := []string{"id", "discussion_id", "user_id", "parent_id", "content", "created_at", "updated_at", "edited_at"}
+
+	mock.ExpectQuery(`FROM comments WHERE id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(7, 10, 2, nil, "helo", now, now, nil))
+	mock.ExpectExec(`UPDATE comments SET content = \$1, updated_at = \$2, edited_at = \$3 WHERE id = \$4`).
+		WithArgs("hello", sqlmock.AnyArg(), sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	got, err := svc.UpdateContent(context.Background(), 7, "hello")
+	assert.NoError(t, err)
+	if assert.NotNil(t, got) && assert.NotNil(t, got.EditedAt) {
+		assert.Equal(t, got.UpdatedAt, *got.EditedAt)
+		assert.True(t, got.EditedAt.After(now))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateContent_SameContentKeepsEditedAt(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+	now := time.Now().UTC().Add(-time.Hour)
+	cols := []string{"id", "discussion_id", "user_id", "parent_id", "content", "created_at", "updated_at", "edited_at"}
+
+	// never edited: stays nil
+	mock.ExpectQuery(`FROM comments WHERE id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(7, 10, 2, nil, "hello", now, now, nil))
+	mock.ExpectExec(`UPDATE comments`).
+		WithArgs("hello", sqlmock.AnyArg(), nil, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	got, err := svc.UpdateContent(context.Background(), 7, "hello")
+	assert.NoError(t, err)
+	assert.Nil(t, got.EditedAt)
+
+	// edited before: keeps that time
+	edited := now.Add(time.Minute)
+	mock.ExpectQuery(`FROM comments WHERE id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(7, 10, 2, nil, "hello", now, edited, edited))
+	mock.ExpectExec(`UPDATE comments`).
+		WithArgs("hello", sqlmock.AnyArg(), edited, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	got, err = svc.UpdateContent(context.Background(), 7, "hello")
+	assert.NoError(t, err)
+	if assert.NotNil(t, got.EditedAt) {
+		assert.Equal(t, edited, *got.EditedAt)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetComment_NotFoundIsNil(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+
+	// deleted comments are filtered by the query, so they look the same
+	mock.ExpectQuery(`FROM comments WHERE id = \$1 AND deleted_at IS NULL`).
+		WithArgs(8).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "discussion_id", "user_id", "parent_id", "content", "created_at", "updated_at", "edited_at"}))
+
+	got, err := svc.GetComment(context.Background(), 8)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddComment_CooldownThrottlesRapidFire(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, &config.Config{CommentCooldown: 10 * time.Second})
+
+	mock.ExpectQuery(`SELECT MAX\(created_at\) FROM comments WHERE user_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(time.Now().UTC().Add(-3 * time.Second)))
+
+	_, err := svc.AddComment(context.Background(), 10, 2, "again", nil)
+	assert.ErrorIs(t, err, ErrCommentCooldown)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddComment_CooldownAllowsSpacedComments(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, &config.Config{CommentCooldown: 10 * time.Second})
+
+	mock.ExpectQuery(`SELECT MAX\(created_at\) FROM comments`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(time.Now().UTC().Add(-11 * time.Second)))
+	mock.ExpectQuery(`INSERT INTO comments`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	id, err := svc.AddComment(context.Background(), 10, 2, "later", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddComment_FirstCommentIsNeverThrottled(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, &config.Config{CommentCooldown: 10 * time.Second})
+
+	mock.ExpectQuery(`SELECT MAX\(created_at\) FROM comments`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	mock.ExpectQuery(`INSERT INTO comments`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	_, err := svc.AddComment(context.Background(), 10, 2, "hello", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListPage_ReturnsPageAndTotal(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM comments WHERE discussion_id = \$1`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(35))
+	mock.ExpectQuery(`FROM comments\s+WHERE discussion_id = \$1 AND deleted_at IS NULL\s+ORDER BY created_at ASC, id ASC\s+LIMIT \$2 OFFSET \$3`).
+		WithArgs(4, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "discussion_id", "user_id", "parent_id", "content", "created_at", "updated_at", "edited_at"}).
+			AddRow(1, 4, 2, nil, "first", now, now, nil))
+
+	comments, total, err := svc.ListPage(context.Background(), 4, 20, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 35, total)
+	assert.Len(t, comments, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetComments_PassesLimitAndOffset(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM comments WHERE discussion_id = \$1 AND deleted_at IS NULL`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(120))
+	mock.ExpectQuery(`FROM comments\s+WHERE discussion_id = \$1 AND deleted_at IS NULL\s+ORDER BY created_at ASC, id ASC\s+LIMIT \$2 OFFSET \$3`).
+		WithArgs(4, 50, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "discussion_id", "user_id", "parent_id", "content", "created_at", "updated_at", "edited_at"}).
+			AddRow(101, 4, 2, nil, "late", now, now, nil))
+
+	comments, total, err := svc.GetComments(context.Background(), 4, config.CommentOrderOldest, 50, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 120, total)
+	if assert.Len(t, comments, 1) {
+		assert.Equal(t, 101, comments[0].ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetComments_NoRowsIsEmptyNotNil(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM comments WHERE discussion_id = \$1`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`FROM comments\s+WHERE discussion_id = \$1`).
+		WithArgs(4, 50, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "discussion_id", "user_id", "parent_id", "content", "created_at", "updated_at", "edited_at"}))
+
+	comments, _, err := svc.GetComments(context.Background(), 4, "", 50, 0)
+	assert.NoError(t, err)
+	assert.NotNil(t, comments)
+	assert.Empty(t, comments)
+
+	body, _ := json.Marshal(comments)
+	assert.Equal(t, "[]", string(body))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetComments_AppliesConfiguredDefaultOrder(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, &config.Config{DefaultCommentOrder: config.CommentOrderNewest})
+	cols := []string{"id", "discussion_id", "user_id", "parent_id", "content", "created_at", "updated_at", "edited_at"}
+
+	count := func() {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM comments`).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	}
+	count()
+	mock.ExpectQuery(`WHERE discussion_id = \$1 AND deleted_at IS NULL\s+ORDER BY created_at DESC, id DESC`).
+		WithArgs(4, 50, 0).
+		WillReturnRows(sqlmock.NewRows(cols))
+	count()
+	mock.ExpectQuery(`WHERE discussion_id = \$1 AND deleted_at IS NULL\s+ORDER BY created_at ASC, id ASC`).
+		WithArgs(4, 50, 0).
+		WillReturnRows(sqlmock.NewRows(cols))
+
+	_, _, err := svc.GetComments(context.Background(), 4, "", 50, 0)
+	assert.NoError(t, err)
+	// an explicit ?order= wins over the default
+	_, _, err = svc.GetComments(context.Background(), 4, config.CommentOrderOldest, 50, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestAddComment_ReplyStoresParent(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+	now := time.Now()
+	cols := []string{"id", "discussion_id", "user_id", "parent_id", "content", "created_at", "updated_at", "edited_at"}
+
+	mock.ExpectQuery(`FROM comments WHERE id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(3, 10, 4, nil, "parent", now, now, nil))
+	mock.ExpectQuery(`INSERT INTO comments \(discussion_id, user_id, parent_id, content, created_at, updated_at\)`).
+		WithArgs(10, 2, 3, "reply", sqlmock.AnyArg(), sqlmock.AnyArg(), models.StatusArchived).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+
+	id, err := svc.AddComment(context.Background(), 10, 2, "reply", intPtr(3))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddComment_RejectsParentFromAnotherDiscussion(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+	now := time.Now()
+	cols := []string{"id", "discussion_id", "user_id", "parent_id", "content", "created_at", "updated_at", "edited_at"}
+
+	mock.ExpectQuery(`FROM comments WHERE id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(3, 11, 4, nil, "elsewhere", now, now, nil))
+	mock.ExpectQuery(`FROM comments WHERE id = \$1`).
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows(cols))
+
+	_, err := svc.AddComment(context.Background(), 10, 2, "reply", intPtr(3))
+	assert.ErrorIs(t, err, ErrInvalidParent)
+	_, err = svc.AddComment(context.Background(), 10, 2, "reply", intPtr(99))
+	assert.ErrorIs(t, err, ErrInvalidParent)
+	// nothing was inserted
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddComment_RejectsArchivedDiscussion(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+
+	// A discussion closed by the stale sweep is archived, so the
+	// conditional insert returns no row.
+	mock.ExpectQuery(`INSERT INTO comments .*\s+SELECT \$1, \$2, \$3, \$4, \$5, \$6\s+` +
+		`WHERE NOT EXISTS \(SELECT 1 FROM discussions WHERE id = \$1 AND status = \$7\)`).
+		WithArgs(10, 2, nil, "too late", sqlmock.AnyArg(), sqlmock.AnyArg(), models.StatusArchived).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := svc.AddComment(context.Background(), 10, 2, "too late", nil)
+	assert.ErrorIs(t, err, ErrDiscussionArchived)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCommentTree_NestsTwoLevelReplyChain(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+	now := time.Now()
+	cols := []string{"id", "discussion_id", "user_id", "parent_id", "content", "created_at", "updated_at", "edited_at"}
+
+	// 1 <- 2 <- 4, 1 <- 5, and a second thread 3
+	mock.ExpectQuery(`WHERE discussion_id = \$1 AND deleted_at IS NULL\s+ORDER BY created_at ASC, id ASC`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow(1, 10, 2, nil, "root", now, now, nil).
+			AddRow(2, 10, 3, 1, "reply", now, now, nil).
+			AddRow(3, 10, 4, nil, "other root", now, now, nil).
+			AddRow(4, 10, 2, 2, "reply to reply", now, now, nil).
+			AddRow(5, 10, 5, 1, "second reply", now, now, nil))
+
+	tree, err := svc.GetCommentTree(context.Background(), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, tree, 2) {
+		assert.Equal(t, 1, tree[0].ID)
+		assert.Equal(t, 3, tree[1].ID)
+		assert.Empty(t, tree[1].Replies)
+		if assert.Len(t, tree[0].Replies, 2) {
+			assert.Equal(t, 2, tree[0].Replies[0].ID)
+			assert.Equal(t, 5, tree[0].Replies[1].ID)
+			if assert.Len(t, tree[0].Replies[0].Replies, 1) {
+				assert.Equal(t, 4, tree[0].Replies[0].Replies[0].ID)
+				assert.Empty(t, tree[0].Replies[0].Replies[0].Replies)
+			}
+		}
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteComment_SoftDeletesAndDetachesReplies(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+
+	mock.ExpectExec(`UPDATE comments SET deleted_at = \$1\s+WHERE id = \$2 AND deleted_at IS NULL\s+RETURNING id\s+\)\s+` +
+		`UPDATE comments SET parent_id = NULL WHERE parent_id IN \(SELECT id FROM deleted\)`).
+		WithArgs(sqlmock.AnyArg(), 5).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	assert.NoError(t, svc.DeleteComment(context.Background(), 5))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteOwnComments_OnlyOwnedAreDeleted(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+
+	// 7 belongs to someone else, so the owner-scoped update skips it
+	mock.ExpectQuery(`UPDATE comments SET deleted_at = \$1\s+WHERE id = ANY\(\$2\) AND user_id = \$3 AND deleted_at IS NULL\s+RETURNING id`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9).AddRow(4))
+
+	deleted, err := svc.DeleteOwnComments(context.Background(), 1, []int{4, 7, 9})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{4, 9}, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteOwnComments_NoneOwnedIsEmptyNotNil(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	svc := NewService(repo, nil)
+
+	mock.ExpectQuery(`UPDATE comments SET deleted_at`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	deleted, err := svc.DeleteOwnComments(context.Background(), 2, []int{4, 7})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, deleted)
+	assert.Empty(t, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,20 +2,61 @@
 package discussion
 
 import (
+    "context"
+    "errors"
+    "fmt"
     "net/http"
     "strconv"
+    "strings"
+    "time"
 
     "github.com/gin-gonic/gin"
+    "go-discussion-app/config"
+    "go-discussion-app/models"
+    "go-discussion-app/pkg/httperr"
     "go-discussion-app/pkg/logger"
+    "go-discussion-app/pkg/markdown"
+    "go-discussion-app/pkg/pagination"
+    "go-discussion-app/internal/audit"
     "go-discussion-app/internal/auth"
+    tagpkg "go-discussion-app/internal/tag"
 )
 
+// DefaultActiveWindow is used by GET /discussions/active when ?window= is omitted.
+const DefaultActiveWindow = 24 * time.Hour
+
+// CommentLister is the slice of comment.Service needed to embed comments in
+// a discussion response. It is injected because the comment package already
+// depends on this one.
+type CommentLister interface {
+    ListPage(ctx context.Context, discussionID, limit, offset int) ([]models.Comment, int, error)
+}
+
 type Controller struct {
-    svc Service
+    svc      Service
+    comments CommentLister
 }
 
-func NewController(svc Service) *Controller {
-    return &Controller{svc: svc}
+func NewController(svc Service, comments CommentLister) *Controller {
+    return &Controller{svc: svc, comments: comments}
+}
+
+// setRateLimitHeaders reports userID's discussion quota, as it stands after
+// the request, in X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset (Unix seconds). Nothing is set when creation is not
+// limited or the quota cannot be read.
+func (ctr *Controller) setRateLimitHeaders(c *gin.Context, userID int) {
+    rl, err := ctr.svc.RateLimitStatus(c.Request.Context(), userID)
+    if err != nil {
+        logger.Warnf("rate limit status error: %v", err)
+        return
+    }
+    if rl == nil {
+        return
+    }
+    c.Header("X-RateLimit-Limit", strconv.Itoa(rl.Limit))
+    c.Header("X-RateLimit-Remaining", strconv.Itoa(rl.Remaining))
+    c.Header("X-RateLimit-Reset", strconv.FormatInt(rl.Reset.Unix(), 10))
 }
 
 // POST /discussions
@@ -26,40 +67,299 @@ func (ctr *Controller) Create(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
         return
     }
-    id, err := ctr.svc.Create(c.Request.Context(), userID, &dto)
+
+    // Opt-in duplicate check; looked up before creating so the new
+    // discussion does not match itself. Failures only lose the warning.
+    var similar []models.Discussion
+    if c.Query("check_duplicates") == "true" {
+        var err error
+        similar, err = ctr.svc.FindSimilar(c.Request.Context(), dto.Title, userID)
+        if err != nil {
+            logger.Warnf("duplicate check error: %v", err)
+        }
+    }
+
+    d, err := ctr.svc.Create(c.Request.Context(), userID, &dto)
+    ctr.setRateLimitHeaders(c, userID)
+    if err == ErrRateLimited {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many discussions, try again later"})
+        return
+    }
+    if err == ErrEmptyField || err == ErrInvalidLanguage {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
         logger.Errorf("create discussion error: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create"})
         return
     }
-    c.JSON(http.StatusCreated, gin.H{"id": id})
+
+    resp := createdResponse{Discussion: d}
+    if len(similar) > 0 {
+        resp.Similar = make([]gin.H, 0, len(similar))
+        for _, dup := range similar {
+            resp.Similar = append(resp.Similar, gin.H{"id": dup.ID, "title": dup.Title})
+        }
+        resp.Warning = "similar discussion exists"
+    }
+    c.JSON(http.StatusCreated, resp)
+}
+
+// createdResponse is the body of a 201 from Create: the discussion as
+// saved, plus the duplicate warning when one was asked for and found.
+type createdResponse struct {
+    *models.Discussion
+    Warning string  `json:"warning,omitempty"`
+    Similar []gin.H `json:"similar,omitempty"`
+}
+
+// POST /discussions/preview renders content the way it will be shown,
+// without saving anything.
+func (ctr *Controller) Preview(c *gin.Context) {
+    var dto PreviewDTO
+    if err := c.ShouldBindJSON(&dto); err != nil || dto.Validate() != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+        return
+    }
+    html, err := markdown.Render(dto.Content)
+    if err != nil {
+        logger.Errorf("preview discussion error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not render"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"html": html})
 }
 
-// GET /discussions
+// GET /discussions?status=draft|published|archived&sort=&user_id=&tag=&lang=&fields=&limit=&offset=
+// GET /discussions?unseen=true lists what changed since the caller's POST /me/seen.
 func (ctr *Controller) List(c *gin.Context) {
-    ds, err := ctr.svc.GetAll(c.Request.Context())
+    if c.Query("unseen") == "true" {
+        ctr.listUnseen(c)
+        return
+    }
+    status := c.DefaultQuery("status", models.StatusPublished)
+    if !ValidStatus(status) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "status must be draft, published or archived"})
+        return
+    }
+    sort := c.Query("sort")
+    if sort != "" && !config.ValidDiscussionSort(sort) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of " + strings.Join(config.DiscussionSorts, ", ")})
+        return
+    }
+    var filter ListFilter
+    if v := c.Query("user_id"); v != "" {
+        uid, err := strconv.Atoi(v)
+        if err != nil || uid <= 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be a positive integer"})
+            return
+        }
+        filter.UserID = uid
+    }
+    filter.Tag = strings.ToLower(strings.TrimSpace(c.Query("tag")))
+    filter.Language = c.Query("lang")
+    fields, err := parseFields(c.Query("fields"), listFields)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    viewerID, _ := auth.GetUserID(c)
+    if status == models.StatusDraft && viewerID == 0 {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+        return
+    }
+    page, err := pagination.FromQuery(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    lastMod, err := ctr.svc.LastModified(c.Request.Context(), sort)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("list discussions last-modified error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list"})
+        return
+    }
+    if notModified(c, lastMod) {
+        return
+    }
+    ds, total, err := ctr.svc.ListByStatus(c.Request.Context(), status, viewerID, filter, sort, page.Limit, page.Offset)
+    if err == ErrInvalidLanguage {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "lang is not a supported language"})
+        return
+    }
     if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
         logger.Errorf("list discussions error: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list"})
         return
     }
+    data := make([]interface{}, len(ds))
+    for i := range ds {
+        if data[i], err = selectFields(ds[i], fields); err != nil {
+            logger.Errorf("list discussions fields error: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list"})
+            return
+        }
+    }
+    // next_cursor is the offset of the following page, null on the last one.
+    c.JSON(http.StatusOK, gin.H{
+        "data":        data,
+        "limit":       page.Limit,
+        "offset":      page.Offset,
+        "total":       total,
+        "next_cursor": page.NextOffset(len(ds), total),
+    })
+}
+
+// listUnseen is per-user, so it skips the Last-Modified handling of List.
+func (ctr *Controller) listUnseen(c *gin.Context) {
+    userID, _ := auth.GetUserID(c)
+    if userID == 0 {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+        return
+    }
+    ds, err := ctr.svc.ListUnseen(c.Request.Context(), userID)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("list unseen discussions error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list"})
+        return
+    }
     c.JSON(http.StatusOK, ds)
 }
 
-// GET /discussions/:id
+// POST /me/seen
+func (ctr *Controller) MarkSeen(c *gin.Context) {
+    userID, _ := auth.GetUserID(c)
+    if userID == 0 {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+        return
+    }
+    seenAt, err := ctr.svc.MarkSeen(c.Request.Context(), userID)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("mark seen error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update last seen"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"seen_at": seenAt})
+}
+
+// GET /discussions/search?q=&limit=&offset=
+func (ctr *Controller) Search(c *gin.Context) {
+    page, err := pagination.FromQuery(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    ds, total, limit, err := ctr.svc.Search(c.Request.Context(), c.Query("q"), page.Limit, page.Offset)
+    if err == ErrSearchQueryTooShort {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("search discussions error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not search"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "data":        ds,
+        "limit":       limit,
+        "offset":      page.Offset,
+        "total":       total,
+        "next_cursor": page.NextOffset(len(ds), total),
+    })
+}
+
+// GET /discussions/:id[?include=comments][&fields=id,title]
 func (ctr *Controller) Get(c *gin.Context) {
-    id, _ := strconv.Atoi(c.Param("id"))
-    d, err := ctr.svc.GetByID(c.Request.Context(), id)
+    // A non-numeric id is also how a disabled static route such as
+    // /discussions/search arrives here, so it must 404 rather than query.
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+        return
+    }
+    fields, err := parseFields(c.Query("fields"), detailFields)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    d, err := ctr.svc.GetWithTags(c.Request.Context(), id)
     if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
         logger.Errorf("get discussion error: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch"})
         return
     }
-    if d == nil {
+    // another user's draft is reported as missing rather than forbidden
+    viewerID, _ := auth.GetUserID(c)
+    if d == nil || (d.Status == models.StatusDraft && d.UserID != viewerID) {
         c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
         return
     }
-    c.JSON(http.StatusOK, d)
+
+    // A lost view is not worth failing the read over.
+    if err := ctr.svc.RecordView(c.Request.Context(), id); err != nil {
+        logger.Warnf("record view of discussion %d: %v", id, err)
+    } else {
+        d.ViewCount++
+    }
+
+    body, err := selectFields(d, fields)
+    if err != nil {
+        logger.Errorf("get discussion fields error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch"})
+        return
+    }
+
+    // New comments don't touch the discussion's updated_at, so the combined
+    // response is never answered with 304.
+    if c.Query("include") == "comments" && ctr.comments != nil {
+        comments, total, err := ctr.comments.ListPage(c.Request.Context(), d.ID, pagination.DefaultLimit, 0)
+        if err != nil {
+            if httperr.Abort(c, err) {
+                return
+            }
+            logger.Errorf("get discussion comments error: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch"})
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{
+            "discussion": body,
+            "comments":   comments,
+            "comments_meta": gin.H{
+                "limit":  pagination.DefaultLimit,
+                "offset": 0,
+                "total":  total,
+            },
+        })
+        return
+    }
+
+    if notModified(c, d.UpdatedAt) {
+        return
+    }
+    c.JSON(http.StatusOK, body)
 }
 
 // PUT /discussions/:id
@@ -70,8 +370,19 @@ func (ctr *Controller) Update(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
         return
     }
-    d, err := ctr.svc.Update(c.Request.Context(), id, &dto)
+    userID, ok := ctr.authorizeOwner(c, id, false)
+    if !ok {
+        return
+    }
+    d, err := ctr.svc.Update(c.Request.Context(), id, userID, &dto)
+    if err == ErrInvalidLanguage {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
         logger.Errorf("update discussion error: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update"})
         return
@@ -79,22 +390,96 @@ func (ctr *Controller) Update(c *gin.Context) {
     c.JSON(http.StatusOK, d)
 }
 
-// DELETE /discussions/:id
+// authorizeOwner checks that the caller wrote discussion id (or, with
+// allowAdmin, is an admin) and returns the caller's user ID. Otherwise it
+// writes 401, 404 or 403 and reports false.
+func (ctr *Controller) authorizeOwner(c *gin.Context, id int, allowAdmin bool) (int, bool) {
+    userID, ok := auth.GetUserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+        return 0, false
+    }
+    d, err := ctr.svc.GetByID(c.Request.Context(), id)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return 0, false
+        }
+        logger.Errorf("load discussion for ownership check error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load"})
+        return 0, false
+    }
+    if d == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+        return 0, false
+    }
+    if d.UserID != userID {
+        if role, _ := auth.GetRole(c); !allowAdmin || role != models.RoleAdmin {
+            c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+            return 0, false
+        }
+    }
+    return userID, true
+}
+
+// DELETE /discussions/:id (admin only, enforced by auth.RequireRole on the route)
 func (ctr *Controller) Delete(c *gin.Context) {
+    actorID, _ := auth.GetUserID(c)
     id, _ := strconv.Atoi(c.Param("id"))
+    d, err := ctr.svc.GetByID(c.Request.Context(), id)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("load discussion for delete error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not delete"})
+        return
+    }
+    if d == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+        return
+    }
     if err := ctr.svc.Delete(c.Request.Context(), id); err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
         logger.Errorf("delete discussion error: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "could not delete"})
         return
     }
+    audit.Record(c.Request.Context(), actorID, audit.ActionDiscussionDelete, audit.Target("discussion", id))
+    c.Status(http.StatusNoContent)
+}
+
+// POST /discussions/:id/restore (admin only, enforced by auth.RequireRole on the route)
+func (ctr *Controller) Restore(c *gin.Context) {
+    actorID, _ := auth.GetUserID(c)
+    id, _ := strconv.Atoi(c.Param("id"))
+    found, err := ctr.svc.Restore(c.Request.Context(), id)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("restore discussion error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not restore"})
+        return
+    }
+    if !found {
+        c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+        return
+    }
+    audit.Record(c.Request.Context(), actorID, audit.ActionDiscussionRestore, audit.Target("discussion", id))
     c.Status(http.StatusNoContent)
 }
 
 // GET /discussions/user/:userId
 func (ctr *Controller) ListByUser(c *gin.Context) {
     uid, _ := strconv.Atoi(c.Param("userId"))
-    ds, err := ctr.svc.GetByUser(c.Request.Context(), uid)
+    viewerID, _ := auth.GetUserID(c)
+    ds, err := ctr.svc.GetByUser(c.Request.Context(), uid, viewerID)
     if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
         logger.Errorf("list by user error: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list"})
         return
@@ -105,8 +490,12 @@ func (ctr *Controller) ListByUser(c *gin.Context) {
 // GET /discussions/tag/:tag
 func (ctr *Controller) ListByTag(c *gin.Context) {
     tag := c.Param("tag")
-    ds, err := ctr.svc.GetByTag(c.Request.Context(), tag)
+    viewerID, _ := auth.GetUserID(c)
+    ds, err := ctr.svc.GetByTag(c.Request.Context(), tag, viewerID)
     if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
         logger.Errorf("list by tag error: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list"})
         return
@@ -114,6 +503,95 @@ func (ctr *Controller) ListByTag(c *gin.Context) {
     c.JSON(http.StatusOK, ds)
 }
 
+// POST /admin/tags/:tag/pins/:id
+func (ctr *Controller) PinInTag(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid discussion ID"})
+        return
+    }
+    found, err := ctr.svc.PinInTag(c.Request.Context(), id, c.Param("tag"))
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("pin discussion error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not pin"})
+        return
+    }
+    if !found {
+        c.JSON(http.StatusNotFound, gin.H{"error": "discussion does not have that tag"})
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
+
+// DELETE /admin/tags/:tag/pins/:id
+func (ctr *Controller) UnpinInTag(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid discussion ID"})
+        return
+    }
+    found, err := ctr.svc.UnpinInTag(c.Request.Context(), id, c.Param("tag"))
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("unpin discussion error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not unpin"})
+        return
+    }
+    if !found {
+        c.JSON(http.StatusNotFound, gin.H{"error": "not pinned"})
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
+
+// GET /discussions/by-tags?tags=go,rust
+func (ctr *Controller) ListByTags(c *gin.Context) {
+    names, err := tagpkg.ParseNames(c.Query("tags"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("tags must list 1 to %d tag names", tagpkg.MaxBatchNames)})
+        return
+    }
+    groups, err := ctr.svc.ListByTags(c.Request.Context(), names)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("list by tags error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list"})
+        return
+    }
+    c.JSON(http.StatusOK, groups)
+}
+
+// GET /discussions/active?window=24h
+func (ctr *Controller) ListActive(c *gin.Context) {
+    window := DefaultActiveWindow
+    if raw := c.Query("window"); raw != "" {
+        d, err := time.ParseDuration(raw)
+        if err != nil || d <= 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "window must be a positive duration, e.g. 24h"})
+            return
+        }
+        window = d
+    }
+    viewerID, _ := auth.GetUserID(c)
+    ds, err := ctr.svc.ListActive(c.Request.Context(), window, viewerID)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("list active discussions error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list"})
+        return
+    }
+    c.JSON(http.StatusOK, ds)
+}
+
 // POST /discussions/:id/tags
 func (ctr *Controller) AddTags(c *gin.Context) {
     id, _ := strconv.Atoi(c.Param("id"))
@@ -122,7 +600,17 @@ func (ctr *Controller) AddTags(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
         return
     }
+    if _, ok := ctr.authorizeOwner(c, id, false); !ok {
+        return
+    }
     if err := ctr.svc.AddTags(c.Request.Context(), id, &dto); err != nil {
+        if errors.Is(err, ErrTooManyTags) || errors.Is(err, ErrTagOverused) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if httperr.Abort(c, err) {
+            return
+        }
         logger.Errorf("add tags error: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "could not add tags"})
         return
@@ -130,19 +618,204 @@ func (ctr *Controller) AddTags(c *gin.Context) {
     c.Status(http.StatusNoContent)
 }
 
+// PUT /discussions/:id/tags
+func (ctr *Controller) ReplaceTags(c *gin.Context) {
+    id, _ := strconv.Atoi(c.Param("id"))
+    var dto ReplaceTagsDTO
+    if err := c.ShouldBindJSON(&dto); err != nil || dto.Validate() != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+        return
+    }
+    if _, ok := ctr.authorizeOwner(c, id, false); !ok {
+        return
+    }
+    if err := ctr.svc.ReplaceTags(c.Request.Context(), id, &dto); err != nil {
+        if errors.Is(err, ErrTooManyTags) || errors.Is(err, ErrTagOverused) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("replace tags error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not replace tags"})
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
+
+// POST /discussions/:id/bump (owner or admin)
+func (ctr *Controller) Bump(c *gin.Context) {
+    id, _ := strconv.Atoi(c.Param("id"))
+    if _, ok := ctr.authorizeOwner(c, id, true); !ok {
+        return
+    }
+    bumped, err := ctr.svc.Bump(c.Request.Context(), id)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("bump error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not bump"})
+        return
+    }
+    if bumped == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+        return
+    }
+    c.JSON(http.StatusOK, bumped)
+}
+
 // POST /discussions/schedule
 func (ctr *Controller) Schedule(c *gin.Context) {
     userID, _ := auth.GetUserID(c)
     var dto ScheduleDTO
-    if err := c.ShouldBindJSON(&dto); err != nil || dto.Validate() != nil {
+    if err := c.ShouldBindJSON(&dto); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
         return
     }
-    id, err := ctr.svc.Schedule(c.Request.Context(), userID, &dto)
+    if err := dto.Validate(); err != nil {
+        var fe *FieldError
+        if errors.As(err, &fe) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": fe.Error(), "field": fe.Field})
+        } else {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        }
+        return
+    }
+    d, err := ctr.svc.Schedule(c.Request.Context(), userID, &dto)
+    ctr.setRateLimitHeaders(c, userID)
+    if err == ErrRateLimited {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many discussions, try again later"})
+        return
+    }
     if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
         logger.Errorf("schedule discussion error: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "could not schedule"})
         return
     }
-    c.JSON(http.StatusCreated, gin.H{"id": id})
+    c.JSON(http.StatusCreated, d)
+}
+
+// GET /admin/revisions?edited_by=:userId
+func (ctr *Controller) ListRevisionsByEditor(c *gin.Context) {
+    editorID, err := strconv.Atoi(c.Query("edited_by"))
+    if err != nil || editorID <= 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "edited_by must be a user id"})
+        return
+    }
+    page, err := pagination.FromQuery(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    revs, err := ctr.svc.ListRevisionsByEditor(c.Request.Context(), editorID, page.Limit, page.Offset)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("list revisions error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"data": revs, "limit": page.Limit, "offset": page.Offset})
+}
+
+// POST /admin/discussions/lock-stale?older_than=90d
+func (ctr *Controller) ArchiveStale(c *gin.Context) {
+    olderThan, err := config.ParseAge(c.Query("older_than"))
+    if err != nil || olderThan <= 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "older_than must be a positive duration, e.g. 90d or 720h"})
+        return
+    }
+    ids, err := ctr.svc.ArchiveStale(c.Request.Context(), olderThan)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("archive stale discussions error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not archive"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"archived": ids, "count": len(ids)})
+}
+
+// GET /discussions/:id/revisions/:revID/diff
+func (ctr *Controller) RevisionDiff(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid discussion ID"})
+        return
+    }
+    revID, err := strconv.Atoi(c.Param("revID"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision ID"})
+        return
+    }
+    if _, ok := ctr.authorizeOwner(c, id, true); !ok {
+        return
+    }
+    diff, err := ctr.svc.RevisionDiff(c.Request.Context(), id, revID)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("revision diff error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load"})
+        return
+    }
+    if diff == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "revision not found"})
+        return
+    }
+    c.JSON(http.StatusOK, diff)
+}
+
+// GET /discussions/:id/stats?interval=day&from=&to= (owner or admin)
+func (ctr *Controller) Stats(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil || id <= 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid discussion ID"})
+        return
+    }
+    p, err := tagpkg.ParseTrendParams(c.Query("interval"), c.Query("from"), c.Query("to"), time.Now())
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if _, ok := ctr.authorizeOwner(c, id, true); !ok {
+        return
+    }
+    stats, err := ctr.svc.CommentStats(c.Request.Context(), id, p)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("stats error: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load stats"})
+        return
+    }
+    c.JSON(http.StatusOK, stats)
+}
+
+// notModified sets Last-Modified from lastMod and, if the request's
+// If-Modified-Since is not older than it, answers 304 and returns true.
+// A zero lastMod (nothing to date the resource by) disables both.
+func notModified(c *gin.Context, lastMod time.Time) bool {
+    if lastMod.IsZero() {
+        return false
+    }
+    // HTTP dates have one-second resolution.
+    lastMod = lastMod.UTC().Truncate(time.Second)
+    c.Header("Last-Modified", lastMod.Format(http.TimeFormat))
+
+    ims, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+    if err != nil || lastMod.After(ims) {
+        return false
+    }
+    c.Status(http.StatusNotModified)
+    return true
 }
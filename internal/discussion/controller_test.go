@@ -15,9 +15,15 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"go-discussion-app/config"
+	"go-discussion-app/internal/audit"
 	authmw "go-discussion-app/internal/auth" // Renamed to avoid conflict with package auth
+	tagpkg "go-discussion-app/internal/tag"
 	"go-discussion-app/models"
+	"go-discussion-app/pkg/linediff"
+	"go-discussion-app/pkg/httperr"
 	"go-discussion-app/pkg/jwtutil"
+	"go-discussion-app/pkg/pagination"
 )
 
 // MockDiscussionService is a mock implementation of discussion.Service
@@ -25,13 +31,16 @@ type MockDiscussionService struct {
 	mock.Mock
 }
 
-func (m *MockDiscussionService) Create(ctx context.Context, userID int, dto *CreateDiscussionDTO) (int, error) {
+func (m *MockDiscussionService) Create(ctx context.Context, userID int, dto *CreateDiscussionDTO) (*models.Discussion, error) {
 	args := m.Called(ctx, userID, dto)
-	return args.Int(0), args.Error(1)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Discussion), args.Error(1)
 }
-func (m *MockDiscussionService) GetAll(ctx context.Context) ([]models.Discussion, error) {
-	args := m.Called(ctx)
-	return args.Get(0).([]models.Discussion), args.Error(1)
+func (m *MockDiscussionService) ListByStatus(ctx context.Context, status string, viewerID int, f ListFilter, sort string, limit, offset int) ([]models.Discussion, int, error) {
+	args := m.Called(ctx, status, viewerID, f, sort, limit, offset)
+	return args.Get(0).([]models.Discussion), args.Int(1), args.Error(2)
 }
 func (m *MockDiscussionService) GetByID(ctx context.Context, id int) (*models.Discussion, error) {
 	args := m.Called(ctx, id)
@@ -40,32 +49,130 @@ func (m *MockDiscussionService) GetByID(ctx context.Context, id int) (*models.Di
 	}
 	return args.Get(0).(*models.Discussion), args.Error(1)
 }
-func (m *MockDiscussionService) Update(ctx context.Context, id int, dto *UpdateDiscussionDTO) (*models.Discussion, error) {
-	args := m.Called(ctx, id, dto)
+func (m *MockDiscussionService) GetWithTags(ctx context.Context, id int) (*DiscussionWithTags, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*DiscussionWithTags), args.Error(1)
+}
+func (m *MockDiscussionService) Update(ctx context.Context, id, editorID int, dto *UpdateDiscussionDTO) (*models.Discussion, error) {
+	args := m.Called(ctx, id, editorID, dto)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Discussion), args.Error(1)
 }
+func (m *MockDiscussionService) RateLimitStatus(ctx context.Context, userID int) (*RateLimit, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*RateLimit), args.Error(1)
+}
+func (m *MockDiscussionService) RecordView(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
 func (m *MockDiscussionService) Delete(ctx context.Context, id int) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
-func (m *MockDiscussionService) GetByUser(ctx context.Context, userID int) ([]models.Discussion, error) {
-	args := m.Called(ctx, userID)
+func (m *MockDiscussionService) Restore(ctx context.Context, id int) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockDiscussionService) PinInTag(ctx context.Context, discussionID int, tag string) (bool, error) {
+	args := m.Called(ctx, discussionID, tag)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockDiscussionService) UnpinInTag(ctx context.Context, discussionID int, tag string) (bool, error) {
+	args := m.Called(ctx, discussionID, tag)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockDiscussionService) GetByUser(ctx context.Context, userID, viewerID int) ([]models.Discussion, error) {
+	args := m.Called(ctx, userID, viewerID)
 	return args.Get(0).([]models.Discussion), args.Error(1)
 }
-func (m *MockDiscussionService) GetByTag(ctx context.Context, tag string) ([]models.Discussion, error) {
-	args := m.Called(ctx, tag)
+func (m *MockDiscussionService) GetByTag(ctx context.Context, tag string, viewerID int) ([]models.Discussion, error) {
+	args := m.Called(ctx, tag, viewerID)
 	return args.Get(0).([]models.Discussion), args.Error(1)
 }
+func (m *MockDiscussionService) ListByTags(ctx context.Context, names []string) ([]TagGroup, error) {
+	args := m.Called(ctx, names)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]TagGroup), args.Error(1)
+}
 func (m *MockDiscussionService) AddTags(ctx context.Context, discussionID int, dto *AddTagsDTO) error {
 	args := m.Called(ctx, discussionID, dto)
 	return args.Error(0)
 }
-func (m *MockDiscussionService) Schedule(ctx context.Context, userID int, dto *ScheduleDTO) (int, error) {
+func (m *MockDiscussionService) ReplaceTags(ctx context.Context, discussionID int, dto *ReplaceTagsDTO) error {
+	args := m.Called(ctx, discussionID, dto)
+	return args.Error(0)
+}
+func (m *MockDiscussionService) Schedule(ctx context.Context, userID int, dto *ScheduleDTO) (*models.Discussion, error) {
 	args := m.Called(ctx, userID, dto)
-	return args.Int(0), args.Error(1)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Discussion), args.Error(1)
+}
+func (m *MockDiscussionService) ListActive(ctx context.Context, window time.Duration, viewerID int) ([]models.Discussion, error) {
+	args := m.Called(ctx, window, viewerID)
+	return args.Get(0).([]models.Discussion), args.Error(1)
+}
+func (m *MockDiscussionService) Bump(ctx context.Context, id int) (*models.Discussion, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Discussion), args.Error(1)
+}
+func (m *MockDiscussionService) ArchiveStale(ctx context.Context, olderThan time.Duration) ([]int, error) {
+	args := m.Called(ctx, olderThan)
+	return args.Get(0).([]int), args.Error(1)
+}
+func (m *MockDiscussionService) LastModified(ctx context.Context, sort string) (time.Time, error) {
+	args := m.Called(ctx, sort)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+func (m *MockDiscussionService) FindSimilar(ctx context.Context, title string, viewerID int) ([]models.Discussion, error) {
+	args := m.Called(ctx, title, viewerID)
+	return args.Get(0).([]models.Discussion), args.Error(1)
+}
+func (m *MockDiscussionService) Search(ctx context.Context, query string, limit, offset int) ([]models.Discussion, int, int, error) {
+	args := m.Called(ctx, query, limit, offset)
+	return args.Get(0).([]models.Discussion), args.Int(1), args.Int(2), args.Error(3)
+}
+func (m *MockDiscussionService) ListUnseen(ctx context.Context, userID int) ([]models.Discussion, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Discussion), args.Error(1)
+}
+func (m *MockDiscussionService) MarkSeen(ctx context.Context, userID int) (time.Time, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+func (m *MockDiscussionService) ListRevisionsByEditor(ctx context.Context, editorID, limit, offset int) ([]models.DiscussionRevision, error) {
+	args := m.Called(ctx, editorID, limit, offset)
+	return args.Get(0).([]models.DiscussionRevision), args.Error(1)
+}
+func (m *MockDiscussionService) RevisionDiff(ctx context.Context, discussionID, revID int) (*RevisionDiff, error) {
+	args := m.Called(ctx, discussionID, revID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*RevisionDiff), args.Error(1)
+}
+
+func (m *MockDiscussionService) CommentStats(ctx context.Context, discussionID int, p tagpkg.TrendParams) (*CommentStats, error) {
+	args := m.Called(ctx, discussionID, p)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*CommentStats), args.Error(1)
 }
 
 // Helper to generate a JWT token for testing
@@ -77,11 +184,19 @@ func generateTestTokenDiscussion(userID int) string {
 	return token
 }
 
+func generateAdminTokenDiscussion(userID int) string {
+	token, err := jwtutil.GenerateTokenWithRole(userID, models.RoleAdmin)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate test token: %v", err))
+	}
+	return token
+}
+
 // Helper to set up the Gin router with DiscussionController and middleware
 func setupDiscussionTestRouter(mockService Service) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-	discussionController := NewController(mockService)
+	discussionController := NewController(mockService, nil)
 
 	// Public routes (if any) - none in this controller based on routes.go structure
 	// Routes requiring authentication
@@ -89,17 +204,30 @@ func setupDiscussionTestRouter(mockService Service) *gin.Engine {
 	authedGroup.Use(authmw.JWTAuthMiddleware())
 	{
 		authedGroup.POST("/discussions", discussionController.Create)
+		authedGroup.POST("/discussions/preview", discussionController.Preview)
 		authedGroup.PUT("/discussions/:id", discussionController.Update)
-		authedGroup.DELETE("/discussions/:id", discussionController.Delete)
+		authedGroup.DELETE("/discussions/:id", authmw.RequireRole(models.RoleAdmin), discussionController.Delete)
+		authedGroup.POST("/discussions/:id/restore", authmw.RequireRole(models.RoleAdmin), discussionController.Restore)
 		authedGroup.POST("/discussions/:id/tags", discussionController.AddTags)
+		authedGroup.PUT("/discussions/:id/tags", discussionController.ReplaceTags)
 		authedGroup.POST("/discussions/schedule", discussionController.Schedule)
+		authedGroup.POST("/discussions/:id/bump", discussionController.Bump)
+		authedGroup.GET("/discussions/:id/revisions/:revID/diff", discussionController.RevisionDiff)
+		authedGroup.GET("/discussions/:id/stats", discussionController.Stats)
+		authedGroup.GET("/discussions/active", discussionController.ListActive)
+		authedGroup.GET("/admin/revisions", authmw.RequireRole(models.RoleAdmin), discussionController.ListRevisionsByEditor)
+		authedGroup.POST("/admin/discussions/lock-stale", authmw.RequireRole(models.RoleAdmin), discussionController.ArchiveStale)
+		authedGroup.POST("/admin/tags/:tag/pins/:id", authmw.RequireRole(models.RoleAdmin), discussionController.PinInTag)
+		authedGroup.DELETE("/admin/tags/:tag/pins/:id", authmw.RequireRole(models.RoleAdmin), discussionController.UnpinInTag)
 	}
 	// Routes that might be public or authed depending on main app setup
 	// For testing, let's assume they don't strictly need auth unless specified for modification
 	router.GET("/discussions", discussionController.List)
+	router.GET("/discussions/search", discussionController.Search)
 	router.GET("/discussions/:id", discussionController.Get)
 	router.GET("/discussions/user/:userId", discussionController.ListByUser)
 	router.GET("/discussions/tag/:tag", discussionController.ListByTag)
+	router.GET("/discussions/by-tags", discussionController.ListByTags)
 
 	return router
 }
@@ -123,6 +251,32 @@ func performDiscussionRequest(r http.Handler, method, path, token string, body i
 }
 
 // --- CreateDiscussion Tests ---
+func TestPreviewDiscussion_RendersSanitizedHTML(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token := generateTestTokenDiscussion(1)
+
+	body := PreviewDTO{Content: "**hi** <script>alert(1)</script>"}
+	w := performDiscussionRequest(router, "POST", "/discussions/preview", token, body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Contains(t, resp["html"], "<strong>hi</strong>")
+	assert.NotContains(t, resp["html"], "<script")
+	// nothing is saved
+	assert.Empty(t, mockService.Calls)
+}
+
+func TestPreviewDiscussion_RequiresContent(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token := generateTestTokenDiscussion(1)
+
+	w := performDiscussionRequest(router, "POST", "/discussions/preview", token, PreviewDTO{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, mockService.Calls)
+}
+
 func TestCreateDiscussion_Success(t *testing.T) {
 	mockService := new(MockDiscussionService)
 	router := setupDiscussionTestRouter(mockService)
@@ -130,13 +284,22 @@ func TestCreateDiscussion_Success(t *testing.T) {
 	token := generateTestTokenDiscussion(actingUserID)
 	dto := CreateDiscussionDTO{Title: "Test Title", Content: "Test Content"}
 
-	mockService.On("Create", mock.Anything, actingUserID, &dto).Return(123, nil)
+	createdAt := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	mockService.On("Create", mock.Anything, actingUserID, &dto).Return(&models.Discussion{
+		ID: 123, UserID: actingUserID, Title: dto.Title, Content: dto.Content,
+		Status: models.StatusPublished, CreatedAt: createdAt, UpdatedAt: createdAt,
+	}, nil)
+	mockService.On("RateLimitStatus", mock.Anything, actingUserID).Return(nil, nil)
 
 	w := performDiscussionRequest(router, "POST", "/discussions", token, dto)
 	assert.Equal(t, http.StatusCreated, w.Code)
-	var resp map[string]int
-	json.Unmarshal(w.Body.Bytes(), &resp)
-	assert.Equal(t, 123, resp["id"])
+	var resp models.Discussion
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 123, resp.ID)
+	assert.Equal(t, "Test Title", resp.Title)
+	assert.Equal(t, "Test Content", resp.Content)
+	assert.Equal(t, models.StatusPublished, resp.Status)
+	assert.True(t, createdAt.Equal(resp.CreatedAt))
 	mockService.AssertExpectations(t)
 }
 
@@ -173,225 +336,1378 @@ func TestCreateDiscussion_ServiceError(t *testing.T) {
 	token := generateTestTokenDiscussion(actingUserID)
 	dto := CreateDiscussionDTO{Title: "Test Title", Content: "Test Content"}
 
-	mockService.On("Create", mock.Anything, actingUserID, &dto).Return(0, assert.AnError)
+	mockService.On("Create", mock.Anything, actingUserID, &dto).Return(nil, assert.AnError)
+	mockService.On("RateLimitStatus", mock.Anything, actingUserID).Return(nil, nil)
 
 	w := performDiscussionRequest(router, "POST", "/discussions", token, dto)
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	mockService.AssertExpectations(t)
 }
 
-
-// --- GetDiscussionByID Tests ---
-func TestGetDiscussionByID_Success(t *testing.T) {
+func TestCreateDiscussion_RateLimited(t *testing.T) {
 	mockService := new(MockDiscussionService)
 	router := setupDiscussionTestRouter(mockService)
-	discussionID := 1
-	expectedDiscussion := &models.Discussion{ID: discussionID, Title: "Test", UserID: 1}
+	actingUserID := 1
+	token := generateTestTokenDiscussion(actingUserID)
+	dto := CreateDiscussionDTO{Title: "Test Title", Content: "Test Content"}
 
-	mockService.On("GetByID", mock.Anything, discussionID).Return(expectedDiscussion, nil)
+	mockService.On("Create", mock.Anything, actingUserID, &dto).Return(nil, ErrRateLimited)
+	mockService.On("RateLimitStatus", mock.Anything, actingUserID).Return(nil, nil)
 
-	w := performDiscussionRequest(router, "GET", "/discussions/"+strconv.Itoa(discussionID), "", nil)
-	assert.Equal(t, http.StatusOK, w.Code)
-	var discussion models.Discussion
-	json.Unmarshal(w.Body.Bytes(), &discussion)
-	assert.Equal(t, expectedDiscussion.Title, discussion.Title)
+	w := performDiscussionRequest(router, "POST", "/discussions", token, dto)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
 	mockService.AssertExpectations(t)
 }
 
-func TestGetDiscussionByID_NotFound(t *testing.T) {
+func TestCreateDiscussion_UnsupportedLanguage(t *testing.T) {
 	mockService := new(MockDiscussionService)
 	router := setupDiscussionTestRouter(mockService)
-	discussionID := 1
+	actingUserID := 1
+	token := generateTestTokenDiscussion(actingUserID)
+	dto := CreateDiscussionDTO{Title: "Test Title", Content: "Test Content", Language: "xx"}
 
-	mockService.On("GetByID", mock.Anything, discussionID).Return(nil, nil) // Service returns nil, nil for not found
+	mockService.On("Create", mock.Anything, actingUserID, &dto).Return(nil, ErrInvalidLanguage)
+	mockService.On("RateLimitStatus", mock.Anything, actingUserID).Return(nil, nil)
 
-	w := performDiscussionRequest(router, "GET", "/discussions/"+strconv.Itoa(discussionID), "", nil)
-	assert.Equal(t, http.StatusNotFound, w.Code) // Controller translates this to 404
+	w := performDiscussionRequest(router, "POST", "/discussions", token, dto)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"language is not supported"}`, w.Body.String())
 	mockService.AssertExpectations(t)
 }
 
-func TestGetDiscussionByID_ServiceError(t *testing.T) {
+func TestCreateDiscussion_RateLimitHeaders(t *testing.T) {
+	// A real service over the in-memory repo, so the quota is actually counted.
+	repo := &fakeRepo{}
+	cfg := &config.Config{DiscussionRateLimit: 3, DiscussionRateWindow: time.Hour}
+	router := setupDiscussionTestRouter(NewService(repo, nil, cfg))
+	token := generateTestTokenDiscussion(1)
+	dto := CreateDiscussionDTO{Title: "Test Title", Content: "Test Content"}
+
+	for _, want := range []string{"2", "1", "0"} {
+		w := performDiscussionRequest(router, "POST", "/discussions", token, dto)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, want, w.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	w := performDiscussionRequest(router, "POST", "/discussions", token, dto)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+	assert.NoError(t, err)
+	oldest := repo.discussions[0].CreatedAt
+	assert.Equal(t, oldest.Add(time.Hour).Unix(), reset)
+
+	// Once the window has passed the quota is full again.
+	for i := range repo.discussions {
+		repo.discussions[i].CreatedAt = repo.discussions[i].CreatedAt.Add(-2 * time.Hour)
+	}
+	w = performDiscussionRequest(router, "POST", "/discussions", token, dto)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestCreateDiscussion_NoRateLimitHeadersWhenUnlimited(t *testing.T) {
+	router := setupDiscussionTestRouter(NewService(&fakeRepo{}, nil, nil))
+
+	w := performDiscussionRequest(router, "POST", "/discussions", generateTestTokenDiscussion(1),
+		CreateDiscussionDTO{Title: "Test Title", Content: "Test Content"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+}
+
+func TestCreateDiscussion_DuplicateWarning(t *testing.T) {
 	mockService := new(MockDiscussionService)
 	router := setupDiscussionTestRouter(mockService)
-	discussionID := 1
+	actingUserID := 1
+	token := generateTestTokenDiscussion(actingUserID)
+	dto := CreateDiscussionDTO{Title: "How to learn Go?", Content: "Test Content"}
 
-	mockService.On("GetByID", mock.Anything, discussionID).Return(nil, assert.AnError)
+	mockService.On("FindSimilar", mock.Anything, dto.Title, actingUserID).
+		Return([]models.Discussion{{ID: 7, Title: "how to learn go"}}, nil)
+	mockService.On("Create", mock.Anything, actingUserID, &dto).Return(&models.Discussion{ID: 123, Title: dto.Title}, nil)
+	mockService.On("RateLimitStatus", mock.Anything, actingUserID).Return(nil, nil)
 
-	w := performDiscussionRequest(router, "GET", "/discussions/"+strconv.Itoa(discussionID), "", nil)
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	w := performDiscussionRequest(router, "POST", "/discussions?check_duplicates=true", token, dto)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(123), resp["id"])
+	assert.Equal(t, dto.Title, resp["title"])
+	assert.Equal(t, "similar discussion exists", resp["warning"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": float64(7), "title": "how to learn go"}}, resp["similar"])
 	mockService.AssertExpectations(t)
 }
 
-// --- ListAllDiscussions Tests ---
-func TestListAllDiscussions_Success(t *testing.T) {
-    mockService := new(MockDiscussionService)
-    router := setupDiscussionTestRouter(mockService)
-    expectedDiscussions := []models.Discussion{{ID: 1, Title: "Disc1"}, {ID: 2, Title: "Disc2"}}
+func TestCreateDiscussion_UniqueTitleNoWarning(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	actingUserID := 1
+	token := generateTestTokenDiscussion(actingUserID)
+	dto := CreateDiscussionDTO{Title: "Something new", Content: "Test Content"}
 
-    mockService.On("GetAll", mock.Anything).Return(expectedDiscussions, nil)
+	mockService.On("FindSimilar", mock.Anything, dto.Title, actingUserID).Return([]models.Discussion{}, nil)
+	mockService.On("Create", mock.Anything, actingUserID, &dto).Return(&models.Discussion{ID: 124, Title: dto.Title}, nil)
+	mockService.On("RateLimitStatus", mock.Anything, actingUserID).Return(nil, nil)
 
-    w := performDiscussionRequest(router, "GET", "/discussions", "", nil)
-    assert.Equal(t, http.StatusOK, w.Code)
-    var discussions []models.Discussion
-    json.Unmarshal(w.Body.Bytes(), &discussions)
-    assert.Len(t, discussions, 2)
-    mockService.AssertExpectations(t)
+	w := performDiscussionRequest(router, "POST", "/discussions?check_duplicates=true", token, dto)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(124), resp["id"])
+	assert.NotContains(t, resp, "warning")
+	assert.NotContains(t, resp, "similar")
+	mockService.AssertExpectations(t)
 }
 
-// --- UpdateDiscussion Tests ---
-func TestUpdateDiscussion_Success(t *testing.T) {
+func TestCreateDiscussion_DuplicateCheckIsOptIn(t *testing.T) {
 	mockService := new(MockDiscussionService)
 	router := setupDiscussionTestRouter(mockService)
 	actingUserID := 1
-	discussionID := 1
 	token := generateTestTokenDiscussion(actingUserID)
-	dto := UpdateDiscussionDTO{Title: new(string)}
-	*dto.Title = "Updated Title"
-	updatedDiscussion := &models.Discussion{ID: discussionID, Title: *dto.Title, UserID: actingUserID}
+	dto := CreateDiscussionDTO{Title: "How to learn Go?", Content: "Test Content"}
 
-	// IMPORTANT: Controller does not do authorization check.
-	// Service's Update method is called regardless of user matching.
-	mockService.On("Update", mock.Anything, discussionID, &dto).Return(updatedDiscussion, nil)
+	mockService.On("Create", mock.Anything, actingUserID, &dto).Return(&models.Discussion{ID: 125}, nil)
+	mockService.On("RateLimitStatus", mock.Anything, actingUserID).Return(nil, nil)
 
-	w := performDiscussionRequest(router, "PUT", "/discussions/"+strconv.Itoa(discussionID), token, dto)
+	w := performDiscussionRequest(router, "POST", "/discussions", token, dto)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockService.AssertNotCalled(t, "FindSimilar", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// --- GetDiscussionByID Tests ---
+
+// withTags wraps d the way Service.GetWithTags returns it.
+func withTags(d *models.Discussion, tags ...string) *DiscussionWithTags {
+	if tags == nil {
+		tags = []string{}
+	}
+	return &DiscussionWithTags{Discussion: *d, Tags: tags, TagCount: len(tags)}
+}
+
+func TestGetDiscussionByID_Success(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	discussionID := 1
+	expectedDiscussion := &models.Discussion{ID: discussionID, Title: "Test", UserID: 1}
+
+	mockService.On("GetWithTags", mock.Anything, discussionID).Return(withTags(expectedDiscussion), nil)
+	mockService.On("RecordView", mock.Anything, discussionID).Return(nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/"+strconv.Itoa(discussionID), "", nil)
 	assert.Equal(t, http.StatusOK, w.Code)
 	var discussion models.Discussion
 	json.Unmarshal(w.Body.Bytes(), &discussion)
-	assert.Equal(t, *dto.Title, discussion.Title)
-	t.Log("NOTE: TestUpdateDiscussion_Success passed, but controller does not enforce that actingUserID matches discussion owner.")
+	assert.Equal(t, expectedDiscussion.Title, discussion.Title)
 	mockService.AssertExpectations(t)
 }
 
-func TestUpdateDiscussion_Forbidden_NotAuthor(t *testing.T) {
+func TestGetDiscussionByID_IncludesTags(t *testing.T) {
 	mockService := new(MockDiscussionService)
 	router := setupDiscussionTestRouter(mockService)
 
-	authorID := 1
-	actingUserID := 2 // Different user
-	discussionID := 10
+	mockService.On("GetWithTags", mock.Anything, 1).Return(withTags(&models.Discussion{ID: 1, Title: "Test"}, "go", "postgres"), nil)
+	mockService.On("RecordView", mock.Anything, 1).Return(nil)
+	mockService.On("GetWithTags", mock.Anything, 2).Return(withTags(&models.Discussion{ID: 2, Title: "Bare"}), nil)
+	mockService.On("RecordView", mock.Anything, 2).Return(nil)
 
-	token := generateTestTokenDiscussion(actingUserID)
-	dto := UpdateDiscussionDTO{Title: new(string)}
-	*dto.Title = "Malicious Update"
+	w := performDiscussionRequest(router, "GET", "/discussions/1", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Test", resp["title"])
+	assert.Equal(t, []interface{}{"go", "postgres"}, resp["tags"])
+	assert.Equal(t, float64(2), resp["tag_count"])
+
+	w = performDiscussionRequest(router, "GET", "/discussions/2", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"tags":[]`)
+	assert.Contains(t, w.Body.String(), `"tag_count":0`)
+}
 
-	// This is what we expect if AuthZ was implemented in the controller
-	// For now, the controller will call the service.
-	// mockService.On("GetDiscussionAuthorID", mock.Anything, discussionID).Return(authorID, nil) // Hypothetical service method
+func TestGetDiscussionByID_SparseFields(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
 
-	// Current behavior: Controller calls service's Update directly.
-	// Service update might succeed or fail based on its own logic, not controller AuthZ.
-	// Assuming service Update itself doesn't do AuthZ and just updates if discussion exists.
-	updatedDiscussion := &models.Discussion{ID: discussionID, Title: *dto.Title, UserID: authorID} // UserID remains authorID
-	mockService.On("Update", mock.Anything, discussionID, &dto).Return(updatedDiscussion, nil)
+	mockService.On("GetWithTags", mock.Anything, 1).Return(withTags(&models.Discussion{ID: 1, Title: "Test", Content: "long"}, "go"), nil)
+	mockService.On("RecordView", mock.Anything, 1).Return(nil)
 
+	w := performDiscussionRequest(router, "GET", "/discussions/1?fields=id,title,tags", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "title": "Test", "tags": []interface{}{"go"}}, resp)
+}
 
-	w := performDiscussionRequest(router, "PUT", "/discussions/"+strconv.Itoa(discussionID), token, dto)
+func TestGetDiscussionByID_RejectsUnknownField(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
 
-	// This should be http.StatusForbidden (403) if authorization was correctly implemented.
-	// Currently, it will be http.StatusOK (200) because the controller doesn't check.
-	assert.Equal(t, http.StatusOK, w.Code, "AuthZ Violation: Expected 403 Forbidden, but got 200. Controller does not check if updater is author.")
-	if w.Code == http.StatusOK {
-		t.Logf("WARNING: TestUpdateDiscussion_Forbidden_NotAuthor: Endpoint allowed user %d to update discussion %d owned by user %d. This is an authorization flaw.", actingUserID, discussionID, authorID)
+	for _, q := range []string{"fields=id,password", "fields=id,,title", "fields=deleted_at"} {
+		w := performDiscussionRequest(router, "GET", "/discussions/1?"+q, "", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
 	}
+	mockService.AssertNotCalled(t, "GetWithTags", mock.Anything, mock.Anything)
+}
+
+func TestGetDiscussionByID_CountsView(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("GetWithTags", mock.Anything, 1).Return(withTags(&models.Discussion{ID: 1, Title: "Test", ViewCount: 41}), nil)
+	mockService.On("RecordView", mock.Anything, 1).Return(nil).Once()
+
+	w := performDiscussionRequest(router, "GET", "/discussions/1", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"view_count":42`)
 	mockService.AssertExpectations(t)
 }
 
+func TestGetDiscussionByID_ViewCountFailureStillServes(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
 
-func TestUpdateDiscussion_Unauthorized(t *testing.T) {
+	mockService.On("GetWithTags", mock.Anything, 1).Return(withTags(&models.Discussion{ID: 1, ViewCount: 41}), nil)
+	mockService.On("RecordView", mock.Anything, 1).Return(assert.AnError)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/1", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"view_count":41`)
+}
+
+func TestListDiscussions_DoNotCountViews(t *testing.T) {
 	mockService := new(MockDiscussionService)
 	router := setupDiscussionTestRouter(mockService)
-	dto := UpdateDiscussionDTO{Title: new(string)}
-	*dto.Title = "Updated Title"
 
-	w := performDiscussionRequest(router, "PUT", "/discussions/1", "", dto) // No token
-	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockService.On("LastModified", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	mockService.On("ListByStatus", mock.Anything, models.StatusPublished, 0, ListFilter{}, "", 20, 0).Return([]models.Discussion{{ID: 1}, {ID: 2}}, 2, nil)
+	mockService.On("GetByUser", mock.Anything, 1, 0).Return([]models.Discussion{{ID: 1}}, nil)
+	mockService.On("GetByTag", mock.Anything, "go", 0).Return([]models.Discussion{{ID: 2}}, nil)
+
+	for _, path := range []string{"/discussions", "/discussions/user/1", "/discussions/tag/go"} {
+		w := performDiscussionRequest(router, "GET", path, generateTestTokenDiscussion(1), nil)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+	mockService.AssertNotCalled(t, "RecordView", mock.Anything, mock.Anything)
 }
 
-// --- DeleteDiscussion Tests ---
-func TestDeleteDiscussion_Success(t *testing.T) {
+func TestGetDiscussionByID_NotFound(t *testing.T) {
 	mockService := new(MockDiscussionService)
 	router := setupDiscussionTestRouter(mockService)
-	actingUserID := 1
 	discussionID := 1
-	token := generateTestTokenDiscussion(actingUserID)
 
-	// IMPORTANT: Controller does not do authorization check.
-	mockService.On("Delete", mock.Anything, discussionID).Return(nil)
+	mockService.On("GetWithTags", mock.Anything, discussionID).Return(nil, nil) // Service returns nil, nil for not found
 
-	w := performDiscussionRequest(router, "DELETE", "/discussions/"+strconv.Itoa(discussionID), token, nil)
-	assert.Equal(t, http.StatusNoContent, w.Code)
-	t.Log("NOTE: TestDeleteDiscussion_Success passed, but controller does not enforce that actingUserID matches discussion owner.")
+	w := performDiscussionRequest(router, "GET", "/discussions/"+strconv.Itoa(discussionID), "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code) // Controller translates this to 404
 	mockService.AssertExpectations(t)
 }
 
-func TestDeleteDiscussion_Forbidden_NotAuthor(t *testing.T) {
+func TestGetDiscussionByID_ServiceError(t *testing.T) {
 	mockService := new(MockDiscussionService)
 	router := setupDiscussionTestRouter(mockService)
+	discussionID := 1
 
-	authorID := 1
-	actingUserID := 2 // Different user
-	discussionID := 10
-	token := generateTestTokenDiscussion(actingUserID)
+	mockService.On("GetWithTags", mock.Anything, discussionID).Return(nil, assert.AnError)
 
-	// Current behavior: Controller calls service's Delete directly.
-	mockService.On("Delete", mock.Anything, discussionID).Return(nil)
+	w := performDiscussionRequest(router, "GET", "/discussions/"+strconv.Itoa(discussionID), "", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	mockService.AssertExpectations(t)
+}
 
-	w := performDiscussionRequest(router, "DELETE", "/discussions/"+strconv.Itoa(discussionID), token, nil)
+func TestGetDiscussionByID_ClientCanceled(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
 
-	// This should be http.StatusForbidden (403)
-	// Currently, it will be http.StatusNoContent (204)
-	assert.Equal(t, http.StatusNoContent, w.Code, "AuthZ Violation: Expected 403 Forbidden, but got 204. Controller does not check if deleter is author.")
-	if w.Code == http.StatusNoContent {
-		t.Logf("WARNING: TestDeleteDiscussion_Forbidden_NotAuthor: Endpoint allowed user %d to delete discussion %d owned by user %d. This is an authorization flaw.", actingUserID, discussionID, authorID)
-	}
+	mockService.On("GetWithTags", mock.Anything, 1).Return(nil, fmt.Errorf("query: %w", context.Canceled))
+
+	w := performDiscussionRequest(router, "GET", "/discussions/1", "", nil)
+	assert.Equal(t, httperr.StatusClientClosedRequest, w.Code)
 	mockService.AssertExpectations(t)
 }
 
-func TestDeleteDiscussion_Unauthorized(t *testing.T) {
+func TestGetDiscussionByID_ClientDisconnectedDriverError(t *testing.T) {
 	mockService := new(MockDiscussionService)
 	router := setupDiscussionTestRouter(mockService)
 
-	w := performDiscussionRequest(router, "DELETE", "/discussions/1", "", nil) // No token
-	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	// The driver's own error does not wrap context.Canceled; the request context tells.
+	mockService.On("GetWithTags", mock.Anything, 1).Return(nil, assert.AnError)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/discussions/1", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, httperr.StatusClientClosedRequest, w.Code)
+	mockService.AssertExpectations(t)
 }
 
-// --- AddTags Tests ---
-func TestAddTags_Success(t *testing.T) {
-    mockService := new(MockDiscussionService)
-    router := setupDiscussionTestRouter(mockService)
-    actingUserID := 1
-    discussionID := 1
-    token := generateTestTokenDiscussion(actingUserID)
-    dto := AddTagsDTO{Tags: []string{"go", "test"}}
+func TestGetDiscussionByID_Timeout(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
 
-    // AuthZ gap: Controller doesn't check if actingUserID can modify discussionID's tags.
-    mockService.On("AddTags", mock.Anything, discussionID, &dto).Return(nil)
+	mockService.On("GetWithTags", mock.Anything, 1).Return(nil, context.DeadlineExceeded)
 
-    w := performDiscussionRequest(router, "POST", "/discussions/"+strconv.Itoa(discussionID)+"/tags", token, dto)
-    assert.Equal(t, http.StatusNoContent, w.Code)
-    t.Log("NOTE: TestAddTags_Success passed, but controller does not enforce specific authorization for adding tags.")
-    mockService.AssertExpectations(t)
+	w := performDiscussionRequest(router, "GET", "/discussions/1", "", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	mockService.AssertExpectations(t)
 }
 
-// --- ScheduleDiscussion Tests ---
-func TestScheduleDiscussion_Success(t *testing.T) {
-    mockService := new(MockDiscussionService)
+// --- ListAllDiscussions Tests ---
+func TestListAllDiscussions_Success(t *testing.T) {
+    mockService := new(MockDiscussionService)
+    router := setupDiscussionTestRouter(mockService)
+    expectedDiscussions := []models.Discussion{{ID: 1, Title: "Disc1"}, {ID: 2, Title: "Disc2"}}
+
+    mockService.On("LastModified", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+    mockService.On("ListByStatus", mock.Anything, models.StatusPublished, 0, ListFilter{}, "", 20, 0).Return(expectedDiscussions, 2, nil)
+
+    w := performDiscussionRequest(router, "GET", "/discussions", "", nil)
+    assert.Equal(t, http.StatusOK, w.Code)
+    var page struct {
+        Data       []models.Discussion `json:"data"`
+        Total      int                 `json:"total"`
+        NextCursor *int                `json:"next_cursor"`
+    }
+    json.Unmarshal(w.Body.Bytes(), &page)
+    assert.Len(t, page.Data, 2)
+    assert.Equal(t, 2, page.Total)
+    assert.Nil(t, page.NextCursor)
+    mockService.AssertExpectations(t)
+}
+
+func TestListAllDiscussions_Paginates(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("LastModified", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	mockService.On("ListByStatus", mock.Anything, models.StatusPublished, 0, ListFilter{}, "", 2, 4).
+		Return([]models.Discussion{{ID: 5}, {ID: 6}}, 9, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions?limit=2&offset=4", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page struct {
+		Limit      int  `json:"limit"`
+		Offset     int  `json:"offset"`
+		Total      int  `json:"total"`
+		NextCursor *int `json:"next_cursor"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, 2, page.Limit)
+	assert.Equal(t, 4, page.Offset)
+	assert.Equal(t, 9, page.Total)
+	if assert.NotNil(t, page.NextCursor) {
+		assert.Equal(t, 6, *page.NextCursor)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestListAllDiscussions_ClampsLimit(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("LastModified", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	mockService.On("ListByStatus", mock.Anything, models.StatusPublished, 0, ListFilter{}, "", 100, 0).Return([]models.Discussion{}, 0, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions?limit=500", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestListAllDiscussions_RejectsNegativePaging(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	for _, q := range []string{"limit=-1", "offset=-5", "limit=abc"} {
+		w := performDiscussionRequest(router, "GET", "/discussions?"+q, "", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	mockService.AssertNotCalled(t, "ListByStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListAllDiscussions_RejectsOffsetBeyondCap(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	pagination.SetMaxOffset(1000)
+	t.Cleanup(func() { pagination.SetMaxOffset(0) })
+
+	mockService.On("LastModified", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	mockService.On("ListByStatus", mock.Anything, models.StatusPublished, 0, ListFilter{}, "", 20, 1000).Return([]models.Discussion{}, 0, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions?offset=1000", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code, "the cap itself is allowed")
+
+	w = performDiscussionRequest(router, "GET", "/discussions?offset=1000000", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "offset is too large: the maximum is 1000")
+	mockService.AssertNumberOfCalls(t, "ListByStatus", 1)
+}
+
+func TestListAllDiscussions_PassesSortAndFilters(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("LastModified", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	mockService.On("ListByStatus", mock.Anything, models.StatusPublished, 0, ListFilter{UserID: 5, Tag: "go"}, config.DiscussionSortMostViewed, 20, 0).
+		Return([]models.Discussion{{ID: 1}}, 1, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions?sort=most_viewed&user_id=5&tag=%20Go%20", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestListAllDiscussions_LanguageFilter(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("LastModified", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	mockService.On("ListByStatus", mock.Anything, models.StatusPublished, 0, ListFilter{Language: "en"}, "", 20, 0).
+		Return([]models.Discussion{{ID: 1, Language: "en"}}, 1, nil)
+	mockService.On("ListByStatus", mock.Anything, models.StatusPublished, 0, ListFilter{Language: "xx"}, "", 20, 0).
+		Return([]models.Discussion(nil), 0, ErrInvalidLanguage)
+
+	w := performDiscussionRequest(router, "GET", "/discussions?lang=en", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"language":"en"`)
+
+	w = performDiscussionRequest(router, "GET", "/discussions?lang=xx", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"lang is not a supported language"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestListAllDiscussions_RejectsBadSortOrUser(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	for _, q := range []string{"sort=hot", "user_id=0", "user_id=abc"} {
+		w := performDiscussionRequest(router, "GET", "/discussions?"+q, "", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	w := performDiscussionRequest(router, "GET", "/discussions?sort=hot", "", nil)
+	assert.Contains(t, w.Body.String(), "newest, oldest, most_commented, most_viewed, subscribers")
+	mockService.AssertNotCalled(t, "ListByStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListAllDiscussions_SparseFields(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("LastModified", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	mockService.On("ListByStatus", mock.Anything, models.StatusPublished, 0, ListFilter{}, "", 20, 0).
+		Return([]models.Discussion{{ID: 1, Title: "a", Content: "x"}, {ID: 2, Title: "b", Content: "y"}}, 2, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions?fields=id,%20title", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data  []map[string]interface{} `json:"data"`
+		Total int                      `json:"total"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Total)
+	assert.Equal(t, []map[string]interface{}{
+		{"id": float64(1), "title": "a"},
+		{"id": float64(2), "title": "b"},
+	}, resp.Data)
+}
+
+func TestListAllDiscussions_RejectsUnknownField(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	// tags are only on the detail response
+	for _, q := range []string{"fields=id,secret", "fields=tags"} {
+		w := performDiscussionRequest(router, "GET", "/discussions?"+q, "", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+		assert.Contains(t, w.Body.String(), "fields must be")
+	}
+	mockService.AssertNotCalled(t, "ListByStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// --- Status filter Tests ---
+
+// setupStatusTestRouter serves the read endpoints behind JWT auth, as in
+// production, so drafts can be resolved against the caller.
+func setupStatusTestRouter(mockService Service) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ctr := NewController(mockService, nil)
+	authed := router.Group("/")
+	authed.Use(authmw.JWTAuthMiddleware())
+	authed.GET("/discussions", ctr.List)
+	authed.GET("/discussions/:id", ctr.Get)
+	authed.POST("/me/seen", ctr.MarkSeen)
+	return router
+}
+
+func TestListDiscussions_FiltersByStatus(t *testing.T) {
+	for _, status := range []string{models.StatusDraft, models.StatusPublished, models.StatusArchived} {
+		t.Run(status, func(t *testing.T) {
+			mockService := new(MockDiscussionService)
+			router := setupStatusTestRouter(mockService)
+			token := generateTestTokenDiscussion(7)
+
+			mockService.On("LastModified", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+			mockService.On("ListByStatus", mock.Anything, status, 7, ListFilter{}, "", 20, 0).
+				Return([]models.Discussion{{ID: 1, UserID: 7, Status: status}}, 1, nil)
+
+			w := performDiscussionRequest(router, "GET", "/discussions?status="+status, token, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var page struct {
+				Data []models.Discussion `json:"data"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+			assert.Equal(t, status, page.Data[0].Status)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListDiscussions_DefaultsToPublished(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupStatusTestRouter(mockService)
+	token := generateTestTokenDiscussion(7)
+
+	mockService.On("LastModified", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	mockService.On("ListByStatus", mock.Anything, models.StatusPublished, 7, ListFilter{}, "", 20, 0).Return([]models.Discussion{}, 0, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestListDiscussions_SortParam(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupStatusTestRouter(mockService)
+	token := generateTestTokenDiscussion(7)
+
+	mockService.On("LastModified", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	mockService.On("ListByStatus", mock.Anything, models.StatusPublished, 7, ListFilter{}, "popular", 20, 0).Return([]models.Discussion{}, 0, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions?sort=popular", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performDiscussionRequest(router, "GET", "/discussions?sort=random", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestListDiscussions_InvalidStatus(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupStatusTestRouter(mockService)
+
+	w := performDiscussionRequest(router, "GET", "/discussions?status=deleted", generateTestTokenDiscussion(7), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "ListByStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListDiscussions_DraftsRequireAuth(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService) // GET /discussions is unauthenticated here
+
+	w := performDiscussionRequest(router, "GET", "/discussions?status=draft", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockService.AssertNotCalled(t, "ListByStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListDiscussions_Unseen(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupStatusTestRouter(mockService)
+
+	mockService.On("ListUnseen", mock.Anything, 7).Return([]models.Discussion{{ID: 3}}, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions?unseen=true", generateTestTokenDiscussion(7), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var ds []models.Discussion
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &ds))
+	assert.Len(t, ds, 1)
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "LastModified", mock.Anything, mock.Anything)
+}
+
+func TestListDiscussions_UnseenRequiresAuth(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	w := performDiscussionRequest(router, "GET", "/discussions?unseen=true", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockService.AssertNotCalled(t, "ListUnseen", mock.Anything, mock.Anything)
+}
+
+func TestMarkSeen_ReturnsMarker(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupStatusTestRouter(mockService)
+	seenAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mockService.On("MarkSeen", mock.Anything, 7).Return(seenAt, nil)
+
+	w := performDiscussionRequest(router, "POST", "/me/seen", generateTestTokenDiscussion(7), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]time.Time
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, seenAt.Equal(resp["seen_at"]))
+	mockService.AssertExpectations(t)
+}
+
+func TestSearchDiscussions_TooShort(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("Search", mock.Anything, "a", 20, 0).Return([]models.Discussion(nil), 0, 0, ErrSearchQueryTooShort)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/search?q=a", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestSearchDiscussions_ReportsClampedLimit(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("Search", mock.Anything, "golang", 100, 0).Return([]models.Discussion{{ID: 1}}, 1, 50, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/search?q=golang&limit=500", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(50), resp["limit"])
+	assert.Len(t, resp["data"], 1)
+	mockService.AssertExpectations(t)
+}
+
+func TestSearchDiscussions_PageEnvelope(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("Search", mock.Anything, "golang", 2, 0).Return([]models.Discussion{{ID: 4}, {ID: 1}}, 5, 2, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/search?q=golang&limit=2", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(5), resp["total"])
+	assert.Equal(t, float64(2), resp["next_cursor"])
+	mockService.AssertExpectations(t)
+}
+
+func TestSearchDiscussions_EmptyQuery(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("Search", mock.Anything, "", 20, 0).Return([]models.Discussion(nil), 0, 0, ErrSearchQueryTooShort)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/search", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetDiscussion_HidesOthersDraft(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupStatusTestRouter(mockService)
+	draft := &models.Discussion{ID: 3, UserID: 7, Status: models.StatusDraft}
+	mockService.On("GetWithTags", mock.Anything, 3).Return(withTags(draft), nil)
+	mockService.On("RecordView", mock.Anything, 3).Return(nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/3", generateTestTokenDiscussion(8), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performDiscussionRequest(router, "GET", "/discussions/3", generateTestTokenDiscussion(7), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// --- include=comments Tests ---
+
+type MockCommentLister struct {
+	mock.Mock
+}
+
+func (m *MockCommentLister) ListPage(ctx context.Context, discussionID, limit, offset int) ([]models.Comment, int, error) {
+	args := m.Called(ctx, discussionID, limit, offset)
+	return args.Get(0).([]models.Comment), args.Int(1), args.Error(2)
+}
+
+func TestGetDiscussion_IncludeComments(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	comments := new(MockCommentLister)
+	router := gin.New()
+	router.GET("/discussions/:id", NewController(mockService, comments).Get)
+
+	mockService.On("GetWithTags", mock.Anything, 4).Return(withTags(&models.Discussion{ID: 4, Title: "t", Status: models.StatusPublished}), nil)
+	mockService.On("RecordView", mock.Anything, 4).Return(nil)
+	comments.On("ListPage", mock.Anything, 4, pagination.DefaultLimit, 0).
+		Return([]models.Comment{{ID: 1, DiscussionID: 4}, {ID: 2, DiscussionID: 4}}, 35, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/4?include=comments", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Last-Modified"))
+
+	var resp struct {
+		Discussion   models.Discussion `json:"discussion"`
+		Comments     []models.Comment  `json:"comments"`
+		CommentsMeta struct {
+			Limit  int `json:"limit"`
+			Offset int `json:"offset"`
+			Total  int `json:"total"`
+		} `json:"comments_meta"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 4, resp.Discussion.ID)
+	assert.Len(t, resp.Comments, 2)
+	assert.Equal(t, pagination.DefaultLimit, resp.CommentsMeta.Limit)
+	assert.Equal(t, 0, resp.CommentsMeta.Offset)
+	assert.Equal(t, 35, resp.CommentsMeta.Total)
+	comments.AssertExpectations(t)
+}
+
+func TestGetDiscussion_PlainByDefault(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	comments := new(MockCommentLister)
+	router := gin.New()
+	router.GET("/discussions/:id", NewController(mockService, comments).Get)
+
+	mockService.On("GetWithTags", mock.Anything, 4).Return(withTags(&models.Discussion{ID: 4, Title: "t"}), nil)
+	mockService.On("RecordView", mock.Anything, 4).Return(nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/4", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var d models.Discussion
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+	assert.Equal(t, 4, d.ID)
+	assert.NotContains(t, w.Body.String(), "comments_meta")
+	comments.AssertNotCalled(t, "ListPage", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// --- Last-Modified / If-Modified-Since Tests ---
+func performConditionalGet(r http.Handler, path, ifModifiedSince string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	if ifModifiedSince != "" {
+		req.Header.Set("If-Modified-Since", ifModifiedSince)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestListAllDiscussions_NotModified(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	lastMod := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+
+	mockService.On("LastModified", mock.Anything, mock.Anything).Return(lastMod, nil)
+
+	w := performConditionalGet(router, "/discussions", lastMod.Format(http.TimeFormat))
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, lastMod.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+	mockService.AssertNotCalled(t, "ListByStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListAllDiscussions_DeleteInvalidatesCache(t *testing.T) {
+	updated := time.Now().UTC().Add(-time.Hour)
+	repo := &fakeRepo{discussions: []models.Discussion{
+		{ID: 1, Title: "stays", Status: models.StatusPublished, UpdatedAt: updated.Add(-time.Hour)},
+		{ID: 2, Title: "goes", Status: models.StatusPublished, UpdatedAt: updated},
+	}}
+	router := setupDiscussionTestRouter(NewService(repo, nil, nil))
+	cached := updated.Format(http.TimeFormat)
+
+	w := performConditionalGet(router, "/discussions", cached)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = performDiscussionRequest(router, "DELETE", "/discussions/2", generateAdminTokenDiscussion(9), nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = performConditionalGet(router, "/discussions", cached)
+	assert.Equal(t, http.StatusOK, w.Code, "a cached listing still showing the deleted discussion must be refreshed")
+	assert.NotContains(t, w.Body.String(), "goes")
+}
+
+func TestListAllDiscussions_ActivitySortIsNeverNotModified(t *testing.T) {
+	updated := time.Now().UTC().Add(-time.Hour)
+	repo := &fakeRepo{discussions: []models.Discussion{{ID: 1, Status: models.StatusPublished, UpdatedAt: updated}}}
+	router := setupDiscussionTestRouter(NewService(repo, nil, nil))
+
+	w := performConditionalGet(router, "/discussions?sort=most_viewed", updated.Format(http.TimeFormat))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Last-Modified"))
+}
+
+func TestListAllDiscussions_ModifiedSince(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	lastMod := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mockService.On("LastModified", mock.Anything, mock.Anything).Return(lastMod, nil)
+	mockService.On("ListByStatus", mock.Anything, models.StatusPublished, 0, ListFilter{}, "", 20, 0).Return([]models.Discussion{{ID: 1}}, 1, nil)
+
+	w := performConditionalGet(router, "/discussions", lastMod.Add(-time.Minute).Format(http.TimeFormat))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, lastMod.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+	mockService.AssertExpectations(t)
+}
+
+func TestGetDiscussionByID_NotModified(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mockService.On("GetWithTags", mock.Anything, 1).Return(withTags(&models.Discussion{ID: 1, UpdatedAt: updated}), nil)
+	mockService.On("RecordView", mock.Anything, 1).Return(nil)
+
+	w := performConditionalGet(router, "/discussions/1", updated.Add(time.Hour).Format(http.TimeFormat))
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = performConditionalGet(router, "/discussions/1", updated.Add(-time.Second).Format(http.TimeFormat))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// --- UpdateDiscussion Tests ---
+func TestUpdateDiscussion_Success(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	actingUserID := 1
+	discussionID := 1
+	token := generateTestTokenDiscussion(actingUserID)
+	dto := UpdateDiscussionDTO{Title: new(string)}
+	*dto.Title = "Updated Title"
+	updatedDiscussion := &models.Discussion{ID: discussionID, Title: *dto.Title, UserID: actingUserID}
+
+	mockService.On("GetByID", mock.Anything, discussionID).Return(&models.Discussion{ID: discussionID, UserID: actingUserID}, nil)
+	mockService.On("Update", mock.Anything, discussionID, actingUserID, &dto).Return(updatedDiscussion, nil)
+
+	w := performDiscussionRequest(router, "PUT", "/discussions/"+strconv.Itoa(discussionID), token, dto)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var discussion models.Discussion
+	json.Unmarshal(w.Body.Bytes(), &discussion)
+	assert.Equal(t, *dto.Title, discussion.Title)
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateDiscussion_Forbidden_NotAuthor(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	authorID := 1
+	actingUserID := 2 // Different user
+	discussionID := 10
+
+	token := generateTestTokenDiscussion(actingUserID)
+	dto := UpdateDiscussionDTO{Title: new(string)}
+	*dto.Title = "Malicious Update"
+
+	mockService.On("GetByID", mock.Anything, discussionID).Return(&models.Discussion{ID: discussionID, UserID: authorID}, nil)
+
+	w := performDiscussionRequest(router, "PUT", "/discussions/"+strconv.Itoa(discussionID), token, dto)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error":"forbidden"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateDiscussion_NotFound(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	dto := UpdateDiscussionDTO{Title: new(string)}
+	*dto.Title = "Updated Title"
+
+	mockService.On("GetByID", mock.Anything, 404).Return(nil, nil)
+
+	w := performDiscussionRequest(router, "PUT", "/discussions/404", generateTestTokenDiscussion(2), dto)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+
+func TestUpdateDiscussion_Unauthorized(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	dto := UpdateDiscussionDTO{Title: new(string)}
+	*dto.Title = "Updated Title"
+
+	w := performDiscussionRequest(router, "PUT", "/discussions/1", "", dto) // No token
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// --- DeleteDiscussion Tests ---
+func TestDeleteDiscussion_Success(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	discussionID := 1
+	token := generateAdminTokenDiscussion(9)
+
+	mockService.On("GetByID", mock.Anything, discussionID).Return(&models.Discussion{ID: discussionID, UserID: 1}, nil)
+	mockService.On("Delete", mock.Anything, discussionID).Return(nil)
+
+	w := performDiscussionRequest(router, "DELETE", "/discussions/"+strconv.Itoa(discussionID), token, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+type auditEntries struct{ entries []models.AuditEntry }
+
+func (a *auditEntries) Insert(ctx context.Context, e *models.AuditEntry) error {
+	a.entries = append(a.entries, *e)
+	return nil
+}
+
+func (a *auditEntries) List(ctx context.Context, f audit.Filter, limit, offset int) ([]models.AuditEntry, error) {
+	return a.entries, nil
+}
+
+func TestDeleteDiscussion_RecordsAudit(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	recorded := &auditEntries{}
+	prev := audit.SetStore(recorded)
+	t.Cleanup(func() { audit.SetStore(prev) })
+
+	mockService.On("GetByID", mock.Anything, 12).Return(&models.Discussion{ID: 12, UserID: 3}, nil)
+	mockService.On("GetByID", mock.Anything, 13).Return(&models.Discussion{ID: 13, UserID: 3}, nil)
+	mockService.On("Delete", mock.Anything, 12).Return(nil)
+	mockService.On("Delete", mock.Anything, 13).Return(assert.AnError)
+
+	w := performDiscussionRequest(router, "DELETE", "/discussions/12", generateAdminTokenDiscussion(9), nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = performDiscussionRequest(router, "DELETE", "/discussions/13", generateAdminTokenDiscussion(9), nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	if assert.Len(t, recorded.entries, 1) {
+		assert.Equal(t, models.AuditEntry{ActorID: 9, Action: audit.ActionDiscussionDelete, Target: "discussion:12", CreatedAt: recorded.entries[0].CreatedAt}, recorded.entries[0])
+	}
+}
+
+func TestDeleteDiscussion_Forbidden_NotAdmin(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	// Even the author cannot delete without the admin role.
+	w := performDiscussionRequest(router, "DELETE", "/discussions/10", generateTestTokenDiscussion(1), nil)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error":"forbidden"}`, w.Body.String())
+	mockService.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	mockService.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestDeleteDiscussion_NotFound(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("GetByID", mock.Anything, 404).Return(nil, nil)
+
+	w := performDiscussionRequest(router, "DELETE", "/discussions/404", generateAdminTokenDiscussion(2), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestDeleteDiscussion_Unauthorized(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	w := performDiscussionRequest(router, "DELETE", "/discussions/1", "", nil) // No token
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// --- RestoreDiscussion Tests ---
+func TestRestoreDiscussion_Success(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	recorded := &auditEntries{}
+	prev := audit.SetStore(recorded)
+	t.Cleanup(func() { audit.SetStore(prev) })
+
+	mockService.On("Restore", mock.Anything, 12).Return(true, nil)
+
+	w := performDiscussionRequest(router, "POST", "/discussions/12/restore", generateAdminTokenDiscussion(9), nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	if assert.Len(t, recorded.entries, 1) {
+		assert.Equal(t, audit.ActionDiscussionRestore, recorded.entries[0].Action)
+		assert.Equal(t, "discussion:12", recorded.entries[0].Target)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestRestoreDiscussion_NotFound(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("Restore", mock.Anything, 404).Return(false, nil)
+
+	w := performDiscussionRequest(router, "POST", "/discussions/404/restore", generateAdminTokenDiscussion(9), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRestoreDiscussion_Forbidden_NotAdmin(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	w := performDiscussionRequest(router, "POST", "/discussions/12/restore", generateTestTokenDiscussion(1), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+}
+
+// --- Tag pin Tests ---
+func TestPinInTag(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token := generateAdminTokenDiscussion(9)
+
+	mockService.On("PinInTag", mock.Anything, 12, "go").Return(true, nil)
+	mockService.On("PinInTag", mock.Anything, 12, "rust").Return(false, nil)
+
+	w := performDiscussionRequest(router, "POST", "/admin/tags/go/pins/12", token, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = performDiscussionRequest(router, "POST", "/admin/tags/rust/pins/12", token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":"discussion does not have that tag"}`, w.Body.String())
+
+	w = performDiscussionRequest(router, "POST", "/admin/tags/go/pins/abc", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestUnpinInTag(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token := generateAdminTokenDiscussion(9)
+
+	mockService.On("UnpinInTag", mock.Anything, 12, "go").Return(true, nil)
+	mockService.On("UnpinInTag", mock.Anything, 13, "go").Return(false, nil)
+
+	w := performDiscussionRequest(router, "DELETE", "/admin/tags/go/pins/12", token, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = performDiscussionRequest(router, "DELETE", "/admin/tags/go/pins/13", token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestPinInTag_Forbidden_NotAdmin(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token := generateTestTokenDiscussion(1)
+
+	w := performDiscussionRequest(router, "POST", "/admin/tags/go/pins/12", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performDiscussionRequest(router, "DELETE", "/admin/tags/go/pins/12", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "PinInTag", mock.Anything, mock.Anything, mock.Anything)
+	mockService.AssertNotCalled(t, "UnpinInTag", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// --- AddTags Tests ---
+func TestAddTags_Success(t *testing.T) {
+    mockService := new(MockDiscussionService)
+    router := setupDiscussionTestRouter(mockService)
+    actingUserID := 1
+    discussionID := 1
+    token := generateTestTokenDiscussion(actingUserID)
+    dto := AddTagsDTO{Tags: []string{"go", "test"}}
+
+    mockService.On("GetByID", mock.Anything, discussionID).Return(&models.Discussion{ID: discussionID, UserID: actingUserID}, nil)
+    mockService.On("AddTags", mock.Anything, discussionID, &dto).Return(nil)
+
+    w := performDiscussionRequest(router, "POST", "/discussions/"+strconv.Itoa(discussionID)+"/tags", token, dto)
+    assert.Equal(t, http.StatusNoContent, w.Code)
+    mockService.AssertExpectations(t)
+}
+
+func TestAddTags_Forbidden_NotOwner(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	dto := AddTagsDTO{Tags: []string{"go"}}
+
+	mockService.On("GetByID", mock.Anything, 1).Return(&models.Discussion{ID: 1, UserID: 1, Status: models.StatusDraft}, nil)
+
+	w := performDiscussionRequest(router, "POST", "/discussions/1/tags", generateTestTokenDiscussion(2), dto)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "AddTags", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAddTags_LimitErrorsAreBadRequest(t *testing.T) {
+	for _, limitErr := range []error{ErrTooManyTags, ErrTagOverused} {
+		mockService := new(MockDiscussionService)
+		router := setupDiscussionTestRouter(mockService)
+		token := generateTestTokenDiscussion(1)
+		dto := AddTagsDTO{Tags: []string{"go"}}
+		wrapped := fmt.Errorf("%w: details", limitErr)
+		mockService.On("GetByID", mock.Anything, 1).Return(&models.Discussion{ID: 1, UserID: 1}, nil)
+		mockService.On("AddTags", mock.Anything, 1, &dto).Return(wrapped)
+
+		w := performDiscussionRequest(router, "POST", "/discussions/1/tags", token, dto)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var resp map[string]string
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, wrapped.Error(), resp["error"])
+	}
+}
+
+// --- ReplaceTags Tests ---
+func TestReplaceTags_Success(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	actingUserID := 1
+	discussionID := 1
+	token := generateTestTokenDiscussion(actingUserID)
+	dto := ReplaceTagsDTO{Tags: []string{"go", "postgres"}}
+
+	mockService.On("GetByID", mock.Anything, discussionID).Return(&models.Discussion{ID: discussionID, UserID: actingUserID}, nil)
+	mockService.On("ReplaceTags", mock.Anything, discussionID, &dto).Return(nil)
+
+	w := performDiscussionRequest(router, "PUT", "/discussions/"+strconv.Itoa(discussionID)+"/tags", token, dto)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestReplaceTags_Forbidden_NotOwner(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	discussionID := 1
+	token := generateTestTokenDiscussion(2)
+	dto := ReplaceTagsDTO{Tags: []string{"go"}}
+
+	mockService.On("GetByID", mock.Anything, discussionID).Return(&models.Discussion{ID: discussionID, UserID: 1}, nil)
+
+	w := performDiscussionRequest(router, "PUT", "/discussions/"+strconv.Itoa(discussionID)+"/tags", token, dto)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "ReplaceTags", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReplaceTags_NotFound(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	discussionID := 1
+	token := generateTestTokenDiscussion(1)
+	dto := ReplaceTagsDTO{Tags: []string{"go"}}
+
+	mockService.On("GetByID", mock.Anything, discussionID).Return(nil, nil)
+
+	w := performDiscussionRequest(router, "PUT", "/discussions/"+strconv.Itoa(discussionID)+"/tags", token, dto)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+// --- ScheduleDiscussion Tests ---
+func TestScheduleDiscussion_Success(t *testing.T) {
+    mockService := new(MockDiscussionService)
     router := setupDiscussionTestRouter(mockService)
     actingUserID := 1
     token := generateTestTokenDiscussion(actingUserID)
-    scheduledTime := time.Now().Add(24 * time.Hour)
+    scheduledTime := time.Now().Add(24 * time.Hour).UTC().Round(0) // strip monotonic reading so it survives the JSON round trip
     dto := ScheduleDTO{Title: "Scheduled Post", Content: "Content here", ScheduledAt: scheduledTime}
 
-    mockService.On("Schedule", mock.Anything, actingUserID, &dto).Return(125, nil)
+    mockService.On("Schedule", mock.Anything, actingUserID, &dto).Return(&models.Discussion{
+        ID: 125, UserID: actingUserID, Title: dto.Title, Content: dto.Content, ScheduledAt: &scheduledTime,
+    }, nil)
+    mockService.On("RateLimitStatus", mock.Anything, actingUserID).Return(nil, nil)
 
     w := performDiscussionRequest(router, "POST", "/discussions/schedule", token, dto)
     assert.Equal(t, http.StatusCreated, w.Code)
-    var resp map[string]int
-    json.Unmarshal(w.Body.Bytes(), &resp)
-    assert.Equal(t, 125, resp["id"])
+    var resp models.Discussion
+    assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+    assert.Equal(t, 125, resp.ID)
+    assert.Equal(t, "Scheduled Post", resp.Title)
+    if assert.NotNil(t, resp.ScheduledAt) {
+        assert.True(t, scheduledTime.Equal(*resp.ScheduledAt))
+    }
     mockService.AssertExpectations(t)
 }
 
+func TestScheduleDiscussion_MissingFields(t *testing.T) {
+    future := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+    cases := []struct {
+        name    string
+        payload map[string]interface{}
+        field   string
+    }{
+        {"title", map[string]interface{}{"content": "c", "scheduled_at": future}, "title"},
+        {"content", map[string]interface{}{"title": "t", "scheduled_at": future}, "content"},
+        {"scheduled_at", map[string]interface{}{"title": "t", "content": "c"}, "scheduled_at"},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            mockService := new(MockDiscussionService)
+            router := setupDiscussionTestRouter(mockService)
+            token := generateTestTokenDiscussion(1)
+
+            w := performDiscussionRequest(router, "POST", "/discussions/schedule", token, tc.payload)
+            assert.Equal(t, http.StatusBadRequest, w.Code)
+            var resp map[string]string
+            json.Unmarshal(w.Body.Bytes(), &resp)
+            assert.Equal(t, tc.field+" is required", resp["error"])
+            assert.Equal(t, tc.field, resp["field"])
+            mockService.AssertNotCalled(t, "Schedule", mock.Anything, mock.Anything, mock.Anything)
+        })
+    }
+}
+
+func TestScheduleDiscussion_MalformedJSON(t *testing.T) {
+    mockService := new(MockDiscussionService)
+    router := setupDiscussionTestRouter(mockService)
+    token := generateTestTokenDiscussion(1)
+
+    w := performDiscussionRequest(router, "POST", "/discussions/schedule", token, "not-json")
+    assert.Equal(t, http.StatusBadRequest, w.Code)
+    var resp map[string]string
+    json.Unmarshal(w.Body.Bytes(), &resp)
+    assert.Equal(t, "invalid payload", resp["error"])
+}
+
+// --- Bump Tests ---
+func TestBump_Owner(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token := generateTestTokenDiscussion(1)
+	d := &models.Discussion{ID: 3, UserID: 1, Title: "t"}
+
+	mockService.On("GetByID", mock.Anything, 3).Return(d, nil)
+	mockService.On("Bump", mock.Anything, 3).Return(d, nil)
+
+	w := performDiscussionRequest(router, "POST", "/discussions/3/bump", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestBump_Admin(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token, _ := jwtutil.GenerateTokenWithRole(99, models.RoleAdmin)
+	d := &models.Discussion{ID: 3, UserID: 1}
+
+	mockService.On("GetByID", mock.Anything, 3).Return(d, nil)
+	mockService.On("Bump", mock.Anything, 3).Return(d, nil)
+
+	w := performDiscussionRequest(router, "POST", "/discussions/3/bump", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestBump_Forbidden_NotOwner(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token := generateTestTokenDiscussion(2)
+
+	mockService.On("GetByID", mock.Anything, 3).Return(&models.Discussion{ID: 3, UserID: 1}, nil)
+
+	w := performDiscussionRequest(router, "POST", "/discussions/3/bump", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "Bump", mock.Anything, mock.Anything)
+}
+
+func TestBump_NotFound(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token := generateTestTokenDiscussion(1)
+
+	mockService.On("GetByID", mock.Anything, 3).Return(nil, nil)
+
+	w := performDiscussionRequest(router, "POST", "/discussions/3/bump", token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// --- Active discussions Tests ---
+func TestListActive_DefaultWindow(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token := generateTestTokenDiscussion(1)
+
+	mockService.On("ListActive", mock.Anything, DefaultActiveWindow, 1).Return([]models.Discussion{{ID: 4}}, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/active", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestListActive_CustomWindow(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token := generateTestTokenDiscussion(1)
+
+	mockService.On("ListActive", mock.Anything, 90*time.Minute, 1).Return([]models.Discussion{}, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/active?window=90m", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestListActive_InvalidWindow(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token := generateTestTokenDiscussion(1)
+
+	for _, raw := range []string{"soon", "-1h", "0s"} {
+		w := performDiscussionRequest(router, "GET", "/discussions/active?window="+raw, token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, raw)
+	}
+}
+
+// --- Admin revisions Tests ---
+func TestListRevisionsByEditor_Admin(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token, _ := jwtutil.GenerateTokenWithRole(99, models.RoleAdmin)
+	revs := []models.DiscussionRevision{{ID: 1, DiscussionID: 3, EditedBy: 5, Title: "new"}}
+
+	mockService.On("ListRevisionsByEditor", mock.Anything, 5, 10, 20).Return(revs, nil)
+
+	w := performDiscussionRequest(router, "GET", "/admin/revisions?edited_by=5&limit=10&offset=20", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data []models.DiscussionRevision `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, revs, resp.Data)
+	mockService.AssertExpectations(t)
+}
+
+func TestListRevisionsByEditor_ForbiddenForMember(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token, _ := jwtutil.GenerateTokenWithRole(1, models.RoleMember)
+
+	w := performDiscussionRequest(router, "GET", "/admin/revisions?edited_by=5", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "ListRevisionsByEditor", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListRevisionsByEditor_MissingEditor(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token, _ := jwtutil.GenerateTokenWithRole(99, models.RoleAdmin)
+
+	w := performDiscussionRequest(router, "GET", "/admin/revisions", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListByTags_NormalizesNames(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	groups := []TagGroup{
+		{Tag: "go", Count: 1, Discussions: []models.Discussion{{ID: 3, Title: "t"}}},
+		{Tag: "rust", Count: 0, Discussions: []models.Discussion{}},
+	}
+	mockService.On("ListByTags", mock.Anything, []string{"go", "rust"}).Return(groups, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/by-tags?tags=Go,%20rust,go", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got []TagGroup
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, groups, got)
+	mockService.AssertExpectations(t)
+}
+
+func TestListByTags_RequiresTags(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/by-tags?tags=,", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "ListByTags", mock.Anything, mock.Anything)
+}
+
 // TODO: Add more tests for ListByUser, ListByTag, and other error cases for each endpoint.
 // This initial set covers the main CRUD operations and highlights the AuthZ issues.
 // For brevity, not all permutations of ServiceError, InvalidPayload for every endpoint are included,
@@ -411,3 +1727,142 @@ func TestUpdateDiscussion_InvalidPayload_EmptyDTO(t *testing.T) {
     json.Unmarshal(w.Body.Bytes(), &resp)
     assert.Equal(t, "invalid payload", resp["error"])
 }
+
+// --- ArchiveStale Tests ---
+func TestArchiveStale_AcceptsDays(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("ArchiveStale", mock.Anything, 90*24*time.Hour).Return([]int{3, 8}, nil)
+
+	w := performDiscussionRequest(router, "POST", "/admin/discussions/lock-stale?older_than=90d", generateAdminTokenDiscussion(9), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Archived []int `json:"archived"`
+		Count    int   `json:"count"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []int{3, 8}, resp.Archived)
+	assert.Equal(t, 2, resp.Count)
+	mockService.AssertExpectations(t)
+}
+
+func TestArchiveStale_RejectsBadAge(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	for _, q := range []string{"", "?older_than=soon", "?older_than=0d", "?older_than=-5h"} {
+		w := performDiscussionRequest(router, "POST", "/admin/discussions/lock-stale"+q, generateAdminTokenDiscussion(9), nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	mockService.AssertNotCalled(t, "ArchiveStale", mock.Anything, mock.Anything)
+}
+
+func TestArchiveStale_AdminOnly(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	w := performDiscussionRequest(router, "POST", "/admin/discussions/lock-stale?older_than=90d", generateTestTokenDiscussion(4), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "ArchiveStale", mock.Anything, mock.Anything)
+}
+
+// --- RevisionDiff Tests ---
+func TestRevisionDiff_OwnerAndAdmin(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	diff := &RevisionDiff{RevisionID: 4, DiscussionID: 1, Content: linediff.Diff("old", "new")}
+
+	mockService.On("GetByID", mock.Anything, 1).Return(&models.Discussion{ID: 1, UserID: 7}, nil)
+	mockService.On("RevisionDiff", mock.Anything, 1, 4).Return(diff, nil)
+
+	for _, token := range []string{generateTestTokenDiscussion(7), generateAdminTokenDiscussion(9)} {
+		w := performDiscussionRequest(router, "GET", "/discussions/1/revisions/4/diff", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got RevisionDiff
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, []linediff.Line{{Op: linediff.Delete, Text: "old"}, {Op: linediff.Insert, Text: "new"}}, got.Content)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestRevisionDiff_ForbiddenForOthers(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("GetByID", mock.Anything, 1).Return(&models.Discussion{ID: 1, UserID: 7}, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/1/revisions/4/diff", generateTestTokenDiscussion(8), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "RevisionDiff", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRevisionDiff_NotFound(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("GetByID", mock.Anything, 1).Return(&models.Discussion{ID: 1, UserID: 7}, nil)
+	mockService.On("GetByID", mock.Anything, 2).Return(nil, nil)
+	mockService.On("RevisionDiff", mock.Anything, 1, 99).Return(nil, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/1/revisions/99/diff", generateTestTokenDiscussion(7), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performDiscussionRequest(router, "GET", "/discussions/2/revisions/1/diff", generateTestTokenDiscussion(7), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performDiscussionRequest(router, "GET", "/discussions/1/revisions/x/diff", generateTestTokenDiscussion(7), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestStats_OwnerAndAdmin(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	p := tagpkg.TrendParams{Interval: tagpkg.IntervalWeek, From: from, To: from.AddDate(0, 1, 0)}
+	stats := &CommentStats{DiscussionID: 1, Interval: tagpkg.IntervalWeek, Total: 3,
+		Buckets: []tagpkg.TrendPoint{{Start: time.Date(2026, 8, 31, 0, 0, 0, 0, time.UTC), Count: 3}}}
+
+	mockService.On("GetByID", mock.Anything, 1).Return(&models.Discussion{ID: 1, UserID: 7}, nil)
+	mockService.On("CommentStats", mock.Anything, 1, p).Return(stats, nil)
+
+	for _, token := range []string{generateTestTokenDiscussion(7), generateAdminTokenDiscussion(9)} {
+		w := performDiscussionRequest(router, "GET", "/discussions/1/stats?interval=week&from=2026-09-01&to=2026-09-30", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got CommentStats
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, 3, got.Total)
+		assert.Len(t, got.Buckets, 1)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestStats_ForbiddenForOthers(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+
+	mockService.On("GetByID", mock.Anything, 1).Return(&models.Discussion{ID: 1, UserID: 7}, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/1/stats", generateTestTokenDiscussion(8), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "CommentStats", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestStats_NotFoundAndBadParams(t *testing.T) {
+	mockService := new(MockDiscussionService)
+	router := setupDiscussionTestRouter(mockService)
+	token := generateTestTokenDiscussion(7)
+
+	mockService.On("GetByID", mock.Anything, 2).Return(nil, nil)
+
+	w := performDiscussionRequest(router, "GET", "/discussions/2/stats", token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	for _, path := range []string{
+		"/discussions/x/stats",
+		"/discussions/1/stats?interval=hour",
+		"/discussions/1/stats?from=2026-10-01&to=2026-09-01",
+	} {
+		w = performDiscussionRequest(router, "GET", path, token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+	mockService.AssertNotCalled(t, "CommentStats", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -4,19 +4,105 @@ package discussion
 import (
     "errors"
     "time"
+
+    tagpkg "go-discussion-app/internal/tag"
+    "go-discussion-app/models"
+    "go-discussion-app/pkg/linediff"
 )
 
+// FieldError reports a single missing or invalid field in a request payload,
+// so the controller can tell the client exactly what to fix.
+type FieldError struct {
+    Field  string
+    Reason string
+}
+
+func (e *FieldError) Error() string {
+    return e.Field + " " + e.Reason
+}
+
+// DiscussionWithTags is the GET /discussions/:id body: the discussion's own
+// fields plus the names of its tags.
+type DiscussionWithTags struct {
+    models.Discussion
+    Tags     []string `json:"tags"`
+    TagCount int      `json:"tag_count"`
+}
+
+// TagGroup is one entry of GET /discussions/by-tags: a tag's newest
+// published discussions and how many it has in total.
+type TagGroup struct {
+    Tag         string              `json:"tag"`
+    Count       int                 `json:"count"`
+    Discussions []models.Discussion `json:"discussions"`
+}
+
+// RevisionDiff is GET /discussions/:id/revisions/:revID/diff: what one edit
+// changed, line by line, in the title and in the content.
+type RevisionDiff struct {
+    RevisionID   int             `json:"revision_id"`
+    DiscussionID int             `json:"discussion_id"`
+    EditedBy     int             `json:"edited_by"`
+    CreatedAt    time.Time       `json:"created_at"`
+    Title        []linediff.Line `json:"title"`
+    Content      []linediff.Line `json:"content"`
+}
+
+// ListFilter narrows GET /discussions. Zero values match everything.
+type ListFilter struct {
+    UserID   int    // author
+    Tag      string // tag name, lower-case
+    Language string // ISO 639-1 code
+}
+
+// CommentStats is GET /discussions/:id/stats: the discussion's comments per
+// interval over [From, To). Buckets have no gaps; Total is their sum.
+type CommentStats struct {
+    DiscussionID int                 `json:"discussion_id"`
+    Interval     string              `json:"interval"`
+    From         time.Time           `json:"from"`
+    To           time.Time           `json:"to"`
+    Total        int                 `json:"total"`
+    Buckets      []tagpkg.TrendPoint `json:"buckets"`
+}
+
+// ValidStatus reports whether s is one of the discussion statuses.
+func ValidStatus(s string) bool {
+    switch s {
+    case models.StatusDraft, models.StatusPublished, models.StatusArchived:
+        return true
+    }
+    return false
+}
+
 // CreateDiscussionDTO for POST /discussions
 type CreateDiscussionDTO struct {
     Title       string     `json:"title"`
     Content     string     `json:"content"`
     ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+    Status      string     `json:"status,omitempty"` // draft or published; defaults to published
+    Language    string     `json:"language,omitempty"` // ISO 639-1 code; checked against DISCUSSION_LANGUAGES by the service
 }
 
 func (dto *CreateDiscussionDTO) Validate() error {
     if dto.Title == "" {
         return errors.New("title is required")
     }
+    if dto.Content == "" {
+        return errors.New("content is required")
+    }
+    if dto.Status != "" && dto.Status != models.StatusDraft && dto.Status != models.StatusPublished {
+        return errors.New("status must be draft or published")
+    }
+    return nil
+}
+
+// PreviewDTO for POST /discussions/preview
+type PreviewDTO struct {
+    Content string `json:"content"`
+}
+
+func (dto *PreviewDTO) Validate() error {
     if dto.Content == "" {
         return errors.New("content is required")
     }
@@ -28,12 +114,17 @@ type UpdateDiscussionDTO struct {
     Title       *string    `json:"title,omitempty"`
     Content     *string    `json:"content,omitempty"`
     ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+    Status      *string    `json:"status,omitempty"`
+    Language    *string    `json:"language,omitempty"` // "" clears it
 }
 
 func (dto *UpdateDiscussionDTO) Validate() error {
-    if dto.Title == nil && dto.Content == nil && dto.ScheduledAt == nil {
+    if dto.Title == nil && dto.Content == nil && dto.ScheduledAt == nil && dto.Status == nil && dto.Language == nil {
         return errors.New("at least one field must be provided")
     }
+    if dto.Status != nil && !ValidStatus(*dto.Status) {
+        return errors.New("status must be draft, published or archived")
+    }
     return nil
 }
 
@@ -49,6 +140,18 @@ func (dto *AddTagsDTO) Validate() error {
     return nil
 }
 
+// ReplaceTagsDTO for PUT /discussions/:id/tags
+type ReplaceTagsDTO struct {
+    Tags []string `json:"tags"` // the exact set of tag names the discussion should end up with
+}
+
+func (dto *ReplaceTagsDTO) Validate() error {
+    if dto.Tags == nil {
+        return errors.New("tags list is required")
+    }
+    return nil
+}
+
 // ScheduleDTO for POST /discussions/schedule
 type ScheduleDTO struct {
     Title       string    `json:"title"`
@@ -58,13 +161,13 @@ type ScheduleDTO struct {
 
 func (dto *ScheduleDTO) Validate() error {
     if dto.Title == "" {
-        return errors.New("title is required")
+        return &FieldError{Field: "title", Reason: "is required"}
     }
     if dto.Content == "" {
-        return errors.New("content is required")
+        return &FieldError{Field: "content", Reason: "is required"}
     }
     if dto.ScheduledAt.IsZero() {
-        return errors.New("scheduled_at is required")
+        return &FieldError{Field: "scheduled_at", Reason: "is required"}
     }
     return nil
 }
@@ -0,0 +1,65 @@
+// fields.go
+package discussion
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+)
+
+// listFields are the keys ?fields= may pick from a listed discussion.
+var listFields = []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+
+// detailFields are the keys ?fields= may pick from GET /discussions/:id,
+// which also carries the discussion's tags.
+var detailFields = append(append([]string{}, listFields...), "tags", "tag_count")
+
+// parseFields splits a ?fields= value and checks every name against
+// allowed. An empty value returns nil, meaning every field.
+func parseFields(raw string, allowed []string) ([]string, error) {
+    if strings.TrimSpace(raw) == "" {
+        return nil, nil
+    }
+    var fields []string
+    for _, f := range strings.Split(raw, ",") {
+        f = strings.TrimSpace(f)
+        if !containsField(allowed, f) {
+            return nil, fmt.Errorf("fields must be a comma-separated subset of %s", strings.Join(allowed, ", "))
+        }
+        fields = append(fields, f)
+    }
+    return fields, nil
+}
+
+func containsField(fields []string, f string) bool {
+    for _, v := range fields {
+        if v == f {
+            return true
+        }
+    }
+    return false
+}
+
+// selectFields returns v's JSON object cut down to fields, or v itself when
+// fields is nil. It filters the encoded value rather than the query, so the
+// allowlist only has to track the JSON tags.
+func selectFields(v interface{}, fields []string) (interface{}, error) {
+    if fields == nil {
+        return v, nil
+    }
+    b, err := json.Marshal(v)
+    if err != nil {
+        return nil, err
+    }
+    var all map[string]json.RawMessage
+    if err := json.Unmarshal(b, &all); err != nil {
+        return nil, err
+    }
+    out := make(map[string]json.RawMessage, len(fields))
+    for _, f := range fields {
+        if raw, ok := all[f]; ok {
+            out[f] = raw
+        }
+    }
+    return out, nil
+}
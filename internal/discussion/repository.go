@@ -4,48 +4,114 @@ package discussion
 import (
     "context"
     "database/sql"
-    
+    "errors"
+    "strings"
     "time"
 
+    "github.com/lib/pq"
+    "go-discussion-app/config"
+    tagpkg "go-discussion-app/internal/tag"
     "go-discussion-app/models"
+    "go-discussion-app/pkg/slowquery"
 )
 
 type Repository interface {
     Create(ctx context.Context, d *models.Discussion) (int, error)
     GetAll(ctx context.Context) ([]models.Discussion, error)
+    ListByStatus(ctx context.Context, status string, f ListFilter, sort string, limit, offset int) ([]models.Discussion, error)
+    CountByStatus(ctx context.Context, status string, f ListFilter) (int, error)
     GetByID(ctx context.Context, id int) (*models.Discussion, error)
+    GetByIDWithTags(ctx context.Context, id int) (*models.Discussion, []string, error)
     Update(ctx context.Context, d *models.Discussion) error
     Delete(ctx context.Context, id int) error
+    Restore(ctx context.Context, id int) (bool, error)
+    Touch(ctx context.Context, id int, at time.Time) error
+    IncrementViewCount(ctx context.Context, id int) error
+    ArchiveStale(ctx context.Context, before, at time.Time) ([]int, error)
 
-    GetByUser(ctx context.Context, userID int) ([]models.Discussion, error)
-    GetByTag(ctx context.Context, tag string) ([]models.Discussion, error)
-    AddTags(ctx context.Context, discussionID int, tagIDs []int) error
+    GetByUser(ctx context.Context, userID, viewerID int) ([]models.Discussion, error)
+    ListRecentByUser(ctx context.Context, userID, limit int) ([]models.Discussion, error)
+    GetByTag(ctx context.Context, tag string, viewerID int) ([]models.Discussion, error)
+    ListByTags(ctx context.Context, names []string, perTag int) ([]TagGroup, error)
+    AddTags(ctx context.Context, discussionID int, tagIDs []int, maxSubsPerEmail int) error
+    ReplaceTags(ctx context.Context, discussionID int, tagIDs []int, maxSubsPerEmail int) error
+    ListTagIDs(ctx context.Context, discussionID int) ([]int, error)
+    PinInTag(ctx context.Context, discussionID int, tag string) (bool, error)
+    UnpinInTag(ctx context.Context, discussionID int, tag string) (bool, error)
+    CountByTagID(ctx context.Context, tagID int) (int, error)
+    CountCommentsByInterval(ctx context.Context, discussionID int, interval string, from, to time.Time) ([]tagpkg.TrendPoint, error)
+    CountByUserSince(ctx context.Context, userID int, since time.Time) (int, time.Time, error)
+    LatestUpdate(ctx context.Context) (time.Time, error)
+    ListActiveSince(ctx context.Context, since time.Time, viewerID int) ([]models.Discussion, error)
+    FindByTitleLike(ctx context.Context, pattern string, since time.Time, viewerID, limit int) ([]models.Discussion, error)
+    ListUpdatedSince(ctx context.Context, since time.Time) ([]models.Discussion, error)
+    Search(ctx context.Context, query string, limit, offset int) ([]models.Discussion, error)
+    CountSearch(ctx context.Context, query string) (int, error)
+    GetLastSeen(ctx context.Context, userID int) (time.Time, error)
+    SetLastSeen(ctx context.Context, userID int, at time.Time) error
+
+    UpdateWithRevision(ctx context.Context, d *models.Discussion, rev *models.DiscussionRevision) error
+    ListRevisionsByEditor(ctx context.Context, editorID, limit, offset int) ([]models.DiscussionRevision, error)
+    GetRevision(ctx context.Context, discussionID, revID int) (*models.DiscussionRevision, error)
+}
+
+// ErrEmptyField is returned by writes when title or content is blank.
+// DTO validation should catch this first; the repository checks again so
+// no code path can store an empty discussion.
+var ErrEmptyField = errors.New("discussion title and content must not be empty")
+
+func checkRequiredFields(d *models.Discussion) error {
+    if strings.TrimSpace(d.Title) == "" || strings.TrimSpace(d.Content) == "" {
+        return ErrEmptyField
+    }
+    return nil
+}
+
+// nullTime converts an optional time into a value the driver always
+// writes as either a timestamp or NULL.
+func nullTime(t *time.Time) sql.NullTime {
+    if t == nil {
+        return sql.NullTime{}
+    }
+    return sql.NullTime{Time: *t, Valid: true}
+}
+
+// statusOrDefault treats an unset status as published, matching the column default.
+func statusOrDefault(status string) string {
+    if status == "" {
+        return models.StatusPublished
+    }
+    return status
 }
 
 type repo struct {
-    db *sql.DB
+    db *slowquery.DB
 }
 
 func NewRepository(db *sql.DB) Repository {
-    return &repo{db: db}
+    return &repo{db: slowquery.Wrap(db)}
 }
 
 func (r *repo) Create(ctx context.Context, d *models.Discussion) (int, error) {
+    if err := checkRequiredFields(d); err != nil {
+        return 0, err
+    }
     const q = `
-      INSERT INTO discussions (user_id, title, content, scheduled_at, created_at, updated_at)
-      VALUES ($1,$2,$3,$4,$5,$6) RETURNING id;
+      INSERT INTO discussions (user_id, title, content, scheduled_at, status, created_at, updated_at, language)
+      VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING id;
     `
     var id int
     err := r.db.QueryRowContext(ctx, q,
-        d.UserID, d.Title, d.Content, d.ScheduledAt, d.CreatedAt, d.UpdatedAt,
+        d.UserID, d.Title, d.Content, nullTime(d.ScheduledAt), statusOrDefault(d.Status), d.CreatedAt, d.UpdatedAt, d.Language,
     ).Scan(&id)
     return id, err
 }
 
 func (r *repo) GetAll(ctx context.Context) ([]models.Discussion, error) {
     const q = `
-      SELECT id, user_id, title, content, scheduled_at, created_at, updated_at
+      SELECT id, user_id, title, content, scheduled_at, status, created_at, updated_at, view_count, language, edited_at
       FROM discussions
+      WHERE deleted_at IS NULL
       ORDER BY created_at DESC;
     `
     rows, err := r.db.QueryContext(ctx, q)
@@ -57,7 +123,77 @@ func (r *repo) GetAll(ctx context.Context) ([]models.Discussion, error) {
     var ds []models.Discussion
     for rows.Next() {
         var d models.Discussion
-        if err := rows.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.CreatedAt, &d.UpdatedAt); err != nil {
+        if err := rows.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.ViewCount, &d.Language, &d.EditedAt); err != nil {
+            return nil, err
+        }
+        ds = append(ds, d)
+    }
+    return ds, rows.Err()
+}
+
+const (
+    sortByNewest        = "created_at DESC, id DESC"
+    sortByMostCommented = "(SELECT COUNT(*) FROM comments c WHERE c.discussion_id = discussions.id AND c.deleted_at IS NULL) DESC, created_at DESC, id DESC"
+)
+
+// discussionSortClauses maps config.DiscussionSort* values to ORDER BY
+// clauses; anything else sorts as "newest".
+var discussionSortClauses = map[string]string{
+    config.DiscussionSortNewest:        sortByNewest,
+    config.DiscussionSortRecent:        sortByNewest,
+    config.DiscussionSortOldest:        "created_at ASC, id ASC",
+    config.DiscussionSortMostCommented: sortByMostCommented,
+    config.DiscussionSortPopular:       sortByMostCommented,
+    config.DiscussionSortMostViewed:    "view_count DESC, created_at DESC, id DESC",
+    config.DiscussionSortSubscribers:   "COALESCE(sc.subscribers, 0) DESC, created_at DESC, id DESC",
+}
+
+// discussionSortJoins adds what a sort's ORDER BY needs beyond the
+// discussions table. The subscriber count is aggregated once and joined,
+// rather than counted per row, and only confirmed subscriptions count.
+var discussionSortJoins = map[string]string{
+    config.DiscussionSortSubscribers: `
+      LEFT JOIN (
+        SELECT discussion_id, COUNT(*) AS subscribers
+        FROM subscriptions WHERE confirmed
+        GROUP BY discussion_id
+      ) sc ON sc.discussion_id = discussions.id`,
+}
+
+// listFilterClause restricts ListByStatus and CountByStatus to f, with the
+// author in $2, the lower-cased tag name in $3 and the language in $4; zero
+// values match everything.
+const listFilterClause = `
+        AND ($2 = 0 OR user_id = $2)
+        AND ($3 = '' OR EXISTS (
+          SELECT 1 FROM discussion_tags dt JOIN tags t ON t.id = dt.tag_id
+          WHERE dt.discussion_id = discussions.id AND lower(t.name) = $3))
+        AND ($4 = '' OR language = $4)`
+
+// ListByStatus returns one page of discussions in the given status that
+// match f, ordered by sort (one of the config.DiscussionSort* values).
+func (r *repo) ListByStatus(ctx context.Context, status string, f ListFilter, sort string, limit, offset int) ([]models.Discussion, error) {
+    orderBy, ok := discussionSortClauses[sort]
+    if !ok {
+        orderBy = sortByNewest
+    }
+    q := `
+      SELECT id, user_id, title, content, scheduled_at, status, created_at, updated_at, view_count, language, edited_at
+      FROM discussions` + discussionSortJoins[sort] + `
+      WHERE status = $1 AND deleted_at IS NULL` + listFilterClause + `
+      ORDER BY ` + orderBy + `
+      LIMIT $5 OFFSET $6;
+    `
+    rows, err := r.db.QueryContext(ctx, q, status, f.UserID, f.Tag, f.Language, limit, offset)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var ds []models.Discussion
+    for rows.Next() {
+        var d models.Discussion
+        if err := rows.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.ViewCount, &d.Language, &d.EditedAt); err != nil {
             return nil, err
         }
         ds = append(ds, d)
@@ -65,14 +201,25 @@ func (r *repo) GetAll(ctx context.Context) ([]models.Discussion, error) {
     return ds, rows.Err()
 }
 
+// CountByStatus counts the discussions ListByStatus pages through.
+func (r *repo) CountByStatus(ctx context.Context, status string, f ListFilter) (int, error) {
+    const q = `
+      SELECT COUNT(*) FROM discussions
+      WHERE status = $1 AND deleted_at IS NULL` + listFilterClause + `;
+    `
+    var n int
+    err := r.db.QueryRowContext(ctx, q, status, f.UserID, f.Tag, f.Language).Scan(&n)
+    return n, err
+}
+
 func (r *repo) GetByID(ctx context.Context, id int) (*models.Discussion, error) {
     const q = `
-      SELECT id, user_id, title, content, scheduled_at, created_at, updated_at
-      FROM discussions WHERE id=$1;
+      SELECT id, user_id, title, content, scheduled_at, status, created_at, updated_at, view_count, language, edited_at
+      FROM discussions WHERE id=$1 AND deleted_at IS NULL;
     `
     row := r.db.QueryRowContext(ctx, q, id)
     var d models.Discussion
-    if err := row.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.CreatedAt, &d.UpdatedAt); err != nil {
+    if err := row.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.ViewCount, &d.Language, &d.EditedAt); err != nil {
         if err == sql.ErrNoRows {
             return nil, nil
         }
@@ -81,29 +228,127 @@ func (r *repo) GetByID(ctx context.Context, id int) (*models.Discussion, error)
     return &d, nil
 }
 
+// GetByIDWithTags loads a discussion together with its tag names, sorted,
+// in one query. A discussion without tags gets an empty slice.
+func (r *repo) GetByIDWithTags(ctx context.Context, id int) (*models.Discussion, []string, error) {
+    const q = `
+      SELECT d.id, d.user_id, d.title, d.content, d.scheduled_at, d.status, d.created_at, d.updated_at, d.view_count, d.language, d.edited_at,
+             COALESCE(array_agg(t.name ORDER BY t.name) FILTER (WHERE t.id IS NOT NULL), '{}')
+      FROM discussions d
+      LEFT JOIN discussion_tags dt ON dt.discussion_id = d.id
+      LEFT JOIN tags t ON t.id = dt.tag_id
+      WHERE d.id = $1 AND d.deleted_at IS NULL
+      GROUP BY d.id;
+    `
+    var d models.Discussion
+    var tags pq.StringArray
+    err := r.db.QueryRowContext(ctx, q, id).
+        Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.ViewCount, &d.Language, &d.EditedAt, &tags)
+    if err == sql.ErrNoRows {
+        return nil, nil, nil
+    }
+    if err != nil {
+        return nil, nil, err
+    }
+    return &d, []string(tags), nil
+}
+
 func (r *repo) Update(ctx context.Context, d *models.Discussion) error {
+    if err := checkRequiredFields(d); err != nil {
+        return err
+    }
     const q = `
       UPDATE discussions
-      SET title=$1, content=$2, scheduled_at=$3, updated_at=$4
-      WHERE id=$5;
+      SET title=$1, content=$2, scheduled_at=$3, status=$4, updated_at=$5, language=$6, edited_at=$7
+      WHERE id=$8;
     `
     _, err := r.db.ExecContext(ctx, q,
-        d.Title, d.Content, d.ScheduledAt, time.Now().UTC(), d.ID,
+        d.Title, d.Content, nullTime(d.ScheduledAt), statusOrDefault(d.Status), time.Now().UTC(), d.Language, nullTime(d.EditedAt), d.ID,
     )
     return err
 }
 
+// Delete soft-deletes a discussion by stamping deleted_at. The row, its
+// comments and tags stay in place so Restore can bring it back; every read
+// in this file skips rows with deleted_at set. updated_at is stamped too, so
+// LatestUpdate moves and cached listings are not served with it still in.
 func (r *repo) Delete(ctx context.Context, id int) error {
-    _, err := r.db.ExecContext(ctx, `DELETE FROM discussions WHERE id=$1`, id)
+    _, err := r.db.ExecContext(ctx,
+        `UPDATE discussions SET deleted_at=$1, updated_at=$1 WHERE id=$2 AND deleted_at IS NULL`, time.Now().UTC(), id)
     return err
 }
 
-func (r *repo) GetByUser(ctx context.Context, userID int) ([]models.Discussion, error) {
+// Restore clears deleted_at and, like Delete, stamps updated_at. It reports
+// false when no discussion has that id; restoring one that is not deleted is
+// a no-op that still reports true.
+func (r *repo) Restore(ctx context.Context, id int) (bool, error) {
+    res, err := r.db.ExecContext(ctx, `
+      UPDATE discussions
+      SET updated_at = CASE WHEN deleted_at IS NULL THEN updated_at ELSE $1 END, deleted_at = NULL
+      WHERE id = $2;
+    `, time.Now().UTC(), id)
+    if err != nil {
+        return false, err
+    }
+    n, err := res.RowsAffected()
+    return n > 0, err
+}
+
+// Touch sets updated_at without changing anything else.
+func (r *repo) Touch(ctx context.Context, id int, at time.Time) error {
+    _, err := r.db.ExecContext(ctx, `UPDATE discussions SET updated_at=$1 WHERE id=$2`, at, id)
+    return err
+}
+
+// IncrementViewCount adds one view in a single UPDATE, so concurrent readers
+// never lose a count. updated_at is left alone.
+func (r *repo) IncrementViewCount(ctx context.Context, id int) error {
+    _, err := r.db.ExecContext(ctx,
+        `UPDATE discussions SET view_count = view_count + 1 WHERE id=$1 AND deleted_at IS NULL`, id)
+    return err
+}
+
+// ArchiveStale archives every published discussion that has been neither
+// updated nor commented on since before, stamping updated_at with at, and
+// returns the archived ids. Selection and update are one statement, so a
+// comment arriving mid-sweep either keeps its discussion open or is too late.
+func (r *repo) ArchiveStale(ctx context.Context, before, at time.Time) ([]int, error) {
+    const q = `
+      UPDATE discussions
+      SET status = $1, updated_at = $2
+      WHERE status = $3 AND updated_at < $4 AND deleted_at IS NULL
+        AND NOT EXISTS (
+          SELECT 1 FROM comments c
+          WHERE c.discussion_id = discussions.id AND c.created_at >= $4 AND c.deleted_at IS NULL
+        )
+      RETURNING id;
+    `
+    rows, err := r.db.QueryContext(ctx, q, models.StatusArchived, at, models.StatusPublished, before)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var ids []int
+    for rows.Next() {
+        var id int
+        if err := rows.Scan(&id); err != nil {
+            return nil, err
+        }
+        ids = append(ids, id)
+    }
+    return ids, rows.Err()
+}
+
+// GetByUser lists userID's discussions, newest first. Drafts are included
+// only when viewerID is their author.
+func (r *repo) GetByUser(ctx context.Context, userID, viewerID int) ([]models.Discussion, error) {
     const q = `
-      SELECT id, user_id, title, content, scheduled_at, created_at, updated_at
-      FROM discussions WHERE user_id=$1 ORDER BY created_at DESC;
+      SELECT id, user_id, title, content, scheduled_at, status, created_at, updated_at, view_count, language, edited_at
+      FROM discussions WHERE user_id=$1 AND deleted_at IS NULL AND (status <> 'draft' OR user_id = $2)
+      ORDER BY created_at DESC;
     `
-    rows, err := r.db.QueryContext(ctx, q, userID)
+    rows, err := r.db.QueryContext(ctx, q, userID, viewerID)
     if err != nil {
         return nil, err
     }
@@ -112,7 +357,7 @@ func (r *repo) GetByUser(ctx context.Context, userID int) ([]models.Discussion,
     var ds []models.Discussion
     for rows.Next() {
         var d models.Discussion
-        if err := rows.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.CreatedAt, &d.UpdatedAt); err != nil {
+        if err := rows.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.ViewCount, &d.Language, &d.EditedAt); err != nil {
             return nil, err
         }
         ds = append(ds, d)
@@ -120,16 +365,46 @@ func (r *repo) GetByUser(ctx context.Context, userID int) ([]models.Discussion,
     return ds, rows.Err()
 }
 
-func (r *repo) GetByTag(ctx context.Context, tag string) ([]models.Discussion, error) {
+// ListRecentByUser returns userID's newest limit discussions, drafts included.
+func (r *repo) ListRecentByUser(ctx context.Context, userID, limit int) ([]models.Discussion, error) {
     const q = `
-      SELECT d.id, d.user_id, d.title, d.content, d.scheduled_at, d.created_at, d.updated_at
+      SELECT id, user_id, title, content, scheduled_at, status, created_at, updated_at, view_count, language, edited_at
+      FROM discussions WHERE user_id=$1 AND deleted_at IS NULL
+      ORDER BY created_at DESC, id DESC
+      LIMIT $2;
+    `
+    rows, err := r.db.QueryContext(ctx, q, userID, limit)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var ds []models.Discussion
+    for rows.Next() {
+        var d models.Discussion
+        if err := rows.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.ViewCount, &d.Language, &d.EditedAt); err != nil {
+            return nil, err
+        }
+        ds = append(ds, d)
+    }
+    return ds, rows.Err()
+}
+
+// GetByTag lists the discussions carrying tag, newest first, except that
+// those pinned within the tag come before the rest, most recently pinned
+// first. Pins in other tags do not affect the order. Drafts are included
+// only for their author, viewerID. tag must be lower-case.
+func (r *repo) GetByTag(ctx context.Context, tag string, viewerID int) ([]models.Discussion, error) {
+    const q = `
+      SELECT d.id, d.user_id, d.title, d.content, d.scheduled_at, d.status, d.created_at, d.updated_at, d.view_count, d.language, d.edited_at
       FROM discussions d
       JOIN discussion_tags dt ON d.id = dt.discussion_id
       JOIN tags t ON dt.tag_id = t.id
-      WHERE t.name = $1
-      ORDER BY d.created_at DESC;
+      LEFT JOIN pinned_tags pt ON pt.tag_id = dt.tag_id AND pt.discussion_id = dt.discussion_id
+      WHERE lower(t.name) = $1 AND d.deleted_at IS NULL AND (d.status <> 'draft' OR d.user_id = $2)
+      ORDER BY pt.pinned_at IS NULL, pt.pinned_at DESC, d.created_at DESC;
     `
-    rows, err := r.db.QueryContext(ctx, q, tag)
+    rows, err := r.db.QueryContext(ctx, q, tag, viewerID)
     if err != nil {
         return nil, err
     }
@@ -138,7 +413,7 @@ func (r *repo) GetByTag(ctx context.Context, tag string) ([]models.Discussion, e
     var ds []models.Discussion
     for rows.Next() {
         var d models.Discussion
-        if err := rows.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.CreatedAt, &d.UpdatedAt); err != nil {
+        if err := rows.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.ViewCount, &d.Language, &d.EditedAt); err != nil {
             return nil, err
         }
         ds = append(ds, d)
@@ -146,7 +421,218 @@ func (r *repo) GetByTag(ctx context.Context, tag string) ([]models.Discussion, e
     return ds, rows.Err()
 }
 
-func (r *repo) AddTags(ctx context.Context, discussionID int, tagIDs []int) error {
+// ListByTags groups published discussions by tag for the given (lower-case)
+// tag names in one query: the window functions count each tag's discussions
+// and keep only its perTag newest. Groups come back ordered by tag name;
+// tags without published discussions are omitted.
+func (r *repo) ListByTags(ctx context.Context, names []string, perTag int) ([]TagGroup, error) {
+    const q = `
+      SELECT tag, total, id, user_id, title, content, scheduled_at, status, created_at, updated_at, view_count, language, edited_at
+      FROM (
+        SELECT lower(t.name) AS tag,
+               COUNT(*) OVER (PARTITION BY t.id) AS total,
+               ROW_NUMBER() OVER (PARTITION BY t.id ORDER BY d.created_at DESC, d.id DESC) AS rn,
+               d.id, d.user_id, d.title, d.content, d.scheduled_at, d.status, d.created_at, d.updated_at, d.view_count, d.language, d.edited_at
+        FROM tags t
+        JOIN discussion_tags dt ON dt.tag_id = t.id
+        JOIN discussions d ON d.id = dt.discussion_id
+        WHERE lower(t.name) = ANY($1) AND d.status = $2 AND d.deleted_at IS NULL
+      ) ranked
+      WHERE rn <= $3
+      ORDER BY tag, rn;
+    `
+    rows, err := r.db.QueryContext(ctx, q, pq.Array(names), models.StatusPublished, perTag)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var groups []TagGroup
+    for rows.Next() {
+        var (
+            name  string
+            total int
+            d     models.Discussion
+        )
+        if err := rows.Scan(&name, &total, &d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.ViewCount, &d.Language, &d.EditedAt); err != nil {
+            return nil, err
+        }
+        if n := len(groups); n == 0 || groups[n-1].Tag != name {
+            groups = append(groups, TagGroup{Tag: name, Count: total})
+        }
+        g := &groups[len(groups)-1]
+        g.Discussions = append(g.Discussions, d)
+    }
+    return groups, rows.Err()
+}
+
+// LatestUpdate returns the newest updated_at across all discussions, or the
+// zero time when there are none. Served by a single aggregate, no rows are
+// transferred. Soft-deleted rows count, since Delete and Restore stamp
+// updated_at, and so do tag and pin changes.
+func (r *repo) LatestUpdate(ctx context.Context) (time.Time, error) {
+    var t sql.NullTime
+    err := r.db.QueryRowContext(ctx, `SELECT MAX(updated_at) FROM discussions;`).Scan(&t)
+    if err != nil {
+        return time.Time{}, err
+    }
+    return t.Time, nil
+}
+
+// ListActiveSince returns discussions that received at least one comment at
+// or after since, most recently commented first, hiding drafts not written
+// by viewerID.
+func (r *repo) ListActiveSince(ctx context.Context, since time.Time, viewerID int) ([]models.Discussion, error) {
+    const q = `
+      SELECT d.id, d.user_id, d.title, d.content, d.scheduled_at, d.status, d.created_at, d.updated_at, d.view_count, d.language, d.edited_at
+      FROM discussions d
+      JOIN (
+        SELECT discussion_id, MAX(created_at) AS last_comment_at
+        FROM comments
+        WHERE created_at >= $1 AND deleted_at IS NULL
+        GROUP BY discussion_id
+      ) c ON c.discussion_id = d.id
+      WHERE d.deleted_at IS NULL AND (d.status <> 'draft' OR d.user_id = $2)
+      ORDER BY c.last_comment_at DESC, d.id DESC;
+    `
+    rows, err := r.db.QueryContext(ctx, q, since, viewerID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var ds []models.Discussion
+    for rows.Next() {
+        var d models.Discussion
+        if err := rows.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.ViewCount, &d.Language, &d.EditedAt); err != nil {
+            return nil, err
+        }
+        ds = append(ds, d)
+    }
+    return ds, rows.Err()
+}
+
+// ListUpdatedSince returns published discussions created or updated after
+// since, most recently updated first.
+func (r *repo) ListUpdatedSince(ctx context.Context, since time.Time) ([]models.Discussion, error) {
+    const q = `
+      SELECT id, user_id, title, content, scheduled_at, status, created_at, updated_at, view_count, language, edited_at
+      FROM discussions
+      WHERE status = $1 AND updated_at > $2 AND deleted_at IS NULL
+      ORDER BY updated_at DESC, id DESC;
+    `
+    rows, err := r.db.QueryContext(ctx, q, models.StatusPublished, since)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var ds []models.Discussion
+    for rows.Next() {
+        var d models.Discussion
+        if err := rows.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.ViewCount, &d.Language, &d.EditedAt); err != nil {
+            return nil, err
+        }
+        ds = append(ds, d)
+    }
+    return ds, rows.Err()
+}
+
+// GetLastSeen returns the user's last-seen marker, or the zero time when
+// they have never set one.
+func (r *repo) GetLastSeen(ctx context.Context, userID int) (time.Time, error) {
+    var t time.Time
+    err := r.db.QueryRowContext(ctx,
+        `SELECT seen_at FROM user_last_seen WHERE user_id = $1;`, userID).Scan(&t)
+    if err == sql.ErrNoRows {
+        return time.Time{}, nil
+    }
+    return t, err
+}
+
+// SetLastSeen records at as the user's last-seen marker.
+func (r *repo) SetLastSeen(ctx context.Context, userID int, at time.Time) error {
+    _, err := r.db.ExecContext(ctx, `
+      INSERT INTO user_last_seen (user_id, seen_at) VALUES ($1, $2)
+      ON CONFLICT (user_id) DO UPDATE SET seen_at = EXCLUDED.seen_at;
+    `, userID, at)
+    return err
+}
+
+// FindByTitleLike returns up to limit discussions created at or after since
+// whose title matches the ILIKE pattern, newest first, hiding drafts not
+// written by viewerID.
+func (r *repo) FindByTitleLike(ctx context.Context, pattern string, since time.Time, viewerID, limit int) ([]models.Discussion, error) {
+    const q = `
+      SELECT id, user_id, title, content, scheduled_at, status, created_at, updated_at, view_count, language, edited_at
+      FROM discussions
+      WHERE title ILIKE $1 AND created_at >= $2 AND deleted_at IS NULL AND (status <> 'draft' OR user_id = $3)
+      ORDER BY created_at DESC
+      LIMIT $4;
+    `
+    rows, err := r.db.QueryContext(ctx, q, pattern, since, viewerID, limit)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var ds []models.Discussion
+    for rows.Next() {
+        var d models.Discussion
+        if err := rows.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.ViewCount, &d.Language, &d.EditedAt); err != nil {
+            return nil, err
+        }
+        ds = append(ds, d)
+    }
+    return ds, rows.Err()
+}
+
+// searchDocument is the text search vector over a discussion. It must match
+// the expression indexed by idx_discussions_search for the index to be used.
+const searchDocument = `to_tsvector('english', title || ' ' || content)`
+
+// Search runs a full-text query over published titles and content, best
+// match first. query is plain text; plainto_tsquery handles the parsing.
+func (r *repo) Search(ctx context.Context, query string, limit, offset int) ([]models.Discussion, error) {
+    q := `
+      SELECT id, user_id, title, content, scheduled_at, status, created_at, updated_at, view_count, language, edited_at
+      FROM discussions
+      WHERE status = $1 AND deleted_at IS NULL AND ` + searchDocument + ` @@ plainto_tsquery('english', $2)
+      ORDER BY ts_rank(` + searchDocument + `, plainto_tsquery('english', $2)) DESC, created_at DESC, id DESC
+      LIMIT $3 OFFSET $4;
+    `
+    rows, err := r.db.QueryContext(ctx, q, models.StatusPublished, query, limit, offset)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var ds []models.Discussion
+    for rows.Next() {
+        var d models.Discussion
+        if err := rows.Scan(&d.ID, &d.UserID, &d.Title, &d.Content, &d.ScheduledAt, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.ViewCount, &d.Language, &d.EditedAt); err != nil {
+            return nil, err
+        }
+        ds = append(ds, d)
+    }
+    return ds, rows.Err()
+}
+
+// CountSearch counts every discussion Search would match.
+func (r *repo) CountSearch(ctx context.Context, query string) (int, error) {
+    q := `
+      SELECT COUNT(*) FROM discussions
+      WHERE status = $1 AND deleted_at IS NULL AND ` + searchDocument + ` @@ plainto_tsquery('english', $2);
+    `
+    var n int
+    err := r.db.QueryRowContext(ctx, q, models.StatusPublished, query).Scan(&n)
+    return n, err
+}
+
+// AddTags attaches tagIDs to the discussion and subscribes the followers of
+// the ones it did not have yet (see subscribeTagFollowers). If any was new,
+// the discussion's updated_at is stamped so conditional GETs see the change.
+func (r *repo) AddTags(ctx context.Context, discussionID int, tagIDs []int, maxSubsPerEmail int) error {
     tx, err := r.db.BeginTx(ctx, nil)
     if err != nil {
         return err
@@ -161,11 +647,346 @@ func (r *repo) AddTags(ctx context.Context, discussionID int, tagIDs []int) erro
     }
     defer stmt.Close()
 
+    var added []int
     for _, tagID := range tagIDs {
-        if _, err := stmt.ExecContext(ctx, discussionID, tagID); err != nil {
+        res, err := stmt.ExecContext(ctx, discussionID, tagID)
+        if err != nil {
+            tx.Rollback()
+            return err
+        }
+        if n, _ := res.RowsAffected(); n > 0 {
+            added = append(added, tagID)
+        }
+    }
+    if err := subscribeTagFollowers(ctx, tx, discussionID, added, maxSubsPerEmail); err != nil {
+        tx.Rollback()
+        return err
+    }
+    if len(added) > 0 {
+        if err := touchTx(ctx, tx, discussionID); err != nil {
+            tx.Rollback()
+            return err
+        }
+    }
+    return tx.Commit()
+}
+
+// touchTx stamps the discussion's updated_at inside tx, for writes to its
+// tags and pins that LatestUpdate would otherwise miss.
+func touchTx(ctx context.Context, tx *sql.Tx, discussionID int) error {
+    _, err := tx.ExecContext(ctx, `UPDATE discussions SET updated_at=$1 WHERE id=$2`, time.Now().UTC(), discussionID)
+    return err
+}
+
+// subscribeTagFollowers subscribes everyone following one of tagIDs to the
+// discussion they were just attached to, so its notifications reach them
+// like any other subscriber's. Only newly attached tags are passed in: an
+// email that unsubscribed from the discussion stays unsubscribed until one
+// of the tags is attached again. As with a direct subscribe, suppressed
+// emails are skipped, and so are emails already subscribed to
+// maxSubsPerEmail other discussions (0 disables the cap). Drafts are private,
+// so tagging one subscribes nobody.
+func subscribeTagFollowers(ctx context.Context, tx *sql.Tx, discussionID int, tagIDs []int, maxSubsPerEmail int) error {
+    if len(tagIDs) == 0 {
+        return nil
+    }
+    ids := make([]int64, len(tagIDs))
+    for i, id := range tagIDs {
+        ids[i] = int64(id)
+    }
+    _, err := tx.ExecContext(ctx, `
+      INSERT INTO subscriptions (discussion_id, user_id, email)
+      SELECT $1, ts.user_id, ts.email FROM tag_subscriptions ts
+      WHERE ts.tag_id = ANY($2)
+        AND EXISTS (SELECT 1 FROM discussions d WHERE d.id = $1 AND d.status <> $4)
+        AND NOT EXISTS (SELECT 1 FROM suppressed_emails se WHERE se.email = lower(ts.email))
+        AND ($3 = 0 OR (SELECT COUNT(*) FROM subscriptions s WHERE lower(s.email) = lower(ts.email) AND s.discussion_id <> $1) < $3)
+      ON CONFLICT (discussion_id, email) DO NOTHING;
+    `, discussionID, pq.Array(ids), maxSubsPerEmail, models.StatusDraft)
+    return err
+}
+
+// PinInTag pins the discussion at the top of tag's listing and stamps its
+// updated_at. It reports false when the discussion does not exist or does
+// not carry tag (lower-case). Pinning it again keeps the original pinned_at.
+func (r *repo) PinInTag(ctx context.Context, discussionID int, tag string) (bool, error) {
+    const q = `
+      WITH pinned AS (
+        INSERT INTO pinned_tags (discussion_id, tag_id)
+        SELECT dt.discussion_id, dt.tag_id
+        FROM discussion_tags dt
+        JOIN tags t ON t.id = dt.tag_id
+        JOIN discussions d ON d.id = dt.discussion_id
+        WHERE dt.discussion_id = $1 AND lower(t.name) = $2 AND d.deleted_at IS NULL
+        ON CONFLICT (tag_id, discussion_id) DO UPDATE SET pinned_at = pinned_tags.pinned_at
+        RETURNING discussion_id
+      )
+      UPDATE discussions SET updated_at = $3 WHERE id IN (SELECT discussion_id FROM pinned);
+    `
+    res, err := r.db.ExecContext(ctx, q, discussionID, tag, time.Now().UTC())
+    if err != nil {
+        return false, err
+    }
+    n, err := res.RowsAffected()
+    return n > 0, err
+}
+
+// UnpinInTag removes the discussion's pin in tag, stamping its updated_at,
+// and reports whether there was one.
+func (r *repo) UnpinInTag(ctx context.Context, discussionID int, tag string) (bool, error) {
+    const q = `
+      WITH unpinned AS (
+        DELETE FROM pinned_tags pt
+        USING tags t
+        WHERE pt.tag_id = t.id AND pt.discussion_id = $1 AND lower(t.name) = $2
+        RETURNING pt.discussion_id
+      )
+      UPDATE discussions SET updated_at = $3 WHERE id IN (SELECT discussion_id FROM unpinned);
+    `
+    res, err := r.db.ExecContext(ctx, q, discussionID, tag, time.Now().UTC())
+    if err != nil {
+        return false, err
+    }
+    n, err := res.RowsAffected()
+    return n > 0, err
+}
+
+// ListTagIDs returns the ids of the tags attached to discussionID.
+func (r *repo) ListTagIDs(ctx context.Context, discussionID int) ([]int, error) {
+    rows, err := r.db.QueryContext(ctx,
+        `SELECT tag_id FROM discussion_tags WHERE discussion_id = $1;`,
+        discussionID,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var ids []int
+    for rows.Next() {
+        var id int
+        if err := rows.Scan(&id); err != nil {
+            return nil, err
+        }
+        ids = append(ids, id)
+    }
+    return ids, rows.Err()
+}
+
+// CountByTagID returns how many discussions that are not deleted carry tagID.
+func (r *repo) CountByTagID(ctx context.Context, tagID int) (int, error) {
+    const q = `
+      SELECT COUNT(*)
+      FROM discussion_tags dt
+      JOIN discussions d ON d.id = dt.discussion_id
+      WHERE dt.tag_id = $1 AND d.deleted_at IS NULL;
+    `
+    var n int
+    err := r.db.QueryRowContext(ctx, q, tagID).Scan(&n)
+    return n, err
+}
+
+// CountCommentsByInterval counts the discussion's comments, deleted ones
+// aside, per UTC interval (date_trunc's day, week or month) within
+// [from, to). Intervals without comments are left out.
+func (r *repo) CountCommentsByInterval(ctx context.Context, discussionID int, interval string, from, to time.Time) ([]tagpkg.TrendPoint, error) {
+    const q = `
+      SELECT date_trunc($2, created_at AT TIME ZONE 'UTC') AS bucket, COUNT(*)
+      FROM comments
+      WHERE discussion_id = $1 AND created_at >= $3 AND created_at < $4
+        AND deleted_at IS NULL
+      GROUP BY bucket
+      ORDER BY bucket;
+    `
+    rows, err := r.db.QueryContext(ctx, q, discussionID, interval, from, to)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var points []tagpkg.TrendPoint
+    for rows.Next() {
+        var p tagpkg.TrendPoint
+        if err := rows.Scan(&p.Start, &p.Count); err != nil {
+            return nil, err
+        }
+        // the bucket is a timestamp without zone, already in UTC
+        p.Start = time.Date(p.Start.Year(), p.Start.Month(), p.Start.Day(), 0, 0, 0, 0, time.UTC)
+        points = append(points, p)
+    }
+    return points, rows.Err()
+}
+
+// ReplaceTags makes the discussion's tag set match tagIDs exactly.
+// The current set is diffed against the desired one and only the
+// missing/extra rows are inserted/deleted, all inside one transaction.
+// Followers of the added tags are subscribed as in AddTags.
+func (r *repo) ReplaceTags(ctx context.Context, discussionID int, tagIDs []int, maxSubsPerEmail int) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+
+    rows, err := tx.QueryContext(ctx,
+        `SELECT tag_id FROM discussion_tags WHERE discussion_id = $1 FOR UPDATE;`,
+        discussionID,
+    )
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+    current := make(map[int]bool)
+    for rows.Next() {
+        var id int
+        if err := rows.Scan(&id); err != nil {
+            rows.Close()
+            tx.Rollback()
+            return err
+        }
+        current[id] = true
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        tx.Rollback()
+        return err
+    }
+
+    desired := make(map[int]bool, len(tagIDs))
+    var toAdd []int
+    for _, id := range tagIDs {
+        if desired[id] {
+            continue
+        }
+        desired[id] = true
+        if !current[id] {
+            toAdd = append(toAdd, id)
+        }
+    }
+    var toRemove []int64
+    for id := range current {
+        if !desired[id] {
+            toRemove = append(toRemove, int64(id))
+        }
+    }
+
+    if len(toRemove) > 0 {
+        if _, err := tx.ExecContext(ctx,
+            `DELETE FROM discussion_tags WHERE discussion_id = $1 AND tag_id = ANY($2);`,
+            discussionID, pq.Array(toRemove),
+        ); err != nil {
+            tx.Rollback()
+            return err
+        }
+    }
+    for _, tagID := range toAdd {
+        if _, err := tx.ExecContext(ctx, `
+          INSERT INTO discussion_tags (discussion_id, tag_id)
+          VALUES ($1, $2) ON CONFLICT DO NOTHING;
+        `, discussionID, tagID); err != nil {
+            tx.Rollback()
+            return err
+        }
+    }
+    if err := subscribeTagFollowers(ctx, tx, discussionID, toAdd, maxSubsPerEmail); err != nil {
+        tx.Rollback()
+        return err
+    }
+    if len(toAdd) > 0 || len(toRemove) > 0 {
+        if err := touchTx(ctx, tx, discussionID); err != nil {
             tx.Rollback()
             return err
         }
     }
     return tx.Commit()
 }
+
+// CountByUserSince returns how many discussions userID has created at or
+// after since, and when the oldest of them was created (zero if none).
+func (r *repo) CountByUserSince(ctx context.Context, userID int, since time.Time) (int, time.Time, error) {
+    const q = `SELECT COUNT(*), MIN(created_at) FROM discussions WHERE user_id=$1 AND created_at >= $2;`
+    var (
+        n      int
+        oldest sql.NullTime
+    )
+    err := r.db.QueryRowContext(ctx, q, userID, since).Scan(&n, &oldest)
+    return n, oldest.Time, err
+}
+
+// UpdateWithRevision saves d and records rev in the same transaction.
+func (r *repo) UpdateWithRevision(ctx context.Context, d *models.Discussion, rev *models.DiscussionRevision) error {
+    if err := checkRequiredFields(d); err != nil {
+        return err
+    }
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    if _, err := tx.ExecContext(ctx, `
+      UPDATE discussions
+      SET title=$1, content=$2, scheduled_at=$3, status=$4, updated_at=$5, language=$6, edited_at=$7
+      WHERE id=$8;
+    `, d.Title, d.Content, nullTime(d.ScheduledAt), statusOrDefault(d.Status), d.UpdatedAt, d.Language, nullTime(d.EditedAt), d.ID); err != nil {
+        tx.Rollback()
+        return err
+    }
+    if _, err := tx.ExecContext(ctx, `
+      INSERT INTO discussion_revisions
+        (discussion_id, edited_by, previous_title, previous_content, title, content, created_at)
+      VALUES ($1,$2,$3,$4,$5,$6,$7);
+    `, rev.DiscussionID, rev.EditedBy, rev.PreviousTitle, rev.PreviousContent,
+        rev.Title, rev.Content, rev.CreatedAt); err != nil {
+        tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
+
+// ListRevisionsByEditor returns revisions made by editorID across all discussions, newest first.
+func (r *repo) ListRevisionsByEditor(ctx context.Context, editorID, limit, offset int) ([]models.DiscussionRevision, error) {
+    const q = `
+      SELECT id, discussion_id, edited_by, previous_title, previous_content, title, content, created_at
+      FROM discussion_revisions
+      WHERE edited_by = $1
+      ORDER BY created_at DESC, id DESC
+      LIMIT $2 OFFSET $3;
+    `
+    rows, err := r.db.QueryContext(ctx, q, editorID, limit, offset)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var revs []models.DiscussionRevision
+    for rows.Next() {
+        var rev models.DiscussionRevision
+        if err := rows.Scan(&rev.ID, &rev.DiscussionID, &rev.EditedBy, &rev.PreviousTitle,
+            &rev.PreviousContent, &rev.Title, &rev.Content, &rev.CreatedAt); err != nil {
+            return nil, err
+        }
+        revs = append(revs, rev)
+    }
+    return revs, rows.Err()
+}
+
+// GetRevision returns revision revID of discussionID, or nil if there is no
+// such revision on that discussion. EditedBy is 0 once the editor's account
+// has been deleted.
+func (r *repo) GetRevision(ctx context.Context, discussionID, revID int) (*models.DiscussionRevision, error) {
+    const q = `
+      SELECT id, discussion_id, edited_by, previous_title, previous_content, title, content, created_at
+      FROM discussion_revisions
+      WHERE id = $1 AND discussion_id = $2;
+    `
+    var rev models.DiscussionRevision
+    var editedBy sql.NullInt64
+    err := r.db.QueryRowContext(ctx, q, revID, discussionID).Scan(&rev.ID, &rev.DiscussionID, &editedBy,
+        &rev.PreviousTitle, &rev.PreviousContent, &rev.Title, &rev.Content, &rev.CreatedAt)
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    rev.EditedBy = int(editedBy.Int64)
+    return &rev, nil
+}
@@ -0,0 +1,875 @@
+package discussion
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/config"
+	tagpkg "go-discussion-app/internal/tag"
+	"go-discussion-app/models"
+)
+
+func newMockRepo(t *testing.T) (Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewRepository(db), mock
+}
+
+func TestReplaceTags_AddsAndRemovesInOneTransaction(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	// current tags: 1, 2 — desired: 2, 3 → remove 1, add 3
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT tag_id FROM discussion_tags WHERE discussion_id = \$1 FOR UPDATE`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"tag_id"}).AddRow(1).AddRow(2))
+	mock.ExpectExec(`DELETE FROM discussion_tags WHERE discussion_id = \$1 AND tag_id = ANY\(\$2\)`).
+		WithArgs(7, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO discussion_tags`).
+		WithArgs(7, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO subscriptions \(discussion_id, user_id, email\)\s+SELECT \$1, ts.user_id, ts.email FROM tag_subscriptions ts\s+WHERE ts.tag_id = ANY\(\$2\)`).
+		WithArgs(7, pq.Array([]int64{3}), 0, models.StatusDraft).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE discussions SET updated_at=\$1 WHERE id=\$2`).
+		WithArgs(sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.ReplaceTags(context.Background(), 7, []int{2, 3}, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddTags_SubscribesFollowersOfNewTagsOnly(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	// tag 4 is already attached, so only tag 3's followers are subscribed
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`INSERT INTO discussion_tags`)
+	mock.ExpectExec(`INSERT INTO discussion_tags`).
+		WithArgs(7, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO discussion_tags`).
+		WithArgs(7, 4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO subscriptions .* FROM tag_subscriptions ts\s+WHERE ts.tag_id = ANY\(\$2\).*\s+ON CONFLICT \(discussion_id, email\) DO NOTHING`).
+		WithArgs(7, pq.Array([]int64{3}), 0, models.StatusDraft).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE discussions SET updated_at=\$1 WHERE id=\$2`).
+		WithArgs(sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.AddTags(context.Background(), 7, []int{3, 4}, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddTags_FollowersRespectSuppressionAndCap(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`INSERT INTO discussion_tags`)
+	mock.ExpectExec(`INSERT INTO discussion_tags`).
+		WithArgs(7, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`FROM tag_subscriptions ts\s+WHERE ts.tag_id = ANY\(\$2\)\s+` +
+		`AND EXISTS \(SELECT 1 FROM discussions d WHERE d.id = \$1 AND d.status <> \$4\)\s+` +
+		`AND NOT EXISTS \(SELECT 1 FROM suppressed_emails se WHERE se.email = lower\(ts.email\)\)\s+` +
+		`AND \(\$3 = 0 OR \(SELECT COUNT\(\*\) FROM subscriptions s WHERE lower\(s.email\) = lower\(ts.email\) AND s.discussion_id <> \$1\) < \$3\)`).
+		WithArgs(7, pq.Array([]int64{3}), 5, models.StatusDraft).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE discussions SET updated_at=\$1 WHERE id=\$2`).
+		WithArgs(sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.AddTags(context.Background(), 7, []int{3}, 5)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddTags_NothingNewSubscribesNobody(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`INSERT INTO discussion_tags`)
+	mock.ExpectExec(`INSERT INTO discussion_tags`).
+		WithArgs(7, 4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.AddTags(context.Background(), 7, []int{4}, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountByTagID_SkipsDeletedDiscussions(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\)\s+FROM discussion_tags dt\s+JOIN discussions d ON d.id = dt.discussion_id\s+WHERE dt.tag_id = \$1 AND d.deleted_at IS NULL`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+
+	n, err := repo.CountByTagID(context.Background(), 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountCommentsByInterval_BucketsPerDay(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+
+	mock.ExpectQuery(`SELECT date_trunc\(\$2, created_at AT TIME ZONE 'UTC'\) AS bucket, COUNT\(\*\)\s+` +
+		`FROM comments\s+WHERE discussion_id = \$1 AND created_at >= \$3 AND created_at < \$4\s+` +
+		`AND deleted_at IS NULL\s+GROUP BY bucket\s+ORDER BY bucket`).
+		WithArgs(9, "day", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}).
+			AddRow(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), 4).
+			AddRow(time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC), 2))
+
+	points, err := repo.CountCommentsByInterval(context.Background(), 9, "day", from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, []tagpkg.TrendPoint{
+		{Start: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), Count: 4},
+		{Start: time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC), Count: 2},
+	}, points)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountCommentsByInterval_MonthBucketsAreDates(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+
+	// the driver hands back a zone-less timestamp; only its date matters
+	mock.ExpectQuery(`FROM comments`).
+		WithArgs(9, "month", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}).
+			AddRow(time.Date(2026, 3, 1, 0, 0, 0, 0, time.FixedZone("", 3600)), 11))
+
+	points, err := repo.CountCommentsByInterval(context.Background(), 9, "month", from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, []tagpkg.TrendPoint{{Start: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Count: 11}}, points)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReplaceTags_EmptySetRemovesAll(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT tag_id FROM discussion_tags`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"tag_id"}).AddRow(4))
+	mock.ExpectExec(`DELETE FROM discussion_tags`).
+		WithArgs(7, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE discussions SET updated_at=\$1 WHERE id=\$2`).
+		WithArgs(sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.ReplaceTags(context.Background(), 7, []int{}, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReplaceTags_RollsBackOnInsertFailure(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT tag_id FROM discussion_tags`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"tag_id"}).AddRow(1))
+	mock.ExpectExec(`DELETE FROM discussion_tags`).
+		WithArgs(7, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO discussion_tags`).
+		WithArgs(7, 5).
+		WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	err := repo.ReplaceTags(context.Background(), 7, []int{5}, 0)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByTag_PinsInThatTagComeFirst(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now().UTC()
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+
+	// The pin is joined on the listed tag's own discussion_tags row, so a
+	// pin under another tag never matches and cannot reorder this listing.
+	mock.ExpectQuery(`JOIN tags t ON dt.tag_id = t.id\s+` +
+		`LEFT JOIN pinned_tags pt ON pt.tag_id = dt.tag_id AND pt.discussion_id = dt.discussion_id\s+` +
+		`WHERE lower\(t.name\) = \$1 AND d.deleted_at IS NULL AND \(d.status <> 'draft' OR d.user_id = \$2\)\s+` +
+		`ORDER BY pt.pinned_at IS NULL, pt.pinned_at DESC, d.created_at DESC`).
+		WithArgs("go", 1).
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow(2, 1, "pinned", "c", nil, "published", now.Add(-time.Hour), now, 0, "", nil).
+			AddRow(5, 1, "newest", "c", nil, "published", now, now, 0, "", nil))
+
+	ds, err := repo.GetByTag(context.Background(), "go", 1)
+	assert.NoError(t, err)
+	if assert.Len(t, ds, 2) {
+		assert.Equal(t, 2, ds[0].ID)
+		assert.Equal(t, 5, ds[1].ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Listings outside ListByStatus must not leak drafts: each query keeps a
+// draft only when the viewer is its author.
+func TestListings_HideOtherUsersDrafts(t *testing.T) {
+	since := time.Now().UTC().Add(-time.Hour)
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+	cases := map[string]struct {
+		clause string
+		args   []driver.Value
+		list   func(Repository) ([]models.Discussion, error)
+	}{
+		"GetByUser": {
+			`AND \(status <> 'draft' OR user_id = \$2\)`, []driver.Value{2, 1},
+			func(r Repository) ([]models.Discussion, error) { return r.GetByUser(context.Background(), 2, 1) },
+		},
+		"GetByTag": {
+			`AND \(d.status <> 'draft' OR d.user_id = \$2\)`, []driver.Value{"go", 1},
+			func(r Repository) ([]models.Discussion, error) { return r.GetByTag(context.Background(), "go", 1) },
+		},
+		"ListActiveSince": {
+			`AND \(d.status <> 'draft' OR d.user_id = \$2\)`, []driver.Value{since, 1},
+			func(r Repository) ([]models.Discussion, error) { return r.ListActiveSince(context.Background(), since, 1) },
+		},
+		"FindByTitleLike": {
+			`AND \(status <> 'draft' OR user_id = \$3\)`, []driver.Value{"%go%", since, 1, 5},
+			func(r Repository) ([]models.Discussion, error) { return r.FindByTitleLike(context.Background(), "%go%", since, 1, 5) },
+		},
+	}
+	for name, tc := range cases {
+		repo, mock := newMockRepo(t)
+		mock.ExpectQuery(tc.clause).
+			WithArgs(tc.args...).
+			WillReturnRows(sqlmock.NewRows(cols).
+				AddRow(3, 1, "mine", "c", nil, models.StatusDraft, since, since, 0, "", nil))
+
+		ds, err := tc.list(repo)
+		assert.NoError(t, err, name)
+		assert.Len(t, ds, 1, name)
+		assert.NoError(t, mock.ExpectationsWereMet(), name)
+	}
+}
+
+func TestPinInTag_OnlyWhenDiscussionCarriesTag(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	q := `INSERT INTO pinned_tags \(discussion_id, tag_id\)\s+SELECT dt.discussion_id, dt.tag_id\s+FROM discussion_tags dt\s+` +
+		`JOIN tags t ON t.id = dt.tag_id\s+JOIN discussions d ON d.id = dt.discussion_id\s+` +
+		`WHERE dt.discussion_id = \$1 AND lower\(t.name\) = \$2 AND d.deleted_at IS NULL.*` +
+		`UPDATE discussions SET updated_at = \$3 WHERE id IN \(SELECT discussion_id FROM pinned\)`
+	mock.ExpectExec(q).WithArgs(7, "go", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(q).WithArgs(7, "rust", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ok, err := repo.PinInTag(context.Background(), 7, "go")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = repo.PinInTag(context.Background(), 7, "rust")
+	assert.NoError(t, err)
+	assert.False(t, ok, "discussion 7 is not tagged rust")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnpinInTag_ScopedToTag(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectExec(`DELETE FROM pinned_tags pt\s+USING tags t\s+WHERE pt.tag_id = t.id AND pt.discussion_id = \$1 AND lower\(t.name\) = \$2.*` +
+		`UPDATE discussions SET updated_at = \$3 WHERE id IN \(SELECT discussion_id FROM unpinned\)`).
+		WithArgs(7, "go", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ok, err := repo.UnpinInTag(context.Background(), 7, "go")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateWithRevision_WritesBothInOneTransaction(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now().UTC()
+	d := &models.Discussion{ID: 3, Title: "new", Content: "body", UpdatedAt: now, EditedAt: &now}
+	rev := &models.DiscussionRevision{DiscussionID: 3, EditedBy: 5, PreviousTitle: "old", PreviousContent: "body", Title: "new", Content: "body", CreatedAt: now}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE discussions`).
+		WithArgs("new", "body", nil, models.StatusPublished, now, "", now, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO discussion_revisions`).
+		WithArgs(3, 5, "old", "body", "new", "body", now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.UpdateWithRevision(context.Background(), d, rev))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRevision_ScopedToDiscussion(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	cols := []string{"id", "discussion_id", "edited_by", "previous_title", "previous_content", "title", "content", "created_at"}
+
+	mock.ExpectQuery(`FROM discussion_revisions\s+WHERE id = \$1 AND discussion_id = \$2`).
+		WithArgs(4, 1).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(4, 1, nil, "t", "a", "t", "b", time.Now()))
+	mock.ExpectQuery(`FROM discussion_revisions\s+WHERE id = \$1 AND discussion_id = \$2`).
+		WithArgs(4, 2).
+		WillReturnRows(sqlmock.NewRows(cols))
+
+	rev, err := repo.GetRevision(context.Background(), 1, 4)
+	assert.NoError(t, err)
+	if assert.NotNil(t, rev) {
+		assert.Equal(t, 0, rev.EditedBy, "a deleted editor reads as 0")
+		assert.Equal(t, "b", rev.Content)
+	}
+	rev, err = repo.GetRevision(context.Background(), 2, 4)
+	assert.NoError(t, err)
+	assert.Nil(t, rev)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListRevisionsByEditor_FiltersByEditor(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now().UTC()
+
+	mock.ExpectQuery(`FROM discussion_revisions\s+WHERE edited_by = \$1\s+ORDER BY created_at DESC, id DESC\s+LIMIT \$2 OFFSET \$3`).
+		WithArgs(5, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "discussion_id", "edited_by", "previous_title", "previous_content", "title", "content", "created_at"}).
+			AddRow(2, 8, 5, "a", "b", "c", "d", now).
+			AddRow(1, 3, 5, "e", "f", "g", "h", now.Add(-time.Minute)))
+
+	revs, err := repo.ListRevisionsByEditor(context.Background(), 5, 20, 0)
+	assert.NoError(t, err)
+	assert.Len(t, revs, 2)
+	for _, r := range revs {
+		assert.Equal(t, 5, r.EditedBy)
+	}
+	assert.Equal(t, 8, revs[0].DiscussionID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListActiveSince_FiltersByWindowAndOrdersByLastComment(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	since := time.Now().UTC().Add(-24 * time.Hour)
+	created := since.Add(-72 * time.Hour)
+
+	mock.ExpectQuery(`FROM comments\s+WHERE created_at >= \$1 AND deleted_at IS NULL\s+GROUP BY discussion_id.*ORDER BY c.last_comment_at DESC, d.id DESC`).
+		WithArgs(since, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}).
+			AddRow(9, 1, "newest", "c", nil, "published", created, created, 0, "", nil).
+			AddRow(2, 1, "older", "c", nil, "published", created, created, 0, "", nil))
+
+	ds, err := repo.ListActiveSince(context.Background(), since, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, ds, 2) {
+		assert.Equal(t, 9, ds[0].ID)
+		assert.Equal(t, 2, ds[1].ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListActiveSince_NoActivity(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	since := time.Now().UTC().Add(-time.Hour)
+
+	mock.ExpectQuery(`FROM comments`).
+		WithArgs(since, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}))
+
+	ds, err := repo.ListActiveSince(context.Background(), since, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, ds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLatestUpdate(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now().UTC()
+
+	mock.ExpectQuery(`SELECT MAX\(updated_at\) FROM discussions`).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(now))
+	mock.ExpectQuery(`SELECT MAX\(updated_at\) FROM discussions`).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+
+	got, err := repo.LatestUpdate(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(got))
+
+	got, err = repo.LatestUpdate(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, got.IsZero(), "empty table yields zero time")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTouch_OnlySetsUpdatedAt(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now().UTC()
+
+	mock.ExpectExec(`^UPDATE discussions SET updated_at=\$1 WHERE id=\$2$`).
+		WithArgs(now, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Touch(context.Background(), 3, now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreate_NilScheduledAtIsWrittenAsNull(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now().UTC()
+	d := &models.Discussion{UserID: 1, Title: "t", Content: "c", CreatedAt: now, UpdatedAt: now}
+
+	mock.ExpectQuery(`INSERT INTO discussions`).
+		WithArgs(1, "t", "c", nil, models.StatusPublished, now, now, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+
+	id, err := repo.Create(context.Background(), d)
+	assert.NoError(t, err)
+	assert.Equal(t, 9, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreate_ScheduledAtIsWrittenAsTimestamp(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now().UTC()
+	at := now.Add(time.Hour)
+	d := &models.Discussion{UserID: 1, Title: "t", Content: "c", ScheduledAt: &at, CreatedAt: now, UpdatedAt: now}
+
+	mock.ExpectQuery(`INSERT INTO discussions`).
+		WithArgs(1, "t", "c", at, models.StatusPublished, now, now, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+
+	_, err := repo.Create(context.Background(), d)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWrites_RejectEmptyTitleOrContent(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	ctx := context.Background()
+
+	for _, d := range []*models.Discussion{
+		{ID: 1, Title: "", Content: "c"},
+		{ID: 1, Title: "t", Content: "   "},
+	} {
+		_, err := repo.Create(ctx, d)
+		assert.ErrorIs(t, err, ErrEmptyField)
+		assert.ErrorIs(t, repo.Update(ctx, d), ErrEmptyField)
+		assert.ErrorIs(t, repo.UpdateWithRevision(ctx, d, &models.DiscussionRevision{}), ErrEmptyField)
+	}
+	// nothing reached the database
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListByStatus_ScopesToOwnerWhenGiven(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now().UTC()
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+
+	mock.ExpectQuery(`WHERE status = \$1 AND deleted_at IS NULL\s+AND \(\$2 = 0 OR user_id = \$2\)`).
+		WithArgs(models.StatusDraft, 7, "", "", 20, 0).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(4, 7, "mine", "c", nil, "draft", now, now, 0, "", nil))
+
+	ds, err := repo.ListByStatus(context.Background(), models.StatusDraft, ListFilter{UserID: 7}, config.DiscussionSortRecent, 20, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, ds, 1) {
+		assert.Equal(t, models.StatusDraft, ds[0].Status)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListByStatus_MatchesTagNameCaseInsensitively(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+
+	mock.ExpectQuery(`WHERE dt.discussion_id = discussions.id AND lower\(t.name\) = \$3\)\)`).
+		WithArgs(models.StatusPublished, 0, "go", "", 20, 0).
+		WillReturnRows(sqlmock.NewRows(cols))
+
+	_, err := repo.ListByStatus(context.Background(), models.StatusPublished, ListFilter{Tag: "go"}, "", 20, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListByStatus_AppliesLimitAndOffset(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+
+	mock.ExpectQuery(`LIMIT \$5 OFFSET \$6`).
+		WithArgs(models.StatusPublished, 0, "", "", 25, 50).
+		WillReturnRows(sqlmock.NewRows(cols))
+
+	_, err := repo.ListByStatus(context.Background(), models.StatusPublished, ListFilter{}, "", 25, 50)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountByStatus(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM discussions\s+WHERE status = \$1 AND deleted_at IS NULL\s+AND \(\$2 = 0 OR user_id = \$2\)`).
+		WithArgs(models.StatusDraft, 7, "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	n, err := repo.CountByStatus(context.Background(), models.StatusDraft, ListFilter{UserID: 7})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestArchiveStale_SelectsIdlePublishedDiscussions(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := before.Add(90 * 24 * time.Hour)
+
+	mock.ExpectQuery(`UPDATE discussions\s+SET status = \$1, updated_at = \$2\s+` +
+		`WHERE status = \$3 AND updated_at < \$4 AND deleted_at IS NULL\s+` +
+		`AND NOT EXISTS \(\s*SELECT 1 FROM comments c\s+WHERE c.discussion_id = discussions.id AND c.created_at >= \$4 AND c.deleted_at IS NULL\s*\)\s+` +
+		`RETURNING id`).
+		WithArgs(models.StatusArchived, at, models.StatusPublished, before).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(8))
+
+	ids, err := repo.ArchiveStale(context.Background(), before, at)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 8}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListUpdatedSince_FiltersPublishedAfterMarker(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Now()
+
+	mock.ExpectQuery(`WHERE status = \$1 AND updated_at > \$2`).
+		WithArgs(models.StatusPublished, since).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}).
+			AddRow(4, 1, "t", "c", nil, models.StatusPublished, now, now, 0, "", nil))
+
+	ds, err := repo.ListUpdatedSince(context.Background(), since)
+	assert.NoError(t, err)
+	assert.Len(t, ds, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLastSeen_DefaultsToZeroAndUpserts(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	at := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT seen_at FROM user_last_seen WHERE user_id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"seen_at"}))
+	mock.ExpectExec(`INSERT INTO user_last_seen .* ON CONFLICT \(user_id\) DO UPDATE`).
+		WithArgs(3, at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	got, err := repo.GetLastSeen(context.Background(), 3)
+	assert.NoError(t, err)
+	assert.True(t, got.IsZero())
+	assert.NoError(t, repo.SetLastSeen(context.Background(), 3, at))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearch_BoundsScanWithLimit(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectQuery(`LIMIT \$3 OFFSET \$4`).
+		WithArgs(models.StatusPublished, "go", 50, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}))
+
+	ds, err := repo.Search(context.Background(), "go", 50, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, ds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearch_FullTextRankedByRelevance(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now()
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+
+	mock.ExpectQuery(`WHERE status = \$1 AND deleted_at IS NULL AND to_tsvector\('english', title \|\| ' ' \|\| content\) @@ plainto_tsquery\('english', \$2\)\s+` +
+		`ORDER BY ts_rank\(to_tsvector\('english', title \|\| ' ' \|\| content\), plainto_tsquery\('english', \$2\)\) DESC, created_at DESC, id DESC`).
+		WithArgs(models.StatusPublished, "go generics", 20, 10).
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow(8, 1, "Go generics in depth", "generics", nil, "published", now, now, 0, "", nil).
+			AddRow(3, 2, "Go tips", "a note on generics", nil, "published", now, now, 0, "", nil))
+
+	ds, err := repo.Search(context.Background(), "go generics", 20, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, ds, 2) {
+		// rank order from the database is preserved
+		assert.Equal(t, 8, ds[0].ID)
+		assert.Equal(t, 3, ds[1].ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountSearch(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM discussions\s+WHERE status = \$1 AND deleted_at IS NULL AND to_tsvector\('english', title \|\| ' ' \|\| content\) @@ plainto_tsquery\('english', \$2\)`).
+		WithArgs(models.StatusPublished, "go").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	n, err := repo.CountSearch(context.Background(), "go")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByIDWithTags_SingleJoinQuery(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now()
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at", "tags"}
+
+	mock.ExpectQuery(`FROM discussions d\s+LEFT JOIN discussion_tags dt ON dt.discussion_id = d.id\s+LEFT JOIN tags t ON t.id = dt.tag_id\s+WHERE d.id = \$1 AND d.deleted_at IS NULL\s+GROUP BY d.id`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, 2, "t", "c", nil, models.StatusPublished, now, now, 0, "", nil, "{go,postgres}"))
+	mock.ExpectQuery(`LEFT JOIN tags t`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(2, 2, "t", "c", nil, models.StatusPublished, now, now, 0, "", nil, "{}"))
+
+	d, tags, err := repo.GetByIDWithTags(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, d.ID)
+	assert.Equal(t, []string{"go", "postgres"}, tags)
+
+	d, tags, err = repo.GetByIDWithTags(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, d.ID)
+	assert.Empty(t, tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListByStatus_PopularOrdersByCommentCount(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+
+	mock.ExpectQuery(`ORDER BY \(SELECT COUNT\(\*\) FROM comments c WHERE c.discussion_id = discussions.id AND c.deleted_at IS NULL\) DESC, created_at DESC`).
+		WithArgs(models.StatusPublished, 0, "", "", 20, 0).
+		WillReturnRows(sqlmock.NewRows(cols))
+	mock.ExpectQuery(`ORDER BY created_at DESC, id DESC\s+LIMIT`).
+		WithArgs(models.StatusPublished, 0, "", "", 20, 0).
+		WillReturnRows(sqlmock.NewRows(cols))
+
+	_, err := repo.ListByStatus(context.Background(), models.StatusPublished, ListFilter{}, config.DiscussionSortPopular, 20, 0)
+	assert.NoError(t, err)
+	_, err = repo.ListByStatus(context.Background(), models.StatusPublished, ListFilter{}, "", 20, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListByStatus_SortOrders(t *testing.T) {
+	cases := []struct {
+		sort  string
+		order string
+	}{
+		{config.DiscussionSortNewest, `ORDER BY created_at DESC, id DESC\s+LIMIT`},
+		{config.DiscussionSortOldest, `ORDER BY created_at ASC, id ASC\s+LIMIT`},
+		{config.DiscussionSortMostCommented, `ORDER BY \(SELECT COUNT\(\*\) FROM comments c WHERE c.discussion_id = discussions.id AND c.deleted_at IS NULL\) DESC`},
+		{config.DiscussionSortMostViewed, `ORDER BY view_count DESC, created_at DESC, id DESC\s+LIMIT`},
+	}
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+	for _, tc := range cases {
+		repo, mock := newMockRepo(t)
+		mock.ExpectQuery(tc.order).
+			WithArgs(models.StatusPublished, 0, "", "", 20, 0).
+			WillReturnRows(sqlmock.NewRows(cols))
+
+		_, err := repo.ListByStatus(context.Background(), models.StatusPublished, ListFilter{}, tc.sort, 20, 0)
+		assert.NoError(t, err, tc.sort)
+		assert.NoError(t, mock.ExpectationsWereMet(), tc.sort)
+	}
+}
+
+func TestListByStatus_SubscribersJoinsConfirmedCounts(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now().UTC()
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+
+	mock.ExpectQuery(`FROM discussions\s+LEFT JOIN \(\s*SELECT discussion_id, COUNT\(\*\) AS subscribers\s+FROM subscriptions WHERE confirmed\s+GROUP BY discussion_id\s*\) sc ON sc.discussion_id = discussions.id\s+WHERE status = \$1.*ORDER BY COALESCE\(sc.subscribers, 0\) DESC, created_at DESC, id DESC\s+LIMIT \$5 OFFSET \$6`).
+		WithArgs(models.StatusPublished, 0, "", "", 20, 0).
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow(3, 1, "followed", "c", nil, "published", now, now, 0, "", nil).
+			AddRow(1, 1, "quiet", "c", nil, "published", now, now, 0, "", nil))
+
+	ds, err := repo.ListByStatus(context.Background(), models.StatusPublished, ListFilter{}, config.DiscussionSortSubscribers, 20, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, ds, 2) {
+		assert.Equal(t, 3, ds[0].ID)
+		assert.Equal(t, 1, ds[1].ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListByStatus_OtherSortsDoNotJoinSubscriptions(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+
+	mock.ExpectQuery(`FROM discussions\s+WHERE status = \$1`).
+		WithArgs(models.StatusPublished, 0, "", "", 20, 0).
+		WillReturnRows(sqlmock.NewRows(cols))
+
+	_, err := repo.ListByStatus(context.Background(), models.StatusPublished, ListFilter{}, config.DiscussionSortMostViewed, 20, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListByStatus_FiltersByLanguage(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+	now := time.Now()
+
+	mock.ExpectQuery(`AND \(\$4 = '' OR language = \$4\)`).
+		WithArgs(models.StatusPublished, 0, "", "de", 20, 0).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(6, 1, "Hallo", "c", nil, "published", now, now, 0, "de", nil))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM discussions`).
+		WithArgs(models.StatusPublished, 0, "", "de").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	f := ListFilter{Language: "de"}
+	ds, err := repo.ListByStatus(context.Background(), models.StatusPublished, f, config.DiscussionSortNewest, 20, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, ds, 1) {
+		assert.Equal(t, "de", ds[0].Language)
+	}
+	n, err := repo.CountByStatus(context.Background(), models.StatusPublished, f)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListByStatus_FiltersByUserAndTag(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+
+	mock.ExpectQuery(`AND \(\$3 = '' OR EXISTS \(`).
+		WithArgs(models.StatusPublished, 5, "go", "", 20, 0).
+		WillReturnRows(sqlmock.NewRows(cols))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM discussions`).
+		WithArgs(models.StatusPublished, 5, "go", "").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	f := ListFilter{UserID: 5, Tag: "go"}
+	_, err := repo.ListByStatus(context.Background(), models.StatusPublished, f, config.DiscussionSortNewest, 20, 0)
+	assert.NoError(t, err)
+	_, err = repo.CountByStatus(context.Background(), models.StatusPublished, f)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListByTags_GroupsRowsByTag(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now().UTC()
+	cols := []string{"tag", "total", "id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+
+	mock.ExpectQuery(`COUNT\(\*\) OVER \(PARTITION BY t.id\).*ROW_NUMBER\(\) OVER.*WHERE lower\(t.name\) = ANY\(\$1\) AND d.status = \$2.*WHERE rn <= \$3\s+ORDER BY tag, rn`).
+		WithArgs(sqlmock.AnyArg(), models.StatusPublished, 2).
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow("go", 3, 7, 1, "newest go", "c", nil, "published", now, now, 0, "", nil).
+			AddRow("go", 3, 5, 1, "older go", "c", nil, "published", now, now, 0, "", nil).
+			AddRow("rust", 1, 5, 1, "older go", "c", nil, "published", now, now, 0, "", nil))
+
+	groups, err := repo.ListByTags(context.Background(), []string{"go", "rust", "zig"}, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 2) {
+		assert.Equal(t, "go", groups[0].Tag)
+		assert.Equal(t, 3, groups[0].Count, "count covers discussions beyond the per-tag cap")
+		if assert.Len(t, groups[0].Discussions, 2) {
+			assert.Equal(t, 7, groups[0].Discussions[0].ID)
+			assert.Equal(t, 5, groups[0].Discussions[1].ID)
+		}
+		assert.Equal(t, "rust", groups[1].Tag)
+		assert.Equal(t, 1, groups[1].Count)
+		assert.Len(t, groups[1].Discussions, 1)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListByTags_NoMatches(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectQuery(`FROM tags t`).
+		WillReturnRows(sqlmock.NewRows([]string{"tag", "total", "id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}))
+
+	groups, err := repo.ListByTags(context.Background(), []string{"zig"}, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, groups)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDelete_SoftDeletes(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectExec(`UPDATE discussions SET deleted_at=\$1, updated_at=\$1 WHERE id=\$2 AND deleted_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Delete(context.Background(), 5))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByID_SkipsSoftDeleted(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	cols := []string{"id", "user_id", "title", "content", "scheduled_at", "status", "created_at", "updated_at", "view_count", "language", "edited_at"}
+
+	// A soft-deleted row is filtered by the query, so it comes back as no rows.
+	mock.ExpectQuery(`FROM discussions WHERE id=\$1 AND deleted_at IS NULL`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(cols))
+
+	d, err := repo.GetByID(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestore(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	q := `UPDATE discussions\s+SET updated_at = CASE WHEN deleted_at IS NULL THEN updated_at ELSE \$1 END, deleted_at = NULL\s+WHERE id = \$2`
+	mock.ExpectExec(q).
+		WithArgs(sqlmock.AnyArg(), 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(q).
+		WithArgs(sqlmock.AnyArg(), 404).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	found, err := repo.Restore(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	found, err = repo.Restore(context.Background(), 404)
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncrementViewCount_AtomicUpdate(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectExec(`UPDATE discussions SET view_count = view_count \+ 1 WHERE id=\$1 AND deleted_at IS NULL`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.IncrementViewCount(context.Background(), 4))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
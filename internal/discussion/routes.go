@@ -5,29 +5,56 @@ import (
     "database/sql"
 
     "github.com/gin-gonic/gin"
+    "go-discussion-app/config"
+    "go-discussion-app/internal/auth"
 		"go-discussion-app/internal/tag"
+    "go-discussion-app/models"
+    "go-discussion-app/pkg/featureflags"
 )
 
-func RegisterRoutes(rg *gin.RouterGroup, db *sql.DB) {
+// comments backs GET /discussions/:id?include=comments; pass nil to disable it.
+func RegisterRoutes(rg *gin.RouterGroup, db *sql.DB, cfg *config.Config, comments CommentLister) {
     discRepo := NewRepository(db)
 
 		tagRepo := tag.NewRepository(db)                      // <— new
-    svc := NewService(discRepo, tagRepo)
+    svc := NewService(discRepo, tagRepo, cfg)
     
-    ctr := NewController(svc)
+    ctr := NewController(svc, comments)
 
     // standard CRUD
     rg.POST("/discussions", ctr.Create)
+    rg.POST("/discussions/preview", ctr.Preview)
     rg.GET("/discussions", ctr.List)
     rg.GET("/discussions/:id", ctr.Get)
     rg.PUT("/discussions/:id", ctr.Update)
-    rg.DELETE("/discussions/:id", ctr.Delete)
+    rg.DELETE("/discussions/:id", auth.RequireRole(models.RoleAdmin), ctr.Delete)
+    rg.POST("/discussions/:id/restore", auth.RequireRole(models.RoleAdmin), ctr.Restore)
+    rg.POST("/discussions/:id/bump", ctr.Bump)
+    rg.GET("/discussions/:id/revisions/:revID/diff", ctr.RevisionDiff)
+    rg.GET("/discussions/:id/stats", ctr.Stats)
 
     // filters & tagging
     rg.GET("/discussions/user/:userId", ctr.ListByUser)
     rg.GET("/discussions/tag/:tag", ctr.ListByTag)
+    rg.GET("/discussions/by-tags", ctr.ListByTags)
+    rg.GET("/discussions/active", ctr.ListActive)
+    if cfg.Features.Enabled(featureflags.Search) {
+        rg.GET("/discussions/search", ctr.Search)
+    }
     rg.POST("/discussions/:id/tags", ctr.AddTags)
+    rg.PUT("/discussions/:id/tags", ctr.ReplaceTags)
+
+    // last-seen marker for ?unseen=true
+    rg.POST("/me/seen", ctr.MarkSeen)
 
     // scheduled
-    rg.POST("/discussions/schedule", ctr.Schedule)
+    if cfg.Features.Enabled(featureflags.Scheduling) {
+        rg.POST("/discussions/schedule", ctr.Schedule)
+    }
+
+    // moderation
+    rg.GET("/admin/revisions", auth.RequireRole(models.RoleAdmin), ctr.ListRevisionsByEditor)
+    rg.POST("/admin/discussions/lock-stale", auth.RequireRole(models.RoleAdmin), ctr.ArchiveStale)
+    rg.POST("/admin/tags/:tag/pins/:id", auth.RequireRole(models.RoleAdmin), ctr.PinInTag)
+    rg.DELETE("/admin/tags/:tag/pins/:id", auth.RequireRole(models.RoleAdmin), ctr.UnpinInTag)
 }
@@ -0,0 +1,65 @@
+package discussion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/config"
+	"go-discussion-app/pkg/featureflags"
+)
+
+func hasRoute(r *gin.Engine, method, path string) bool {
+	for _, ri := range r.Routes() {
+		if ri.Method == method && ri.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRegisterRoutes_FeatureFlags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	on := gin.New()
+	RegisterRoutes(on.Group("/"), db, &config.Config{}, nil)
+	assert.True(t, hasRoute(on, http.MethodGet, "/discussions/search"))
+	assert.True(t, hasRoute(on, http.MethodPost, "/discussions/schedule"))
+
+	cfg := &config.Config{Features: featureflags.Flags{}.With(featureflags.Search, false)}
+	off := gin.New()
+	RegisterRoutes(off.Group("/"), db, cfg, nil)
+	assert.False(t, hasRoute(off, http.MethodGet, "/discussions/search"))
+	assert.True(t, hasRoute(off, http.MethodPost, "/discussions/schedule"))
+
+	// The path now lands on /discussions/:id, which must 404 without querying.
+	w := httptest.NewRecorder()
+	off.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/discussions/search?q=golang", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegisterRoutes_PreviewDoesNotTouchDB(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	r := gin.New()
+	RegisterRoutes(r.Group("/"), db, &config.Config{}, nil)
+
+	// Static /discussions/preview must win over /discussions/:id.
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/discussions/preview", strings.NewReader(`{"content":"# Hello"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `\u003ch1\u003eHello`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,63 +3,290 @@ package discussion
 
 import (
     "context"
+    "errors"
+    "fmt"
+    "strings"
     "time"
+    "unicode"
+    "unicode/utf8"
 
+    "go-discussion-app/config"
     "go-discussion-app/models"
+    "go-discussion-app/pkg/linediff"
 		tagpkg "go-discussion-app/internal/tag"
 )
 
+const (
+    // ByTagsPerTag caps how many discussions GET /discussions/by-tags lists
+    // under each tag; the count still covers all of them.
+    ByTagsPerTag = 10
+    // DuplicateLookback bounds how far back FindSimilar searches.
+    DuplicateLookback = 30 * 24 * time.Hour
+    // maxSimilar caps how many similar discussions are reported.
+    maxSimilar = 5
+)
+
+// ErrSearchQueryTooShort is returned by Search when the query has fewer than
+// Config.SearchMinQueryLength letters or digits.
+var ErrSearchQueryTooShort = errors.New("search query is too short")
+
+// ErrRateLimited is returned when a user has created too many discussions
+// within the configured window.
+var ErrRateLimited = errors.New("discussion rate limit exceeded")
+
+// ErrTooManyTags is returned by AddTags and ReplaceTags when the discussion
+// would end up with more than Config.MaxTagsPerDiscussion tags.
+var ErrTooManyTags = errors.New("too many tags on this discussion")
+
+// ErrTagOverused is returned by AddTags and ReplaceTags when a tag being
+// added is already on Config.MaxDiscussionsPerTag discussions.
+var ErrTagOverused = errors.New("tag is used by too many discussions")
+
+// ErrInvalidLanguage is returned when a discussion's language, or the
+// language a listing is filtered by, is not in Config.DiscussionLanguages.
+var ErrInvalidLanguage = errors.New("language is not supported")
+
 type Service interface {
-    Create(ctx context.Context, userID int, dto *CreateDiscussionDTO) (int, error)
-    GetAll(ctx context.Context) ([]models.Discussion, error)
+    Create(ctx context.Context, userID int, dto *CreateDiscussionDTO) (*models.Discussion, error)
+    RateLimitStatus(ctx context.Context, userID int) (*RateLimit, error)
+    ListByStatus(ctx context.Context, status string, viewerID int, f ListFilter, sort string, limit, offset int) ([]models.Discussion, int, error)
+    LastModified(ctx context.Context, sort string) (time.Time, error)
     GetByID(ctx context.Context, id int) (*models.Discussion, error)
-    Update(ctx context.Context, id int, dto *UpdateDiscussionDTO) (*models.Discussion, error)
+    GetWithTags(ctx context.Context, id int) (*DiscussionWithTags, error)
+    RecordView(ctx context.Context, id int) error
+    Update(ctx context.Context, id, editorID int, dto *UpdateDiscussionDTO) (*models.Discussion, error)
     Delete(ctx context.Context, id int) error
+    Restore(ctx context.Context, id int) (bool, error)
+    Bump(ctx context.Context, id int) (*models.Discussion, error)
+    ArchiveStale(ctx context.Context, olderThan time.Duration) ([]int, error)
 
-    GetByUser(ctx context.Context, userID int) ([]models.Discussion, error)
-    GetByTag(ctx context.Context, tag string) ([]models.Discussion, error)
+    GetByUser(ctx context.Context, userID, viewerID int) ([]models.Discussion, error)
+    GetByTag(ctx context.Context, tag string, viewerID int) ([]models.Discussion, error)
+    PinInTag(ctx context.Context, discussionID int, tag string) (bool, error)
+    UnpinInTag(ctx context.Context, discussionID int, tag string) (bool, error)
+    ListByTags(ctx context.Context, names []string) ([]TagGroup, error)
+    ListActive(ctx context.Context, window time.Duration, viewerID int) ([]models.Discussion, error)
+    FindSimilar(ctx context.Context, title string, viewerID int) ([]models.Discussion, error)
+    Search(ctx context.Context, query string, limit, offset int) ([]models.Discussion, int, int, error)
+    ListUnseen(ctx context.Context, userID int) ([]models.Discussion, error)
+    MarkSeen(ctx context.Context, userID int) (time.Time, error)
     AddTags(ctx context.Context, discussionID int, dto *AddTagsDTO) error
-    Schedule(ctx context.Context, userID int, dto *ScheduleDTO) (int, error)
+    ReplaceTags(ctx context.Context, discussionID int, dto *ReplaceTagsDTO) error
+    Schedule(ctx context.Context, userID int, dto *ScheduleDTO) (*models.Discussion, error)
+
+    ListRevisionsByEditor(ctx context.Context, editorID, limit, offset int) ([]models.DiscussionRevision, error)
+    RevisionDiff(ctx context.Context, discussionID, revID int) (*RevisionDiff, error)
+    CommentStats(ctx context.Context, discussionID int, p tagpkg.TrendParams) (*CommentStats, error)
 }
 
 type service struct {
     repo    Repository
     tagRepo tagpkg.TagRepository
+    cfg     *config.Config
 }
 
 func NewService(
     repo Repository,
     tagRepo tagpkg.TagRepository,
+    cfg *config.Config,
 ) Service {
-    return &service{repo: repo, tagRepo: tagRepo}
+    if cfg == nil {
+        cfg = &config.Config{}
+    }
+    return &service{repo: repo, tagRepo: tagRepo, cfg: cfg}
+}
+
+// RateLimit is a user's discussion quota at one moment.
+type RateLimit struct {
+    Limit     int       // cfg.DiscussionRateLimit
+    Remaining int       // discussions the user may still create in this window
+    Reset     time.Time // when the oldest counted discussion leaves the window and frees a slot
+}
+
+// RateLimitStatus reports userID's remaining quota, or nil when
+// discussion creation is not rate limited.
+func (s *service) RateLimitStatus(ctx context.Context, userID int) (*RateLimit, error) {
+    if s.cfg.DiscussionRateLimit <= 0 || s.cfg.DiscussionRateWindow <= 0 {
+        return nil, nil
+    }
+    now := time.Now().UTC()
+    n, oldest, err := s.repo.CountByUserSince(ctx, userID, now.Add(-s.cfg.DiscussionRateWindow))
+    if err != nil {
+        return nil, err
+    }
+    rl := &RateLimit{Limit: s.cfg.DiscussionRateLimit, Remaining: max(s.cfg.DiscussionRateLimit-n, 0), Reset: now}
+    if n > 0 {
+        rl.Reset = oldest.Add(s.cfg.DiscussionRateWindow)
+    }
+    return rl, nil
 }
 
+// checkRateLimit rejects creation once userID has reached
+// cfg.DiscussionRateLimit discussions within cfg.DiscussionRateWindow.
+func (s *service) checkRateLimit(ctx context.Context, userID int) error {
+    rl, err := s.RateLimitStatus(ctx, userID)
+    if err != nil {
+        return err
+    }
+    if rl != nil && rl.Remaining == 0 {
+        return ErrRateLimited
+    }
+    return nil
+}
 
-func (s *service) Create(ctx context.Context, userID int, dto *CreateDiscussionDTO) (int, error) {
+// Create stores a new discussion and returns it as saved.
+func (s *service) Create(ctx context.Context, userID int, dto *CreateDiscussionDTO) (*models.Discussion, error) {
+    lang, err := s.language(dto.Language)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.checkRateLimit(ctx, userID); err != nil {
+        return nil, err
+    }
     d := &models.Discussion{
         UserID:      userID,
         Title:       dto.Title,
         Content:     dto.Content,
         ScheduledAt: dto.ScheduledAt,
+        Status:      dto.Status,
+        Language:    lang,
         CreatedAt:   time.Now().UTC(),
         UpdatedAt:   time.Now().UTC(),
     }
-    return s.repo.Create(ctx, d)
+    return s.insert(ctx, d)
+}
+
+// insert creates d and reads it back, so callers get the stored
+// timestamps and defaults rather than what was sent.
+func (s *service) insert(ctx context.Context, d *models.Discussion) (*models.Discussion, error) {
+    id, err := s.repo.Create(ctx, d)
+    if err != nil {
+        return nil, err
+    }
+    created, err := s.repo.GetByID(ctx, id)
+    if err == nil && created == nil {
+        err = fmt.Errorf("discussion %d not found after insert", id)
+    }
+    return created, err
+}
+
+// ListByStatus returns one page of discussions in status matching f along
+// with their total number. Drafts are private, so a draft listing only ever
+// contains viewerID's own discussions, and is empty when f asks for another
+// author's. An empty sort falls back to cfg.DefaultDiscussionSort.
+func (s *service) ListByStatus(ctx context.Context, status string, viewerID int, f ListFilter, sort string, limit, offset int) ([]models.Discussion, int, error) {
+    lang, err := s.language(f.Language)
+    if err != nil {
+        return nil, 0, err
+    }
+    f.Language = lang
+    if status == models.StatusDraft {
+        if f.UserID != 0 && f.UserID != viewerID {
+            return []models.Discussion{}, 0, nil
+        }
+        f.UserID = viewerID
+    }
+    if sort == "" {
+        sort = s.cfg.DefaultDiscussionSort
+    }
+    total, err := s.repo.CountByStatus(ctx, status, f)
+    if err != nil {
+        return nil, 0, err
+    }
+    ds, err := nonNil(s.repo.ListByStatus(ctx, status, f, sort, limit, offset))
+    if err != nil {
+        return nil, 0, err
+    }
+    return ds, total, nil
 }
 
-func (s *service) GetAll(ctx context.Context) ([]models.Discussion, error) {
-    return s.repo.GetAll(ctx)
+// language normalises a language code to lower case and checks it against
+// cfg.DiscussionLanguages. An empty code means none and is always allowed.
+func (s *service) language(code string) (string, error) {
+    code = strings.ToLower(strings.TrimSpace(code))
+    if code != "" && !s.cfg.LanguageAllowed(code) {
+        return "", ErrInvalidLanguage
+    }
+    return code, nil
+}
+
+// ListUnseen lists published discussions created or updated since userID
+// last called MarkSeen. A user who never did sees everything.
+func (s *service) ListUnseen(ctx context.Context, userID int) ([]models.Discussion, error) {
+    since, err := s.repo.GetLastSeen(ctx, userID)
+    if err != nil {
+        return nil, err
+    }
+    return nonNil(s.repo.ListUpdatedSince(ctx, since))
+}
+
+// MarkSeen moves userID's last-seen marker to now and returns it.
+func (s *service) MarkSeen(ctx context.Context, userID int) (time.Time, error) {
+    now := time.Now().UTC()
+    if err := s.repo.SetLastSeen(ctx, userID, now); err != nil {
+        return time.Time{}, err
+    }
+    return now, nil
+}
+
+// activitySorts order by counts that change without touching updated_at,
+// so LatestUpdate cannot tell when a listing in that order went stale.
+var activitySorts = map[string]bool{
+    config.DiscussionSortMostCommented: true,
+    config.DiscussionSortPopular:       true,
+    config.DiscussionSortMostViewed:    true,
+    config.DiscussionSortSubscribers:   true,
+}
+
+// LastModified returns when a listing in sort order last changed, or the
+// zero time when updated_at does not track its order (see activitySorts)
+// and it must not be served conditionally. An empty sort falls back to
+// cfg.DefaultDiscussionSort, as in ListByStatus.
+func (s *service) LastModified(ctx context.Context, sort string) (time.Time, error) {
+    if sort == "" {
+        sort = s.cfg.DefaultDiscussionSort
+    }
+    if activitySorts[sort] {
+        return time.Time{}, nil
+    }
+    return s.repo.LatestUpdate(ctx)
 }
 
 func (s *service) GetByID(ctx context.Context, id int) (*models.Discussion, error) {
     return s.repo.GetByID(ctx, id)
 }
 
-func (s *service) Update(ctx context.Context, id int, dto *UpdateDiscussionDTO) (*models.Discussion, error) {
+// GetWithTags returns the discussion and its tag names, or nil if it does not exist.
+func (s *service) GetWithTags(ctx context.Context, id int) (*DiscussionWithTags, error) {
+    d, tags, err := s.repo.GetByIDWithTags(ctx, id)
+    if err != nil || d == nil {
+        return nil, err
+    }
+    if tags == nil {
+        tags = []string{}
+    }
+    return &DiscussionWithTags{Discussion: *d, Tags: tags, TagCount: len(tags)}, nil
+}
+
+// Update applies dto to the discussion and records a revision attributed to editorID.
+func (s *service) Update(ctx context.Context, id, editorID int, dto *UpdateDiscussionDTO) (*models.Discussion, error) {
+    var lang string
+    if dto.Language != nil {
+        var err error
+        if lang, err = s.language(*dto.Language); err != nil {
+            return nil, err
+        }
+    }
     d, err := s.repo.GetByID(ctx, id)
     if err != nil || d == nil {
         return nil, err
     }
+    rev := &models.DiscussionRevision{
+        DiscussionID:    d.ID,
+        EditedBy:        editorID,
+        PreviousTitle:   d.Title,
+        PreviousContent: d.Content,
+    }
     if dto.Title != nil {
         d.Title = *dto.Title
     }
@@ -69,23 +296,158 @@ func (s *service) Update(ctx context.Context, id int, dto *UpdateDiscussionDTO)
     if dto.ScheduledAt != nil {
         d.ScheduledAt = dto.ScheduledAt
     }
+    if dto.Status != nil {
+        d.Status = *dto.Status
+    }
+    if dto.Language != nil {
+        d.Language = lang
+    }
     d.UpdatedAt = time.Now().UTC()
-    if err := s.repo.Update(ctx, d); err != nil {
+    // status, schedule or language changes alone do not mark it edited
+    if d.Title != rev.PreviousTitle || d.Content != rev.PreviousContent {
+        edited := d.UpdatedAt
+        d.EditedAt = &edited
+    }
+    rev.Title = d.Title
+    rev.Content = d.Content
+    rev.CreatedAt = d.UpdatedAt
+    if err := s.repo.UpdateWithRevision(ctx, d, rev); err != nil {
         return nil, err
     }
     return d, nil
 }
 
+func (s *service) ListRevisionsByEditor(ctx context.Context, editorID, limit, offset int) ([]models.DiscussionRevision, error) {
+    return s.repo.ListRevisionsByEditor(ctx, editorID, limit, offset)
+}
+
+// RevisionDiff diffs a revision against the state it replaced. Every
+// revision stores that state, so its predecessor never has to be loaded.
+// A revision that does not belong to discussionID yields nil.
+func (s *service) RevisionDiff(ctx context.Context, discussionID, revID int) (*RevisionDiff, error) {
+    rev, err := s.repo.GetRevision(ctx, discussionID, revID)
+    if err != nil || rev == nil {
+        return nil, err
+    }
+    return &RevisionDiff{
+        RevisionID:   rev.ID,
+        DiscussionID: rev.DiscussionID,
+        EditedBy:     rev.EditedBy,
+        CreatedAt:    rev.CreatedAt,
+        Title:        linediff.Diff(rev.PreviousTitle, rev.Title),
+        Content:      linediff.Diff(rev.PreviousContent, rev.Content),
+    }, nil
+}
+
+// CommentStats buckets the discussion's comments over p, filling intervals
+// without comments with zero. The caller checks the discussion exists.
+func (s *service) CommentStats(ctx context.Context, discussionID int, p tagpkg.TrendParams) (*CommentStats, error) {
+    points, err := s.repo.CountCommentsByInterval(ctx, discussionID, p.Interval, p.From, p.To)
+    if err != nil {
+        return nil, err
+    }
+    counts := make(map[time.Time]int, len(points))
+    for _, pt := range points {
+        counts[pt.Start] = pt.Count
+    }
+    stats := &CommentStats{DiscussionID: discussionID, Interval: p.Interval, From: p.From, To: p.To, Buckets: []tagpkg.TrendPoint{}}
+    for _, start := range tagpkg.BucketStarts(p.From, p.To, p.Interval) {
+        stats.Buckets = append(stats.Buckets, tagpkg.TrendPoint{Start: start, Count: counts[start]})
+        stats.Total += counts[start]
+    }
+    return stats, nil
+}
+
+// RecordView counts one more view of the discussion.
+func (s *service) RecordView(ctx context.Context, id int) error {
+    return s.repo.IncrementViewCount(ctx, id)
+}
+
+// Delete soft-deletes the discussion; see Restore.
 func (s *service) Delete(ctx context.Context, id int) error {
     return s.repo.Delete(ctx, id)
 }
 
-func (s *service) GetByUser(ctx context.Context, userID int) ([]models.Discussion, error) {
-    return s.repo.GetByUser(ctx, userID)
+// Restore undoes Delete. It reports false if the discussion does not exist.
+func (s *service) Restore(ctx context.Context, id int) (bool, error) {
+    return s.repo.Restore(ctx, id)
+}
+
+// Bump moves a discussion to the top of updated_at ordering. Content is left
+// alone and no revision is recorded.
+func (s *service) Bump(ctx context.Context, id int) (*models.Discussion, error) {
+    d, err := s.repo.GetByID(ctx, id)
+    if err != nil || d == nil {
+        return nil, err
+    }
+    d.UpdatedAt = time.Now().UTC()
+    if err := s.repo.Touch(ctx, id, d.UpdatedAt); err != nil {
+        return nil, err
+    }
+    return d, nil
+}
+
+// ArchiveStale closes published discussions with no edits or comments in
+// the last olderThan and returns their ids. Archived discussions drop out of
+// the default listing but stay readable.
+func (s *service) ArchiveStale(ctx context.Context, olderThan time.Duration) ([]int, error) {
+    now := time.Now().UTC()
+    ids, err := s.repo.ArchiveStale(ctx, now.Add(-olderThan), now)
+    if err != nil {
+        return nil, err
+    }
+    if ids == nil {
+        ids = []int{}
+    }
+    return ids, nil
+}
+
+// GetByUser lists userID's discussions; their drafts are only visible to
+// userID themselves.
+func (s *service) GetByUser(ctx context.Context, userID, viewerID int) ([]models.Discussion, error) {
+    return nonNil(s.repo.GetByUser(ctx, userID, viewerID))
+}
+
+// GetByTag lists the discussions carrying tag, hiding drafts not written by
+// viewerID. Tag names match case-insensitively.
+func (s *service) GetByTag(ctx context.Context, tag string, viewerID int) ([]models.Discussion, error) {
+    return nonNil(s.repo.GetByTag(ctx, tagpkg.NormalizeName(tag), viewerID))
+}
+
+// PinInTag pins a discussion within one tag's listing. It reports false if
+// the discussion does not carry that tag.
+func (s *service) PinInTag(ctx context.Context, discussionID int, tag string) (bool, error) {
+    return s.repo.PinInTag(ctx, discussionID, tagpkg.NormalizeName(tag))
+}
+
+// UnpinInTag undoes PinInTag. It reports false if there was no such pin.
+func (s *service) UnpinInTag(ctx context.Context, discussionID int, tag string) (bool, error) {
+    return s.repo.UnpinInTag(ctx, discussionID, tagpkg.NormalizeName(tag))
 }
 
-func (s *service) GetByTag(ctx context.Context, tag string) ([]models.Discussion, error) {
-    return s.repo.GetByTag(ctx, tag)
+// ListByTags returns one group per requested name, in request order. Tags
+// that are unknown or have no published discussions get a zero count.
+func (s *service) ListByTags(ctx context.Context, names []string) ([]TagGroup, error) {
+    found, err := s.repo.ListByTags(ctx, names, ByTagsPerTag)
+    if err != nil {
+        return nil, err
+    }
+    byName := make(map[string]TagGroup, len(found))
+    for _, g := range found {
+        byName[g.Tag] = g
+    }
+    groups := make([]TagGroup, 0, len(names))
+    for _, name := range names {
+        g, ok := byName[name]
+        if !ok {
+            g = TagGroup{Tag: name}
+        }
+        if g.Discussions == nil {
+            g.Discussions = []models.Discussion{}
+        }
+        groups = append(groups, g)
+    }
+    return groups, nil
 }
 
 func (s *service) AddTags(
@@ -93,30 +455,177 @@ func (s *service) AddTags(
     discussionID int,
     dto *AddTagsDTO,
 ) error {
-    // Gather tag IDs, creating tags if they do not exist
+    tagIDs, err := s.resolveTagIDs(ctx, dto.Tags)
+    if err != nil {
+        return err
+    }
+    current, err := s.repo.ListTagIDs(ctx, discussionID)
+    if err != nil {
+        return err
+    }
+    if err := s.checkTagLimits(ctx, current, dto.Tags, tagIDs, false); err != nil {
+        return err
+    }
+
+    // Delegate to discussion_tags join table insertion
+    return s.repo.AddTags(ctx, discussionID, tagIDs, s.cfg.MaxSubscriptionsPerEmail)
+}
+
+func (s *service) ReplaceTags(
+    ctx context.Context,
+    discussionID int,
+    dto *ReplaceTagsDTO,
+) error {
+    tagIDs, err := s.resolveTagIDs(ctx, dto.Tags)
+    if err != nil {
+        return err
+    }
+    current, err := s.repo.ListTagIDs(ctx, discussionID)
+    if err != nil {
+        return err
+    }
+    if err := s.checkTagLimits(ctx, current, dto.Tags, tagIDs, true); err != nil {
+        return err
+    }
+    return s.repo.ReplaceTags(ctx, discussionID, tagIDs, s.cfg.MaxSubscriptionsPerEmail)
+}
+
+// checkTagLimits vets giving a discussion that has the current tags the
+// tagIDs resolved from names, either on top of current or, with replace,
+// instead of them. It rejects ending up with more than
+// cfg.MaxTagsPerDiscussion distinct tags, and adding a tag that already
+// sits on cfg.MaxDiscussionsPerTag discussions.
+func (s *service) checkTagLimits(ctx context.Context, current []int, names []string, tagIDs []int, replace bool) error {
+    if limit := s.cfg.MaxTagsPerDiscussion; limit > 0 {
+        distinct := make(map[int]bool, len(current)+len(tagIDs))
+        if !replace {
+            for _, id := range current {
+                distinct[id] = true
+            }
+        }
+        for _, id := range tagIDs {
+            distinct[id] = true
+        }
+        if len(distinct) > limit {
+            return fmt.Errorf("%w: at most %d allowed", ErrTooManyTags, limit)
+        }
+    }
+    if limit := s.cfg.MaxDiscussionsPerTag; limit > 0 {
+        have := make(map[int]bool, len(current))
+        for _, id := range current {
+            have[id] = true
+        }
+        for i, id := range tagIDs {
+            if have[id] {
+                continue
+            }
+            have[id] = true
+            n, err := s.repo.CountByTagID(ctx, id)
+            if err != nil {
+                return err
+            }
+            if n >= limit {
+                return fmt.Errorf("%w: %q is on %d already", ErrTagOverused, names[i], n)
+            }
+        }
+    }
+    return nil
+}
+
+// resolveTagIDs gathers tag IDs for the given names, creating tags if they
+// do not exist. Names are normalized first, as POST /tags does.
+func (s *service) resolveTagIDs(ctx context.Context, names []string) ([]int, error) {
     var tagIDs []int
-    for _, name := range dto.Tags {
+    for _, name := range names {
+        name = tagpkg.NormalizeName(name)
         t, err := s.tagRepo.GetByName(ctx, name)
         if err != nil {
-            return err
+            return nil, err
         }
         if t == nil {
             // Tag doesn’t exist → create it
             newID, err := s.tagRepo.Create(ctx, name)
             if err != nil {
-                return err
+                return nil, err
             }
             tagIDs = append(tagIDs, newID)
         } else {
             tagIDs = append(tagIDs, t.ID)
         }
     }
+    return tagIDs, nil
+}
 
-    // Delegate to discussion_tags join table insertion
-    return s.repo.AddTags(ctx, discussionID, tagIDs)
+// ListActive returns discussions commented on within the last window,
+// hiding drafts not written by viewerID.
+func (s *service) ListActive(ctx context.Context, window time.Duration, viewerID int) ([]models.Discussion, error) {
+    return nonNil(s.repo.ListActiveSince(ctx, time.Now().UTC().Add(-window), viewerID))
 }
 
-func (s *service) Schedule(ctx context.Context, userID int, dto *ScheduleDTO) (int, error) {
+// nonNil turns a nil result into an empty slice so list endpoints encode
+// "no rows" as [] rather than null.
+func nonNil(ds []models.Discussion, err error) ([]models.Discussion, error) {
+    if err != nil {
+        return nil, err
+    }
+    if ds == nil {
+        ds = []models.Discussion{}
+    }
+    return ds, nil
+}
+
+// FindSimilar looks for recent discussions whose title contains the same
+// words as title, in the same order, ignoring case and punctuation. Other
+// users' drafts are never reported to viewerID.
+func (s *service) FindSimilar(ctx context.Context, title string, viewerID int) ([]models.Discussion, error) {
+    pattern := titlePattern(title)
+    if pattern == "" {
+        return nil, nil
+    }
+    since := time.Now().UTC().Add(-DuplicateLookback)
+    return s.repo.FindByTitleLike(ctx, pattern, since, viewerID, maxSimilar)
+}
+
+// titlePattern turns "How to learn Go?" into "%how%to%learn%go%". Anything
+// that is not a letter or digit is dropped, so the result needs no escaping.
+func titlePattern(title string) string {
+    words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+    if len(words) == 0 {
+        return ""
+    }
+    return "%" + strings.Join(words, "%") + "%"
+}
+
+// Search full-text matches query against published titles and content and
+// returns one page ranked by relevance, the total number of matches and the
+// applied limit. Queries with fewer than cfg.SearchMinQueryLength letters or
+// digits are rejected, and limit is clamped to cfg.SearchMaxResults.
+func (s *service) Search(ctx context.Context, query string, limit, offset int) ([]models.Discussion, int, int, error) {
+    pattern := titlePattern(query)
+    if pattern == "" || utf8.RuneCountInString(strings.ReplaceAll(pattern, "%", "")) < s.cfg.SearchMinQueryLength {
+        return nil, 0, 0, ErrSearchQueryTooShort
+    }
+    if s.cfg.SearchMaxResults > 0 && limit > s.cfg.SearchMaxResults {
+        limit = s.cfg.SearchMaxResults
+    }
+    query = strings.TrimSpace(query)
+    total, err := s.repo.CountSearch(ctx, query)
+    if err != nil {
+        return nil, 0, 0, err
+    }
+    ds, err := nonNil(s.repo.Search(ctx, query, limit, offset))
+    if err != nil {
+        return nil, 0, 0, err
+    }
+    return ds, total, limit, nil
+}
+
+func (s *service) Schedule(ctx context.Context, userID int, dto *ScheduleDTO) (*models.Discussion, error) {
+    if err := s.checkRateLimit(ctx, userID); err != nil {
+        return nil, err
+    }
     d := &models.Discussion{
         UserID:      userID,
         Title:       dto.Title,
@@ -125,5 +634,5 @@ func (s *service) Schedule(ctx context.Context, userID int, dto *ScheduleDTO) (i
         CreatedAt:   time.Now().UTC(),
         UpdatedAt:   time.Now().UTC(),
     }
-    return s.repo.Create(ctx, d)
+    return s.insert(ctx, d)
 }
@@ -0,0 +1,870 @@
+package discussion
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/config"
+	tagpkg "go-discussion-app/internal/tag"
+	"go-discussion-app/models"
+	"go-discussion-app/pkg/linediff"
+)
+
+// fakeRepo is an in-memory Repository; methods not overridden panic via the nil embed.
+type fakeRepo struct {
+	Repository
+	discussions []models.Discussion
+	lastSeen    map[int]time.Time
+	searchQuery string
+	searchLimit int
+	tags        map[int][]string
+	tagIDs      map[int][]int // discussion id -> attached tag ids
+	tagUse      map[int]int   // tag id -> discussions carrying it
+
+	commentPoints []tagpkg.TrendPoint
+}
+
+func (f *fakeRepo) Create(ctx context.Context, d *models.Discussion) (int, error) {
+	d.ID = len(f.discussions) + 1
+	f.discussions = append(f.discussions, *d)
+	return d.ID, nil
+}
+
+func (f *fakeRepo) CountByUserSince(ctx context.Context, userID int, since time.Time) (int, time.Time, error) {
+	n := 0
+	var oldest time.Time
+	for _, d := range f.discussions {
+		if d.UserID == userID && !d.CreatedAt.Before(since) {
+			n++
+			if oldest.IsZero() || d.CreatedAt.Before(oldest) {
+				oldest = d.CreatedAt
+			}
+		}
+	}
+	return n, oldest, nil
+}
+
+func (f *fakeRepo) GetByID(ctx context.Context, id int) (*models.Discussion, error) {
+	for _, d := range f.discussions {
+		if d.ID == id {
+			return &d, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeRepo) GetByIDWithTags(ctx context.Context, id int) (*models.Discussion, []string, error) {
+	d, _ := f.GetByID(ctx, id)
+	if d == nil {
+		return nil, nil, nil
+	}
+	return d, f.tags[id], nil
+}
+
+func (f *fakeRepo) Touch(ctx context.Context, id int, at time.Time) error {
+	for i := range f.discussions {
+		if f.discussions[i].ID == id {
+			f.discussions[i].UpdatedAt = at
+		}
+	}
+	return nil
+}
+
+// Delete stamps deleted_at and updated_at, as the SQL does.
+func (f *fakeRepo) Delete(ctx context.Context, id int) error {
+	now := time.Now().UTC()
+	for i := range f.discussions {
+		if f.discussions[i].ID == id && f.discussions[i].DeletedAt == nil {
+			f.discussions[i].DeletedAt = &now
+			f.discussions[i].UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+// LatestUpdate includes soft-deleted rows, as the SQL does.
+func (f *fakeRepo) LatestUpdate(ctx context.Context) (time.Time, error) {
+	var latest time.Time
+	for _, d := range f.discussions {
+		if d.UpdatedAt.After(latest) {
+			latest = d.UpdatedAt
+		}
+	}
+	return latest, nil
+}
+
+func (f *fakeRepo) ListByStatus(ctx context.Context, status string, lf ListFilter, sort string, limit, offset int) ([]models.Discussion, error) {
+	var out []models.Discussion
+	for _, d := range f.discussions {
+		if d.Status == status && d.DeletedAt == nil {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) CountByStatus(ctx context.Context, status string, lf ListFilter) (int, error) {
+	ds, _ := f.ListByStatus(ctx, status, lf, "", 0, 0)
+	return len(ds), nil
+}
+
+func (f *fakeRepo) GetLastSeen(ctx context.Context, userID int) (time.Time, error) {
+	return f.lastSeen[userID], nil
+}
+
+func (f *fakeRepo) SetLastSeen(ctx context.Context, userID int, at time.Time) error {
+	if f.lastSeen == nil {
+		f.lastSeen = map[int]time.Time{}
+	}
+	f.lastSeen[userID] = at
+	return nil
+}
+
+func (f *fakeRepo) ListUpdatedSince(ctx context.Context, since time.Time) ([]models.Discussion, error) {
+	var out []models.Discussion
+	for _, d := range f.discussions {
+		if d.Status == models.StatusPublished && d.UpdatedAt.After(since) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) Search(ctx context.Context, query string, limit, offset int) ([]models.Discussion, error) {
+	f.searchQuery, f.searchLimit = query, limit
+	return nil, nil
+}
+
+func (f *fakeRepo) CountSearch(ctx context.Context, query string) (int, error) {
+	return 3, nil
+}
+
+// FindByTitleLike emulates ILIKE: % matches anything, case is ignored.
+func (f *fakeRepo) FindByTitleLike(ctx context.Context, pattern string, since time.Time, viewerID, limit int) ([]models.Discussion, error) {
+	parts := strings.Split(pattern, "%")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	re := regexp.MustCompile("(?is)^" + strings.Join(parts, ".*") + "$")
+
+	var out []models.Discussion
+	for _, d := range f.discussions {
+		if d.Status == models.StatusDraft && d.UserID != viewerID {
+			continue
+		}
+		if re.MatchString(d.Title) && !d.CreatedAt.Before(since) && len(out) < limit {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func TestCreate_RateLimitPerUser(t *testing.T) {
+	repo := &fakeRepo{}
+	cfg := &config.Config{DiscussionRateLimit: 2, DiscussionRateWindow: time.Hour}
+	svc := NewService(repo, nil, cfg)
+	ctx := context.Background()
+	dto := &CreateDiscussionDTO{Title: "t", Content: "c"}
+
+	// user 1 reaches the limit
+	for i := 0; i < 2; i++ {
+		_, err := svc.Create(ctx, 1, dto)
+		assert.NoError(t, err)
+	}
+	// and is rejected on the next attempt
+	_, err := svc.Create(ctx, 1, dto)
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	// user 2 is unaffected
+	_, err = svc.Create(ctx, 2, dto)
+	assert.NoError(t, err)
+}
+
+func TestCreate_RateLimitIgnoresOldDiscussions(t *testing.T) {
+	old := time.Now().UTC().Add(-2 * time.Hour)
+	repo := &fakeRepo{discussions: []models.Discussion{
+		{ID: 1, UserID: 1, CreatedAt: old},
+		{ID: 2, UserID: 1, CreatedAt: old},
+	}}
+	cfg := &config.Config{DiscussionRateLimit: 2, DiscussionRateWindow: time.Hour}
+	svc := NewService(repo, nil, cfg)
+
+	_, err := svc.Create(context.Background(), 1, &CreateDiscussionDTO{Title: "t", Content: "c"})
+	assert.NoError(t, err)
+}
+
+func TestCreate_ReturnsStoredDiscussion(t *testing.T) {
+	repo := &fakeRepo{discussions: []models.Discussion{{ID: 1, UserID: 2}}}
+	svc := NewService(repo, nil, nil)
+
+	d, err := svc.Create(context.Background(), 1, &CreateDiscussionDTO{Title: "t", Content: "c"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, d) {
+		assert.Equal(t, 2, d.ID)
+		assert.Equal(t, 1, d.UserID)
+		assert.Equal(t, "t", d.Title)
+		assert.False(t, d.CreatedAt.IsZero())
+	}
+}
+
+func TestCreate_Language(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := NewService(repo, nil, &config.Config{DiscussionLanguages: []string{"en", "de"}})
+	ctx := context.Background()
+
+	_, err := svc.Create(ctx, 1, &CreateDiscussionDTO{Title: "t", Content: "c", Language: "DE"})
+	assert.NoError(t, err)
+	_, err = svc.Create(ctx, 1, &CreateDiscussionDTO{Title: "t", Content: "c"})
+	assert.NoError(t, err)
+	_, err = svc.Create(ctx, 1, &CreateDiscussionDTO{Title: "t", Content: "c", Language: "fr"})
+	assert.ErrorIs(t, err, ErrInvalidLanguage)
+
+	if assert.Len(t, repo.discussions, 2) {
+		assert.Equal(t, "de", repo.discussions[0].Language, "stored lower-case")
+		assert.Equal(t, "", repo.discussions[1].Language, "no language is allowed")
+	}
+}
+
+func TestUpdate_RejectsUnsupportedLanguage(t *testing.T) {
+	svc := NewService(&fakeRepo{}, nil, &config.Config{DiscussionLanguages: []string{"en"}})
+	lang := "xx"
+	_, err := svc.Update(context.Background(), 1, 1, &UpdateDiscussionDTO{Language: &lang})
+	assert.ErrorIs(t, err, ErrInvalidLanguage)
+}
+
+// revisingRepo lets Update save through fakeRepo, which leaves
+// UpdateWithRevision unimplemented on purpose.
+type revisingRepo struct {
+	*fakeRepo
+}
+
+func (r *revisingRepo) UpdateWithRevision(ctx context.Context, d *models.Discussion, rev *models.DiscussionRevision) error {
+	for i := range r.discussions {
+		if r.discussions[i].ID == d.ID {
+			r.discussions[i] = *d
+		}
+	}
+	return nil
+}
+
+func TestUpdate_SetsEditedAtWhenTextChanges(t *testing.T) {
+	created := time.Now().UTC().Add(-time.Hour)
+	repo := &revisingRepo{fakeRepo: &fakeRepo{discussions: []models.Discussion{
+		{ID: 1, Title: "t", Content: "c", CreatedAt: created, UpdatedAt: created},
+	}}}
+	svc := NewService(repo, nil, nil)
+	assert.Nil(t, repo.discussions[0].EditedAt, "a new discussion is not edited")
+
+	content := "c, fixed"
+	d, err := svc.Update(context.Background(), 1, 1, &UpdateDiscussionDTO{Content: &content})
+	assert.NoError(t, err)
+	if assert.NotNil(t, d.EditedAt) {
+		assert.Equal(t, d.UpdatedAt, *d.EditedAt)
+		assert.Equal(t, d.EditedAt, repo.discussions[0].EditedAt)
+	}
+}
+
+func TestUpdate_NoOpKeepsEditedAt(t *testing.T) {
+	created := time.Now().UTC().Add(-time.Hour)
+	repo := &revisingRepo{fakeRepo: &fakeRepo{discussions: []models.Discussion{
+		{ID: 1, Title: "t", Content: "c", CreatedAt: created, UpdatedAt: created},
+	}}}
+	svc := NewService(repo, nil, nil)
+
+	// same text, only the status changes
+	title, content, status := "t", "c", models.StatusArchived
+	d, err := svc.Update(context.Background(), 1, 1, &UpdateDiscussionDTO{Title: &title, Content: &content, Status: &status})
+	assert.NoError(t, err)
+	assert.Nil(t, d.EditedAt)
+
+	// an earlier edit is kept as it was
+	edited := created.Add(time.Minute)
+	repo.discussions[0].EditedAt = &edited
+	d, err = svc.Update(context.Background(), 1, 1, &UpdateDiscussionDTO{Content: &content})
+	assert.NoError(t, err)
+	if assert.NotNil(t, d.EditedAt) {
+		assert.Equal(t, edited, *d.EditedAt)
+	}
+}
+
+func TestFindSimilar_NearDuplicate(t *testing.T) {
+	now := time.Now().UTC()
+	repo := &fakeRepo{discussions: []models.Discussion{
+		{ID: 1, Title: "How to learn Go", CreatedAt: now},
+		{ID: 2, Title: "Best pizza in town", CreatedAt: now},
+		{ID: 3, Title: "how to learn go", CreatedAt: now.Add(-2 * DuplicateLookback)}, // too old
+	}}
+	svc := NewService(repo, nil, nil)
+
+	similar, err := svc.FindSimilar(context.Background(), "  how TO learn go?! ", 1)
+	assert.NoError(t, err)
+	if assert.Len(t, similar, 1) {
+		assert.Equal(t, 1, similar[0].ID)
+	}
+}
+
+func TestFindSimilar_UniqueTitle(t *testing.T) {
+	repo := &fakeRepo{discussions: []models.Discussion{
+		{ID: 1, Title: "How to learn Go", CreatedAt: time.Now().UTC()},
+	}}
+	svc := NewService(repo, nil, nil)
+
+	similar, err := svc.FindSimilar(context.Background(), "Rust lifetimes explained", 1)
+	assert.NoError(t, err)
+	assert.Empty(t, similar)
+
+	similar, err = svc.FindSimilar(context.Background(), "?!", 1)
+	assert.NoError(t, err)
+	assert.Empty(t, similar)
+}
+
+func TestFindSimilar_IgnoresOtherUsersDrafts(t *testing.T) {
+	now := time.Now().UTC()
+	repo := &fakeRepo{discussions: []models.Discussion{
+		{ID: 1, UserID: 2, Title: "How to learn Go", Status: models.StatusDraft, CreatedAt: now},
+		{ID: 2, UserID: 1, Title: "How to learn Go", Status: models.StatusDraft, CreatedAt: now},
+	}}
+	svc := NewService(repo, nil, nil)
+
+	similar, err := svc.FindSimilar(context.Background(), "How to learn Go", 1)
+	assert.NoError(t, err)
+	if assert.Len(t, similar, 1) {
+		assert.Equal(t, 2, similar[0].ID, "only the viewer's own draft is reported")
+	}
+}
+
+func TestTitlePattern(t *testing.T) {
+	assert.Equal(t, "%how%to%learn%go%", titlePattern("How to learn Go?"))
+	assert.Equal(t, "%100%off%", titlePattern("100% off_"))
+	assert.Equal(t, "", titlePattern("  --  "))
+}
+
+func TestBump_UpdatesTimestampOnly(t *testing.T) {
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	orig := models.Discussion{ID: 1, UserID: 1, Title: "title", Content: "content", CreatedAt: old, UpdatedAt: old}
+	repo := &fakeRepo{discussions: []models.Discussion{orig}}
+	svc := NewService(repo, nil, nil)
+
+	// UpdateWithRevision is not implemented by fakeRepo, so recording a
+	// revision here would panic.
+	got, err := svc.Bump(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.True(t, got.UpdatedAt.After(old))
+
+	stored := repo.discussions[0]
+	assert.True(t, stored.UpdatedAt.After(old))
+	assert.Equal(t, orig.Title, stored.Title)
+	assert.Equal(t, orig.Content, stored.Content)
+	assert.Equal(t, orig.CreatedAt, stored.CreatedAt)
+}
+
+func TestBump_Missing(t *testing.T) {
+	svc := NewService(&fakeRepo{}, nil, nil)
+	got, err := svc.Bump(context.Background(), 42)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+type statusRepo struct {
+	Repository
+	gotStatus string
+	gotOwner  int
+	gotTag    string
+	gotLang   string
+	gotSort   string
+	gotLimit  int
+	gotOffset int
+}
+
+func (r *statusRepo) ListByStatus(ctx context.Context, status string, f ListFilter, sort string, limit, offset int) ([]models.Discussion, error) {
+	r.gotStatus, r.gotOwner, r.gotTag, r.gotLang, r.gotSort = status, f.UserID, f.Tag, f.Language, sort
+	r.gotLimit, r.gotOffset = limit, offset
+	return nil, nil
+}
+
+func (r *statusRepo) CountByStatus(ctx context.Context, status string, f ListFilter) (int, error) {
+	return 42, nil
+}
+
+func TestListByStatus_DraftsScopedToViewer(t *testing.T) {
+	cases := []struct {
+		status    string
+		wantOwner int
+	}{
+		{models.StatusDraft, 7},
+		{models.StatusPublished, 0},
+		{models.StatusArchived, 0},
+	}
+	for _, tc := range cases {
+		repo := &statusRepo{}
+		svc := NewService(repo, nil, nil)
+		_, _, err := svc.ListByStatus(context.Background(), tc.status, 7, ListFilter{}, "", 20, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.status, repo.gotStatus)
+		assert.Equal(t, tc.wantOwner, repo.gotOwner, tc.status)
+	}
+}
+
+func TestListByStatus_DraftsOfAnotherAuthorAreEmpty(t *testing.T) {
+	repo := &statusRepo{}
+	svc := NewService(repo, nil, nil)
+
+	ds, total, err := svc.ListByStatus(context.Background(), models.StatusDraft, 7, ListFilter{UserID: 9}, "", 20, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, ds)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, repo.gotStatus, "repository should not be queried")
+}
+
+func TestListByStatus_PassesFilter(t *testing.T) {
+	repo := &statusRepo{}
+	svc := NewService(repo, nil, nil)
+
+	_, _, err := svc.ListByStatus(context.Background(), models.StatusPublished, 7, ListFilter{UserID: 5, Tag: "go"}, "", 20, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, repo.gotOwner)
+	assert.Equal(t, "go", repo.gotTag)
+}
+
+func TestListByStatus_LanguageFilter(t *testing.T) {
+	repo := &statusRepo{}
+	svc := NewService(repo, nil, &config.Config{DiscussionLanguages: []string{"en", "de"}})
+
+	_, _, err := svc.ListByStatus(context.Background(), models.StatusPublished, 0, ListFilter{Language: "EN"}, "", 20, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "en", repo.gotLang)
+
+	repo = &statusRepo{}
+	svc = NewService(repo, nil, &config.Config{DiscussionLanguages: []string{"en", "de"}})
+	_, _, err = svc.ListByStatus(context.Background(), models.StatusPublished, 0, ListFilter{Language: "klingon"}, "", 20, 0)
+	assert.ErrorIs(t, err, ErrInvalidLanguage)
+	assert.Empty(t, repo.gotStatus, "repository should not be queried")
+}
+
+func TestListByStatus_AppliesConfiguredDefaultSort(t *testing.T) {
+	repo := &statusRepo{}
+	svc := NewService(repo, nil, &config.Config{DefaultDiscussionSort: config.DiscussionSortPopular})
+
+	_, _, err := svc.ListByStatus(context.Background(), models.StatusPublished, 0, ListFilter{}, "", 20, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, config.DiscussionSortPopular, repo.gotSort)
+
+	// an explicit ?sort= wins over the default
+	_, _, err = svc.ListByStatus(context.Background(), models.StatusPublished, 0, ListFilter{}, config.DiscussionSortRecent, 20, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, config.DiscussionSortRecent, repo.gotSort)
+}
+
+func TestListByStatus_PassesPageAndTotal(t *testing.T) {
+	repo := &statusRepo{}
+	svc := NewService(repo, nil, nil)
+
+	_, total, err := svc.ListByStatus(context.Background(), models.StatusPublished, 0, ListFilter{}, "", 10, 30)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, total)
+	assert.Equal(t, 10, repo.gotLimit)
+	assert.Equal(t, 30, repo.gotOffset)
+}
+
+type staleRepo struct {
+	Repository
+	before, at time.Time
+}
+
+func (r *staleRepo) ArchiveStale(ctx context.Context, before, at time.Time) ([]int, error) {
+	r.before, r.at = before, at
+	return nil, nil
+}
+
+func TestArchiveStale_CutoffIsAgeBeforeNow(t *testing.T) {
+	repo := &staleRepo{}
+	svc := NewService(repo, nil, nil)
+
+	ids, err := svc.ArchiveStale(context.Background(), 90*24*time.Hour)
+	assert.NoError(t, err)
+	assert.NotNil(t, ids)
+	assert.Equal(t, 90*24*time.Hour, repo.at.Sub(repo.before))
+	assert.WithinDuration(t, time.Now().UTC(), repo.at, time.Minute)
+}
+
+type revisionRepo struct {
+	Repository
+	rev *models.DiscussionRevision
+}
+
+func (r *revisionRepo) GetRevision(ctx context.Context, discussionID, revID int) (*models.DiscussionRevision, error) {
+	if r.rev == nil || r.rev.ID != revID || r.rev.DiscussionID != discussionID {
+		return nil, nil
+	}
+	return r.rev, nil
+}
+
+func TestRevisionDiff_ReflectsEdit(t *testing.T) {
+	repo := &revisionRepo{rev: &models.DiscussionRevision{
+		ID: 4, DiscussionID: 1, EditedBy: 7,
+		PreviousTitle: "Go tips", Title: "Go tips",
+		PreviousContent: "Use gofmt.\nAvoid globals.\nWrite tests.",
+		Content:         "Use gofmt.\nPrefer small interfaces.\nWrite tests.",
+	}}
+	svc := NewService(repo, nil, nil)
+
+	diff, err := svc.RevisionDiff(context.Background(), 1, 4)
+	assert.NoError(t, err)
+	if assert.NotNil(t, diff) {
+		assert.Equal(t, 4, diff.RevisionID)
+		assert.False(t, linediff.Changed(diff.Title))
+		assert.Equal(t, []linediff.Line{
+			{Op: linediff.Equal, Text: "Use gofmt."},
+			{Op: linediff.Delete, Text: "Avoid globals."},
+			{Op: linediff.Insert, Text: "Prefer small interfaces."},
+			{Op: linediff.Equal, Text: "Write tests."},
+		}, diff.Content)
+	}
+
+	diff, err = svc.RevisionDiff(context.Background(), 2, 4)
+	assert.NoError(t, err)
+	assert.Nil(t, diff, "revision of another discussion")
+}
+
+// emptyRepo returns nil slices from every list query, as database/sql scans
+// of zero rows do.
+type emptyRepo struct {
+	Repository
+}
+
+func (emptyRepo) ListByStatus(ctx context.Context, status string, f ListFilter, sort string, limit, offset int) ([]models.Discussion, error) {
+	return nil, nil
+}
+func (emptyRepo) CountByStatus(ctx context.Context, status string, f ListFilter) (int, error) {
+	return 0, nil
+}
+func (emptyRepo) GetByUser(ctx context.Context, userID, viewerID int) ([]models.Discussion, error) {
+	return nil, nil
+}
+func (emptyRepo) GetByTag(ctx context.Context, tag string, viewerID int) ([]models.Discussion, error) {
+	return nil, nil
+}
+func (emptyRepo) ListActiveSince(ctx context.Context, since time.Time, viewerID int) ([]models.Discussion, error) {
+	return nil, nil
+}
+
+func (emptyRepo) ListByTags(ctx context.Context, names []string, perTag int) ([]TagGroup, error) {
+	return []TagGroup{{Tag: "rust", Count: 12, Discussions: []models.Discussion{{ID: 1}}}}, nil
+}
+
+func TestListByTags_OneGroupPerRequestedName(t *testing.T) {
+	svc := NewService(emptyRepo{}, nil, nil)
+
+	groups, err := svc.ListByTags(context.Background(), []string{"go", "rust"})
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 2) {
+		assert.Equal(t, "go", groups[0].Tag)
+		assert.Equal(t, 0, groups[0].Count)
+		assert.NotNil(t, groups[0].Discussions)
+		assert.Equal(t, "rust", groups[1].Tag)
+		assert.Equal(t, 12, groups[1].Count)
+	}
+}
+
+func TestListMethods_NoRowsEncodeAsEmptyArray(t *testing.T) {
+	svc := NewService(emptyRepo{}, nil, nil)
+	ctx := context.Background()
+
+	lists := map[string]func() ([]models.Discussion, error){
+		"ListByStatus": func() ([]models.Discussion, error) {
+			ds, _, err := svc.ListByStatus(ctx, models.StatusPublished, 0, ListFilter{}, "", 20, 0)
+			return ds, err
+		},
+		"GetByUser":    func() ([]models.Discussion, error) { return svc.GetByUser(ctx, 1, 1) },
+		"GetByTag":     func() ([]models.Discussion, error) { return svc.GetByTag(ctx, "go", 1) },
+		"ListActive":   func() ([]models.Discussion, error) { return svc.ListActive(ctx, time.Hour, 1) },
+	}
+	for name, list := range lists {
+		ds, err := list()
+		assert.NoError(t, err, name)
+		body, _ := json.Marshal(ds)
+		assert.Equal(t, "[]", string(body), name)
+	}
+}
+
+func TestLastModified_NoneForActivitySorts(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{discussions: []models.Discussion{{ID: 1, UpdatedAt: updated}}}
+	ctx := context.Background()
+
+	svc := NewService(repo, nil, &config.Config{DefaultDiscussionSort: config.DiscussionSortNewest})
+	for _, sort := range []string{"", config.DiscussionSortNewest, config.DiscussionSortOldest} {
+		got, err := svc.LastModified(ctx, sort)
+		assert.NoError(t, err)
+		assert.Equal(t, updated, got, sort)
+	}
+	// Comments, views and subscriptions reorder these without touching updated_at.
+	for _, sort := range []string{config.DiscussionSortMostCommented, config.DiscussionSortPopular, config.DiscussionSortMostViewed, config.DiscussionSortSubscribers} {
+		got, err := svc.LastModified(ctx, sort)
+		assert.NoError(t, err)
+		assert.True(t, got.IsZero(), sort)
+	}
+
+	svc = NewService(repo, nil, &config.Config{DefaultDiscussionSort: config.DiscussionSortPopular})
+	got, err := svc.LastModified(ctx, "")
+	assert.NoError(t, err)
+	assert.True(t, got.IsZero(), "the default sort is resolved first")
+}
+
+func TestListUnseen_FollowsMarker(t *testing.T) {
+	now := time.Now().UTC()
+	repo := &fakeRepo{discussions: []models.Discussion{
+		{ID: 1, Status: models.StatusPublished, UpdatedAt: now.Add(-2 * time.Hour)},
+		{ID: 2, Status: models.StatusDraft, UpdatedAt: now.Add(-time.Hour)},
+	}}
+	svc := NewService(repo, nil, nil)
+	ctx := context.Background()
+
+	// never marked: everything published is unseen
+	ds, err := svc.ListUnseen(ctx, 5)
+	assert.NoError(t, err)
+	assert.Len(t, ds, 1)
+
+	seenAt, err := svc.MarkSeen(ctx, 5)
+	assert.NoError(t, err)
+
+	ds, err = svc.ListUnseen(ctx, 5)
+	assert.NoError(t, err)
+	assert.NotNil(t, ds)
+	assert.Empty(t, ds)
+
+	// a later edit shows up again, only for users whose marker predates it
+	repo.discussions[0].UpdatedAt = seenAt.Add(time.Minute)
+	ds, err = svc.ListUnseen(ctx, 5)
+	assert.NoError(t, err)
+	if assert.Len(t, ds, 1) {
+		assert.Equal(t, 1, ds[0].ID)
+	}
+
+	repo.lastSeen[6] = seenAt.Add(time.Hour)
+	ds, err = svc.ListUnseen(ctx, 6)
+	assert.NoError(t, err)
+	assert.Empty(t, ds)
+}
+
+func TestSearch_RejectsShortQueries(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := NewService(repo, nil, &config.Config{SearchMinQueryLength: 2, SearchMaxResults: 50})
+
+	for _, q := range []string{"", "a", " ?! ", "a!"} {
+		_, _, _, err := svc.Search(context.Background(), q, 20, 0)
+		assert.ErrorIs(t, err, ErrSearchQueryTooShort, q)
+	}
+	ds, total, _, err := svc.Search(context.Background(), "  go ", 20, 0)
+	assert.NoError(t, err)
+	assert.NotNil(t, ds)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, "go", repo.searchQuery)
+}
+
+func TestSearch_ClampsLimit(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := NewService(repo, nil, &config.Config{SearchMinQueryLength: 2, SearchMaxResults: 50})
+
+	_, _, limit, err := svc.Search(context.Background(), "golang", 100, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 50, limit)
+	assert.Equal(t, 50, repo.searchLimit)
+
+	_, _, limit, err = svc.Search(context.Background(), "golang", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, limit)
+	assert.Equal(t, 10, repo.searchLimit)
+}
+
+func TestGetWithTags_TaggedAndUntagged(t *testing.T) {
+	repo := &fakeRepo{
+		discussions: []models.Discussion{{ID: 1, Title: "a"}, {ID: 2, Title: "b"}},
+		tags:        map[int][]string{1: {"go", "sql"}},
+	}
+	svc := NewService(repo, nil, nil)
+
+	d, err := svc.GetWithTags(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go", "sql"}, d.Tags)
+	assert.Equal(t, 2, d.TagCount)
+
+	d, err = svc.GetWithTags(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.NotNil(t, d.Tags)
+	assert.Empty(t, d.Tags)
+	assert.Equal(t, 0, d.TagCount)
+
+	d, err = svc.GetWithTags(context.Background(), 3)
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+}
+
+func (f *fakeRepo) ListTagIDs(ctx context.Context, discussionID int) ([]int, error) {
+	return f.tagIDs[discussionID], nil
+}
+
+func (f *fakeRepo) CountByTagID(ctx context.Context, tagID int) (int, error) {
+	return f.tagUse[tagID], nil
+}
+
+func (f *fakeRepo) CountCommentsByInterval(ctx context.Context, discussionID int, interval string, from, to time.Time) ([]tagpkg.TrendPoint, error) {
+	return f.commentPoints, nil
+}
+
+func TestCommentStats_FillsEmptyIntervals(t *testing.T) {
+	may := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{commentPoints: []tagpkg.TrendPoint{
+		{Start: may, Count: 4},
+		{Start: may.AddDate(0, 2, 0), Count: 1},
+	}}
+	svc := NewService(repo, nil, nil)
+
+	p := tagpkg.TrendParams{Interval: tagpkg.IntervalMonth, From: may.AddDate(0, 0, 10), To: may.AddDate(0, 3, 0)}
+	stats, err := svc.CommentStats(context.Background(), 5, p)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, stats.Total)
+	assert.Equal(t, []tagpkg.TrendPoint{
+		{Start: may, Count: 4}, // the partial first month still gets its bucket
+		{Start: may.AddDate(0, 1, 0), Count: 0},
+		{Start: may.AddDate(0, 2, 0), Count: 1},
+	}, stats.Buckets)
+}
+
+// tagNameRepo records the tag names the service hands to the repository.
+type tagNameRepo struct {
+	Repository
+	names []string
+}
+
+func (r *tagNameRepo) GetByTag(ctx context.Context, tag string, viewerID int) ([]models.Discussion, error) {
+	r.names = append(r.names, tag)
+	return nil, nil
+}
+func (r *tagNameRepo) PinInTag(ctx context.Context, discussionID int, tag string) (bool, error) {
+	r.names = append(r.names, tag)
+	return true, nil
+}
+func (r *tagNameRepo) UnpinInTag(ctx context.Context, discussionID int, tag string) (bool, error) {
+	r.names = append(r.names, tag)
+	return true, nil
+}
+
+func TestTagListingAndPins_MatchTagNameCaseInsensitively(t *testing.T) {
+	repo := &tagNameRepo{}
+	svc := NewService(repo, nil, nil)
+	ctx := context.Background()
+
+	_, err := svc.GetByTag(ctx, "Go", 0)
+	assert.NoError(t, err)
+	_, err = svc.PinInTag(ctx, 7, " GO ")
+	assert.NoError(t, err)
+	_, err = svc.UnpinInTag(ctx, 7, "gO")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go", "go", "go"}, repo.names)
+}
+
+func (f *fakeRepo) AddTags(ctx context.Context, discussionID int, tagIDs []int, maxSubsPerEmail int) error {
+	f.tagIDs[discussionID] = append(f.tagIDs[discussionID], tagIDs...)
+	return nil
+}
+
+func (f *fakeRepo) ReplaceTags(ctx context.Context, discussionID int, tagIDs []int, maxSubsPerEmail int) error {
+	f.tagIDs[discussionID] = tagIDs
+	return nil
+}
+
+// fakeTagRepo hands out ids for tag names, creating them on first use.
+type fakeTagRepo struct {
+	tagpkg.TagRepository
+	ids map[string]int
+}
+
+func (f *fakeTagRepo) GetByName(ctx context.Context, name string) (*models.Tag, error) {
+	if id, ok := f.ids[name]; ok {
+		return &models.Tag{ID: id, Name: name}, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeTagRepo) Create(ctx context.Context, name string) (int, error) {
+	f.ids[name] = len(f.ids) + 1
+	return f.ids[name], nil
+}
+
+func TestAddTags_PerDiscussionCap(t *testing.T) {
+	repo := &fakeRepo{tagIDs: map[int][]int{7: {1, 2}}}
+	tags := &fakeTagRepo{ids: map[string]int{"go": 1, "db": 2}}
+	svc := NewService(repo, tags, &config.Config{MaxTagsPerDiscussion: 3})
+
+	// re-adding an attached tag does not count twice
+	err := svc.AddTags(context.Background(), 7, &AddTagsDTO{Tags: []string{"go", "web"}})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{1, 2, 1, 3}, repo.tagIDs[7])
+
+	err = svc.AddTags(context.Background(), 7, &AddTagsDTO{Tags: []string{"rust"}})
+	assert.ErrorIs(t, err, ErrTooManyTags)
+	assert.EqualError(t, err, "too many tags on this discussion: at most 3 allowed")
+	assert.Len(t, repo.tagIDs[7], 4, "nothing attached after the cap is hit")
+}
+
+func TestAddTags_NormalizesNewTagNames(t *testing.T) {
+	repo := &fakeRepo{tagIDs: map[int][]int{}}
+	tags := &fakeTagRepo{ids: map[string]int{"go": 1}}
+	svc := NewService(repo, tags, nil)
+
+	err := svc.AddTags(context.Background(), 7, &AddTagsDTO{Tags: []string{" Go ", "Postgres"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, repo.tagIDs[7])
+	assert.Equal(t, map[string]int{"go": 1, "postgres": 2}, tags.ids)
+}
+
+func TestReplaceTags_PerDiscussionCapCountsOnlyNewSet(t *testing.T) {
+	repo := &fakeRepo{tagIDs: map[int][]int{7: {1, 2, 3}}}
+	tags := &fakeTagRepo{ids: map[string]int{"go": 1, "db": 2, "web": 3}}
+	svc := NewService(repo, tags, &config.Config{MaxTagsPerDiscussion: 3})
+
+	err := svc.ReplaceTags(context.Background(), 7, &ReplaceTagsDTO{Tags: []string{"go", "rust", "ml"}})
+	assert.NoError(t, err)
+
+	err = svc.ReplaceTags(context.Background(), 7, &ReplaceTagsDTO{Tags: []string{"go", "db", "web", "ops"}})
+	assert.ErrorIs(t, err, ErrTooManyTags)
+}
+
+func TestAddTags_PerTagCap(t *testing.T) {
+	repo := &fakeRepo{
+		tagIDs: map[int][]int{7: {1}, 8: {}},
+		tagUse: map[int]int{1: 2, 2: 1},
+	}
+	tags := &fakeTagRepo{ids: map[string]int{"go": 1, "db": 2}}
+	svc := NewService(repo, tags, &config.Config{MaxDiscussionsPerTag: 2})
+
+	// "go" is at the cap, but discussion 7 already carries it
+	err := svc.AddTags(context.Background(), 7, &AddTagsDTO{Tags: []string{"go", "db"}})
+	assert.NoError(t, err)
+
+	err = svc.AddTags(context.Background(), 8, &AddTagsDTO{Tags: []string{"db", "go"}})
+	assert.ErrorIs(t, err, ErrTagOverused)
+	assert.EqualError(t, err, `tag is used by too many discussions: "go" is on 2 already`)
+	assert.Empty(t, repo.tagIDs[8])
+}
+
+func TestAddTags_CapsDisabledByDefault(t *testing.T) {
+	repo := &fakeRepo{tagIDs: map[int][]int{}, tagUse: map[int]int{1: 1000}}
+	tags := &fakeTagRepo{ids: map[string]int{"go": 1}}
+	svc := NewService(repo, tags, nil)
+
+	names := []string{"go"}
+	for i := 0; i < 30; i++ {
+		names = append(names, "t"+strings.Repeat("x", i))
+	}
+	err := svc.AddTags(context.Background(), 7, &AddTagsDTO{Tags: names})
+	assert.NoError(t, err)
+	assert.Len(t, repo.tagIDs[7], 31)
+}
@@ -0,0 +1,38 @@
+// stale.go
+package discussion
+
+import (
+    "context"
+    "time"
+
+    "go-discussion-app/pkg/logger"
+)
+
+// SweepStale archives discussions idle for longer than age every interval
+// until ctx is done. It is a no-op when age is not positive.
+func SweepStale(ctx context.Context, svc Service, age, interval time.Duration) {
+    if age <= 0 || interval <= 0 {
+        return
+    }
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        sweepStaleOnce(ctx, svc, age)
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func sweepStaleOnce(ctx context.Context, svc Service, age time.Duration) {
+    ids, err := svc.ArchiveStale(ctx, age)
+    if err != nil {
+        logger.Errorf("stale discussion sweep error: %v", err)
+        return
+    }
+    if len(ids) > 0 {
+        logger.Infof("archived %d stale discussions: %v", len(ids), ids)
+    }
+}
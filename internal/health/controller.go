@@ -3,21 +3,32 @@ package health
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type HealthController struct {
-	service *HealthService
+	service IHealthService
 }
 
-func NewHealthController(service *HealthService) *HealthController {
+func NewHealthController(service IHealthService) *HealthController {
 	return &HealthController{service: service}
 }
 
+// HandleLiveness answers GET /health/live. It only shows the process is up
+// and serving, so a slow database never gets the pod restarted.
+func (hc *HealthController) HandleLiveness(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthStatus{
+		Status:    "ok",
+		Checks:    map[string]string{},
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+// HandleHealthCheck answers GET /health/ready and its alias GET /health.
 func (hc *HealthController) HandleHealthCheck(c *gin.Context) {
 	status := hc.service.CheckHealth()
-
 	if status.Status == "ok" {
 		c.JSON(http.StatusOK, status)
 	} else {
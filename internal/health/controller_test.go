@@ -2,22 +2,18 @@ package health
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-// IHealthService is an interface that MockHealthService will implement.
-// It mirrors the methods of HealthService that the controller uses.
-type IHealthService interface {
-	CheckHealth() HealthStatus
-}
-
 // MockHealthService is a mock implementation of IHealthService
 type MockHealthService struct {
 	mock.Mock
@@ -28,121 +24,18 @@ func (m *MockHealthService) CheckHealth() HealthStatus {
 	return args.Get(0).(HealthStatus)
 }
 
-// Helper function to set up the Gin router with HealthController
-// This setup assumes HealthController can accept an IHealthService.
-// If HealthController strictly requires *HealthService (concrete struct),
-// this setup would need adjustment, or the controller refactored.
-// For this test, we proceed assuming the controller can work with the interface.
+// setupHealthTestRouter serves the health routes from a controller backed
+// by service.
 func setupHealthTestRouter(service IHealthService) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-
-	// This is where the injection happens.
-	// The actual NewHealthController takes *HealthService.
-	// To make this testable without changing production code for DI,
-	// we'd typically pass a real HealthService whose *own* dependencies (like DB) are mocked.
-	// However, for a pure controller test, mocking the service it calls is standard.
-	// Let's assume we can adapt the controller or provide a real service that uses a mock DB indirectly.
-	// For this exercise, I'll mock the service interface as it's the cleanest controller test.
-	// This implies that `NewHealthController` should ideally take `IHealthService`.
-	// If it doesn't, the alternative is to pass `NewHealthController(nil)` and then
-	// try to set its service field, or test controller+service as a unit.
-
-	// Let's write the test as if `NewHealthController` accepts our `IHealthService` mock.
-	// This is a common pattern if the code is designed for testability.
-	// If the actual `NewHealthController` panics with `IHealthService`, then the code isn't easily unit-testable at this layer without changes.
-	// For the purpose of this tool, assume `controller.service = serviceInterface` can be done.
-
-	// The actual NewHealthController takes *health.HealthService.
-	// The mock service needs to be adapted or the controller needs to take an interface.
-	// Let's make the mock usable by creating a real HealthController and setting its service field
-	// if possible, or by assuming NewHealthController can take the interface.
-	// Since HealthController has `service *HealthService`, we can't directly pass IHealthService.
-	//
-	// The most practical way to test this controller without changing its signature,
-	// and without mocking the DB via sqlmock (which is for testing the service itself),
-	// is to have the MockHealthService's CheckHealth be called by the real HealthService.
-	// This is not possible if HealthService directly calls hs.db.Ping().
-	//
-	// So, the mock should be for `HealthService.CheckHealth()`.
-	// We will pass the `*MockHealthService` to `NewHealthController`. This requires
-	// `NewHealthController` to be able to take this type, or an interface it implements.
-	// Since `NewHealthController` takes `*HealthService`, our `MockHealthService`
-	// cannot be passed directly.
-	//
-	// The most straightforward way for this exercise is to assume `HealthController`
-	// is refactored to take an interface `IHealthService`.
-
-	healthController := NewHealthController(service.(*HealthService)) // This line is problematic if service is IHealthService.
-                                                                    // It should be: healthController := NewHealthController(service)
-                                                                    // And NewHealthController should take IHealthService.
-                                                                    // For now, I will write the mock and tests as if this is the case.
-                                                                    // The tool should generate a MockHealthService that implements IHealthService.
-                                                                    // And the setup will use that.
-
-	// Corrected approach for mocking:
-	// The controller takes `*HealthService`. The service calls `hs.db.Ping()`.
-	// To test the controller, we need to control what `hc.service.CheckHealth()` returns.
-	// So, we mock `HealthService` itself.
-	// This means `MockHealthService` should effectively "be" a `HealthService` for the test.
-
-	// Let's define MockHealthService such that it can be used by the controller.
-	// This means it should have the same methods as HealthService that controller calls.
-	// The controller calls `hc.service.CheckHealth()`.
-	// So, `MockHealthService` needs `CheckHealth`.
-
-	// The `NewHealthController` takes `*health.HealthService`.
-	// Our `service` param is `IHealthService`.
-	// This setup highlights the need for DI with interfaces in the production code.
-	// For the test to work, we'll assume `NewHealthController` can accept our `MockHealthService`
-	// cast to `*health.HealthService` if the mock is structured carefully, or (preferably)
-	// that `NewHealthController` takes an interface.
-	// I'll write the mock to implement `IHealthService` and the test will pass this to `NewHealthController`.
-	// This implies `NewHealthController` has to be flexible or take an interface.
-
-	// Let the `service` parameter be of the concrete type `*MockHealthService` for clarity in test.
-	// And `NewHealthController` will take this `*MockHealthService` cast as `*HealthService`.
-	// This is not type-safe.
-	// The cleanest is: `NewHealthController` takes `IHealthService`.
-
-	controllerToTest := NewHealthController(service.(IHealthService).(*HealthService)) // This is still not right.
-	// If `service` is our `*MockHealthService` which implements `IHealthService`.
-	// And `NewHealthController` takes `*HealthService` (concrete).
-	// We can't pass `*MockHealthService` to `NewHealthController(*HealthService)`.
-
-	// The most robust way to write this test without altering production code:
-	// 1. `MockHealthService` will be the mock.
-	// 2. `NewHealthController` takes `*HealthService`.
-	// 3. In the test, we create `NewHealthController(mockHealthServiceAdaptor)`
-	//    where `mockHealthServiceAdaptor` is a `*HealthService` whose methods are shimmed
-	//    to call our `MockHealthService`. This is overly complex.
-
-	// Simpler for tool: Assume NewHealthController takes IHealthService.
-	healthControllerActual := NewTestableHealthController(service)
-
-
-	router.GET("/health", healthControllerActual.HandleHealthCheck)
+	healthController := NewHealthController(service)
+	router.GET("/health/live", healthController.HandleLiveness)
+	router.GET("/health/ready", healthController.HandleHealthCheck)
+	router.GET("/health", healthController.HandleHealthCheck)
 	return router
 }
 
-// NewTestableHealthController allows injecting IHealthService for testing.
-// This would be the ideal constructor in health.go for testability.
-type TestableHealthController struct {
-	service IHealthService // Use the interface
-}
-func NewTestableHealthController(service IHealthService) *TestableHealthController {
-	return &TestableHealthController{service: service}
-}
-func (hc *TestableHealthController) HandleHealthCheck(c *gin.Context) { // Copied from actual controller
-	status := hc.service.CheckHealth()
-	if status.Status == "ok" {
-		c.JSON(http.StatusOK, status)
-	} else {
-		c.JSON(http.StatusServiceUnavailable, status)
-	}
-}
-
-
 func performHealthRequest(r http.Handler, method, path string) *httptest.ResponseRecorder {
 	req, _ := http.NewRequest(method, path, nil)
 	w := httptest.NewRecorder()
@@ -194,3 +87,65 @@ func TestHealthCheck_StatusFail(t *testing.T) {
 	assert.Equal(t, expectedStatus.Checks, actualStatus.Checks)
 	mockService.AssertExpectations(t)
 }
+
+func TestHealthLive_DoesNotCheckDependencies(t *testing.T) {
+	mockService := new(MockHealthService)
+	router := setupHealthTestRouter(mockService)
+
+	w := performHealthRequest(router, "GET", "/health/live")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var actualStatus HealthStatus
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actualStatus))
+	assert.Equal(t, "ok", actualStatus.Status)
+	mockService.AssertNotCalled(t, "CheckHealth")
+}
+
+func TestHealthReady_StatusOkAndFail(t *testing.T) {
+	for _, tc := range []struct {
+		status string
+		code   int
+	}{
+		{"ok", http.StatusOK},
+		{"fail", http.StatusServiceUnavailable},
+	} {
+		mockService := new(MockHealthService)
+		router := setupHealthTestRouter(mockService)
+		mockService.On("CheckHealth").Return(HealthStatus{
+			Status:    tc.status,
+			Checks:    map[string]string{"database": tc.status},
+			Timestamp: time.Now().UTC(),
+		})
+
+		w := performHealthRequest(router, "GET", "/health/ready")
+
+		assert.Equal(t, tc.code, w.Code, tc.status)
+		var actualStatus HealthStatus
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actualStatus))
+		assert.Equal(t, map[string]string{"database": tc.status}, actualStatus.Checks)
+		mockService.AssertExpectations(t)
+	}
+}
+
+func TestRegisterRoutes_LiveSkipsDatabasePing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, sqlMock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	router := gin.New()
+	RegisterRoutes(router, db, nil)
+
+	// a failing database leaves the pod alive but not ready
+	w := performHealthRequest(router, "GET", "/health/live")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	sqlMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	w = performHealthRequest(router, "GET", "/health/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	sqlMock.ExpectPing()
+	w = performHealthRequest(router, "GET", "/health")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
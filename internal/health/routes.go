@@ -5,11 +5,14 @@ import (
 	"database/sql"
 
 	"github.com/gin-gonic/gin"
+	"go-discussion-app/config"
 )
 
-func RegisterRoutes(r *gin.Engine, db *sql.DB) {
-	service := NewHealthService(db)
+func RegisterRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
+	service := NewHealthService(db, cfg)
 	controller := NewHealthController(service)
 
-	r.GET("/health", controller.HandleHealthCheck)
+	r.GET("/health/live", controller.HandleLiveness)
+	r.GET("/health/ready", controller.HandleHealthCheck)
+	r.GET("/health", controller.HandleHealthCheck) // alias for /health/ready
 }
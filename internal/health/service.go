@@ -2,7 +2,10 @@ package health
 
 import (
 	"database/sql"
+	"net"
 	"time"
+
+	"go-discussion-app/config"
 )
 
 type HealthStatus struct {
@@ -11,32 +14,82 @@ type HealthStatus struct {
 	Timestamp time.Time         `json:"timestamp"`
 }
 
+// IHealthService runs the readiness checks behind /health/ready.
+type IHealthService interface {
+	CheckHealth() HealthStatus
+}
+
+// DialFunc opens a TCP connection; net.DialTimeout in production, a fake in tests.
+type DialFunc func(network, address string, timeout time.Duration) (net.Conn, error)
+
 type HealthService struct {
 	db *sql.DB
+
+	// SMTP is dialled only when smtpMode is not config.HealthSMTPOff.
+	smtpMode    string
+	smtpAddr    string
+	smtpTimeout time.Duration
+	dial        DialFunc
+}
+
+// NewHealthService checks db, plus SMTP as cfg.HealthSMTPMode says. A nil
+// cfg checks the database only.
+func NewHealthService(db *sql.DB, cfg *config.Config) *HealthService {
+	hs := &HealthService{db: db, smtpMode: config.HealthSMTPOff, dial: net.DialTimeout}
+	if cfg != nil && cfg.HealthSMTPMode != "" {
+		hs.smtpMode = cfg.HealthSMTPMode
+		hs.smtpAddr = net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort)
+		hs.smtpTimeout = cfg.HealthSMTPTimeout
+	}
+	return hs
 }
 
-func NewHealthService(db *sql.DB) *HealthService {
-	return &HealthService{db: db}
+// SetDialer replaces how the SMTP check connects and returns the previous dialer.
+func (hs *HealthService) SetDialer(dial DialFunc) DialFunc {
+	prev := hs.dial
+	hs.dial = dial
+	return prev
 }
 
+// CheckHealth reports whether the app can serve traffic. Every check is
+// recorded in Checks, but only required ones (the database always, SMTP
+// when HEALTH_CHECK_SMTP=required) turn the status to "fail".
 func (hs *HealthService) CheckHealth() HealthStatus {
 	checks := make(map[string]string)
+	status := "ok"
 
 	// Check DB connection
 	if err := hs.db.Ping(); err != nil {
 		checks["database"] = "fail"
-		return HealthStatus{
-			Status:    "fail",
-			Checks:    checks,
-			Timestamp: time.Now().UTC(),
-		}
+		status = "fail"
+	} else {
+		checks["database"] = "ok"
 	}
 
-	checks["database"] = "ok"
+	if hs.smtpMode != config.HealthSMTPOff {
+		if err := hs.checkSMTP(); err != nil {
+			checks["smtp"] = "fail"
+			if hs.smtpMode == config.HealthSMTPRequired {
+				status = "fail"
+			}
+		} else {
+			checks["smtp"] = "ok"
+		}
+	}
 
 	return HealthStatus{
-		Status:    "ok",
+		Status:    status,
 		Checks:    checks,
 		Timestamp: time.Now().UTC(),
 	}
 }
+
+// checkSMTP connects to the mail server and hangs up straight away; it does
+// not speak SMTP, so it proves reachability rather than working credentials.
+func (hs *HealthService) checkSMTP() error {
+	conn, err := hs.dial("tcp", hs.smtpAddr, hs.smtpTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,100 @@
+package health
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/config"
+)
+
+// fakeDialer records the address it was asked for and fails when err is set.
+type fakeDialer struct {
+	err     error
+	addr    string
+	timeout time.Duration
+	calls   int
+}
+
+func (f *fakeDialer) dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	f.calls++
+	f.addr, f.timeout = address, timeout
+	if f.err != nil {
+		return nil, f.err
+	}
+	client, server := net.Pipe()
+	server.Close()
+	return client, nil
+}
+
+func newPingingService(t *testing.T, cfg *config.Config) (*HealthService, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return NewHealthService(db, cfg), mock
+}
+
+func smtpConfig(mode string) *config.Config {
+	return &config.Config{SMTPHost: "mail.example.com", SMTPPort: "587", HealthSMTPMode: mode, HealthSMTPTimeout: time.Second}
+}
+
+func TestCheckHealth_SMTPOffIsNotDialled(t *testing.T) {
+	hs, mock := newPingingService(t, smtpConfig(config.HealthSMTPOff))
+	dialer := &fakeDialer{}
+	hs.SetDialer(dialer.dial)
+	mock.ExpectPing()
+
+	status := hs.CheckHealth()
+	assert.Equal(t, "ok", status.Status)
+	assert.Equal(t, map[string]string{"database": "ok"}, status.Checks)
+	assert.Zero(t, dialer.calls)
+}
+
+func TestCheckHealth_SMTPReachable(t *testing.T) {
+	hs, mock := newPingingService(t, smtpConfig(config.HealthSMTPRequired))
+	dialer := &fakeDialer{}
+	hs.SetDialer(dialer.dial)
+	mock.ExpectPing()
+
+	status := hs.CheckHealth()
+	assert.Equal(t, "ok", status.Status)
+	assert.Equal(t, map[string]string{"database": "ok", "smtp": "ok"}, status.Checks)
+	assert.Equal(t, "mail.example.com:587", dialer.addr)
+	assert.Equal(t, time.Second, dialer.timeout)
+}
+
+func TestCheckHealth_SMTPFailure(t *testing.T) {
+	cases := []struct {
+		mode       string
+		wantStatus string
+	}{
+		{config.HealthSMTPOptional, "ok"},
+		{config.HealthSMTPRequired, "fail"},
+	}
+	for _, tc := range cases {
+		hs, mock := newPingingService(t, smtpConfig(tc.mode))
+		hs.SetDialer((&fakeDialer{err: errors.New("connection refused")}).dial)
+		mock.ExpectPing()
+
+		status := hs.CheckHealth()
+		assert.Equal(t, tc.wantStatus, status.Status, tc.mode)
+		assert.Equal(t, "fail", status.Checks["smtp"], tc.mode)
+		assert.Equal(t, "ok", status.Checks["database"], tc.mode)
+	}
+}
+
+func TestCheckHealth_DatabaseFailureStillChecksSMTP(t *testing.T) {
+	hs, mock := newPingingService(t, smtpConfig(config.HealthSMTPOptional))
+	dialer := &fakeDialer{}
+	hs.SetDialer(dialer.dial)
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	status := hs.CheckHealth()
+	assert.Equal(t, "fail", status.Status)
+	assert.Equal(t, map[string]string{"database": "fail", "smtp": "ok"}, status.Checks)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
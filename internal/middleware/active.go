@@ -0,0 +1,12 @@
+// active.go 
+package middleware
+
+import "database/sql"
+import "go-discussion-app/internal/auth"
+import "go-discussion-app/internal/user"
+import "github.com/gin-gonic/gin"
+
+// RequireActive is the shared alias for auth.RequireActive backed by db.
+func RequireActive(db *sql.DB) gin.HandlerFunc {
+  return auth.RequireActive(user.NewRepository(db))
+}
@@ -0,0 +1,109 @@
+// compress.go
+package middleware
+
+import (
+  "bytes"
+  "compress/gzip"
+  "net/http"
+  "strconv"
+  "strings"
+
+  "github.com/gin-gonic/gin"
+)
+
+// Gzip compresses response bodies for clients that send
+// Accept-Encoding: gzip. Bodies shorter than minSize bytes are sent as-is,
+// since gzip framing would outweigh the saving.
+func Gzip(minSize int) gin.HandlerFunc {
+  return func(c *gin.Context) {
+    if !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+      c.Next()
+      return
+    }
+    c.Header("Vary", "Accept-Encoding")
+
+    gw := &gzipWriter{ResponseWriter: c.Writer, minSize: minSize}
+    c.Writer = gw
+    defer func() {
+      gw.finish()
+      c.Writer = gw.ResponseWriter
+    }()
+    c.Next()
+  }
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+  for _, part := range strings.Split(header, ",") {
+    params := strings.Split(part, ";")
+    if !strings.EqualFold(strings.TrimSpace(params[0]), "gzip") {
+      continue
+    }
+    for _, p := range params[1:] {
+      k, v, _ := strings.Cut(p, "=")
+      if strings.TrimSpace(k) != "q" {
+        continue
+      }
+      if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && q == 0 {
+        return false
+      }
+    }
+    return true
+  }
+  return false
+}
+
+// gzipWriter buffers the body until it reaches minSize, then switches to
+// streaming it through gzip. Smaller bodies are flushed uncompressed by finish.
+type gzipWriter struct {
+  gin.ResponseWriter
+  minSize int
+  buf     bytes.Buffer
+  gz      *gzip.Writer
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+  if w.gz != nil {
+    return w.gz.Write(b)
+  }
+  w.buf.Write(b)
+  if w.buf.Len() < w.minSize || w.Header().Get("Content-Encoding") != "" {
+    return len(b), nil
+  }
+
+  h := w.Header()
+  h.Set("Content-Encoding", "gzip")
+  h.Del("Content-Length")
+  w.gz = gzip.NewWriter(w.ResponseWriter)
+  if _, err := w.gz.Write(w.buf.Bytes()); err != nil {
+    return 0, err
+  }
+  w.buf.Reset()
+  return len(b), nil
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+  return w.Write([]byte(s))
+}
+
+// finish closes the gzip stream, or writes out a body that stayed below minSize.
+func (w *gzipWriter) finish() {
+  if w.gz != nil {
+    w.gz.Close()
+    return
+  }
+  if w.buf.Len() > 0 {
+    w.ResponseWriter.Write(w.buf.Bytes())
+  }
+}
+
+// Flush is a no-op until compression starts, so buffered bytes are not
+// sent before the encoding is decided.
+func (w *gzipWriter) Flush() {
+  if w.gz != nil {
+    w.gz.Flush()
+    w.ResponseWriter.Flush()
+  }
+}
+
+var _ http.Flusher = (*gzipWriter)(nil)
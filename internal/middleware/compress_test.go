@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCompressRouter(minSize int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Gzip(minSize))
+	router.GET("/large", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": strings.Repeat("discussion ", 500)})
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	return router
+}
+
+func performCompressRequest(r http.Handler, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGzip_CompressesLargeResponse(t *testing.T) {
+	router := setupCompressRouter(1024)
+
+	w := performCompressRequest(router, "/large", "br, gzip")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+
+	zr, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(zr)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"data":"discussion discussion`)
+	assert.Less(t, w.Body.Len(), len(body))
+}
+
+func TestGzip_PlainWithoutAcceptEncoding(t *testing.T) {
+	router := setupCompressRouter(1024)
+
+	for _, ae := range []string{"", "br", "gzip;q=0"} {
+		w := performCompressRequest(router, "/large", ae)
+		assert.Equal(t, http.StatusOK, w.Code, ae)
+		assert.Empty(t, w.Header().Get("Content-Encoding"), ae)
+		assert.True(t, strings.HasPrefix(w.Body.String(), `{"data":"discussion`), ae)
+	}
+}
+
+func TestGzip_SkipsSmallResponse(t *testing.T) {
+	router := setupCompressRouter(1024)
+
+	w := performCompressRequest(router, "/small", "gzip")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.JSONEq(t, `{"ok":true}`, w.Body.String())
+}
@@ -0,0 +1,29 @@
+// concurrency.go
+package middleware
+
+import (
+  "net/http"
+
+  "github.com/gin-gonic/gin"
+)
+
+// ConcurrencyLimit caps the number of requests handled at once at n, using a
+// buffered channel as a semaphore. A request arriving while n are in flight
+// is rejected immediately with 503 rather than queued, so a spike cannot pile
+// up waiting on database connections. n <= 0 disables the limit.
+func ConcurrencyLimit(n int) gin.HandlerFunc {
+  if n <= 0 {
+    return func(c *gin.Context) { c.Next() }
+  }
+  sem := make(chan struct{}, n)
+  return func(c *gin.Context) {
+    select {
+    case sem <- struct{}{}:
+      defer func() { <-sem }()
+      c.Next()
+    default:
+      c.Header("Retry-After", "1")
+      c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is busy, try again shortly"})
+    }
+  }
+}
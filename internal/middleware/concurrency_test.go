@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupConcurrencyRouter serves /slow, which blocks until release is closed
+// and reports each arrival on entered.
+func setupConcurrencyRouter(n int, entered chan<- struct{}, release <-chan struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ConcurrencyLimit(n))
+	router.GET("/slow", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestConcurrencyLimit_RejectsBeyondLimit(t *testing.T) {
+	const limit, total = 2, 5
+	entered := make(chan struct{}, total)
+	release := make(chan struct{})
+	router := setupConcurrencyRouter(limit, entered, release)
+
+	codes := make(chan int, total)
+	var wg sync.WaitGroup
+	serve := func() {
+		defer wg.Done()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		codes <- w.Code
+	}
+
+	// Fill every slot and wait until those handlers are running.
+	wg.Add(limit)
+	for i := 0; i < limit; i++ {
+		go serve()
+	}
+	for i := 0; i < limit; i++ {
+		<-entered
+	}
+
+	// Everything beyond the limit is turned away straight away.
+	wg.Add(total - limit)
+	for i := limit; i < total; i++ {
+		go serve()
+	}
+	for i := limit; i < total; i++ {
+		assert.Equal(t, http.StatusServiceUnavailable, <-codes)
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		assert.Equal(t, http.StatusOK, code)
+	}
+}
+
+func TestConcurrencyLimit_FreesSlots(t *testing.T) {
+	entered := make(chan struct{}, 3)
+	release := make(chan struct{})
+	close(release)
+	router := setupConcurrencyRouter(1, entered, release)
+
+	// Sequential requests never overlap, so none is rejected.
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+func TestConcurrencyLimit_ZeroDisables(t *testing.T) {
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	close(release)
+	router := setupConcurrencyRouter(0, entered, release)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
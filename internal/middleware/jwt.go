@@ -1,10 +1,19 @@
 // jwt.go 
 package middleware
 
+import "database/sql"
+import "go-discussion-app/config"
 import "go-discussion-app/internal/auth"
+import "go-discussion-app/internal/user"
 import "github.com/gin-gonic/gin"
 
-// JWTAuth is the shared alias for auth.JWTAuthMiddleware
-func JWTAuth() gin.HandlerFunc {
-  return auth.JWTAuthMiddleware()
+// JWTAuth is the shared alias for auth.AuthMiddleware, accepting both
+// session JWTs and API tokens stored in db. With TRUST_GATEWAY_HEADERS on,
+// requests from the gateway's proxies may carry the user id in a header instead.
+func JWTAuth(db *sql.DB, cfg *config.Config) gin.HandlerFunc {
+  mw := auth.AuthMiddleware(auth.NewTokenRepository(db))
+  if cfg != nil && cfg.TrustGatewayHeaders {
+    mw = auth.GatewayMiddleware(mw, user.NewRepository(db), cfg.GatewayUserHeader, cfg.GatewayTrustedProxies)
+  }
+  return mw
 }
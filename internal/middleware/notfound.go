@@ -0,0 +1,16 @@
+// notfound.go
+package middleware
+
+import (
+  "net/http"
+
+  "github.com/gin-gonic/gin"
+)
+
+// NotFound answers requests that match no route with a JSON 404, so clients
+// can parse every error body the same way. Register it with router.NoRoute.
+func NotFound() gin.HandlerFunc {
+  return func(c *gin.Context) {
+    c.JSON(http.StatusNotFound, gin.H{"error": "not found", "code": "route_not_found"})
+  }
+}
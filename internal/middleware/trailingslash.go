@@ -0,0 +1,31 @@
+// trailingslash.go
+package middleware
+
+import (
+  "net/http"
+  "strings"
+)
+
+// TrimTrailingSlash strips trailing slashes from the request path before h
+// routes it, so /discussions/ reaches the same handler as /discussions with
+// no redirect in between. Gin matches routes before any gin middleware runs,
+// which is why this wraps the engine instead; turn off the engine's
+// RedirectTrailingSlash alongside it. The root path "/" is left as it is.
+func TrimTrailingSlash(h http.Handler) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if p := r.URL.Path; len(p) > 1 && strings.HasSuffix(p, "/") {
+      r.URL.Path = trimSlashes(p)
+      if r.URL.RawPath != "" {
+        r.URL.RawPath = trimSlashes(r.URL.RawPath)
+      }
+    }
+    h.ServeHTTP(w, r)
+  })
+}
+
+func trimSlashes(p string) string {
+  if p = strings.TrimRight(p, "/"); p == "" {
+    return "/"
+  }
+  return p
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSlashRouter is set up the way main.go does it: redirects off, the
+// engine wrapped in TrimTrailingSlash.
+func newSlashRouter() http.Handler {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.RedirectTrailingSlash = false
+	router.NoRoute(NotFound())
+	router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "root") })
+	router.GET("/discussions", func(c *gin.Context) { c.String(http.StatusOK, "list") })
+	router.POST("/discussions", func(c *gin.Context) { c.String(http.StatusCreated, "create") })
+	router.GET("/discussions/:id", func(c *gin.Context) { c.String(http.StatusOK, "get "+c.Param("id")) })
+	return TrimTrailingSlash(router)
+}
+
+func TestTrimTrailingSlash_BothFormsReachHandler(t *testing.T) {
+	r := newSlashRouter()
+	cases := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{http.MethodGet, "/discussions", http.StatusOK, "list"},
+		{http.MethodGet, "/discussions/", http.StatusOK, "list"},
+		{http.MethodGet, "/discussions//", http.StatusOK, "list"},
+		{http.MethodPost, "/discussions/", http.StatusCreated, "create"},
+		{http.MethodGet, "/discussions/7", http.StatusOK, "get 7"},
+		{http.MethodGet, "/discussions/7/?x=1", http.StatusOK, "get 7"},
+		{http.MethodGet, "/", http.StatusOK, "root"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.code, w.Code, tc.method+" "+tc.path)
+		assert.Equal(t, tc.body, w.Body.String(), tc.method+" "+tc.path)
+		assert.Empty(t, w.Header().Get("Location"), "no redirect for "+tc.path)
+	}
+}
+
+func TestTrimTrailingSlash_UnknownPathStill404(t *testing.T) {
+	w := httptest.NewRecorder()
+	newSlashRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/nope/", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -2,19 +2,46 @@
 package subscription
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/mail"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"go-discussion-app/internal/auth"
 	"go-discussion-app/models"
+	"go-discussion-app/pkg/mailer"
+	"go-discussion-app/pkg/pagination"
 )
 
+// SubscriptionService is the behaviour the controller needs from the service layer.
+type SubscriptionService interface {
+	Subscribe(sub *models.Subscription) error
+	SubscribeAnonymous(sub *models.Subscription, ip string) error
+	ConfirmSubscription(token string) error
+	Unsubscribe(discussionID int, email string) error
+	SubscribeTag(name string, sub *models.TagSubscription) error
+	UnsubscribeTag(name, email string) error
+	UnsubscribeWithToken(discussionID int, email, token string) error
+	NotifySubscribers(ctx context.Context, discussionID, afterID int, subject, body string) (*NotifyResult, error)
+	DiscussionOwner(discussionID int) (int, bool, error)
+	ForceUnsubscribe(email string, suppress bool) (int64, error)
+	Suppress(email, reason string) error
+	Unsuppress(email string) (bool, error)
+	ListSubscribers(discussionID int, sort, order string, limit, offset int) ([]models.Subscription, error)
+}
+
 type SubscriptionController struct {
-	service *Service
+	service SubscriptionService
+	jobs    *JobStore
 }
 
-func NewSubscriptionController(service *Service) *SubscriptionController {
-	return &SubscriptionController{service}
+// NewSubscriptionController wires the handlers to service. jobs backs Notify
+// and NotifyJob and may be nil where those routes are not mounted.
+func NewSubscriptionController(service SubscriptionService, jobs *JobStore) *SubscriptionController {
+	return &SubscriptionController{service: service, jobs: jobs}
 }
 
 // POST /discussions/:id/subscribe
@@ -31,8 +58,7 @@ func (sc *SubscriptionController) Subscribe(c *gin.Context) {
 		return
 	}
 
-	userID, _ := c.Get("userId") // Optional
-	uid, _ := userID.(int)
+	uid, _ := auth.GetUserID(c) // Optional
 
 	sub := &models.Subscription{
 		DiscussionID: discussionID,
@@ -44,6 +70,14 @@ func (sc *SubscriptionController) Subscribe(c *gin.Context) {
 	}
 
 	if err := sc.service.Subscribe(sub); err != nil {
+		if err == ErrEmailSuppressed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "this email cannot be subscribed"})
+			return
+		}
+		if err == ErrSubscriptionLimit {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "subscription limit reached"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to subscribe"})
 		return
 	}
@@ -51,6 +85,85 @@ func (sc *SubscriptionController) Subscribe(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": "subscribed successfully"})
 }
 
+// POST /discussions/:id/subscribe/anonymous (no token)
+func (sc *SubscriptionController) SubscribeAnonymous(c *gin.Context) {
+	discussionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid discussion ID"})
+		return
+	}
+
+	var subDTO SubscribeDTO
+	if err := c.ShouldBindJSON(&subDTO); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub := &models.Subscription{
+		DiscussionID: discussionID,
+		Email:        subDTO.Email,
+		SubscribedAt: subDTO.SubscribedAt,
+	}
+
+	err = sc.service.SubscribeAnonymous(sub, c.ClientIP())
+	switch {
+	case err == nil:
+		c.JSON(http.StatusAccepted, gin.H{"message": "check your email to confirm the subscription"})
+	case err == ErrAnonRateLimited:
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many subscribe requests, try again later"})
+	case err == ErrEmailSuppressed:
+		c.JSON(http.StatusForbidden, gin.H{"error": "this email cannot be subscribed"})
+	case err == ErrSubscriptionLimit:
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "subscription limit reached"})
+	case errors.Is(err, mailer.ErrNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "email not configured"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to subscribe"})
+	}
+}
+
+// GET /subscriptions/confirm?token=...
+func (sc *SubscriptionController) ConfirmSubscription(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	if err := sc.service.ConfirmSubscription(token); err != nil {
+		if err == ErrInvalidConfirmToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to confirm subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "subscription confirmed"})
+}
+
+// GET /subscriptions/unsubscribe?discussion_id=&email=&token=
+// is the link at the foot of every notification email.
+func (sc *SubscriptionController) UnsubscribeLink(c *gin.Context) {
+	discussionID, err := strconv.Atoi(c.Query("discussion_id"))
+	email, token := c.Query("email"), c.Query("token")
+	if err != nil || email == "" || token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "discussion_id, email and token are required"})
+		return
+	}
+
+	if err := sc.service.UnsubscribeWithToken(discussionID, email, token); err != nil {
+		if err == ErrInvalidUnsubscribeToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unsubscribe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "unsubscribed successfully"})
+}
+
 // DELETE /discussions/:id/unsubscribe
 func (sc *SubscriptionController) Unsubscribe(c *gin.Context) {
 	discussionID, err := strconv.Atoi(c.Param("id"))
@@ -75,7 +188,61 @@ func (sc *SubscriptionController) Unsubscribe(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "unsubscribed successfully"})
 }
 
-// POST /discussions/:id/notify
+// POST /tags/:id/subscribe, where the segment is the tag's name
+func (sc *SubscriptionController) SubscribeTag(c *gin.Context) {
+	var subDTO SubscribeDTO
+	if err := c.ShouldBindJSON(&subDTO); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub := &models.TagSubscription{
+		Email:        subDTO.Email,
+		SubscribedAt: subDTO.SubscribedAt,
+	}
+	if uid, _ := auth.GetUserID(c); uid != 0 {
+		sub.UserID = &uid
+	}
+
+	if err := sc.service.SubscribeTag(c.Param("id"), sub); err != nil {
+		if err == ErrTagNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+			return
+		}
+		if err == ErrEmailSuppressed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "this email cannot be subscribed"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to subscribe"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "subscribed successfully"})
+}
+
+// DELETE /tags/:id/unsubscribe, where the segment is the tag's name
+func (sc *SubscriptionController) UnsubscribeTag(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := sc.service.UnsubscribeTag(c.Param("id"), req.Email); err != nil {
+		if err == ErrTagNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unsubscribe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "unsubscribed successfully"})
+}
+
+// POST /discussions/:id/notify (owner or admin)
 func (sc *SubscriptionController) Notify(c *gin.Context) {
 	discussionID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -83,19 +250,170 @@ func (sc *SubscriptionController) Notify(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Subject string `json:"subject"`
-		Body    string `json:"body"`
+	var req NotifyDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := auth.GetUserID(c)
+	if role, _ := auth.GetRole(c); role != models.RoleAdmin {
+		ownerID, found, err := sc.service.DiscussionOwner(discussionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load discussion"})
+			return
+		}
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": "discussion not found"})
+			return
+		}
+		if ownerID != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+	}
+
+	// The body was validated above, so the job can only fail while sending.
+	job, err := sc.startNotify(discussionID, userID, 0, req.Subject, req.Body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start notification job"})
+		return
+	}
+
+	c.Header("Location", "/notify/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// startNotify starts a job mailing the subscribers after afterID. A job that
+// stops at the recipient cap starts the next one, each under its own
+// deadline, and links to it from its result.
+func (sc *SubscriptionController) startNotify(discussionID, userID, afterID int, subject, body string) (NotifyJob, error) {
+	return sc.jobs.Start(discussionID, userID, func(ctx context.Context) (*NotifyResult, error) {
+		result, err := sc.service.NotifySubscribers(ctx, discussionID, afterID, subject, body)
+		if err != nil || result == nil || result.Next == 0 {
+			return result, err
+		}
+		next, err := sc.startNotify(discussionID, userID, result.Next, subject, body)
+		if err != nil {
+			return result, fmt.Errorf("failed to start follow-up notify job: %w", err)
+		}
+		result.NextJobID = next.ID
+		return result, nil
+	})
+}
+
+// GET /notify/jobs/:id (whoever started the job, or an admin). A job started
+// by someone else reads as not found.
+func (sc *SubscriptionController) NotifyJob(c *gin.Context) {
+	job, ok := sc.jobs.Get(c.Param("id"))
+	if ok {
+		userID, _ := auth.GetUserID(c)
+		role, _ := auth.GetRole(c)
+		ok = job.UserID == userID || role == models.RoleAdmin
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// DELETE /admin/subscriptions?email=...&suppress=true (admin only)
+func (sc *SubscriptionController) ForceUnsubscribe(c *gin.Context) {
+	email := c.Query("email")
+	if _, err := mail.ParseAddress(email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a valid email query parameter is required"})
+		return
 	}
+	suppress := c.Query("suppress") == "true"
+
+	removed, err := sc.service.ForceUnsubscribe(email, suppress)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unsubscribe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"removed":    removed,
+		"suppressed": suppress,
+	})
+}
+
+// POST /admin/suppressions (admin only)
+func (sc *SubscriptionController) AddSuppression(c *gin.Context) {
+	var req SuppressDTO
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := sc.service.NotifySubscribers(discussionID, req.Subject, req.Body); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send notifications"})
+	if err := sc.service.Suppress(req.Email, req.Reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to suppress email"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "email suppressed"})
+}
+
+// DELETE /admin/suppressions?email=... (admin only)
+func (sc *SubscriptionController) RemoveSuppression(c *gin.Context) {
+	email := c.Query("email")
+	if _, err := mail.ParseAddress(email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a valid email query parameter is required"})
+		return
+	}
+
+	removed, err := sc.service.Unsuppress(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove suppression"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "email is not suppressed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "suppression removed"})
+}
+
+// GET /admin/discussions/:id/subscribers?sort=&order=&limit=&offset= (admin only)
+func (sc *SubscriptionController) ListSubscribers(c *gin.Context) {
+	discussionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid discussion ID"})
+		return
+	}
+
+	var q ListSubscribersQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := q.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subs, err := sc.service.ListSubscribers(discussionID, q.Sort, q.Order, page.Limit, page.Offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list subscribers"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "notifications sent"})
+	c.JSON(http.StatusOK, gin.H{
+		"data":   subs,
+		"limit":  page.Limit,
+		"offset": page.Offset,
+		"sort":   q.Sort,
+		"order":  q.Order,
+	})
 }
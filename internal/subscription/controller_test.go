@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -17,7 +19,7 @@ import (
 	authmw "go-discussion-app/internal/auth"
 	"go-discussion-app/models"
 	"go-discussion-app/pkg/jwtutil"
-	// mailer "go-discussion-app/pkg/mailer" // mailer.SendMail is called by service
+	"go-discussion-app/pkg/mailer"
 )
 
 // ISubscriptionRepository mirrors the public methods of subscription.Repository
@@ -69,151 +71,29 @@ func generateTestTokenSub(userID int) string {
 	return token
 }
 
-// Helper to set up the Gin router
-// It creates a real SubscriptionService with a mocked ISubscriptionRepository,
-// then a real SubscriptionController with that service.
-func setupSubscriptionTestRouter(mockRepo ISubscriptionRepository) *gin.Engine {
+// Helper to set up the Gin router with a SubscriptionController backed by the given service.
+func setupSubscriptionTestRouter(mockService SubscriptionService) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-
-	// The NewService expects a concrete *Repository.
-	// We can't directly pass the mockRepo (which is ISubscriptionRepository).
-	// This highlights a difficulty in testing when concrete types are used for dependencies.
-	// For the purpose of this test, we'll assume that if `Service` was refactored to take
-	// `ISubscriptionRepository`, this setup would be:
-	// realService := NewService(mockRepo)
-	// controller := NewSubscriptionController(realService)
-
-	// Workaround: Since we can't easily inject mockRepo into the real NewService
-	// without code changes or more complex mocking of the concrete Repository struct itself,
-	// we will define a simplified Service mock for the controller test directly.
-	// This means we are not testing the actual service logic here, only the controller.
-	// This is a common adjustment when the service layer isn't easily mockable.
-
-	// --- Option A: Mock the Service directly (Preferred for pure controller test) ---
-	// This requires SubscriptionController to take an interface, or Service to be an interface.
-	// The current SubscriptionController takes *Service (concrete).
-	// Let's define a MockSubscriptionService for the controller.
-
-	// --- Option B: Test Controller+Service as a unit, mocking Repository (Chosen) ---
-	// To make this work, the `Service` struct's `repo` field would need to be settable to our mock.
-	// Example:
-	//  realService := NewService(nil) // Pass nil for DB as it won't be used by mock
-	//  realService.repo = mockRepo // This would require repo to be exported or a setter
-	// Since `repo` is not exported in `Service`, this is also hard.
-
-	// Let's stick to the principle: what does the controller *call*? It calls methods on `Service`.
-	// So, ideally, we mock `Service`.
-	// If `Service` is `*Service` (struct), we need a way to control its behavior.
-	// One way is to make `NewSubscriptionController` take a `SubscriptionServiceInterface`.
-	// If we cannot change production code:
-	// We test the controller by providing its concrete service dependency.
-	// The service will then call its concrete repository dependency.
-	// We have to ensure our mock repository is called by the service.
-	// This is tricky if `NewRepository` is called inside `RegisterRoutes` and `NewService` uses that.
-
-	// The `RegisterRoutes` in `subscription/routes.go` does:
-	// repo := NewRepository(db)
-	// service := NewService(repo)
-	// controller := NewSubscriptionController(service)
-	// So, the test setup should mirror this, replacing the `repo` with our `mockRepo`.
-
-	// Create the actual service, but it will use the methods defined on our mockRepo
-	// because we will effectively make the service use our mock.
-	// This is done by making the service's `repo` field (if it were an interface) point to mockRepo.
-	// Since service.repo is `*Repository`, we can't directly assign `ISubscriptionRepository`.
-	// This test setup is becoming problematic due to Go's type system vs. typical mocking patterns
-	// when concrete types are heavily used for DI.
-
-	// Simplest path for now: Assume `NewService` could take our `ISubscriptionRepository` for testing.
-	// If not, these tests will implicitly test the actual service too, if `mockRepo`
-	// is somehow made the repository that the actual service uses.
-	// For the purpose of this generated code, I will proceed as if `NewService` can accept
-	// a compatible repository interface that `MockSubscriptionRepository` fulfills.
-	// This implies a hypothetical refactor of `NewService` or `Service` struct for testability.
-
-	subscriptionService := NewService(&Repository{}) // Create actual service, it will internally manage its repo
-	                                              // We can't inject the mock repo directly into this structure easily.
-
-	// Given this limitation, the most direct way to test the CONTROLLER in isolation
-	// is to define a SubscriptionServiceInterface and have the controller depend on that.
-	// Since I cannot change the controller's signature, I'll mock the methods of the *concrete*
-	// service struct for the purpose of these controller tests. This is less ideal than interface mocking.
-	// However, `testify/mock` is best used with interfaces.
-
-	// Let's assume we create a `MockService` instead of `MockRepository` for controller tests.
-
-	// --- REVISED STRATEGY: Mock the SERVICE directly for controller tests ---
-	// This is the standard way to unit test controllers.
-	// Requires `Controller` to take an interface `SubscriptionServiceInterface`.
-	// Let's assume `Service` struct *is* the de-facto interface for now and create a mock for it.
-
-	mockSvc := new(MockFullSubscriptionService) // This mock will implement methods of *Service struct
-	subscriptionController := NewSubscriptionController(mockSvc.ToConcreteService()) // Needs a way to convert
-
-	// This is getting too complex due to the concrete dependencies.
-	// A pragmatic approach for this exercise:
-	// The controller calls `sc.service.Subscribe(...)`, `sc.service.Unsubscribe(...)`, etc.
-	// I will create a `MockedSubscriptionService` that has these methods.
-	// The `NewSubscriptionController` will take this mock.
-
-	// This requires `SubscriptionController` to accept an interface.
-	// If `SubscriptionController` must take `*Service`, then we are stuck testing the real service
-	// unless we can manipulate its internal `repo`.
-
-	// Final Simplification for this tool:
-	// I will define `MockSubscriptionService` and assume the controller can take it.
-	// This is the most common pattern for controller unit tests.
-	mockService := new(MockDirectService) // This mock will have Subscribe, Unsubscribe, NotifySubscribers
-	controller := NewSubscriptionController(mockService) // Assumes NewSubscriptionController takes this interface
-
-	// Routes
-	// The problem is `NewSubscriptionController` takes `*Service` (concrete).
-	// So, I MUST provide `*Service`.
-	// The `*Service` has a `repo *Repository`.
-	// So, my mock must be at the `Repository` level.
-	// `setupSubscriptionTestRouter` will take `MockSubscriptionRepository`.
-	// It will create `realService := NewService(mockRepositoryAdapter)`.
-	// Then `controller := NewSubscriptionController(realService)`.
-	// The `mockRepositoryAdapter` needs to be a `*Repository` that internally calls the mock.
-
-	// Let's use the initial plan: mock the REPOSITORY.
-	// The `NewService` takes `*Repository`.
-	// The `MockSubscriptionRepository` (our ISubscriptionRepository) is not a `*Repository`.
-	// This cannot be directly passed to `NewService`.
-
-	// The most robust way without code change is to test controller+service as a unit,
-	// and mock what the repository does.
-	// So, the mockRepo is `MockSubscriptionRepository` which implements `ISubscriptionRepository`.
-	// The `setupSubscriptionTestRouter` will construct the *actual* service.
-	// The expectations will be set on `mockRepo` and we trust the actual service calls them.
-	// This means `NewService` must be able to be constructed such that its internal repo
-	// calls are verifiable. This is typically done if `NewService` takes a repo *interface*.
-	// Since it takes `*Repository` (concrete), this is hard.
-
-	// I will proceed by creating a mock for the Service methods directly.
-	// This is the only clean way to test the controller in isolation without refactoring.
-	// This means `NewController` would need to accept an interface.
-	// Let's assume `Service` struct's methods are what we mock.
-
-	finalMockService := new(MockServiceForController) // This will have Subscribe, Unsubscribe, NotifySubscribers methods
-	// And `NewController` will accept this. This implies `Service` is treated as an interface by the controller.
-	// For the tool, I'll assume this is feasible.
-
-	subscriptionController := NewSubscriptionController(finalMockService)
-
-
-	rg := router.Group("/") // Assuming middleware applied by main app or here
-	// Apply JWT middleware if routes are meant to be protected
-	// Subscribe can be authed or anon. Unsubscribe is by email. Notify likely admin.
-	// For simplicity in test setup, let's assume JWTAuthMiddleware is applied for POST/DELETE.
-	// GET routes are often public.
-	// The controller's Subscribe checks for userID, so it should be in an authed group.
-	// Unsubscribe does not check userID. Notify is likely admin.
-
-	rg.POST("/discussions/:id/subscribe", authmw.JWTAuthMiddleware(), subscriptionController.Subscribe) // Authed
-	rg.DELETE("/discussions/:id/unsubscribe", subscriptionController.Unsubscribe) // Public or authed? Let's assume public for now based on no userID check.
-	rg.POST("/discussions/:id/notify", authmw.JWTAuthMiddleware(), subscriptionController.Notify) // Likely Admin, but test with general auth
+	subscriptionController := NewSubscriptionController(mockService, NewJobStore(time.Minute))
+
+	rg := router.Group("/")
+	// Subscribe reads the userID from context, so it sits behind the JWT middleware.
+	// Unsubscribe is by email and does not check the token. Notify is likely admin.
+	rg.POST("/discussions/:id/subscribe", authmw.JWTAuthMiddleware(), subscriptionController.Subscribe)
+	rg.DELETE("/discussions/:id/unsubscribe", subscriptionController.Unsubscribe)
+	rg.POST("/tags/:id/subscribe", authmw.JWTAuthMiddleware(), subscriptionController.SubscribeTag)
+	rg.DELETE("/tags/:id/unsubscribe", authmw.JWTAuthMiddleware(), subscriptionController.UnsubscribeTag)
+	rg.POST("/discussions/:id/subscribe/anonymous", subscriptionController.SubscribeAnonymous)
+	rg.GET("/subscriptions/confirm", subscriptionController.ConfirmSubscription)
+	rg.GET("/subscriptions/unsubscribe", subscriptionController.UnsubscribeLink)
+	rg.POST("/discussions/:id/notify", authmw.JWTAuthMiddleware(), subscriptionController.Notify)
+	rg.GET("/notify/jobs/:id", authmw.JWTAuthMiddleware(), subscriptionController.NotifyJob)
+	admin := rg.Group("/admin", authmw.JWTAuthMiddleware(), authmw.RequireRole(models.RoleAdmin))
+	admin.DELETE("/subscriptions", subscriptionController.ForceUnsubscribe)
+	admin.POST("/suppressions", subscriptionController.AddSuppression)
+	admin.DELETE("/suppressions", subscriptionController.RemoveSuppression)
+	admin.GET("/discussions/:id/subscribers", subscriptionController.ListSubscribers)
 
 	return router
 }
@@ -227,14 +107,57 @@ func (m *MockServiceForController) Subscribe(sub *models.Subscription) error {
 	args := m.Called(sub)
 	return args.Error(0)
 }
+func (m *MockServiceForController) SubscribeAnonymous(sub *models.Subscription, ip string) error {
+	args := m.Called(sub, ip)
+	return args.Error(0)
+}
+func (m *MockServiceForController) ConfirmSubscription(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
 func (m *MockServiceForController) Unsubscribe(discussionID int, email string) error {
 	args := m.Called(discussionID, email)
 	return args.Error(0)
 }
-func (m *MockServiceForController) NotifySubscribers(discussionID int, subject, body string) error {
-	args := m.Called(discussionID, subject, body)
+func (m *MockServiceForController) UnsubscribeWithToken(discussionID int, email, token string) error {
+	args := m.Called(discussionID, email, token)
+	return args.Error(0)
+}
+func (m *MockServiceForController) SubscribeTag(name string, sub *models.TagSubscription) error {
+	args := m.Called(name, sub)
+	return args.Error(0)
+}
+func (m *MockServiceForController) UnsubscribeTag(name, email string) error {
+	args := m.Called(name, email)
+	return args.Error(0)
+}
+func (m *MockServiceForController) ForceUnsubscribe(email string, suppress bool) (int64, error) {
+	args := m.Called(email, suppress)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockServiceForController) Suppress(email, reason string) error {
+	args := m.Called(email, reason)
 	return args.Error(0)
 }
+func (m *MockServiceForController) Unsuppress(email string) (bool, error) {
+	args := m.Called(email)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockServiceForController) ListSubscribers(discussionID int, sort, order string, limit, offset int) ([]models.Subscription, error) {
+	args := m.Called(discussionID, sort, order, limit, offset)
+	return args.Get(0).([]models.Subscription), args.Error(1)
+}
+func (m *MockServiceForController) NotifySubscribers(ctx context.Context, discussionID, afterID int, subject, body string) (*NotifyResult, error) {
+	args := m.Called(ctx, discussionID, afterID, subject, body)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*NotifyResult), args.Error(1)
+}
+func (m *MockServiceForController) DiscussionOwner(discussionID int) (int, bool, error) {
+	args := m.Called(discussionID)
+	return args.Int(0), args.Bool(1), args.Error(2)
+}
 
 
 func performSubscriptionRequest(r http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
@@ -323,10 +246,238 @@ func TestSubscribe_ServiceError(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestSubscribe_LimitReached(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token := generateTestTokenSub(1)
+	dto := SubscribeDTO{Email: "user@example.com", SubscribedAt: time.Now()}
+
+	mockService.On("Subscribe", mock.AnythingOfType("*models.Subscription")).Return(ErrSubscriptionLimit)
+
+	w := performSubscriptionRequest(router, "POST", "/discussions/10/subscribe", token, dto)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestSubscribe_SuppressedEmail(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token := generateTestTokenSub(1)
+	dto := SubscribeDTO{Email: "complained@example.com", SubscribedAt: time.Now()}
+
+	mockService.On("Subscribe", mock.AnythingOfType("*models.Subscription")).Return(ErrEmailSuppressed)
+
+	w := performSubscriptionRequest(router, "POST", "/discussions/10/subscribe", token, dto)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+// --- Anonymous Subscribe Tests (POST /discussions/:id/subscribe/anonymous) ---
+
+func TestSubscribeAnonymous_Accepted(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	dto := SubscribeDTO{Email: "anon@example.com", SubscribedAt: time.Now()}
+
+	mockService.On("SubscribeAnonymous", mock.MatchedBy(func(sub *models.Subscription) bool {
+		return sub.DiscussionID == 10 && sub.Email == dto.Email && sub.UserID == nil
+	}), mock.AnythingOfType("string")).Return(nil)
+
+	w := performSubscriptionRequest(router, "POST", "/discussions/10/subscribe/anonymous", "", dto)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestSubscribeAnonymous_RateLimited(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	dto := SubscribeDTO{Email: "anon@example.com", SubscribedAt: time.Now()}
+
+	mockService.On("SubscribeAnonymous", mock.AnythingOfType("*models.Subscription"), mock.AnythingOfType("string")).Return(ErrAnonRateLimited)
+
+	w := performSubscriptionRequest(router, "POST", "/discussions/10/subscribe/anonymous", "", dto)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestSubscribeAnonymous_TrustsForwardedForOnlyFromProxy(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	assert.NoError(t, router.SetTrustedProxies([]string{"10.0.0.1"}))
+	dto := SubscribeDTO{Email: "anon@example.com", SubscribedAt: time.Now()}
+
+	mockService.On("SubscribeAnonymous", mock.AnythingOfType("*models.Subscription"), "203.0.113.7").Return(nil).Once()
+	mockService.On("SubscribeAnonymous", mock.AnythingOfType("*models.Subscription"), "192.0.2.2").Return(nil).Once()
+
+	for _, peer := range []struct{ remote, forwarded string }{
+		{"203.0.113.7", "192.0.2.1"}, // untrusted peer: its own address counts
+		{"10.0.0.1", "192.0.2.2"},    // trusted proxy: the forwarded client counts
+	} {
+		body, _ := json.Marshal(dto)
+		req := httptest.NewRequest("POST", "/discussions/10/subscribe/anonymous", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", peer.forwarded)
+		req.RemoteAddr = peer.remote + ":5000"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestSubscribeAnonymous_MailerNotConfigured(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	dto := SubscribeDTO{Email: "anon@example.com", SubscribedAt: time.Now()}
+
+	mockService.On("SubscribeAnonymous", mock.AnythingOfType("*models.Subscription"), mock.AnythingOfType("string")).
+		Return(fmt.Errorf("%w: missing SMTP_HOST", mailer.ErrNotConfigured))
+
+	w := performSubscriptionRequest(router, "POST", "/discussions/10/subscribe/anonymous", "", dto)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestConfirmSubscription(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+
+	mockService.On("ConfirmSubscription", "good").Return(nil)
+	mockService.On("ConfirmSubscription", "bad").Return(ErrInvalidConfirmToken)
+
+	w := performSubscriptionRequest(router, "GET", "/subscriptions/confirm?token=good", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performSubscriptionRequest(router, "GET", "/subscriptions/confirm?token=bad", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performSubscriptionRequest(router, "GET", "/subscriptions/confirm", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+// --- Force Unsubscribe Tests (DELETE /admin/subscriptions?email=) ---
+
+func TestForceUnsubscribe_Admin(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token, _ := jwtutil.GenerateTokenWithRole(99, models.RoleAdmin)
+
+	mockService.On("ForceUnsubscribe", "user@example.com", true).Return(int64(3), nil)
+
+	w := performSubscriptionRequest(router, "DELETE", "/admin/subscriptions?email=user@example.com&suppress=true", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(3), resp["removed"])
+	assert.Equal(t, true, resp["suppressed"])
+	mockService.AssertExpectations(t)
+}
+
+func TestForceUnsubscribe_RequiresAdmin(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+
+	w := performSubscriptionRequest(router, "DELETE", "/admin/subscriptions?email=user@example.com", generateTestTokenSub(1), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "ForceUnsubscribe", mock.Anything, mock.Anything)
+}
+
+func TestForceUnsubscribe_InvalidEmail(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token, _ := jwtutil.GenerateTokenWithRole(99, models.RoleAdmin)
+
+	w := performSubscriptionRequest(router, "DELETE", "/admin/subscriptions?email=nope", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "ForceUnsubscribe", mock.Anything, mock.Anything)
+}
+
+// --- Suppression Tests (/admin/suppressions) ---
+
+func TestAddSuppression_Admin(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token, _ := jwtutil.GenerateTokenWithRole(99, models.RoleAdmin)
+
+	mockService.On("Suppress", "spam@example.com", "complaint").Return(nil)
+
+	w := performSubscriptionRequest(router, "POST", "/admin/suppressions", token,
+		SuppressDTO{Email: "spam@example.com", Reason: "complaint"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAddSuppression_RequiresAdmin(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+
+	w := performSubscriptionRequest(router, "POST", "/admin/suppressions", generateTestTokenSub(1),
+		SuppressDTO{Email: "spam@example.com"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "Suppress", mock.Anything, mock.Anything)
+}
+
+func TestRemoveSuppression(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token, _ := jwtutil.GenerateTokenWithRole(99, models.RoleAdmin)
+
+	mockService.On("Unsuppress", "spam@example.com").Return(true, nil)
+	mockService.On("Unsuppress", "other@example.com").Return(false, nil)
+
+	w := performSubscriptionRequest(router, "DELETE", "/admin/suppressions?email=spam@example.com", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performSubscriptionRequest(router, "DELETE", "/admin/suppressions?email=other@example.com", token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+// --- Admin subscriber list Tests (GET /admin/discussions/:id/subscribers) ---
+
+func TestListSubscribers_DefaultsAndPagination(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token, _ := jwtutil.GenerateTokenWithRole(99, models.RoleAdmin)
+
+	mockService.On("ListSubscribers", 10, "subscribed_at", "desc", 20, 0).Return([]models.Subscription{{ID: 1}}, nil)
+	mockService.On("ListSubscribers", 10, "email", "asc", 5, 10).Return([]models.Subscription{}, nil)
+
+	w := performSubscriptionRequest(router, "GET", "/admin/discussions/10/subscribers", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performSubscriptionRequest(router, "GET", "/admin/discussions/10/subscribers?sort=email&order=asc&limit=5&offset=10", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(5), resp["limit"])
+	assert.Equal(t, float64(10), resp["offset"])
+	assert.Equal(t, []interface{}{}, resp["data"])
+	mockService.AssertExpectations(t)
+}
+
+func TestListSubscribers_RejectsUnknownSortAndOrder(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token, _ := jwtutil.GenerateTokenWithRole(99, models.RoleAdmin)
+
+	for _, q := range []string{"?sort=password", "?sort=email%3BDROP", "?order=sideways", "?limit=-1"} {
+		w := performSubscriptionRequest(router, "GET", "/admin/discussions/10/subscribers"+q, token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	mockService.AssertNotCalled(t, "ListSubscribers", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListSubscribers_RequiresAdmin(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+
+	w := performSubscriptionRequest(router, "GET", "/admin/discussions/10/subscribers", generateTestTokenSub(1), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
 // --- Unsubscribe Tests (DELETE /discussions/:discussionID/unsubscribe) ---
 func TestUnsubscribe_Success(t *testing.T) {
 	mockService := new(MockServiceForController)
-	router := setupSubscriptionTestRouter(mockService)
 	discussionID := 10
 	userEmail := "user@example.com"
 	// No token needed as per controller logic, but route might be protected by group middleware in real app.
@@ -338,7 +489,7 @@ func TestUnsubscribe_Success(t *testing.T) {
 	// Let's assume Unsubscribe is public for this test.
 	gin.SetMode(gin.TestMode)
 	testRouter := gin.New()
-	ctrlr := NewSubscriptionController(mockService)
+	ctrlr := NewSubscriptionController(mockService, nil)
 	testRouter.DELETE("/discussions/:id/unsubscribe", ctrlr.Unsubscribe)
 
 
@@ -355,7 +506,7 @@ func TestUnsubscribe_ServiceError(t *testing.T) {
 	// Setup router assuming Unsubscribe is public
 	gin.SetMode(gin.TestMode)
 	testRouter := gin.New()
-	ctrlr := NewSubscriptionController(mockService)
+	ctrlr := NewSubscriptionController(mockService, nil)
 	testRouter.DELETE("/discussions/:id/unsubscribe", ctrlr.Unsubscribe)
 
 	discussionID := 10
@@ -372,7 +523,7 @@ func TestUnsubscribe_InvalidEmail(t *testing.T) {
 	mockService := new(MockServiceForController)
 	gin.SetMode(gin.TestMode)
 	testRouter := gin.New()
-	ctrlr := NewSubscriptionController(mockService)
+	ctrlr := NewSubscriptionController(mockService, nil)
 	testRouter.DELETE("/discussions/:id/unsubscribe", ctrlr.Unsubscribe)
 	discussionID := 10
 
@@ -381,25 +532,235 @@ func TestUnsubscribe_InvalidEmail(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code) // Gin binding should fail
 }
 
+// --- Tag subscription tests (POST /tags/:name/subscribe, DELETE /tags/:name/unsubscribe) ---
+func TestSubscribeTag_Success(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token := generateTestTokenSub(1)
+	dto := SubscribeDTO{Email: "user@example.com", SubscribedAt: time.Now()}
+
+	mockService.On("SubscribeTag", "golang", mock.MatchedBy(func(sub *models.TagSubscription) bool {
+		return sub.Email == dto.Email && sub.UserID != nil && *sub.UserID == 1
+	})).Return(nil)
+
+	w := performSubscriptionRequest(router, "POST", "/tags/golang/subscribe", token, dto)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestSubscribeTag_Errors(t *testing.T) {
+	cases := []struct {
+		err  error
+		code int
+	}{
+		{ErrTagNotFound, http.StatusNotFound},
+		{ErrEmailSuppressed, http.StatusForbidden},
+		{assert.AnError, http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		mockService := new(MockServiceForController)
+		router := setupSubscriptionTestRouter(mockService)
+		mockService.On("SubscribeTag", "golang", mock.AnythingOfType("*models.TagSubscription")).Return(tc.err)
+
+		dto := SubscribeDTO{Email: "user@example.com", SubscribedAt: time.Now()}
+		w := performSubscriptionRequest(router, "POST", "/tags/golang/subscribe", generateTestTokenSub(1), dto)
+		assert.Equal(t, tc.code, w.Code, tc.err.Error())
+	}
+}
+
+func TestSubscribeTag_RequiresTokenAndEmail(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	dto := SubscribeDTO{Email: "user@example.com", SubscribedAt: time.Now()}
+
+	w := performSubscriptionRequest(router, "POST", "/tags/golang/subscribe", "", dto)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performSubscriptionRequest(router, "POST", "/tags/golang/subscribe", generateTestTokenSub(1), map[string]string{"email": "nope"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "SubscribeTag", mock.Anything, mock.Anything)
+}
+
+func TestUnsubscribeTag(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token := generateTestTokenSub(1)
+
+	mockService.On("UnsubscribeTag", "golang", "user@example.com").Return(nil)
+	mockService.On("UnsubscribeTag", "missing", "user@example.com").Return(ErrTagNotFound)
+
+	payload := map[string]string{"email": "user@example.com"}
+	w := performSubscriptionRequest(router, "DELETE", "/tags/golang/unsubscribe", token, payload)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performSubscriptionRequest(router, "DELETE", "/tags/missing/unsubscribe", token, payload)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestUnsubscribeLink(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+
+	mockService.On("UnsubscribeWithToken", 10, "a@example.com", "good").Return(nil)
+	mockService.On("UnsubscribeWithToken", 10, "a@example.com", "bad").Return(ErrInvalidUnsubscribeToken)
+	mockService.On("UnsubscribeWithToken", 11, "a@example.com", "good").Return(errors.New("db down"))
+
+	w := performSubscriptionRequest(router, "GET", "/subscriptions/unsubscribe?discussion_id=10&email=a%40example.com&token=good", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performSubscriptionRequest(router, "GET", "/subscriptions/unsubscribe?discussion_id=10&email=a%40example.com&token=bad", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performSubscriptionRequest(router, "GET", "/subscriptions/unsubscribe?discussion_id=11&email=a%40example.com&token=good", "", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	for _, q := range []string{"email=a%40example.com&token=good", "discussion_id=x&email=a%40example.com&token=good", "discussion_id=10&token=good", "discussion_id=10&email=a%40example.com"} {
+		w = performSubscriptionRequest(router, "GET", "/subscriptions/unsubscribe?"+q, "", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	mockService.AssertExpectations(t)
+}
+
 
 // --- Notify Tests (POST /discussions/:id/notify) ---
-// Assuming this is an admin-like action and requires auth
-func TestNotify_Success(t *testing.T) {
+
+// waitForJob polls GET /notify/jobs/:id until the job leaves JobRunning.
+func waitForJob(t *testing.T, router http.Handler, token, id string) NotifyJob {
+	var job NotifyJob
+	assert.Eventually(t, func() bool {
+		w := performSubscriptionRequest(router, "GET", "/notify/jobs/"+id, token, nil)
+		if w.Code != http.StatusOK {
+			return false
+		}
+		job = NotifyJob{}
+		return json.Unmarshal(w.Body.Bytes(), &job) == nil && job.Status != JobRunning
+	}, time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestNotify_AcceptedAndRunsInBackground(t *testing.T) {
 	mockService := new(MockServiceForController)
-	router := setupSubscriptionTestRouter(mockService) // Uses JWTAuthMiddleware for this route
+	router := setupSubscriptionTestRouter(mockService)
+	token := generateTestTokenSub(999)
+	payload := map[string]string{"subject": "Update", "body": "New post!"}
 
-	adminUserID := 999
-	token := generateTestTokenSub(adminUserID)
-	discussionID := 10
+	result := &NotifyResult{
+		Sent:   []string{"ok@example.com"},
+		Failed: []FailedDelivery{{Email: "bad@example.com", Reason: "mailbox unavailable"}},
+	}
+	release := make(chan struct{})
+	mockService.On("DiscussionOwner", 10).Return(999, true, nil)
+	mockService.On("NotifySubscribers", mock.Anything, 10, 0, "Update", "New post!").
+		Run(func(mock.Arguments) { <-release }).Return(result, nil)
+
+	w := performSubscriptionRequest(router, "POST", "/discussions/10/notify", token, payload)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var accepted NotifyJob
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+	assert.NotEmpty(t, accepted.ID)
+	assert.Equal(t, JobRunning, accepted.Status)
+	assert.Equal(t, 10, accepted.DiscussionID)
+	assert.Equal(t, "/notify/jobs/"+accepted.ID, w.Header().Get("Location"))
+
+	// Still running while the mailer is blocked.
+	w = performSubscriptionRequest(router, "GET", "/notify/jobs/"+accepted.ID, token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"running"`)
+
+	close(release)
+	job := waitForJob(t, router, token, accepted.ID)
+	assert.Equal(t, JobSucceeded, job.Status)
+	if assert.NotNil(t, job.Result) {
+		assert.Equal(t, result.Sent, job.Result.Sent)
+		assert.Equal(t, result.Failed, job.Result.Failed)
+	}
+	assert.NotNil(t, job.FinishedAt)
+	mockService.AssertExpectations(t)
+}
+
+func TestNotify_ChunksAcrossJobsAboveRecipientCap(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token := generateTestTokenSub(1)
 	payload := map[string]string{"subject": "Update", "body": "New post!"}
 
-	mockService.On("NotifySubscribers", discussionID, payload["subject"], payload["body"]).Return(nil)
+	mockService.On("DiscussionOwner", 10).Return(1, true, nil)
+	// The first two runs stop at the cap; the third reaches the end.
+	mockService.On("NotifySubscribers", mock.Anything, 10, 0, "Update", "New post!").
+		Return(&NotifyResult{Sent: []string{"a@example.com"}, Failed: []FailedDelivery{}, Next: 3}, nil)
+	mockService.On("NotifySubscribers", mock.Anything, 10, 3, "Update", "New post!").
+		Return(&NotifyResult{Sent: []string{"b@example.com"}, Failed: []FailedDelivery{}, Next: 7}, nil)
+	mockService.On("NotifySubscribers", mock.Anything, 10, 7, "Update", "New post!").
+		Return(&NotifyResult{Sent: []string{"c@example.com"}, Failed: []FailedDelivery{}}, nil)
+
+	w := performSubscriptionRequest(router, "POST", "/discussions/10/notify", token, payload)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var accepted NotifyJob
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+
+	var sent []string
+	id := accepted.ID
+	for i := 0; i < 3; i++ {
+		job := waitForJob(t, router, token, id)
+		assert.Equal(t, JobSucceeded, job.Status)
+		if !assert.NotNil(t, job.Result) {
+			return
+		}
+		sent = append(sent, job.Result.Sent...)
+		id = job.Result.NextJobID
+	}
+	assert.Empty(t, id, "the last job has no follow-up")
+	assert.Equal(t, []string{"a@example.com", "b@example.com", "c@example.com"}, sent)
+	mockService.AssertExpectations(t)
+}
 
-	w := performSubscriptionRequest(router, "POST", fmt.Sprintf("/discussions/%d/notify", discussionID), token, payload)
-	assert.Equal(t, http.StatusOK, w.Code)
+func TestNotify_JobGetsItsOwnDeadline(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	payload := map[string]string{"subject": "Update", "body": "New post!"}
+
+	mockService.On("DiscussionOwner", 10).Return(1, true, nil)
+	mockService.On("NotifySubscribers", mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	}), 10, 0, "Update", "New post!").Return(&NotifyResult{}, nil)
+
+	w := performSubscriptionRequest(router, "POST", "/discussions/10/notify", generateTestTokenSub(1), payload)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var accepted NotifyJob
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+	assert.Equal(t, JobSucceeded, waitForJob(t, router, generateTestTokenSub(1), accepted.ID).Status)
 	mockService.AssertExpectations(t)
 }
 
+func TestNotify_MailerNotConfiguredFailsJob(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	payload := map[string]string{"subject": "Update", "body": "New post!"}
+
+	mockService.On("DiscussionOwner", 10).Return(1, true, nil)
+	mockService.On("NotifySubscribers", mock.Anything, 10, 0, "Update", "New post!").
+		Return(&NotifyResult{}, fmt.Errorf("%w: missing SMTP_HOST", mailer.ErrNotConfigured))
+
+	w := performSubscriptionRequest(router, "POST", "/discussions/10/notify", generateTestTokenSub(1), payload)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var accepted NotifyJob
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+
+	job := waitForJob(t, router, generateTestTokenSub(1), accepted.ID)
+	assert.Equal(t, JobFailed, job.Status)
+	assert.Contains(t, job.Error, "email not configured")
+	mockService.AssertExpectations(t)
+}
+
+func TestNotifyJob_NotFound(t *testing.T) {
+	router := setupSubscriptionTestRouter(new(MockServiceForController))
+
+	w := performSubscriptionRequest(router, "GET", "/notify/jobs/nope", generateTestTokenSub(1), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
 func TestNotify_Unauthorized(t *testing.T) {
 	mockService := new(MockServiceForController)
 	router := setupSubscriptionTestRouter(mockService)
@@ -410,6 +771,94 @@ func TestNotify_Unauthorized(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 }
 
+func TestNotify_OwnerOrAdminOnly(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	payload := map[string]string{"subject": "Update", "body": "New post!"}
+
+	mockService.On("DiscussionOwner", 10).Return(1, true, nil)
+	mockService.On("DiscussionOwner", 11).Return(0, false, nil)
+	mockService.On("NotifySubscribers", mock.Anything, 10, 0, "Update", "New post!").
+		Return(&NotifyResult{Sent: []string{"a@example.com"}, Failed: []FailedDelivery{}}, nil)
+
+	w := performSubscriptionRequest(router, "POST", "/discussions/10/notify", generateTestTokenSub(2), payload)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performSubscriptionRequest(router, "POST", "/discussions/11/notify", generateTestTokenSub(2), payload)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertNotCalled(t, "NotifySubscribers", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// An admin may notify any discussion without the ownership lookup.
+	admin, _ := jwtutil.GenerateTokenWithRole(99, models.RoleAdmin)
+	w = performSubscriptionRequest(router, "POST", "/discussions/10/notify", admin, payload)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var accepted NotifyJob
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+	assert.Equal(t, JobSucceeded, waitForJob(t, router, admin, accepted.ID).Status)
+	mockService.AssertNumberOfCalls(t, "DiscussionOwner", 2)
+
+	// The job, and the addresses in its result, are hidden from other users.
+	w = performSubscriptionRequest(router, "GET", "/notify/jobs/"+accepted.ID, generateTestTokenSub(1), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NotContains(t, w.Body.String(), "a@example.com")
+}
+
+func TestNotify_TrimsFields(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token := generateTestTokenSub(1)
+
+	mockService.On("DiscussionOwner", 10).Return(1, true, nil)
+	mockService.On("NotifySubscribers", mock.Anything, 10, 0, "Update", "New post!").Return(&NotifyResult{Sent: []string{}, Failed: []FailedDelivery{}}, nil)
+
+	w := performSubscriptionRequest(router, "POST", "/discussions/10/notify", token,
+		NotifyDTO{Subject: "  Update ", Body: "\nNew post!\n"})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var accepted NotifyJob
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+	waitForJob(t, router, token, accepted.ID)
+	mockService.AssertExpectations(t)
+}
+
+func TestNotify_RejectsBadTemplate(t *testing.T) {
+	mockService := new(MockServiceForController)
+	router := setupSubscriptionTestRouter(mockService)
+	token := generateTestTokenSub(1)
+
+	for _, body := range []string{"Hi {{.Username", "Hi {{.Nickname}}", "{{range 300000000}}x{{end}}", `{{printf "%s" .Email}}`, "{{.Username | len}}"} {
+		w := performSubscriptionRequest(router, "POST", "/discussions/10/notify", token,
+			NotifyDTO{Subject: "Update", Body: body})
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), "body is not a valid template", body)
+	}
+	mockService.AssertNotCalled(t, "NotifySubscribers", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNotify_InvalidPayload(t *testing.T) {
+	cases := []struct {
+		name    string
+		dto     NotifyDTO
+		wantErr string
+	}{
+		{"empty subject", NotifyDTO{Subject: "", Body: "b"}, "subject is required"},
+		{"blank subject", NotifyDTO{Subject: "   ", Body: "b"}, "subject is required"},
+		{"long subject", NotifyDTO{Subject: strings.Repeat("s", MaxNotifySubjectLength+1), Body: "b"}, "subject must be at most"},
+		{"multi-line subject", NotifyDTO{Subject: "Hi\r\nBcc: x@example.com", Body: "b"}, "subject must be a single line"},
+		{"empty body", NotifyDTO{Subject: "s", Body: ""}, "body is required"},
+		{"blank body", NotifyDTO{Subject: "s", Body: " \t "}, "body is required"},
+		{"long body", NotifyDTO{Subject: "s", Body: strings.Repeat("b", MaxNotifyBodyLength+1)}, "body must be at most"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockServiceForController)
+			router := setupSubscriptionTestRouter(mockService)
+
+			w := performSubscriptionRequest(router, "POST", "/discussions/10/notify", generateTestTokenSub(1), tc.dto)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), tc.wantErr)
+			mockService.AssertNotCalled(t, "NotifySubscribers", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
 
 // Notes on missing tests due to feature gaps:
 // - Subscribing to Tags: Not implemented.
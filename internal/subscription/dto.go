@@ -1,8 +1,140 @@
 package subscription
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxNotifySubjectLength caps the subject line of a notification email.
+	MaxNotifySubjectLength = 200
+	// MaxNotifyBodyLength caps the body of a notification email.
+	MaxNotifyBodyLength = 10000
+)
 
 type SubscribeDTO struct {
 	Email        string    `json:"email" binding:"required,email"`
 	SubscribedAt time.Time `json:"subscribed_at" binding:"required"`
 }
+
+// SuppressDTO binds POST /admin/suppressions.
+type SuppressDTO struct {
+	Email  string `json:"email" binding:"required,email"`
+	Reason string `json:"reason"`
+}
+
+// NotifyRecipient is what a notify body template is rendered with, once per
+// recipient, e.g. "Hi {{.Username}}".
+type NotifyRecipient struct {
+	Username string // the subscriber's username, or their email's local part if they have no account
+	Email    string
+}
+
+// notifyFields are the NotifyRecipient fields a notify body may reference.
+var notifyFields = map[string]bool{"Username": true, "Email": true}
+
+// parseNotifyBody parses body as a text/template and rejects anything but
+// plain text and {{.Username}}/{{.Email}}. Loops, conditionals, functions and
+// nested templates are refused up front, since the body is rendered once per
+// recipient and e.g. {{range 300000000}} would otherwise run unchecked.
+func parseNotifyBody(body string) (*template.Template, error) {
+	tmpl, err := template.New("body").Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range tmpl.Tree.Root.Nodes {
+		if !isNotifyNode(n) {
+			return nil, fmt.Errorf("only {{.Username}} and {{.Email}} are allowed, got %s", n)
+		}
+	}
+	return tmpl, nil
+}
+
+// isNotifyNode reports whether n is plain text or a bare {{.Username}} or
+// {{.Email}} action.
+func isNotifyNode(n parse.Node) bool {
+	switch n := n.(type) {
+	case *parse.TextNode:
+		return true
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) != 0 || len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) != 1 {
+			return false
+		}
+		f, ok := n.Pipe.Cmds[0].Args[0].(*parse.FieldNode)
+		return ok && len(f.Ident) == 1 && notifyFields[f.Ident[0]]
+	}
+	return false
+}
+
+// isStaticTemplate reports whether tmpl is plain text with no actions, so it
+// renders the same for everyone.
+func isStaticTemplate(tmpl *template.Template) bool {
+	for _, n := range tmpl.Tree.Root.Nodes {
+		if n.Type() != parse.NodeText {
+			return false
+		}
+	}
+	return true
+}
+
+// NotifyDTO binds POST /discussions/:id/notify. Body may use
+// NotifyRecipient's fields as template placeholders.
+type NotifyDTO struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Validate trims both fields and ensures each is non-empty and within its
+// limit, and that the subject is a single line.
+func (dto *NotifyDTO) Validate() error {
+	dto.Subject = strings.TrimSpace(dto.Subject)
+	dto.Body = strings.TrimSpace(dto.Body)
+	if dto.Subject == "" {
+		return errors.New("subject is required")
+	}
+	if n := utf8.RuneCountInString(dto.Subject); n > MaxNotifySubjectLength {
+		return fmt.Errorf("subject must be at most %d characters (got %d)", MaxNotifySubjectLength, n)
+	}
+	if strings.ContainsAny(dto.Subject, "\r\n") {
+		return errors.New("subject must be a single line")
+	}
+	if dto.Body == "" {
+		return errors.New("body is required")
+	}
+	if n := utf8.RuneCountInString(dto.Body); n > MaxNotifyBodyLength {
+		return fmt.Errorf("body must be at most %d characters (got %d)", MaxNotifyBodyLength, n)
+	}
+	if _, err := parseNotifyBody(dto.Body); err != nil {
+		return fmt.Errorf("body is not a valid template: %v", err)
+	}
+	return nil
+}
+
+// ListSubscribersQuery binds the sort options of GET /admin/discussions/:id/subscribers.
+type ListSubscribersQuery struct {
+	Sort  string `form:"sort"`
+	Order string `form:"order"`
+}
+
+// Validate fills in defaults (subscribed_at, desc) and rejects values
+// outside the allowlist.
+func (q *ListSubscribersQuery) Validate() error {
+	if q.Sort == "" {
+		q.Sort = "subscribed_at"
+	}
+	if q.Order == "" {
+		q.Order = "desc"
+	}
+	if _, ok := subscriberSortColumns[q.Sort]; !ok {
+		return errors.New("sort must be one of subscribed_at, email, id")
+	}
+	if q.Order != "asc" && q.Order != "desc" {
+		return errors.New("order must be asc or desc")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// jobs.go
+package subscription
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-discussion-app/pkg/logger"
+)
+
+// Notify job statuses.
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// DefaultNotifyJobTimeout bounds a notify job when no timeout is configured.
+const DefaultNotifyJobTimeout = 10 * time.Minute
+
+// jobRetention is how long a finished job stays available to
+// GET /notify/jobs/:id before it is dropped.
+const jobRetention = time.Hour
+
+// NotifyJob is a snapshot of one asynchronous NotifySubscribers run. Result
+// holds whatever was sent before the run ended, even when it failed.
+type NotifyJob struct {
+	ID           string        `json:"id"`
+	DiscussionID int           `json:"discussion_id"`
+	UserID       int           `json:"-"` // who started the job; only they and admins may read it
+	Status       string        `json:"status"`
+	Result       *NotifyResult `json:"result,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	FinishedAt   *time.Time    `json:"finished_at,omitempty"`
+}
+
+// JobStore runs notify jobs in the background and keeps their status in
+// memory. Jobs are lost on restart and are not shared between instances.
+type JobStore struct {
+	mu      sync.Mutex
+	jobs    map[string]*NotifyJob
+	timeout time.Duration
+}
+
+// NewJobStore returns a JobStore whose jobs are cancelled after timeout.
+// A non-positive timeout uses DefaultNotifyJobTimeout.
+func NewJobStore(timeout time.Duration) *JobStore {
+	if timeout <= 0 {
+		timeout = DefaultNotifyJobTimeout
+	}
+	return &JobStore{jobs: map[string]*NotifyJob{}, timeout: timeout}
+}
+
+// Start records a running job and calls run in a new goroutine. run gets a
+// context of its own, cancelled after the store's timeout, so the job
+// outlives the request that started it. userID is recorded as the job's
+// starter.
+func (js *JobStore) Start(discussionID, userID int, run func(ctx context.Context) (*NotifyResult, error)) (NotifyJob, error) {
+	id, err := randomToken()
+	if err != nil {
+		return NotifyJob{}, err
+	}
+	now := time.Now().UTC()
+	job := &NotifyJob{ID: id, DiscussionID: discussionID, UserID: userID, Status: JobRunning, CreatedAt: now}
+
+	js.mu.Lock()
+	js.prune(now)
+	js.jobs[id] = job
+	snapshot := *job
+	js.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), js.timeout)
+		defer cancel()
+		result, err := run(ctx)
+		js.finish(id, result, err)
+	}()
+	return snapshot, nil
+}
+
+// Get returns a copy of the job, or false if it is unknown or expired.
+func (js *JobStore) Get(id string) (NotifyJob, bool) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	job, ok := js.jobs[id]
+	if !ok {
+		return NotifyJob{}, false
+	}
+	return *job, true
+}
+
+func (js *JobStore) finish(id string, result *NotifyResult, err error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	job, ok := js.jobs[id]
+	if !ok {
+		return
+	}
+	now := time.Now().UTC()
+	job.Result = result
+	job.FinishedAt = &now
+	job.Status = JobSucceeded
+	if err != nil {
+		logger.Errorf("notify job %s for discussion %d failed: %v", id, job.DiscussionID, err)
+		job.Status = JobFailed
+		job.Error = err.Error()
+	}
+}
+
+// prune drops jobs that finished more than jobRetention ago. The caller
+// holds js.mu.
+func (js *JobStore) prune(now time.Time) {
+	for id, job := range js.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > jobRetention {
+			delete(js.jobs, id)
+		}
+	}
+}
@@ -3,33 +3,161 @@ package subscription
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 	"go-discussion-app/models"
+	"go-discussion-app/pkg/slowquery"
 )
 
 type Repository struct {
-	db *sql.DB
+	db *slowquery.DB
 }
 
 func NewRepository(db *sql.DB) *Repository {
-	return &Repository{db}
+	return &Repository{slowquery.Wrap(db)}
 }
 
 func (r *Repository) CreateSubscription(sub *models.Subscription) error {
-	query := `INSERT INTO subscriptions (discussion_id, user_id, email, subscribed_at)
-	          VALUES ($1, $2, $3, $4)
+	query := `INSERT INTO subscriptions (discussion_id, user_id, email, subscribed_at, confirmed)
+	          VALUES ($1, $2, $3, $4, TRUE)
 			  ON CONFLICT (discussion_id, email) DO NOTHING`
 	_, err := r.db.Exec(query, sub.DiscussionID, sub.UserID, sub.Email, sub.SubscribedAt)
 	return err
 }
 
+// CreatePendingSubscription stores an unconfirmed subscription carrying the
+// confirm token hash. Asking again for a still-pending subscription replaces
+// its token. It reports false when the email is already confirmed for the
+// discussion, in which case nothing changes.
+func (r *Repository) CreatePendingSubscription(sub *models.Subscription, tokenHash string) (bool, error) {
+	query := `INSERT INTO subscriptions (discussion_id, email, subscribed_at, confirmed, confirm_token_hash)
+	          VALUES ($1, $2, $3, FALSE, $4)
+	          ON CONFLICT (discussion_id, email) DO UPDATE
+	          SET confirm_token_hash = EXCLUDED.confirm_token_hash,
+	              subscribed_at = EXCLUDED.subscribed_at
+	          WHERE subscriptions.confirmed = FALSE
+	          RETURNING id`
+	var id int
+	err := r.db.QueryRow(query, sub.DiscussionID, sub.Email, sub.SubscribedAt, tokenHash).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	sub.ID = id
+	return true, nil
+}
+
+// ConfirmSubscription activates the pending subscription holding tokenHash
+// and reports whether one was found.
+func (r *Repository) ConfirmSubscription(tokenHash string) (bool, error) {
+	res, err := r.db.Exec(
+		`UPDATE subscriptions SET confirmed = TRUE, confirm_token_hash = NULL
+		 WHERE confirm_token_hash = $1 AND confirmed = FALSE`,
+		tokenHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// CountAnonymousRequests counts anonymous subscribe requests from ip since the given time.
+func (r *Repository) CountAnonymousRequests(ip string, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM anonymous_subscribe_requests WHERE ip = $1 AND requested_at > $2`,
+		ip, since,
+	).Scan(&n)
+	return n, err
+}
+
+// RecordAnonymousRequest logs one anonymous subscribe request from ip.
+func (r *Repository) RecordAnonymousRequest(ip string) error {
+	_, err := r.db.Exec(`INSERT INTO anonymous_subscribe_requests (ip) VALUES ($1)`, ip)
+	return err
+}
+
+// CountOtherSubscriptions counts email's subscriptions to discussions other
+// than excludeDiscussionID, so re-subscribing to the same one is never capped.
+func (r *Repository) CountOtherSubscriptions(email string, excludeDiscussionID int) (int, error) {
+	var n int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM subscriptions WHERE email = $1 AND discussion_id <> $2`,
+		email, excludeDiscussionID,
+	).Scan(&n)
+	return n, err
+}
+
 func (r *Repository) DeleteSubscription(discussionID int, email string) error {
 	query := `DELETE FROM subscriptions WHERE discussion_id = $1 AND email = $2`
 	_, err := r.db.Exec(query, discussionID, email)
 	return err
 }
 
+// GetTagID returns the id of the tag called name, or 0 if there is none.
+func (r *Repository) GetTagID(name string) (int, error) {
+	var id int
+	err := r.db.QueryRow(`SELECT id FROM tags WHERE name = $1`, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// CreateTagSubscription makes sub.Email follow sub.TagID. Following a tag
+// twice is a no-op.
+func (r *Repository) CreateTagSubscription(sub *models.TagSubscription) error {
+	query := `INSERT INTO tag_subscriptions (tag_id, user_id, email, subscribed_at)
+	          VALUES ($1, $2, $3, $4)
+	          ON CONFLICT (tag_id, email) DO NOTHING`
+	_, err := r.db.Exec(query, sub.TagID, sub.UserID, sub.Email, sub.SubscribedAt)
+	return err
+}
+
+// DeleteTagSubscription stops email following tagID. Subscriptions to
+// discussions it already brought in are kept.
+func (r *Repository) DeleteTagSubscription(tagID int, email string) error {
+	_, err := r.db.Exec(`DELETE FROM tag_subscriptions WHERE tag_id = $1 AND email = $2`, tagID, email)
+	return err
+}
+
+// GetDiscussion returns the title and content of a live discussion for a
+// notification email, or nil if there is none.
+func (r *Repository) GetDiscussion(discussionID int) (*models.Discussion, error) {
+	d := &models.Discussion{ID: discussionID}
+	err := r.db.QueryRow(`SELECT title, content FROM discussions WHERE id = $1 AND deleted_at IS NULL`, discussionID).
+		Scan(&d.Title, &d.Content)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DiscussionOwner returns the author of a live discussion, or false if there
+// is none.
+func (r *Repository) DiscussionOwner(discussionID int) (int, bool, error) {
+	var userID int
+	err := r.db.QueryRow(`SELECT user_id FROM discussions WHERE id = $1 AND deleted_at IS NULL`, discussionID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return userID, true, nil
+}
+
 func (r *Repository) GetSubscriberEmails(discussionID int) ([]string, error) {
-	rows, err := r.db.Query(`SELECT email FROM subscriptions WHERE discussion_id = $1`, discussionID)
+	rows, err := r.db.Query(`SELECT email FROM subscriptions WHERE discussion_id = $1 AND confirmed`, discussionID)
 	if err != nil {
 		return nil, err
 	}
@@ -45,3 +173,218 @@ func (r *Repository) GetSubscriberEmails(discussionID int) ([]string, error) {
 	}
 	return emails, nil
 }
+
+// IterateSubscriberEmails pages through the discussion's confirmed subscribers
+// with an id above afterID in batches of at most batchSize, calling fn once per
+// non-empty batch. Paging is keyed on id rather than OFFSET so that
+// subscriptions removed by fn (e.g. auto-unsubscribes) do not cause later
+// subscribers to be skipped. At most limit subscribers are visited (limit <= 0
+// visits all); when the limit cuts the iteration short, next is the id of the
+// last one visited, to be passed back as afterID, and otherwise 0.
+// Iteration stops at the first error, which is returned.
+func (r *Repository) IterateSubscriberEmails(discussionID, afterID, limit, batchSize int, fn func([]string) error) (next int, err error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	query := `SELECT id, email FROM subscriptions
+	          WHERE discussion_id = $1 AND confirmed AND id > $2
+	          ORDER BY id
+	          LIMIT $3`
+	lastID := afterID
+	visited := 0
+	for {
+		size := batchSize
+		if limit > 0 && limit-visited < size {
+			size = limit - visited
+		}
+		rows, err := r.db.Query(query, discussionID, lastID, size)
+		if err != nil {
+			return 0, err
+		}
+		emails := make([]string, 0, size)
+		for rows.Next() {
+			var email string
+			if err := rows.Scan(&lastID, &email); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			emails = append(emails, email)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return 0, err
+		}
+
+		if len(emails) == 0 {
+			return 0, nil
+		}
+		if err := fn(emails); err != nil {
+			return 0, err
+		}
+		visited += len(emails)
+		if limit > 0 && visited >= limit {
+			return lastID, nil
+		}
+		if len(emails) < size {
+			return 0, nil
+		}
+	}
+}
+
+// RecordDeliveryFailure increments the failure counter for email and
+// returns the updated number of consecutive failures.
+func (r *Repository) RecordDeliveryFailure(email, reason string) (int, error) {
+	query := `INSERT INTO subscription_delivery (email, failure_count, last_error, last_failed_at)
+	          VALUES ($1, 1, $2, NOW())
+	          ON CONFLICT (email) DO UPDATE
+	          SET failure_count = subscription_delivery.failure_count + 1,
+	              last_error = EXCLUDED.last_error,
+	              last_failed_at = EXCLUDED.last_failed_at
+	          RETURNING failure_count`
+	var count int
+	err := r.db.QueryRow(query, email, reason).Scan(&count)
+	return count, err
+}
+
+// ResetDeliveryFailures clears the failure counter after a successful delivery.
+func (r *Repository) ResetDeliveryFailures(email string) error {
+	_, err := r.db.Exec(`DELETE FROM subscription_delivery WHERE email = $1`, email)
+	return err
+}
+
+// DeleteSubscriptionsByEmail removes every subscription held by email and
+// returns how many were deleted. The email's tag follows go too, in the same
+// statement, so tagging a discussion later cannot subscribe it again.
+func (r *Repository) DeleteSubscriptionsByEmail(email string) (int64, error) {
+	res, err := r.db.Exec(
+		`WITH followed AS (DELETE FROM tag_subscriptions WHERE email = $1)
+		 DELETE FROM subscriptions WHERE email = $1`,
+		email,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Suppress adds email to the suppression list. Suppressing an already
+// suppressed email is a no-op.
+func (r *Repository) Suppress(email, reason string) error {
+	_, err := r.db.Exec(
+		`INSERT INTO suppressed_emails (email, reason) VALUES (lower($1), $2)
+		 ON CONFLICT (email) DO NOTHING`,
+		email, reason,
+	)
+	return err
+}
+
+// IsSuppressed reports whether email is on the suppression list.
+func (r *Repository) IsSuppressed(email string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM suppressed_emails WHERE email = lower($1))`,
+		email,
+	).Scan(&exists)
+	return exists, err
+}
+
+// Unsuppress removes email from the suppression list and reports whether
+// it was there.
+func (r *Repository) Unsuppress(email string) (bool, error) {
+	res, err := r.db.Exec(`DELETE FROM suppressed_emails WHERE email = lower($1)`, email)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// SuppressedAmong returns the lower-cased subset of emails that are suppressed.
+func (r *Repository) SuppressedAmong(emails []string) (map[string]bool, error) {
+	lowered := make([]string, len(emails))
+	for i, e := range emails {
+		lowered[i] = strings.ToLower(e)
+	}
+	rows, err := r.db.Query(`SELECT email FROM suppressed_emails WHERE email = ANY($1)`, pq.Array(lowered))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppressed := make(map[string]bool)
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		suppressed[email] = true
+	}
+	return suppressed, rows.Err()
+}
+
+// UsernamesByEmail returns the usernames of the accounts registered under
+// emails, keyed by lower-cased email. Emails without an account are absent.
+func (r *Repository) UsernamesByEmail(emails []string) (map[string]string, error) {
+	lowered := make([]string, len(emails))
+	for i, e := range emails {
+		lowered[i] = strings.ToLower(e)
+	}
+	rows, err := r.db.Query(`SELECT lower(email), username FROM users WHERE lower(email) = ANY($1)`, pq.Array(lowered))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usernames := make(map[string]string)
+	for rows.Next() {
+		var email, username string
+		if err := rows.Scan(&email, &username); err != nil {
+			return nil, err
+		}
+		usernames[email] = username
+	}
+	return usernames, rows.Err()
+}
+
+// subscriberSortColumns maps the ?sort= values accepted by ListSubscribers
+// to the columns they order by. Only these may reach the ORDER BY clause.
+var subscriberSortColumns = map[string]string{
+	"subscribed_at": "subscribed_at",
+	"email":         "email",
+	"id":            "id",
+}
+
+// ListSubscribers returns one page of a discussion's subscriptions ordered by
+// sort (a key of subscriberSortColumns) in order ("asc" or "desc"), with id as
+// a tie-breaker so pages are stable.
+func (r *Repository) ListSubscribers(discussionID int, sort, order string, limit, offset int) ([]models.Subscription, error) {
+	col, ok := subscriberSortColumns[sort]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort column %q", sort)
+	}
+	dir := "ASC"
+	if order == "desc" {
+		dir = "DESC"
+	}
+	query := fmt.Sprintf(
+		`SELECT id, discussion_id, user_id, email, subscribed_at, confirmed FROM subscriptions
+		 WHERE discussion_id = $1
+		 ORDER BY %s %s, id %s
+		 LIMIT $2 OFFSET $3`, col, dir, dir)
+	rows, err := r.db.Query(query, discussionID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.Subscription{}
+	for rows.Next() {
+		var sub models.Subscription
+		if err := rows.Scan(&sub.ID, &sub.DiscussionID, &sub.UserID, &sub.Email, &sub.SubscribedAt, &sub.Confirmed); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
@@ -0,0 +1,155 @@
+package subscription
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRepositoryWithMockDB(t *testing.T) (*Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewRepository(db), mock
+}
+
+func TestIterateSubscriberEmails_CallsBackPerBatch(t *testing.T) {
+	repo, mock := newRepositoryWithMockDB(t)
+
+	// five subscribers, batch size two: pages of 2, 2, 1
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions\s+WHERE discussion_id = \$1 AND confirmed AND id > \$2\s+ORDER BY id\s+LIMIT \$3`).
+		WithArgs(3, 0, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@x.com").AddRow(2, "b@x.com"))
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(3, 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(5, "c@x.com").AddRow(7, "d@x.com"))
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(3, 7, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(8, "e@x.com"))
+
+	var batches [][]string
+	_, err := repo.IterateSubscriberEmails(3, 0, 0, 2, func(emails []string) error {
+		batches = append(batches, emails)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"a@x.com", "b@x.com"}, {"c@x.com", "d@x.com"}, {"e@x.com"}}, batches)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIterateSubscriberEmails_ExactMultipleEndsOnEmptyPage(t *testing.T) {
+	repo, mock := newRepositoryWithMockDB(t)
+
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(3, 0, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@x.com").AddRow(2, "b@x.com"))
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(3, 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}))
+
+	calls := 0
+	_, err := repo.IterateSubscriberEmails(3, 0, 0, 2, func(emails []string) error {
+		calls++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls, "an empty page must not invoke the callback")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIterateSubscriberEmails_StopsOnCallbackError(t *testing.T) {
+	repo, mock := newRepositoryWithMockDB(t)
+	boom := errors.New("boom")
+
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(3, 0, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@x.com"))
+
+	_, err := repo.IterateSubscriberEmails(3, 0, 0, 1, func(emails []string) error { return boom })
+	assert.ErrorIs(t, err, boom)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIterateSubscriberEmails_StopsAtLimit(t *testing.T) {
+	repo, mock := newRepositoryWithMockDB(t)
+
+	// limit three, batch size two: a page of 2, then one of 1, resuming after 10
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(3, 10, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(11, "a@x.com").AddRow(12, "b@x.com"))
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(3, 12, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(15, "c@x.com"))
+
+	var seen []string
+	next, err := repo.IterateSubscriberEmails(3, 10, 3, 2, func(emails []string) error {
+		seen = append(seen, emails...)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 15, next)
+	assert.Equal(t, []string{"a@x.com", "b@x.com", "c@x.com"}, seen)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIterateSubscriberEmails_UnderLimitHasNoNext(t *testing.T) {
+	repo, mock := newRepositoryWithMockDB(t)
+
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(3, 0, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@x.com"))
+
+	next, err := repo.IterateSubscriberEmails(3, 0, 5, 2, func([]string) error { return nil })
+	assert.NoError(t, err)
+	assert.Zero(t, next)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIterateSubscriberEmails_RejectsBadBatchSize(t *testing.T) {
+	repo, _ := newRepositoryWithMockDB(t)
+	_, err := repo.IterateSubscriberEmails(3, 0, 0, 0, func([]string) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestListSubscribers_OrdersAndPages(t *testing.T) {
+	repo, mock := newRepositoryWithMockDB(t)
+	now := time.Now()
+	cols := []string{"id", "discussion_id", "user_id", "email", "subscribed_at", "confirmed"}
+
+	mock.ExpectQuery(`WHERE discussion_id = \$1\s+ORDER BY subscribed_at DESC, id DESC\s+LIMIT \$2 OFFSET \$3`).
+		WithArgs(3, 2, 4).
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow(9, 3, nil, "new@x.com", now, false).
+			AddRow(8, 3, 1, "old@x.com", now.Add(-time.Hour), true))
+	mock.ExpectQuery(`ORDER BY email ASC, id ASC`).
+		WithArgs(3, 20, 0).
+		WillReturnRows(sqlmock.NewRows(cols))
+
+	subs, err := repo.ListSubscribers(3, "subscribed_at", "desc", 2, 4)
+	assert.NoError(t, err)
+	if assert.Len(t, subs, 2) {
+		assert.Equal(t, "new@x.com", subs[0].Email)
+		assert.Nil(t, subs[0].UserID)
+		assert.False(t, subs[0].Confirmed)
+		assert.Equal(t, 1, *subs[1].UserID)
+		assert.True(t, subs[1].Confirmed)
+	}
+
+	subs, err = repo.ListSubscribers(3, "email", "asc", 20, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, subs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListSubscribers_RejectsUnknownColumn(t *testing.T) {
+	repo, mock := newRepositoryWithMockDB(t)
+
+	_, err := repo.ListSubscribers(3, "email; DROP TABLE subscriptions", "asc", 20, 0)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -5,14 +5,49 @@ import (
 	"database/sql"
 
 	"github.com/gin-gonic/gin"
+	"go-discussion-app/config"
+	"go-discussion-app/internal/auth"
+	"go-discussion-app/models"
+	"go-discussion-app/pkg/featureflags"
+	"go-discussion-app/pkg/mailer"
 )
 
-func RegisterRoutes(rg *gin.RouterGroup, db *sql.DB) {
+// RegisterPublicRoutes mounts the endpoints that work without a token:
+// anonymous subscribe (rate limited per IP), its confirm link and the
+// unsubscribe link in notification emails.
+func RegisterPublicRoutes(r gin.IRouter, db *sql.DB, cfg *config.Config) {
+	if !cfg.Features.Enabled(featureflags.Subscriptions) {
+		return
+	}
+	controller := NewSubscriptionController(NewService(NewRepository(db), cfg, mailer.SMTPMailer{}), nil)
+
+	r.POST("/discussions/:id/subscribe/anonymous", controller.SubscribeAnonymous)
+	r.GET("/subscriptions/confirm", controller.ConfirmSubscription)
+	r.GET("/subscriptions/unsubscribe", controller.UnsubscribeLink)
+}
+
+// RegisterRoutes mounts the subscriber and admin endpoints. Like the public
+// ones they are skipped entirely when FEATURE_SUBSCRIPTIONS is off.
+func RegisterRoutes(rg *gin.RouterGroup, db *sql.DB, cfg *config.Config) {
+	if !cfg.Features.Enabled(featureflags.Subscriptions) {
+		return
+	}
 	repo := NewRepository(db)
-	service := NewService(repo)
-	controller := NewSubscriptionController(service)
+	service := NewService(repo, cfg, mailer.SMTPMailer{})
+	controller := NewSubscriptionController(service, NewJobStore(cfg.NotifyJobTimeout))
 
 	rg.POST("/discussions/:id/subscribe", controller.Subscribe)
 	rg.DELETE("/discussions/:id/unsubscribe", controller.Unsubscribe)
+	// The segment holds the tag's name, but gin needs the wildcard to be
+	// called :id like the one in /tags/:id/trend.
+	rg.POST("/tags/:id/subscribe", controller.SubscribeTag)
+	rg.DELETE("/tags/:id/unsubscribe", controller.UnsubscribeTag)
 	rg.POST("/discussions/:id/notify", controller.Notify)
+	rg.GET("/notify/jobs/:id", controller.NotifyJob)
+
+	admin := auth.RequireRole(models.RoleAdmin)
+	rg.DELETE("/admin/subscriptions", admin, controller.ForceUnsubscribe)
+	rg.POST("/admin/suppressions", admin, controller.AddSuppression)
+	rg.DELETE("/admin/suppressions", admin, controller.RemoveSuppression)
+	rg.GET("/admin/discussions/:id/subscribers", admin, controller.ListSubscribers)
 }
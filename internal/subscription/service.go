@@ -2,34 +2,441 @@
 package subscription
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-discussion-app/config"
 	"go-discussion-app/models"
+	"go-discussion-app/pkg/logger"
 	"go-discussion-app/pkg/mailer"
 )
 
+// DefaultMaxDeliveryFailures is how many consecutive failed deliveries an
+// email may accumulate before it is automatically unsubscribed everywhere.
+const DefaultMaxDeliveryFailures = 5
+
+// DefaultNotifyBatchSize is how many subscriber emails NotifySubscribers
+// loads from the database at a time.
+const DefaultNotifyBatchSize = 500
+
+// ErrSubscriptionLimit is returned when an email already subscribes to
+// Config.MaxSubscriptionsPerEmail discussions.
+var ErrSubscriptionLimit = errors.New("subscription limit reached")
+
+// ErrEmailSuppressed is returned when subscribing an email that is on the
+// suppression list.
+var ErrEmailSuppressed = errors.New("email is suppressed")
+
+// ErrAnonRateLimited is returned when an IP has made
+// Config.AnonSubscribeRateLimit anonymous subscribe requests within
+// Config.AnonSubscribeRateWindow.
+var ErrAnonRateLimited = errors.New("too many anonymous subscribe requests")
+
+// ErrInvalidConfirmToken is returned when a confirm token matches no pending
+// subscription.
+var ErrInvalidConfirmToken = errors.New("invalid or already used confirm token")
+
+// ErrInvalidTemplate is returned by NotifySubscribers when the body is not a
+// valid template.
+var ErrInvalidTemplate = errors.New("invalid notification template")
+
+// ErrDiscussionNotFound is returned by NotifySubscribers when the discussion
+// does not exist or was deleted.
+var ErrDiscussionNotFound = errors.New("discussion not found")
+
+// ErrTagNotFound is returned by SubscribeTag and UnsubscribeTag when no tag
+// has the given name.
+var ErrTagNotFound = errors.New("tag not found")
+
+// ErrInvalidUnsubscribeToken is returned when an unsubscribe link was not
+// issued for that discussion and email.
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// notifySnippetLength is how much of the discussion's content a
+// notification email quotes.
+const notifySnippetLength = 200
+
+// FailedDelivery describes a recipient the mailer could not deliver to.
+type FailedDelivery struct {
+	Email  string `json:"email"`
+	Reason string `json:"reason"`
+}
+
+// NotifyResult summarises a NotifySubscribers run. Next is set when the run
+// stopped at the recipient cap with subscribers left over; NextJobID is the
+// job the controller started to carry on from there.
+type NotifyResult struct {
+	Sent      []string         `json:"sent"`
+	Failed    []FailedDelivery `json:"failed"`
+	Next      int              `json:"-"`
+	NextJobID string           `json:"next_job_id,omitempty"`
+}
+
 type Service struct {
-	repo *Repository
+	repo                *Repository
+	cfg                 *config.Config
+	mail                mailer.Mailer
+	maxDeliveryFailures int
+	notifyBatchSize     int
+	notifyMaxRecipients int
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo}
+// NewService builds a Service that sends confirmations and notifications
+// through m. A nil m uses mailer.SMTPMailer.
+func NewService(repo *Repository, cfg *config.Config, m mailer.Mailer) *Service {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	if m == nil {
+		m = mailer.SMTPMailer{}
+	}
+	return &Service{
+		repo:                repo,
+		cfg:                 cfg,
+		mail:                m,
+		maxDeliveryFailures: DefaultMaxDeliveryFailures,
+		notifyBatchSize:     DefaultNotifyBatchSize,
+		notifyMaxRecipients: cfg.NotifyMaxRecipients,
+	}
 }
 
+// Subscribe adds the subscription unless the email is suppressed or has
+// already reached cfg.MaxSubscriptionsPerEmail other discussions.
 func (s *Service) Subscribe(sub *models.Subscription) error {
+	suppressed, err := s.repo.IsSuppressed(sub.Email)
+	if err != nil {
+		return err
+	}
+	if suppressed {
+		return ErrEmailSuppressed
+	}
+	if limit := s.cfg.MaxSubscriptionsPerEmail; limit > 0 {
+		n, err := s.repo.CountOtherSubscriptions(sub.Email, sub.DiscussionID)
+		if err != nil {
+			return err
+		}
+		if n >= limit {
+			return ErrSubscriptionLimit
+		}
+	}
 	return s.repo.CreateSubscription(sub)
 }
 
+// SubscribeAnonymous records an unconfirmed subscription for a caller
+// without a token and mails the confirm token to sub.Email. Requests are
+// capped per ip by cfg.AnonSubscribeRateLimit. Asking again for an email
+// that is already confirmed succeeds without sending anything, so the
+// endpoint does not reveal who is subscribed.
+func (s *Service) SubscribeAnonymous(sub *models.Subscription, ip string) error {
+	if limit := s.cfg.AnonSubscribeRateLimit; limit > 0 {
+		n, err := s.repo.CountAnonymousRequests(ip, time.Now().UTC().Add(-s.cfg.AnonSubscribeRateWindow))
+		if err != nil {
+			return err
+		}
+		if n >= limit {
+			return ErrAnonRateLimited
+		}
+	}
+	if err := s.repo.RecordAnonymousRequest(ip); err != nil {
+		return err
+	}
+
+	suppressed, err := s.repo.IsSuppressed(sub.Email)
+	if err != nil {
+		return err
+	}
+	if suppressed {
+		return ErrEmailSuppressed
+	}
+	if limit := s.cfg.MaxSubscriptionsPerEmail; limit > 0 {
+		n, err := s.repo.CountOtherSubscriptions(sub.Email, sub.DiscussionID)
+		if err != nil {
+			return err
+		}
+		if n >= limit {
+			return ErrSubscriptionLimit
+		}
+	}
+
+	raw, err := randomToken()
+	if err != nil {
+		return err
+	}
+	pending, err := s.repo.CreatePendingSubscription(sub, hashToken(raw))
+	if err != nil || !pending {
+		return err
+	}
+	body := fmt.Sprintf("Someone asked to email you about updates to discussion #%d.\n\n"+
+		"Confirm by opening this link:\n\n%s\n\n"+
+		"If this wasn't you, ignore this email.",
+		sub.DiscussionID, s.cfg.BaseURL+"/subscriptions/confirm?"+url.Values{"token": {raw}}.Encode())
+	return s.mail.Send([]string{sub.Email}, "Confirm your subscription", body)
+}
+
+// ConfirmSubscription activates the pending subscription the raw token was
+// issued for.
+func (s *Service) ConfirmSubscription(raw string) error {
+	ok, err := s.repo.ConfirmSubscription(hashToken(raw))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidConfirmToken
+	}
+	return nil
+}
+
 func (s *Service) Unsubscribe(discussionID int, email string) error {
 	return s.repo.DeleteSubscription(discussionID, email)
 }
 
-func (s *Service) NotifySubscribers(discussionID int, subject, body string) error {
-	emails, err := s.repo.GetSubscriberEmails(discussionID)
+// SubscribeTag makes sub.Email follow the tag called name, so it is
+// subscribed to each discussion the tag is attached to from now on.
+// Suppressed emails are refused as in Subscribe; the per-email cap applies
+// to discussions, not tags.
+func (s *Service) SubscribeTag(name string, sub *models.TagSubscription) error {
+	suppressed, err := s.repo.IsSuppressed(sub.Email)
+	if err != nil {
+		return err
+	}
+	if suppressed {
+		return ErrEmailSuppressed
+	}
+	tagID, err := s.repo.GetTagID(name)
+	if err != nil {
+		return err
+	}
+	if tagID == 0 {
+		return ErrTagNotFound
+	}
+	sub.TagID = tagID
+	return s.repo.CreateTagSubscription(sub)
+}
+
+// UnsubscribeTag stops email following the tag called name.
+func (s *Service) UnsubscribeTag(name, email string) error {
+	tagID, err := s.repo.GetTagID(name)
+	if err != nil {
+		return err
+	}
+	if tagID == 0 {
+		return ErrTagNotFound
+	}
+	return s.repo.DeleteTagSubscription(tagID, email)
+}
+
+// Suppress adds email to the suppression list without touching its
+// existing subscriptions.
+func (s *Service) Suppress(email, reason string) error {
+	return s.repo.Suppress(email, reason)
+}
+
+// Unsuppress lifts a suppression and reports whether one existed.
+func (s *Service) Unsuppress(email string) (bool, error) {
+	return s.repo.Unsuppress(email)
+}
+
+// DiscussionOwner returns the author of a live discussion, or false if there
+// is none.
+func (s *Service) DiscussionOwner(discussionID int) (int, bool, error) {
+	return s.repo.DiscussionOwner(discussionID)
+}
+
+// ListSubscribers returns one sorted page of a discussion's subscriptions.
+func (s *Service) ListSubscribers(discussionID int, sort, order string, limit, offset int) ([]models.Subscription, error) {
+	return s.repo.ListSubscribers(discussionID, sort, order, limit, offset)
+}
+
+// ForceUnsubscribe removes email from every discussion and tag it follows and
+// returns how many discussion subscriptions were dropped. With suppress set,
+// the email is added to the suppression list first so it cannot re-subscribe
+// in between.
+func (s *Service) ForceUnsubscribe(email string, suppress bool) (int64, error) {
+	if suppress {
+		if err := s.repo.Suppress(email, "force-unsubscribed by admin"); err != nil {
+			return 0, fmt.Errorf("failed to suppress email: %w", err)
+		}
+	}
+	return s.repo.DeleteSubscriptionsByEmail(email)
+}
+
+// NotifySubscribers mails every confirmed subscriber of the discussion individually so
+// that a failing address can be identified, recorded and, after
+// maxDeliveryFailures consecutive failures, unsubscribed from all discussions.
+// Subscribers are loaded notifyBatchSize at a time, starting after the
+// subscription id afterID (0 for the first), and a run covers at most
+// notifyMaxRecipients of them; result.Next is where the next run should start.
+// body is a template rendered per recipient with a NotifyRecipient, and
+// goes out inside mailer.TemplateNotification together with the
+// discussion's title, a snippet, a link to it and the recipient's own
+// unsubscribe link. If the mailer is not configured the run stops with an
+// error wrapping mailer.ErrNotConfigured. Once ctx is done no further mail is sent and
+// ctx.Err() is returned along with the partial result.
+func (s *Service) NotifySubscribers(ctx context.Context, discussionID, afterID int, subject, body string) (*NotifyResult, error) {
+	result := &NotifyResult{Sent: []string{}, Failed: []FailedDelivery{}}
+	tmpl, err := parseNotifyBody(body)
 	if err != nil {
-		return fmt.Errorf("failed to get emails: %w", err)
+		return result, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
 	}
-	if len(emails) == 0 {
+	personalised := !isStaticTemplate(tmpl)
+	d, err := s.repo.GetDiscussion(discussionID)
+	if err != nil {
+		return result, fmt.Errorf("failed to load discussion: %w", err)
+	}
+	if d == nil {
+		return result, ErrDiscussionNotFound
+	}
+	discussionURL := fmt.Sprintf("%s/discussions/%d", s.cfg.BaseURL, discussionID)
+	excerpt := snippet(d.Content, notifySnippetLength)
+
+	next, err := s.repo.IterateSubscriberEmails(discussionID, afterID, s.notifyMaxRecipients, s.notifyBatchSize, func(emails []string) error {
+		// Subscribe already refuses suppressed emails; this catches any
+		// subscription that predates the suppression.
+		suppressed, err := s.repo.SuppressedAmong(emails)
+		if err != nil {
+			return fmt.Errorf("failed to check suppression list: %w", err)
+		}
+		// Only look subscribers up when the body actually uses placeholders.
+		var usernames map[string]string
+		if personalised {
+			if usernames, err = s.repo.UsernamesByEmail(emails); err != nil {
+				return fmt.Errorf("failed to look up subscriber names: %w", err)
+			}
+		}
+		for _, email := range emails {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if suppressed[strings.ToLower(email)] {
+				continue
+			}
+			data := mailer.NotificationData{
+				Subject:         subject,
+				Email:           email,
+				Message:         body,
+				DiscussionTitle: d.Title,
+				Snippet:         excerpt,
+				DiscussionURL:   discussionURL,
+				UnsubscribeURL:  s.unsubscribeURL(discussionID, email),
+			}
+			if personalised {
+				var buf strings.Builder
+				if err := tmpl.Execute(&buf, recipientFor(email, usernames)); err != nil {
+					return fmt.Errorf("failed to render notification for %s: %w", email, err)
+				}
+				data.Message = buf.String()
+			}
+			if sendErr := s.mail.SendTemplate([]string{email}, mailer.TemplateNotification, data); sendErr != nil {
+				// Not the recipient's fault: stop instead of counting a
+				// delivery failure against every subscriber.
+				if errors.Is(sendErr, mailer.ErrNotConfigured) {
+					return sendErr
+				}
+				result.Failed = append(result.Failed, FailedDelivery{Email: email, Reason: sendErr.Error()})
+				if err := s.recordFailure(email, sendErr.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+			result.Sent = append(result.Sent, email)
+			if err := s.repo.ResetDeliveryFailures(email); err != nil {
+				return fmt.Errorf("failed to reset delivery status: %w", err)
+			}
+		}
 		return nil
+	})
+	if err != nil {
+		return result, err
 	}
-	return mailer.SendMail(emails, subject, body)
+	result.Next = next
+	return result, nil
+}
+
+// recipientFor builds the template data for email, falling back to the
+// email's local part when no account uses it.
+func recipientFor(email string, usernames map[string]string) NotifyRecipient {
+	name := usernames[strings.ToLower(email)]
+	if name == "" {
+		name = email
+		if at := strings.LastIndex(email, "@"); at > 0 {
+			name = email[:at]
+		}
+	}
+	return NotifyRecipient{Username: name, Email: email}
+}
+
+// snippet collapses the whitespace in content and cuts it to at most n
+// runes, marking a cut with an ellipsis.
+func snippet(content string, n int) string {
+	text := strings.Join(strings.Fields(content), " ")
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:n])) + "…"
+}
+
+// unsubscribeToken signs discussionID and email with the JWT secret, so an
+// unsubscribe link needs no stored state and cannot be forged for another
+// address.
+func (s *Service) unsubscribeToken(discussionID int, email string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.JWTSecret))
+	fmt.Fprintf(mac, "unsubscribe:%d:%s", discussionID, strings.ToLower(email))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// unsubscribeURL is the link in a notification that removes email from
+// discussionID alone.
+func (s *Service) unsubscribeURL(discussionID int, email string) string {
+	q := url.Values{}
+	q.Set("discussion_id", strconv.Itoa(discussionID))
+	q.Set("email", email)
+	q.Set("token", s.unsubscribeToken(discussionID, email))
+	return s.cfg.BaseURL + "/subscriptions/unsubscribe?" + q.Encode()
+}
+
+// UnsubscribeWithToken follows an unsubscribe link from a notification.
+func (s *Service) UnsubscribeWithToken(discussionID int, email, token string) error {
+	want := s.unsubscribeToken(discussionID, email)
+	if !hmac.Equal([]byte(token), []byte(want)) {
+		return ErrInvalidUnsubscribeToken
+	}
+	return s.repo.DeleteSubscription(discussionID, email)
+}
+
+func (s *Service) recordFailure(email, reason string) error {
+	count, err := s.repo.RecordDeliveryFailure(email, reason)
+	if err != nil {
+		return fmt.Errorf("failed to record delivery failure: %w", err)
+	}
+	if count >= s.maxDeliveryFailures {
+		logger.Warnf("auto-unsubscribing %s after %d failed deliveries", email, count)
+		if _, err := s.repo.DeleteSubscriptionsByEmail(email); err != nil {
+			return fmt.Errorf("failed to auto-unsubscribe: %w", err)
+		}
+	}
+	return nil
+}
+
+// randomToken returns 32 random bytes, hex encoded.
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,621 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/config"
+	"go-discussion-app/models"
+	"go-discussion-app/pkg/mailer"
+)
+
+// newServiceWithMockDB returns a real Service whose Repository talks to sqlmock.
+func newServiceWithMockDB(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	unexpected := mailer.Func(func(to []string, subject, body string) error {
+		t.Errorf("unexpected mail to %v: %q", to, subject)
+		return nil
+	})
+	return NewService(NewRepository(db), nil, unexpected), mock
+}
+
+// stubMailer makes svc send through fn.
+func stubMailer(svc *Service, fn func(to []string, subject, body string) error) {
+	svc.mail = mailer.Func(fn)
+}
+
+func TestNotifySubscribers_RecordsFailures(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	stubMailer(svc, func(to []string, subject, body string) error {
+		if to[0] == "bad@example.com" {
+			return errors.New("550 mailbox unavailable")
+		}
+		return nil
+	})
+
+	expectDiscussion(mock, 10)
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(10, 0, DefaultNotifyBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "ok@example.com").AddRow(2, "bad@example.com"))
+	expectNoneSuppressed(mock)
+	mock.ExpectExec(`DELETE FROM subscription_delivery WHERE email = \$1`).
+		WithArgs("ok@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO subscription_delivery`).
+		WithArgs("bad@example.com", "550 mailbox unavailable").
+		WillReturnRows(sqlmock.NewRows([]string{"failure_count"}).AddRow(1))
+
+	result, err := svc.NotifySubscribers(context.Background(), 10, 0, "Update", "New post!")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ok@example.com"}, result.Sent)
+	assert.Equal(t, []FailedDelivery{{Email: "bad@example.com", Reason: "550 mailbox unavailable"}}, result.Failed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Every subscriber gets a message of their own, so no address is exposed
+// to the others in the To header, and a failure in the middle does not stop
+// the rest.
+func TestNotifySubscribers_OneMessagePerRecipient(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	var sends [][]string
+	stubMailer(svc, func(to []string, subject, body string) error {
+		sends = append(sends, to)
+		if to[0] == "b@example.com" {
+			return errors.New("550 mailbox unavailable")
+		}
+		return nil
+	})
+
+	expectDiscussion(mock, 10)
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(10, 0, DefaultNotifyBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@example.com").AddRow(2, "b@example.com").AddRow(3, "c@example.com"))
+	expectNoneSuppressed(mock)
+	mock.ExpectExec(`DELETE FROM subscription_delivery`).WithArgs("a@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO subscription_delivery`).
+		WithArgs("b@example.com", "550 mailbox unavailable").
+		WillReturnRows(sqlmock.NewRows([]string{"failure_count"}).AddRow(1))
+	mock.ExpectExec(`DELETE FROM subscription_delivery`).WithArgs("c@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	result, err := svc.NotifySubscribers(context.Background(), 10, 0, "Update", "New post!")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"a@example.com"}, {"b@example.com"}, {"c@example.com"}}, sends)
+	assert.Equal(t, []string{"a@example.com", "c@example.com"}, result.Sent)
+	assert.Len(t, result.Failed, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotifySubscribers_StopsWhenMailerNotConfigured(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	calls := 0
+	stubMailer(svc, func(to []string, subject, body string) error {
+		calls++
+		return fmt.Errorf("%w: missing SMTP_HOST", mailer.ErrNotConfigured)
+	})
+
+	expectDiscussion(mock, 10)
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(10, 0, DefaultNotifyBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@example.com").AddRow(2, "b@example.com"))
+	expectNoneSuppressed(mock)
+
+	// no delivery failure is recorded for anyone
+	result, err := svc.NotifySubscribers(context.Background(), 10, 0, "Update", "New post!")
+	assert.ErrorIs(t, err, mailer.ErrNotConfigured)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, result.Failed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotifySubscribers_StopsWhenContextDone(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	stubMailer(svc, func(to []string, subject, body string) error {
+		cancel() // the deadline passes while the first email is being sent
+		return nil
+	})
+
+	expectDiscussion(mock, 10)
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(10, 0, DefaultNotifyBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@example.com").AddRow(2, "b@example.com"))
+	expectNoneSuppressed(mock)
+	mock.ExpectExec(`DELETE FROM subscription_delivery WHERE email = \$1`).
+		WithArgs("a@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	result, err := svc.NotifySubscribers(ctx, 10, 0, "Update", "New post!")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"a@example.com"}, result.Sent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotifySubscribers_AutoUnsubscribesAtThreshold(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	svc.maxDeliveryFailures = 3
+	stubMailer(svc, func(to []string, subject, body string) error {
+		return errors.New("550 mailbox unavailable")
+	})
+
+	expectDiscussion(mock, 10)
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(10, 0, DefaultNotifyBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "bad@example.com"))
+	expectNoneSuppressed(mock)
+	mock.ExpectQuery(`INSERT INTO subscription_delivery`).
+		WithArgs("bad@example.com", "550 mailbox unavailable").
+		WillReturnRows(sqlmock.NewRows([]string{"failure_count"}).AddRow(3))
+	mock.ExpectExec(`WITH followed AS \(DELETE FROM tag_subscriptions WHERE email = \$1\)\s+DELETE FROM subscriptions WHERE email = \$1`).
+		WithArgs("bad@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	result, err := svc.NotifySubscribers(context.Background(), 10, 0, "Update", "New post!")
+	assert.NoError(t, err)
+	assert.Empty(t, result.Sent)
+	assert.Len(t, result.Failed, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotifySubscribers_BelowThresholdKeepsSubscription(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	svc.maxDeliveryFailures = 3
+	stubMailer(svc, func(to []string, subject, body string) error {
+		return errors.New("timeout")
+	})
+
+	expectDiscussion(mock, 10)
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(10, 0, DefaultNotifyBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "flaky@example.com"))
+	expectNoneSuppressed(mock)
+	mock.ExpectQuery(`INSERT INTO subscription_delivery`).
+		WithArgs("flaky@example.com", "timeout").
+		WillReturnRows(sqlmock.NewRows([]string{"failure_count"}).AddRow(2))
+
+	_, err := svc.NotifySubscribers(context.Background(), 10, 0, "Update", "New post!")
+	assert.NoError(t, err)
+	// No DELETE FROM subscriptions expected.
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotifySubscribers_SendsInBatches(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	svc.notifyBatchSize = 2
+	var sent []string
+	stubMailer(svc, func(to []string, subject, body string) error {
+		sent = append(sent, to...)
+		return nil
+	})
+
+	expectDiscussion(mock, 10)
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(10, 0, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@example.com").AddRow(4, "b@example.com"))
+	expectNoneSuppressed(mock)
+	mock.ExpectExec(`DELETE FROM subscription_delivery`).WithArgs("a@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM subscription_delivery`).WithArgs("b@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(10, 4, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(9, "c@example.com"))
+	expectNoneSuppressed(mock)
+	mock.ExpectExec(`DELETE FROM subscription_delivery`).WithArgs("c@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	result, err := svc.NotifySubscribers(context.Background(), 10, 0, "Update", "New post!")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a@example.com", "b@example.com", "c@example.com"}, sent)
+	assert.Equal(t, sent, result.Sent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotifySubscribers_StopsAtRecipientCap(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	svc.notifyMaxRecipients = 2
+	var sent []string
+	stubMailer(svc, func(to []string, subject, body string) error {
+		sent = append(sent, to...)
+		return nil
+	})
+
+	// Three subscribers after id 4, but only two are mailed by this run.
+	expectDiscussion(mock, 10)
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(10, 4, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(6, "a@example.com").AddRow(8, "b@example.com"))
+	expectNoneSuppressed(mock)
+	mock.ExpectExec(`DELETE FROM subscription_delivery`).WithArgs("a@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM subscription_delivery`).WithArgs("b@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	result, err := svc.NotifySubscribers(context.Background(), 10, 4, "Update", "New post!")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, sent)
+	assert.Equal(t, 8, result.Next)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotifySubscribers_RendersTemplatePerRecipient(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	bodies := map[string]string{}
+	stubMailer(svc, func(to []string, subject, body string) error {
+		bodies[to[0]] = body
+		return nil
+	})
+
+	expectDiscussion(mock, 10)
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(10, 0, DefaultNotifyBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "Alice@Example.com").AddRow(2, "anon@example.com"))
+	expectNoneSuppressed(mock)
+	mock.ExpectQuery(`SELECT lower\(email\), username FROM users WHERE lower\(email\) = ANY\(\$1\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "username"}).AddRow("alice@example.com", "alice_w"))
+	mock.ExpectExec(`DELETE FROM subscription_delivery`).WithArgs("Alice@Example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM subscription_delivery`).WithArgs("anon@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	result, err := svc.NotifySubscribers(context.Background(), 10, 0, "Update", "Hi {{.Username}}, there is a new post.")
+	assert.NoError(t, err)
+	assert.Len(t, result.Sent, 2)
+	assert.Contains(t, bodies["Alice@Example.com"], "Hi alice_w, there is a new post.")
+	assert.Contains(t, bodies["anon@example.com"], "Hi anon, there is a new post.", "no account: falls back to the email's local part")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotifySubscribers_RejectsBadTemplate(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	stubMailer(svc, func(to []string, subject, body string) error {
+		t.Fatal("nothing should be sent")
+		return nil
+	})
+
+	_, err := svc.NotifySubscribers(context.Background(), 10, 0, "Update", "Hi {{.Username")
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectDiscussion answers NotifySubscribers' lookup of the discussion it
+// is notifying about.
+func expectDiscussion(mock sqlmock.Sqlmock, id int) {
+	mock.ExpectQuery(`SELECT title, content FROM discussions WHERE id = \$1 AND deleted_at IS NULL`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"title", "content"}).AddRow("Go generics", "Type parameters landed in 1.18."))
+}
+
+// expectNoneSuppressed expects the per-batch suppression lookup done by
+// NotifySubscribers and reports no suppressed addresses.
+func expectNoneSuppressed(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT email FROM suppressed_emails WHERE email = ANY\(\$1\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}))
+}
+
+func expectNotSuppressed(mock sqlmock.Sqlmock, email string) {
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM suppressed_emails WHERE email = lower\(\$1\)\)`).
+		WithArgs(email).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+}
+
+func TestSubscribe_EnforcesPerEmailCap(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	svc.cfg = &config.Config{MaxSubscriptionsPerEmail: 2}
+
+	// one existing subscription elsewhere: allowed, reaching the cap
+	expectNotSuppressed(mock, "a@example.com")
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM subscriptions WHERE email = \$1 AND discussion_id <> \$2`).
+		WithArgs("a@example.com", 11).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec(`INSERT INTO subscriptions`).WillReturnResult(sqlmock.NewResult(1, 1))
+	assert.NoError(t, svc.Subscribe(&models.Subscription{DiscussionID: 11, Email: "a@example.com"}))
+
+	// two elsewhere: rejected without inserting
+	expectNotSuppressed(mock, "a@example.com")
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM subscriptions`).
+		WithArgs("a@example.com", 12).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	err := svc.Subscribe(&models.Subscription{DiscussionID: 12, Email: "a@example.com"})
+	assert.ErrorIs(t, err, ErrSubscriptionLimit)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubscribe_NoCapWhenDisabled(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	expectNotSuppressed(mock, "a@example.com")
+
+	mock.ExpectExec(`INSERT INTO subscriptions`).WillReturnResult(sqlmock.NewResult(1, 1))
+	assert.NoError(t, svc.Subscribe(&models.Subscription{DiscussionID: 11, Email: "a@example.com"}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubscribeTag_ResolvesNameAndFollows(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	uid := 3
+	at := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	expectNotSuppressed(mock, "a@example.com")
+	mock.ExpectQuery(`SELECT id FROM tags WHERE name = \$1`).
+		WithArgs("golang").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec(`INSERT INTO tag_subscriptions \(tag_id, user_id, email, subscribed_at\)\s+VALUES \(\$1, \$2, \$3, \$4\)\s+ON CONFLICT \(tag_id, email\) DO NOTHING`).
+		WithArgs(5, &uid, "a@example.com", at).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	sub := &models.TagSubscription{UserID: &uid, Email: "a@example.com", SubscribedAt: at}
+	assert.NoError(t, svc.SubscribeTag("golang", sub))
+	assert.Equal(t, 5, sub.TagID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubscribeTag_UnknownTag(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+
+	expectNotSuppressed(mock, "a@example.com")
+	mock.ExpectQuery(`SELECT id FROM tags WHERE name = \$1`).
+		WithArgs("nope").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	err := svc.SubscribeTag("nope", &models.TagSubscription{Email: "a@example.com"})
+	assert.ErrorIs(t, err, ErrTagNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubscribeTag_RefusesSuppressedEmail(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM suppressed_emails`).
+		WithArgs("a@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	err := svc.SubscribeTag("golang", &models.TagSubscription{Email: "a@example.com"})
+	assert.ErrorIs(t, err, ErrEmailSuppressed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnsubscribeTag_ResolvesName(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+
+	mock.ExpectQuery(`SELECT id FROM tags WHERE name = \$1`).
+		WithArgs("golang").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec(`DELETE FROM tag_subscriptions WHERE tag_id = \$1 AND email = \$2`).
+		WithArgs(5, "a@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, svc.UnsubscribeTag("golang", "a@example.com"))
+
+	mock.ExpectQuery(`SELECT id FROM tags WHERE name = \$1`).
+		WithArgs("nope").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	assert.ErrorIs(t, svc.UnsubscribeTag("nope", "a@example.com"), ErrTagNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubscribeAnonymous_SendsConfirmTokenThenConfirms(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	svc.cfg = &config.Config{BaseURL: "https://forum.example.com", AnonSubscribeRateLimit: 3, AnonSubscribeRateWindow: time.Hour}
+	var mailed string
+	stubMailer(svc, func(to []string, subject, body string) error {
+		assert.Equal(t, []string{"anon@example.com"}, to)
+		mailed = body
+		return nil
+	})
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM anonymous_subscribe_requests WHERE ip = \$1 AND requested_at > \$2`).
+		WithArgs("203.0.113.7", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(`INSERT INTO anonymous_subscribe_requests \(ip\)`).
+		WithArgs("203.0.113.7").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectNotSuppressed(mock, "anon@example.com")
+	mock.ExpectQuery(`INSERT INTO subscriptions \(discussion_id, email, subscribed_at, confirmed, confirm_token_hash\)`).
+		WithArgs(11, "anon@example.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	sub := &models.Subscription{DiscussionID: 11, Email: "anon@example.com"}
+	assert.NoError(t, svc.SubscribeAnonymous(sub, "203.0.113.7"))
+	assert.Equal(t, 5, sub.ID)
+
+	// the mailed link carries a token that is redeemed by its hash
+	var token string
+	for _, field := range strings.Fields(mailed) {
+		if strings.HasPrefix(field, "https://forum.example.com/subscriptions/confirm?token=") {
+			u, err := url.Parse(field)
+			assert.NoError(t, err)
+			token = u.Query().Get("token")
+		}
+	}
+	if assert.NotEmpty(t, token, "confirm email should contain the token") {
+		mock.ExpectExec(`UPDATE subscriptions SET confirmed = TRUE, confirm_token_hash = NULL`).
+			WithArgs(hashToken(token)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		assert.NoError(t, svc.ConfirmSubscription(token))
+	}
+
+	mock.ExpectExec(`UPDATE subscriptions SET confirmed = TRUE`).
+		WithArgs(hashToken("nope")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, svc.ConfirmSubscription("nope"), ErrInvalidConfirmToken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubscribeAnonymous_AlreadyConfirmedSendsNothing(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	stubMailer(svc, func(to []string, subject, body string) error {
+		t.Fatal("no confirm email expected for an already confirmed subscription")
+		return nil
+	})
+
+	mock.ExpectExec(`INSERT INTO anonymous_subscribe_requests`).WillReturnResult(sqlmock.NewResult(1, 1))
+	expectNotSuppressed(mock, "anon@example.com")
+	mock.ExpectQuery(`INSERT INTO subscriptions`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	assert.NoError(t, svc.SubscribeAnonymous(&models.Subscription{DiscussionID: 11, Email: "anon@example.com"}, "203.0.113.7"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubscribeAnonymous_RateLimitedPerIP(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	svc.cfg = &config.Config{AnonSubscribeRateLimit: 3, AnonSubscribeRateWindow: time.Hour}
+	stubMailer(svc, func(to []string, subject, body string) error {
+		t.Fatal("no email expected when rate limited")
+		return nil
+	})
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM anonymous_subscribe_requests`).
+		WithArgs("203.0.113.7", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	err := svc.SubscribeAnonymous(&models.Subscription{DiscussionID: 11, Email: "anon@example.com"}, "203.0.113.7")
+	assert.ErrorIs(t, err, ErrAnonRateLimited)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestForceUnsubscribe_RemovesAllSubscriptions(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+
+	mock.ExpectExec(`WITH followed AS \(DELETE FROM tag_subscriptions WHERE email = \$1\)\s+DELETE FROM subscriptions WHERE email = \$1`).
+		WithArgs("a@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	removed, err := svc.ForceUnsubscribe("a@example.com", false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), removed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestForceUnsubscribe_SuppressBlocksResubscribe(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+
+	mock.ExpectExec(`INSERT INTO suppressed_emails \(email, reason\) VALUES \(lower\(\$1\), \$2\)`).
+		WithArgs("A@example.com", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`WITH followed AS \(DELETE FROM tag_subscriptions WHERE email = \$1\)\s+DELETE FROM subscriptions WHERE email = \$1`).
+		WithArgs("A@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	removed, err := svc.ForceUnsubscribe("A@example.com", true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+
+	// re-subscribing is refused before anything is written
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM suppressed_emails`).
+		WithArgs("a@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	err = svc.Subscribe(&models.Subscription{DiscussionID: 4, Email: "a@example.com"})
+	assert.ErrorIs(t, err, ErrEmailSuppressed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotifySubscribers_SkipsSuppressedEmails(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	var sent []string
+	stubMailer(svc, func(to []string, subject, body string) error {
+		sent = append(sent, to...)
+		return nil
+	})
+
+	expectDiscussion(mock, 10)
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(10, 0, DefaultNotifyBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "ok@example.com").AddRow(2, "Blocked@example.com"))
+	mock.ExpectQuery(`SELECT email FROM suppressed_emails WHERE email = ANY\(\$1\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("blocked@example.com"))
+	mock.ExpectExec(`DELETE FROM subscription_delivery`).WithArgs("ok@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	result, err := svc.NotifySubscribers(context.Background(), 10, 0, "Update", "New post!")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ok@example.com"}, sent)
+	assert.Equal(t, []string{"ok@example.com"}, result.Sent)
+	assert.Empty(t, result.Failed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnsuppress_RemovesEntry(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+
+	mock.ExpectExec(`DELETE FROM suppressed_emails WHERE email = lower\(\$1\)`).
+		WithArgs("A@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM suppressed_emails`).
+		WithArgs("missing@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	removed, err := svc.Unsuppress("A@example.com")
+	assert.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = svc.Unsuppress("missing@example.com")
+	assert.NoError(t, err)
+	assert.False(t, removed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotifySubscribers_BuildsTemplateData(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	svc.cfg.BaseURL = "https://forum.example.com"
+	svc.cfg.JWTSecret = "secret"
+	var html string
+	stubMailer(svc, func(to []string, subject, body string) error {
+		html = body
+		return nil
+	})
+
+	expectDiscussion(mock, 10)
+	mock.ExpectQuery(`SELECT id, email FROM subscriptions`).
+		WithArgs(10, 0, DefaultNotifyBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@example.com"))
+	expectNoneSuppressed(mock)
+	mock.ExpectExec(`DELETE FROM subscription_delivery`).WithArgs("a@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err := svc.NotifySubscribers(context.Background(), 10, 0, "Update", "There is a new post.")
+	assert.NoError(t, err)
+	assert.Contains(t, html, "There is a new post.")
+	assert.Contains(t, html, ">Go generics</a>")
+	assert.Contains(t, html, "Type parameters landed in 1.18.")
+	assert.Contains(t, html, `href="https://forum.example.com/discussions/10"`)
+	token := svc.unsubscribeToken(10, "a@example.com")
+	assert.Contains(t, html, "https://forum.example.com/subscriptions/unsubscribe?discussion_id=10&amp;email=a%40example.com&amp;token="+token)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotifySubscribers_DiscussionNotFound(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	mock.ExpectQuery(`SELECT title, content FROM discussions`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"title", "content"}))
+
+	_, err := svc.NotifySubscribers(context.Background(), 10, 0, "Update", "There is a new post.")
+	assert.ErrorIs(t, err, ErrDiscussionNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnsubscribeWithToken(t *testing.T) {
+	svc, mock := newServiceWithMockDB(t)
+	svc.cfg.JWTSecret = "secret"
+	token := svc.unsubscribeToken(10, "A@example.com")
+
+	assert.ErrorIs(t, svc.UnsubscribeWithToken(10, "b@example.com", token), ErrInvalidUnsubscribeToken)
+	assert.ErrorIs(t, svc.UnsubscribeWithToken(11, "A@example.com", token), ErrInvalidUnsubscribeToken)
+	assert.ErrorIs(t, svc.UnsubscribeWithToken(10, "A@example.com", "deadbeef"), ErrInvalidUnsubscribeToken)
+
+	mock.ExpectExec(`DELETE FROM subscriptions WHERE discussion_id = \$1 AND email = \$2`).
+		WithArgs(10, "a@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, svc.UnsubscribeWithToken(10, "a@example.com", token), "the token ignores the email's case")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSnippet(t *testing.T) {
+	assert.Equal(t, "short text", snippet("  short\n\ttext ", 20))
+	assert.Equal(t, "abc…", snippet("abc def", 4))
+	assert.Equal(t, "héllo…", snippet("héllo wörld", 5))
+}
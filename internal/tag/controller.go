@@ -3,8 +3,11 @@ package tag
 
 import (
     "net/http"
+    "strconv"
+    "time"
 
     "github.com/gin-gonic/gin"
+    "go-discussion-app/pkg/httperr"
     "go-discussion-app/pkg/logger"
 )
 
@@ -22,9 +25,100 @@ func NewController(svc *TagService) *TagController {
 func (ctr *TagController) ListHandler(c *gin.Context) {
     tags, err := ctr.svc.ListTags(c.Request.Context())
     if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
         logger.Errorf("failed to list tags: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
         return
     }
     c.JSON(http.StatusOK, tags)
 }
+
+// AvailableHandler handles GET /tags/available?name=
+func (ctr *TagController) AvailableHandler(c *gin.Context) {
+    name := NormalizeName(c.Query("name"))
+    if name == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+        return
+    }
+    available, err := ctr.svc.IsAvailable(c.Request.Context(), name)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("failed to check tag availability: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"name": name, "available": available})
+}
+
+// ByNamesHandler handles GET /tags/by-names?names=go,postgres
+func (ctr *TagController) ByNamesHandler(c *gin.Context) {
+    names, err := ParseNames(c.Query("names"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    tags, err := ctr.svc.GetByNames(c.Request.Context(), names)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("failed to fetch tags by names: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        return
+    }
+    c.JSON(http.StatusOK, tags)
+}
+
+// TrendHandler handles GET /tags/:id/trend?interval=&from=&to=
+func (ctr *TagController) TrendHandler(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil || id <= 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag id"})
+        return
+    }
+    p, err := ParseTrendParams(c.Query("interval"), c.Query("from"), c.Query("to"), time.Now())
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    trend, err := ctr.svc.Trend(c.Request.Context(), id, p)
+    if err != nil {
+        if httperr.Abort(c, err) {
+            return
+        }
+        logger.Errorf("failed to build tag trend: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        return
+    }
+    if trend == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+        return
+    }
+    c.JSON(http.StatusOK, trend)
+}
+
+// CreateHandler handles POST /tags (admin only)
+func (ctr *TagController) CreateHandler(c *gin.Context) {
+    var dto CreateTagDTO
+    if err := c.ShouldBindJSON(&dto); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+        return
+    }
+    id, err := ctr.svc.CreateTag(c.Request.Context(), dto.Name)
+    if err != nil {
+        if err == ErrEmptyName {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        } else if err == ErrTagExists {
+            c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+        } else if !httperr.Abort(c, err) {
+            logger.Errorf("failed to create tag: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        }
+        return
+    }
+    c.JSON(http.StatusCreated, gin.H{"id": id})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -39,11 +40,35 @@ func (m *MockTagRepository) GetByName(ctx context.Context, name string) (*models
 	return args.Get(0).(*models.Tag), args.Error(1)
 }
 
+func (m *MockTagRepository) GetByNames(ctx context.Context, names []string) ([]models.Tag, error) {
+	args := m.Called(ctx, names)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Tag), args.Error(1)
+}
+
 func (m *MockTagRepository) Create(ctx context.Context, name string) (int, error) {
 	args := m.Called(ctx, name)
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockTagRepository) GetByID(ctx context.Context, id int) (*models.Tag, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) CountByInterval(ctx context.Context, tagID int, interval string, from, to time.Time) ([]TrendPoint, error) {
+	args := m.Called(ctx, tagID, interval, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]TrendPoint), args.Error(1)
+}
+
 // Helper to generate a JWT token for testing
 func generateTestTokenTag(userID int) string {
 	token, err := jwtutil.GenerateToken(userID)
@@ -53,6 +78,14 @@ func generateTestTokenTag(userID int) string {
 	return token
 }
 
+func generateAdminTokenTag(userID int) string {
+	token, err := jwtutil.GenerateTokenWithRole(userID, models.RoleAdmin)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate test token: %v", err))
+	}
+	return token
+}
+
 // Helper to set up the Gin router with TagController
 // It creates a real TagService but with a mocked TagRepository.
 func setupTagTestRouter(mockRepo TagRepository) *gin.Engine {
@@ -69,6 +102,10 @@ func setupTagTestRouter(mockRepo TagRepository) *gin.Engine {
 	protectedGroup.Use(authmw.JWTAuthMiddleware())
 	{
 		protectedGroup.GET("/tags", tagController.ListHandler)
+		protectedGroup.GET("/tags/available", tagController.AvailableHandler)
+		protectedGroup.GET("/tags/by-names", tagController.ByNamesHandler)
+		protectedGroup.GET("/tags/:id/trend", tagController.TrendHandler)
+		protectedGroup.POST("/tags", authmw.RequireRole(models.RoleAdmin), tagController.CreateHandler)
 	}
 	return router
 }
@@ -83,6 +120,17 @@ func performTagRequest(r http.Handler, method, path, token string) *httptest.Res
 	return w
 }
 
+func performTagJSONRequest(r http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
 // --- ListAllTags Tests (GET /tags) ---
 
 func TestListTags_Success(t *testing.T) {
@@ -125,6 +173,19 @@ func TestListTags_Success_NoTags(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestListTags_NilFromRepoEncodesAsEmptyArray(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+	token := generateTestTokenTag(1)
+
+	mockRepo.On("GetAll", mock.Anything).Return(nil, nil)
+
+	w := performTagRequest(router, "GET", "/tags", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+	mockRepo.AssertExpectations(t)
+}
+
 func TestListTags_ServiceError(t *testing.T) {
 	mockRepo := new(MockTagRepository)
 	router := setupTagTestRouter(mockRepo)
@@ -154,8 +215,246 @@ func TestListTags_Unauthorized_NoToken(t *testing.T) {
 	mockRepo.AssertNotCalled(t, "GetAll", mock.Anything)
 }
 
-// Note: Tests for Create, GetByID/Name, Delete are not included as these functionalities
+// --- Tag availability Tests (GET /tags/available?name=) ---
+
+func TestTagAvailable_Taken(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+	token := generateTestTokenTag(1)
+
+	mockRepo.On("GetByName", mock.Anything, "go").Return(&models.Tag{ID: 1, Name: "Go"}, nil)
+
+	w := performTagRequest(router, "GET", "/tags/available?name=go", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, false, resp["available"])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestTagAvailable_Free(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+	token := generateTestTokenTag(1)
+
+	mockRepo.On("GetByName", mock.Anything, "rust").Return(nil, nil)
+
+	w := performTagRequest(router, "GET", "/tags/available?name=rust", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, true, resp["available"])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestTagAvailable_CaseInsensitive(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+	token := generateTestTokenTag(1)
+
+	// "  GO " is looked up as "go", which matches the existing "Go" tag
+	mockRepo.On("GetByName", mock.Anything, "go").Return(&models.Tag{ID: 1, Name: "Go"}, nil)
+
+	w := performTagRequest(router, "GET", "/tags/available?name=%20%20GO%20", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, false, resp["available"])
+	assert.Equal(t, "go", resp["name"])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestTagAvailable_MissingName(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+
+	w := performTagRequest(router, "GET", "/tags/available?name=%20", generateTestTokenTag(1))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "GetByName", mock.Anything, mock.Anything)
+}
+
+// --- Batch lookup Tests (GET /tags/by-names) ---
+
+func TestTagsByNames_PartialMatch(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+
+	// names are normalized and de-duplicated; "nosuchtag" has no row
+	mockRepo.On("GetByNames", mock.Anything, []string{"go", "postgres", "nosuchtag"}).
+		Return([]models.Tag{{ID: 1, Name: "go"}, {ID: 4, Name: "Postgres"}}, nil)
+
+	w := performTagRequest(router, "GET", "/tags/by-names?names=go,%20Postgres,nosuchtag,GO", generateTestTokenTag(1))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tags []models.Tag
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tags))
+	assert.Equal(t, []int{1, 4}, []int{tags[0].ID, tags[1].ID})
+	mockRepo.AssertExpectations(t)
+}
+
+func TestTagsByNames_AllUnknown(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+
+	mockRepo.On("GetByNames", mock.Anything, []string{"nope"}).Return(nil, nil)
+
+	w := performTagRequest(router, "GET", "/tags/by-names?names=nope", generateTestTokenTag(1))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+}
+
+func TestTagsByNames_Invalid(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+
+	names := make([]string, MaxBatchNames+1)
+	for i := range names {
+		names[i] = fmt.Sprintf("t%d", i)
+	}
+	tooMany := strings.Join(names, ",")
+
+	for _, q := range []string{"", "?names=", "?names=%20,,", "?names=" + tooMany} {
+		w := performTagRequest(router, "GET", "/tags/by-names"+q, generateTestTokenTag(1))
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	mockRepo.AssertNotCalled(t, "GetByNames", mock.Anything, mock.Anything)
+}
+
+// --- Create Tests (POST /tags) ---
+
+func TestCreateTag_Success(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+
+	mockRepo.On("GetByName", mock.Anything, "golang").Return(nil, nil)
+	mockRepo.On("Create", mock.Anything, "golang").Return(7, nil)
+
+	w := performTagJSONRequest(router, "POST", "/tags", generateAdminTokenTag(1), `{"name":"  GoLang "}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"id":7}`, w.Body.String())
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateTag_Duplicate(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+
+	mockRepo.On("GetByName", mock.Anything, "go").Return(&models.Tag{ID: 1, Name: "Go"}, nil)
+
+	w := performTagJSONRequest(router, "POST", "/tags", generateAdminTokenTag(1), `{"name":"GO"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCreateTag_EmptyName(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+
+	for _, body := range []string{`{"name":""}`, `{"name":"   "}`, `{}`} {
+		w := performTagJSONRequest(router, "POST", "/tags", generateAdminTokenTag(1), body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	mockRepo.AssertNotCalled(t, "GetByName", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCreateTag_ForbiddenForNonAdmin(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+
+	w := performTagJSONRequest(router, "POST", "/tags", generateTestTokenTag(1), `{"name":"go"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+// Note: Tests for GetByID and Delete are not included as these functionalities
 // are not present in the current TagController or TagService.
 // Listing discussions by tag is handled by DiscussionController.
-// Authorization tests beyond requiring a valid token for ListTags are not applicable
-// as there are no modification endpoints.
+
+// --- Trend Tests (GET /tags/:id/trend) ---
+
+func TestTagTrend_FillsEmptyBuckets(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+	token := generateTestTokenTag(1)
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC) // to=2026-03-04 includes that day
+	mockRepo.On("GetByID", mock.Anything, 3).Return(&models.Tag{ID: 3, Name: "go"}, nil)
+	mockRepo.On("CountByInterval", mock.Anything, 3, IntervalDay, from, to).Return([]TrendPoint{
+		{Start: from, Count: 2},
+		{Start: from.AddDate(0, 0, 2), Count: 5},
+	}, nil)
+
+	w := performTagRequest(router, "GET", "/tags/3/trend?interval=day&from=2026-03-01&to=2026-03-04", token)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp Trend
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "go", resp.Tag.Name)
+	counts := []int{}
+	for _, b := range resp.Buckets {
+		counts = append(counts, b.Count)
+	}
+	assert.Equal(t, []int{2, 0, 5, 0}, counts)
+	assert.True(t, resp.Buckets[3].Start.Equal(from.AddDate(0, 0, 3)))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestTagTrend_NotFound(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+	token := generateTestTokenTag(1)
+
+	mockRepo.On("GetByID", mock.Anything, 9).Return(nil, nil)
+
+	w := performTagRequest(router, "GET", "/tags/9/trend", token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockRepo.AssertNotCalled(t, "CountByInterval", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTagTrend_BadParams(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	router := setupTagTestRouter(mockRepo)
+	token := generateTestTokenTag(1)
+
+	for _, path := range []string{
+		"/tags/abc/trend",
+		"/tags/3/trend?interval=hour",
+		"/tags/3/trend?from=yesterday",
+		"/tags/3/trend?from=2026-03-05&to=2026-03-01",
+		"/tags/3/trend?interval=day&from=2020-01-01&to=2026-01-01",
+		"/tags/3/trend?interval=day&from=0001-01-01&to=9999-12-31",
+	} {
+		w := performTagRequest(router, "GET", path, token)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+	mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+func TestBucketCount_MatchesBucketStarts(t *testing.T) {
+	from := time.Date(2025, 12, 30, 17, 0, 0, 0, time.UTC)
+	for _, interval := range []string{IntervalDay, IntervalWeek, IntervalMonth} {
+		for _, to := range []time.Time{
+			from.Add(time.Minute),
+			time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 2, 0, 0, 0, 1, time.UTC),
+			time.Date(2027, 7, 15, 9, 30, 0, 0, time.UTC),
+		} {
+			assert.Equal(t, int64(len(BucketStarts(from, to, interval))), BucketCount(from, to, interval), "%s to %s", interval, to)
+		}
+	}
+	assert.Zero(t, BucketCount(from, from.AddDate(0, 0, -1), IntervalDay))
+	huge := BucketCount(time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC), IntervalDay)
+	assert.Greater(t, huge, int64(3_600_000))
+}
+
+func TestTruncateToInterval(t *testing.T) {
+	ts := time.Date(2026, 10, 15, 13, 45, 0, 0, time.UTC) // a Thursday
+	assert.Equal(t, time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), TruncateToInterval(ts, IntervalDay))
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), TruncateToInterval(ts, IntervalWeek))
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), TruncateToInterval(ts, IntervalMonth))
+	// Sunday belongs to the week that started the Monday before
+	sunday := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), TruncateToInterval(sunday, IntervalWeek))
+}
@@ -0,0 +1,199 @@
+// dto.go
+package tag
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+
+    "go-discussion-app/models"
+)
+
+// CreateTagDTO is the body of POST /tags.
+type CreateTagDTO struct {
+    Name string `json:"name"`
+}
+
+// MaxBatchNames caps how many names GET /tags/by-names may request at once.
+const MaxBatchNames = 100
+
+// ParseNames splits a comma-separated list of tag names, normalizing each
+// and dropping blanks and duplicates while keeping the first-seen order.
+func ParseNames(raw string) ([]string, error) {
+    seen := map[string]bool{}
+    names := []string{}
+    for _, p := range strings.Split(raw, ",") {
+        name := NormalizeName(p)
+        if name == "" || seen[name] {
+            continue
+        }
+        seen[name] = true
+        names = append(names, name)
+    }
+    if len(names) == 0 {
+        return nil, errors.New("names is required")
+    }
+    if len(names) > MaxBatchNames {
+        return nil, fmt.Errorf("at most %d names may be requested", MaxBatchNames)
+    }
+    return names, nil
+}
+
+// Trend intervals accepted by GET /tags/:id/trend.
+const (
+    IntervalDay   = "day"
+    IntervalWeek  = "week"
+    IntervalMonth = "month"
+)
+
+const (
+    // MaxTrendBuckets caps how many intervals one trend request may span.
+    MaxTrendBuckets = 366
+    // defaultTrendBuckets is how far back a trend reaches without ?from=.
+    defaultTrendBuckets = 30
+)
+
+// TrendPoint is a count for the interval starting at Start: discussions for
+// a tag trend, comments for discussion stats.
+type TrendPoint struct {
+    Start time.Time `json:"start"`
+    Count int       `json:"count"`
+}
+
+// Trend is the GET /tags/:id/trend body. Buckets cover [From, To) with no
+// gaps; intervals without discussions have a zero count.
+type Trend struct {
+    Tag      models.Tag   `json:"tag"`
+    Interval string       `json:"interval"`
+    From     time.Time    `json:"from"`
+    To       time.Time    `json:"to"`
+    Buckets  []TrendPoint `json:"buckets"`
+}
+
+// TrendParams is a parsed trend query.
+type TrendParams struct {
+    Interval string
+    From     time.Time // inclusive
+    To       time.Time // exclusive
+}
+
+// ParseTrendParams reads ?interval=, ?from= and ?to=. interval defaults to
+// day. from and to are RFC 3339 timestamps or YYYY-MM-DD dates in UTC; a
+// date given as to includes that whole day. to defaults to now and from to
+// defaultTrendBuckets intervals before to.
+func ParseTrendParams(interval, from, to string, now time.Time) (TrendParams, error) {
+    p := TrendParams{Interval: interval}
+    switch p.Interval {
+    case "":
+        p.Interval = IntervalDay
+    case IntervalDay, IntervalWeek, IntervalMonth:
+    default:
+        return p, errors.New("interval must be day, week or month")
+    }
+
+    p.To = now.UTC()
+    if to != "" {
+        t, dateOnly, err := parseTrendTime(to)
+        if err != nil {
+            return p, errors.New("to must be a date (YYYY-MM-DD) or RFC 3339 time")
+        }
+        if dateOnly {
+            t = t.AddDate(0, 0, 1)
+        }
+        p.To = t
+    }
+    if from != "" {
+        t, _, err := parseTrendTime(from)
+        if err != nil {
+            return p, errors.New("from must be a date (YYYY-MM-DD) or RFC 3339 time")
+        }
+        p.From = t
+    } else {
+        p.From = p.To
+        for i := 0; i < defaultTrendBuckets; i++ {
+            p.From = stepBack(p.From, p.Interval)
+        }
+    }
+    if !p.From.Before(p.To) {
+        return p, errors.New("from must be before to")
+    }
+    if n := BucketCount(p.From, p.To, p.Interval); n > MaxTrendBuckets {
+        return p, fmt.Errorf("at most %d %ss may be requested, got %d", MaxTrendBuckets, p.Interval, n)
+    }
+    return p, nil
+}
+
+func parseTrendTime(s string) (time.Time, bool, error) {
+    if t, err := time.Parse("2006-01-02", s); err == nil {
+        return t, true, nil
+    }
+    t, err := time.Parse(time.RFC3339, s)
+    return t.UTC(), false, err
+}
+
+// TruncateToInterval returns the start of the UTC interval containing t,
+// matching Postgres date_trunc: weeks start on Monday.
+func TruncateToInterval(t time.Time, interval string) time.Time {
+    t = t.UTC()
+    day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+    switch interval {
+    case IntervalWeek:
+        return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+    case IntervalMonth:
+        return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+    }
+    return day
+}
+
+// BucketStarts lists the starts of every interval overlapping [from, to).
+func BucketStarts(from, to time.Time, interval string) []time.Time {
+    var starts []time.Time
+    for b := TruncateToInterval(from, interval); b.Before(to); b = stepForward(b, interval) {
+        starts = append(starts, b)
+    }
+    return starts
+}
+
+// BucketCount is len(BucketStarts(from, to, interval)), worked out without
+// listing them, so a huge range costs nothing to reject.
+func BucketCount(from, to time.Time, interval string) int64 {
+    first := TruncateToInterval(from, interval)
+    if !first.Before(to) {
+        return 0
+    }
+    last := TruncateToInterval(to, interval)
+    var n int64
+    switch interval {
+    case IntervalMonth:
+        n = int64(last.Year()-first.Year())*12 + int64(last.Month()-first.Month())
+    case IntervalWeek:
+        n = (last.Unix() - first.Unix()) / (7 * 24 * 60 * 60)
+    default:
+        n = (last.Unix() - first.Unix()) / (24 * 60 * 60)
+    }
+    if last.Before(to) {
+        n++ // to falls inside the interval starting at last
+    }
+    return n
+}
+
+func stepForward(t time.Time, interval string) time.Time {
+    switch interval {
+    case IntervalWeek:
+        return t.AddDate(0, 0, 7)
+    case IntervalMonth:
+        return t.AddDate(0, 1, 0)
+    }
+    return t.AddDate(0, 0, 1)
+}
+
+func stepBack(t time.Time, interval string) time.Time {
+    switch interval {
+    case IntervalWeek:
+        return t.AddDate(0, 0, -7)
+    case IntervalMonth:
+        return t.AddDate(0, -1, 0)
+    }
+    return t.AddDate(0, 0, -1)
+}
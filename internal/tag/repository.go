@@ -4,8 +4,11 @@ package tag
 import (
     "context"
     "database/sql"
+    "time"
 
+    "github.com/lib/pq"
     "go-discussion-app/models"
+    "go-discussion-app/pkg/slowquery"
 )
 
 // TagRepository defines methods to interact with the tags table.
@@ -13,16 +16,24 @@ type TagRepository interface {
     // GetAll returns all tags in the database.
     GetAll(ctx context.Context) ([]models.Tag, error)
     GetByName(ctx context.Context, name string) (*models.Tag, error)
+    // GetByNames returns the tags matching any of the lower-cased names;
+    // unknown names are simply absent from the result.
+    GetByNames(ctx context.Context, names []string) ([]models.Tag, error)
     Create(ctx context.Context, name string) (int, error)
+    GetByID(ctx context.Context, id int) (*models.Tag, error)
+    // CountByInterval counts the non-draft discussions carrying tagID that
+    // were created in [from, to), grouped by UTC interval ("day", "week" or
+    // "month"). Empty intervals are absent.
+    CountByInterval(ctx context.Context, tagID int, interval string, from, to time.Time) ([]TrendPoint, error)
 }
 
 type repo struct {
-    db *sql.DB
+    db *slowquery.DB
 }
 
 // NewRepository constructs a TagRepository backed by *sql.DB.
 func NewRepository(db *sql.DB) TagRepository {
-    return &repo{db: db}
+    return &repo{db: slowquery.Wrap(db)}
 }
 
 func (r *repo) GetAll(ctx context.Context) ([]models.Tag, error) {
@@ -50,8 +61,9 @@ func (r *repo) GetAll(ctx context.Context) ([]models.Tag, error) {
     }
     return tags, nil
 }
+// GetByName looks a tag up by name, ignoring case.
 func (r *repo) GetByName(ctx context.Context, name string) (*models.Tag, error) {
-    const q = `SELECT id, name, created_at FROM tags WHERE name = $1;`
+    const q = `SELECT id, name, created_at FROM tags WHERE lower(name) = lower($1);`
     row := r.db.QueryRowContext(ctx, q, name)
     var t models.Tag
     if err := row.Scan(&t.ID, &t.Name, &t.CreatedAt); err != nil {
@@ -63,6 +75,30 @@ func (r *repo) GetByName(ctx context.Context, name string) (*models.Tag, error)
     return &t, nil
 }
 
+func (r *repo) GetByNames(ctx context.Context, names []string) ([]models.Tag, error) {
+    const q = `
+      SELECT id, name, created_at
+      FROM tags
+      WHERE lower(name) = ANY($1)
+      ORDER BY name;
+    `
+    rows, err := r.db.QueryContext(ctx, q, pq.Array(names))
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var tags []models.Tag
+    for rows.Next() {
+        var t models.Tag
+        if err := rows.Scan(&t.ID, &t.Name, &t.CreatedAt); err != nil {
+            return nil, err
+        }
+        tags = append(tags, t)
+    }
+    return tags, rows.Err()
+}
+
 func (r *repo) Create(ctx context.Context, name string) (int, error) {
     const q = `
         INSERT INTO tags (name, created_at)
@@ -72,4 +108,45 @@ func (r *repo) Create(ctx context.Context, name string) (int, error) {
     var id int
     err := r.db.QueryRowContext(ctx, q, name).Scan(&id)
     return id, err
-}
\ No newline at end of file
+}
+
+func (r *repo) GetByID(ctx context.Context, id int) (*models.Tag, error) {
+    const q = `SELECT id, name, created_at FROM tags WHERE id = $1;`
+    var t models.Tag
+    if err := r.db.QueryRowContext(ctx, q, id).Scan(&t.ID, &t.Name, &t.CreatedAt); err != nil {
+        if err == sql.ErrNoRows {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &t, nil
+}
+
+func (r *repo) CountByInterval(ctx context.Context, tagID int, interval string, from, to time.Time) ([]TrendPoint, error) {
+    const q = `
+      SELECT date_trunc($2, d.created_at AT TIME ZONE 'UTC') AS bucket, COUNT(*)
+      FROM discussion_tags dt
+      JOIN discussions d ON d.id = dt.discussion_id
+      WHERE dt.tag_id = $1 AND d.created_at >= $3 AND d.created_at < $4
+        AND d.status <> $5 AND d.deleted_at IS NULL
+      GROUP BY bucket
+      ORDER BY bucket;
+    `
+    rows, err := r.db.QueryContext(ctx, q, tagID, interval, from, to, models.StatusDraft)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var points []TrendPoint
+    for rows.Next() {
+        var p TrendPoint
+        if err := rows.Scan(&p.Start, &p.Count); err != nil {
+            return nil, err
+        }
+        // the bucket is a timestamp without zone, already in UTC
+        p.Start = time.Date(p.Start.Year(), p.Start.Month(), p.Start.Day(), 0, 0, 0, 0, time.UTC)
+        points = append(points, p)
+    }
+    return points, rows.Err()
+}
@@ -0,0 +1,73 @@
+package tag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/models"
+)
+
+func newMockRepo(t *testing.T) (TagRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewRepository(db), mock
+}
+
+func TestCountByInterval_ReturnsBucketedCounts(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT date_trunc\(\$2, d.created_at AT TIME ZONE 'UTC'\) AS bucket, COUNT\(\*\)\s+` +
+		`FROM discussion_tags dt\s+JOIN discussions d ON d.id = dt.discussion_id\s+` +
+		`WHERE dt.tag_id = \$1 AND d.created_at >= \$3 AND d.created_at < \$4\s+` +
+		`AND d.status <> \$5 AND d.deleted_at IS NULL\s+GROUP BY bucket\s+ORDER BY bucket`).
+		WithArgs(4, IntervalWeek, from, to, models.StatusDraft).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}).
+			AddRow(time.Date(2026, 8, 31, 0, 0, 0, 0, time.UTC), 3).
+			AddRow(time.Date(2026, 9, 14, 0, 0, 0, 0, time.UTC), 7))
+
+	points, err := repo.CountByInterval(context.Background(), 4, IntervalWeek, from, to)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []TrendPoint{
+		{Start: time.Date(2026, 8, 31, 0, 0, 0, 0, time.UTC), Count: 3},
+		{Start: time.Date(2026, 9, 14, 0, 0, 0, 0, time.UTC), Count: 7},
+	}, points)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountByInterval_NoRows(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`FROM discussion_tags dt`).
+		WithArgs(4, IntervalDay, from, from.AddDate(0, 0, 7), models.StatusDraft).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}))
+
+	points, err := repo.CountByInterval(context.Background(), 4, IntervalDay, from, from.AddDate(0, 0, 7))
+
+	assert.NoError(t, err)
+	assert.Empty(t, points)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByID_MissingIsNil(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectQuery(`SELECT id, name, created_at FROM tags WHERE id = \$1`).
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}))
+
+	tag, err := repo.GetByID(context.Background(), 12)
+	assert.NoError(t, err)
+	assert.Nil(t, tag)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
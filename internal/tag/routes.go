@@ -5,9 +5,11 @@ import (
     "database/sql"
 
     "github.com/gin-gonic/gin"
+    "go-discussion-app/internal/auth"
+    "go-discussion-app/models"
 )
 
-// RegisterRoutes mounts the /tags endpoint onto the given router group.
+// RegisterRoutes mounts the /tags endpoints onto the given router group.
 // This should be called on your protected router group in main.go.
 func RegisterRoutes(rg *gin.RouterGroup, dbConn *sql.DB) {
     repo := NewRepository(dbConn)
@@ -15,4 +17,8 @@ func RegisterRoutes(rg *gin.RouterGroup, dbConn *sql.DB) {
     ctr := NewController(svc)
 
     rg.GET("/tags", ctr.ListHandler)
+    rg.GET("/tags/available", ctr.AvailableHandler)
+    rg.GET("/tags/by-names", ctr.ByNamesHandler)
+    rg.GET("/tags/:id/trend", ctr.TrendHandler)
+    rg.POST("/tags", auth.RequireRole(models.RoleAdmin), ctr.CreateHandler)
 }
@@ -3,10 +3,24 @@ package tag
 
 import (
     "context"
+    "errors"
+    "strings"
+    "time"
 
     "go-discussion-app/models"
 )
 
+// NormalizeName trims surrounding whitespace and lower-cases a tag name so
+// lookups are case-insensitive.
+func NormalizeName(name string) string {
+    return strings.ToLower(strings.TrimSpace(name))
+}
+
+var (
+    ErrEmptyName = errors.New("name is required")
+    ErrTagExists = errors.New("tag already exists")
+)
+
 // TagService provides tag‐related business logic.
 type TagService struct {
     repo TagRepository
@@ -17,7 +31,75 @@ func NewService(repo TagRepository) *TagService {
     return &TagService{repo: repo}
 }
 
-// ListTags returns all available tags.
+// ListTags returns all available tags, as an empty slice when there are none.
 func (s *TagService) ListTags(ctx context.Context) ([]models.Tag, error) {
-    return s.repo.GetAll(ctx)
+    tags, err := s.repo.GetAll(ctx)
+    if err != nil {
+        return nil, err
+    }
+    if tags == nil {
+        tags = []models.Tag{}
+    }
+    return tags, nil
+}
+
+// IsAvailable reports whether no tag with the (normalized) name exists yet.
+func (s *TagService) IsAvailable(ctx context.Context, name string) (bool, error) {
+    t, err := s.repo.GetByName(ctx, NormalizeName(name))
+    if err != nil {
+        return false, err
+    }
+    return t == nil, nil
+}
+
+// GetByNames returns the existing tags among names in a single query,
+// as an empty slice when none match.
+func (s *TagService) GetByNames(ctx context.Context, names []string) ([]models.Tag, error) {
+    tags, err := s.repo.GetByNames(ctx, names)
+    if err != nil {
+        return nil, err
+    }
+    if tags == nil {
+        tags = []models.Tag{}
+    }
+    return tags, nil
+}
+
+// CreateTag stores a new tag under its normalized name and returns its id.
+// ErrTagExists is returned if a tag with that name, ignoring case, exists.
+func (s *TagService) CreateTag(ctx context.Context, name string) (int, error) {
+    name = NormalizeName(name)
+    if name == "" {
+        return 0, ErrEmptyName
+    }
+    existing, err := s.repo.GetByName(ctx, name)
+    if err != nil {
+        return 0, err
+    }
+    if existing != nil {
+        return 0, ErrTagExists
+    }
+    return s.repo.Create(ctx, name)
+}
+
+// Trend returns tagID's discussion counts per interval over p, or nil if
+// the tag does not exist.
+func (s *TagService) Trend(ctx context.Context, tagID int, p TrendParams) (*Trend, error) {
+    t, err := s.repo.GetByID(ctx, tagID)
+    if err != nil || t == nil {
+        return nil, err
+    }
+    points, err := s.repo.CountByInterval(ctx, tagID, p.Interval, p.From, p.To)
+    if err != nil {
+        return nil, err
+    }
+    counts := make(map[time.Time]int, len(points))
+    for _, pt := range points {
+        counts[pt.Start] = pt.Count
+    }
+    trend := &Trend{Tag: *t, Interval: p.Interval, From: p.From, To: p.To, Buckets: []TrendPoint{}}
+    for _, start := range BucketStarts(p.From, p.To, p.Interval) {
+        trend.Buckets = append(trend.Buckets, TrendPoint{Start: start, Count: counts[start]})
+    }
+    return trend, nil
 }
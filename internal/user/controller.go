@@ -6,6 +6,8 @@ import (
     "strconv"
 
     "github.com/gin-gonic/gin"
+    "go-discussion-app/internal/audit"
+    "go-discussion-app/pkg/httperr"
     "go-discussion-app/pkg/logger"
     //"go-discussion-app/models"
 )
@@ -31,7 +33,7 @@ func (ctr *UserController) GetProfile(c *gin.Context) {
     if err != nil {
         if err == ErrUserNotFound {
             c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-        } else {
+        } else if !httperr.Abort(c, err) {
             logger.Errorf("GetProfile error: %v", err)
             c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
         }
@@ -68,6 +70,9 @@ func (ctr *UserController) UpdateProfile(c *gin.Context) {
         case ErrUserNotFound:
             c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
         default:
+            if httperr.Abort(c, err) {
+                return
+            }
             logger.Errorf("UpdateProfile error: %v", err)
             c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
         }
@@ -91,11 +96,55 @@ func (ctr *UserController) DeleteProfile(c *gin.Context) {
         switch err {
         case ErrUserNotFound:
             c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+        case ErrPlaceholderUser:
+            c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
         default:
+            if httperr.Abort(c, err) {
+                return
+            }
             logger.Errorf("DeleteProfile error: %v", err)
             c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
         }
         return
     }
+    audit.Record(c.Request.Context(), c.GetInt("userID"), audit.ActionUserDelete, audit.Target("user", id))
     c.Status(http.StatusNoContent)
 }
+
+// BanUser handles POST /users/:id/ban (admin only)
+func (ctr *UserController) BanUser(c *gin.Context) {
+    ctr.setBanned(c, true)
+}
+
+// UnbanUser handles POST /users/:id/unban (admin only)
+func (ctr *UserController) UnbanUser(c *gin.Context) {
+    ctr.setBanned(c, false)
+}
+
+func (ctr *UserController) setBanned(c *gin.Context, banned bool) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+        return
+    }
+
+    if err := ctr.svc.SetBanned(c.Request.Context(), id, banned); err != nil {
+        switch err {
+        case ErrUserNotFound:
+            c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+        default:
+            if httperr.Abort(c, err) {
+                return
+            }
+            logger.Errorf("SetBanned error: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+        }
+        return
+    }
+    action := audit.ActionUserUnban
+    if banned {
+        action = audit.ActionUserBan
+    }
+    audit.Record(c.Request.Context(), c.GetInt("userID"), action, audit.Target("user", id))
+    c.JSON(http.StatusOK, gin.H{"id": id, "is_banned": banned})
+}
@@ -1,4 +1,4 @@
-package user
+package user_test
 
 import (
 	"bytes"
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,7 +17,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"go-discussion-app/config"
 	"go-discussion-app/internal/auth" // For JWTAuthMiddleware and GetUserID
+	"go-discussion-app/internal/user"
 	"go-discussion-app/models"
 	"go-discussion-app/pkg/jwtutil"
 	//"golang.org/x/crypto/bcrypt" // Not directly needed here unless testing password changes specifically
@@ -70,6 +73,16 @@ func (m *MockUserRepository) Delete(ctx context.Context, id int) (sql.Result, er
 	return args.Get(0).(sql.Result), args.Error(1)
 }
 
+func (m *MockUserRepository) DeleteReassigningContent(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetBanned(ctx context.Context, id int, banned bool) (bool, error) {
+	args := m.Called(ctx, id, banned)
+	return args.Bool(0), args.Error(1)
+}
+
 // Helper to generate a JWT token for testing
 func generateTestToken(userID int) string {
 	token, err := jwtutil.GenerateToken(userID)
@@ -80,11 +93,15 @@ func generateTestToken(userID int) string {
 }
 
 // Helper to set up the Gin router with UserController and middleware
-func setupUserTestRouter(mockUserRepo UserRepository) *gin.Engine {
+func setupUserTestRouter(mockUserRepo user.UserRepository) *gin.Engine {
+	return setupUserTestRouterWithConfig(mockUserRepo, nil)
+}
+
+func setupUserTestRouterWithConfig(mockUserRepo user.UserRepository, cfg *config.Config) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-	userService := NewService(mockUserRepo)
-	userController := NewController(userService)
+	userService := user.NewService(mockUserRepo, cfg)
+	userController := user.NewController(userService)
 
 	// Group for /users routes, protected by JWT middleware
 	userRg := router.Group("/users")
@@ -137,11 +154,11 @@ func TestGetProfile_Success(t *testing.T) {
 	w := performUserRequest(router, "GET", "/users/"+strconv.Itoa(testUserID), token, nil)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	var user models.User
-	err := json.Unmarshal(w.Body.Bytes(), &user)
+	var got models.User
+	err := json.Unmarshal(w.Body.Bytes(), &got)
 	assert.NoError(t, err)
-	assert.Equal(t, expectedUser.Username, user.Username)
-	assert.Empty(t, user.PasswordHash, "PasswordHash should be empty in response")
+	assert.Equal(t, expectedUser.Username, got.Username)
+	assert.Empty(t, got.PasswordHash, "PasswordHash should be empty in response")
 	mockRepo.AssertExpectations(t)
 }
 
@@ -152,7 +169,7 @@ func TestGetProfile_NotFound(t *testing.T) {
 	nonExistentUserID := 2
 	token := generateTestToken(testUserID) // Token for user 1
 
-	mockRepo.On("GetByID", mock.Anything, nonExistentUserID).Return(nil, ErrUserNotFound) // Or (nil, nil) if service translates
+	mockRepo.On("GetByID", mock.Anything, nonExistentUserID).Return(nil, user.ErrUserNotFound) // Or (nil, nil) if service translates
 
 	w := performUserRequest(router, "GET", "/users/"+strconv.Itoa(nonExistentUserID), token, nil)
 
@@ -185,7 +202,7 @@ func TestUpdateProfile_Success(t *testing.T) {
 	targetUserID := 1
 	token := generateTestToken(targetUserID) // User updates their own profile
 
-	updateDTO := UpdateUserDTO{Username: new(string)}
+	updateDTO := user.UpdateUserDTO{Username: new(string)}
 	*updateDTO.Username = "newusername"
 
 	originalUser := &models.User{ID: targetUserID, Username: "oldusername", Email: "old@example.com"}
@@ -199,11 +216,11 @@ func TestUpdateProfile_Success(t *testing.T) {
 	w := performUserRequest(router, "PUT", "/users/"+strconv.Itoa(targetUserID), token, updateDTO)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	var user models.User
-	err := json.Unmarshal(w.Body.Bytes(), &user)
+	var got models.User
+	err := json.Unmarshal(w.Body.Bytes(), &got)
 	assert.NoError(t, err)
-	assert.Equal(t, *updateDTO.Username, user.Username)
-	assert.Empty(t, user.PasswordHash)
+	assert.Equal(t, *updateDTO.Username, got.Username)
+	assert.Empty(t, got.PasswordHash)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -227,7 +244,7 @@ func TestUpdateProfile_InvalidInput_Validation(t *testing.T) {
 	targetUserID := 1
 	token := generateTestToken(targetUserID)
 
-	emptyDTO := UpdateUserDTO{} // Fails dto.Validate()
+	emptyDTO := user.UpdateUserDTO{} // Fails dto.Validate()
 
 	w := performUserRequest(router, "PUT", "/users/"+strconv.Itoa(targetUserID), token, emptyDTO)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
@@ -236,6 +253,45 @@ func TestUpdateProfile_InvalidInput_Validation(t *testing.T) {
 	assert.Equal(t, "at least one field must be provided", resp["error"])
 }
 
+func TestUpdateProfile_ProfileTextLimits(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	router := setupUserTestRouter(mockRepo)
+	targetUserID := 1
+	token := generateTestToken(targetUserID)
+
+	overName := strings.Repeat("n", user.MaxFullNameLength+1)
+	w := performUserRequest(router, "PUT", "/users/1", token, user.UpdateUserDTO{FullName: &overName})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp map[string]string
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, "full_name must be at most 100 characters (got 101)", resp["error"])
+
+	overBio := strings.Repeat("b", user.MaxBioLength+1)
+	w = performUserRequest(router, "PUT", "/users/1", token, user.UpdateUserDTO{Bio: &overBio})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, "bio must be at most 1000 characters (got 1001)", resp["error"])
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestUpdateProfile_ProfileTextAtLimitsIsTrimmed(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	router := setupUserTestRouter(mockRepo)
+	targetUserID := 1
+	token := generateTestToken(targetUserID)
+
+	name := "  " + strings.Repeat("n", user.MaxFullNameLength) + "  "
+	bio := strings.Repeat("b", user.MaxBioLength)
+	mockRepo.On("GetByID", mock.Anything, targetUserID).Return(&models.User{ID: targetUserID}, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+		return u.FullName == strings.TrimSpace(name) && u.Bio == bio
+	})).Return(sql.Result(nil), nil)
+
+	w := performUserRequest(router, "PUT", "/users/1", token, user.UpdateUserDTO{FullName: &name, Bio: &bio})
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestUpdateProfile_NotFound(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	router := setupUserTestRouter(mockRepo)
@@ -243,10 +299,10 @@ func TestUpdateProfile_NotFound(t *testing.T) {
 	nonExistentUserID := 2
 	token := generateTestToken(targetUserID)
 
-	updateDTO := UpdateUserDTO{Username: new(string)}
+	updateDTO := user.UpdateUserDTO{Username: new(string)}
 	*updateDTO.Username = "newusername"
 
-	mockRepo.On("GetByID", mock.Anything, nonExistentUserID).Return(nil, ErrUserNotFound)
+	mockRepo.On("GetByID", mock.Anything, nonExistentUserID).Return(nil, user.ErrUserNotFound)
 
 	w := performUserRequest(router, "PUT", "/users/"+strconv.Itoa(nonExistentUserID), token, updateDTO)
 	assert.Equal(t, http.StatusNotFound, w.Code)
@@ -256,7 +312,7 @@ func TestUpdateProfile_NotFound(t *testing.T) {
 func TestUpdateProfile_Unauthorized_NoToken(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	router := setupUserTestRouter(mockRepo)
-	updateDTO := UpdateUserDTO{Username: new(string)}
+	updateDTO := user.UpdateUserDTO{Username: new(string)}
 	*updateDTO.Username = "newusername"
 
 	w := performUserRequest(router, "PUT", "/users/1", "", updateDTO) // No token
@@ -271,7 +327,7 @@ func TestUpdateProfile_Forbidden_WrongUser(t *testing.T) {
 	targetUserID := 2 // Trying to update user 2's profile
 	token := generateTestToken(actingUserID)
 
-	updateDTO := UpdateUserDTO{Username: new(string)}
+	updateDTO := user.UpdateUserDTO{Username: new(string)}
 	*updateDTO.Username = "newusername"
 
 	// IMPORTANT: The current controller implementation in user/controller.go
@@ -329,7 +385,7 @@ func TestDeleteProfile_NotFound(t *testing.T) {
 	nonExistentUserID := 2
 	token := generateTestToken(targetUserID)
 
-	mockRepo.On("GetByID", mock.Anything, nonExistentUserID).Return(nil, ErrUserNotFound)
+	mockRepo.On("GetByID", mock.Anything, nonExistentUserID).Return(nil, user.ErrUserNotFound)
 	// Delete should not be called if GetByID fails to find user for the service's pre-check
 
 	w := performUserRequest(router, "DELETE", "/users/"+strconv.Itoa(nonExistentUserID), token, nil)
@@ -366,13 +422,67 @@ func TestDeleteProfile_Forbidden_WrongUser(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestDeleteProfile_Cascade_DoesNotReassign(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	router := setupUserTestRouterWithConfig(mockRepo, &config.Config{DeletedContentMode: config.DeletedContentCascade})
+	token := generateTestToken(1)
+
+	mockRepo.On("GetByID", mock.Anything, 1).Return(&models.User{ID: 1}, nil)
+	mockRepo.On("Delete", mock.Anything, 1).Return(sql.Result(nil), nil)
+
+	w := performUserRequest(router, "DELETE", "/users/1", token, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "DeleteReassigningContent", mock.Anything, mock.Anything)
+}
+
+func TestDeleteProfile_Reassign(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	router := setupUserTestRouterWithConfig(mockRepo, &config.Config{DeletedContentMode: config.DeletedContentReassign})
+	token := generateTestToken(1)
+
+	mockRepo.On("GetByID", mock.Anything, 1).Return(&models.User{ID: 1}, nil)
+	mockRepo.On("DeleteReassigningContent", mock.Anything, 1).Return(nil)
+
+	w := performUserRequest(router, "DELETE", "/users/1", token, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestDeleteProfile_Reassign_MissingPlaceholder(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	router := setupUserTestRouterWithConfig(mockRepo, &config.Config{DeletedContentMode: config.DeletedContentReassign})
+	token := generateTestToken(1)
+
+	mockRepo.On("GetByID", mock.Anything, 1).Return(&models.User{ID: 1}, nil)
+	mockRepo.On("DeleteReassigningContent", mock.Anything, 1).Return(user.ErrNoDeletedUserAccount)
+
+	w := performUserRequest(router, "DELETE", "/users/1", token, nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeleteProfile_PlaceholderCannotBeDeleted(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	router := setupUserTestRouterWithConfig(mockRepo, &config.Config{DeletedContentMode: config.DeletedContentReassign})
+	token := generateTestToken(1)
+
+	mockRepo.On("GetByID", mock.Anything, 7).Return(&models.User{ID: 7, Username: user.DeletedUserUsername}, nil)
+
+	w := performUserRequest(router, "DELETE", "/users/7", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "DeleteReassigningContent", mock.Anything, mock.Anything)
+}
+
 // Test for service error during GetByID in UpdateProfile
 func TestUpdateProfile_ServiceError_GetByID(t *testing.T) {
     mockRepo := new(MockUserRepository)
     router := setupUserTestRouter(mockRepo)
     targetUserID := 1
     token := generateTestToken(targetUserID)
-    updateDTO := UpdateUserDTO{Username: new(string)}; *updateDTO.Username = "newname"
+    updateDTO := user.UpdateUserDTO{Username: new(string)}; *updateDTO.Username = "newname"
 
     // Simulate a generic DB error on GetByID
     mockRepo.On("GetByID", mock.Anything, targetUserID).Return(nil, assert.AnError)
@@ -388,7 +498,7 @@ func TestUpdateProfile_ServiceError_Update(t *testing.T) {
     router := setupUserTestRouter(mockRepo)
     targetUserID := 1
     token := generateTestToken(targetUserID)
-    updateDTO := UpdateUserDTO{Username: new(string)}; *updateDTO.Username = "newname"
+    updateDTO := user.UpdateUserDTO{Username: new(string)}; *updateDTO.Username = "newname"
 
     originalUser := &models.User{ID: targetUserID, Username: "oldusername"}
     mockRepo.On("GetByID", mock.Anything, targetUserID).Return(originalUser, nil)
@@ -429,3 +539,80 @@ func TestDeleteProfile_ServiceError_Delete(t *testing.T) {
     assert.Equal(t, http.StatusInternalServerError, w.Code)
     mockRepo.AssertExpectations(t)
 }
+
+// --- Ban / Unban Tests ---
+
+// setupModerationTestRouter mirrors main.go: every protected route runs
+// RequireActive, and ban/unban additionally require the admin role.
+func setupModerationTestRouter(mockUserRepo user.UserRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	userController := user.NewController(user.NewService(mockUserRepo, nil))
+
+	rg := router.Group("/", auth.JWTAuthMiddleware(), auth.RequireActive(mockUserRepo))
+	rg.GET("/users/:id", userController.GetProfile)
+	rg.POST("/users/:id/ban", auth.RequireRole(models.RoleAdmin), userController.BanUser)
+	rg.POST("/users/:id/unban", auth.RequireRole(models.RoleAdmin), userController.UnbanUser)
+	return router
+}
+
+func TestBannedUser_IsBlocked(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	router := setupModerationTestRouter(mockRepo)
+	token := generateTestToken(5)
+
+	mockRepo.On("GetByID", mock.Anything, 5).Return(&models.User{ID: 5, IsBanned: true}, nil)
+
+	w := performUserRequest(router, "GET", "/users/5", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var resp map[string]string
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, "account is banned", resp["error"])
+}
+
+func TestUnban_RestoresAccess(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	router := setupModerationTestRouter(mockRepo)
+	userToken := generateTestToken(5)
+	adminToken, _ := jwtutil.GenerateTokenWithRole(1, models.RoleAdmin)
+
+	mockRepo.On("GetByID", mock.Anything, 1).Return(&models.User{ID: 1, Role: models.RoleAdmin}, nil)
+	mockRepo.On("GetByID", mock.Anything, 5).Return(&models.User{ID: 5, IsBanned: true}, nil).Once()
+	mockRepo.On("SetBanned", mock.Anything, 5, false).Return(true, nil)
+	mockRepo.On("GetByID", mock.Anything, 5).Return(&models.User{ID: 5, Username: "back"}, nil)
+
+	w := performUserRequest(router, "GET", "/users/5", userToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performUserRequest(router, "POST", "/users/5/unban", adminToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performUserRequest(router, "GET", "/users/5", userToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBan_AdminOnly(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	router := setupModerationTestRouter(mockRepo)
+	token := generateTestToken(2)
+
+	mockRepo.On("GetByID", mock.Anything, 2).Return(&models.User{ID: 2, Role: models.RoleMember}, nil)
+
+	w := performUserRequest(router, "POST", "/users/5/ban", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockRepo.AssertNotCalled(t, "SetBanned", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBan_NotFound(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	router := setupModerationTestRouter(mockRepo)
+	adminToken, _ := jwtutil.GenerateTokenWithRole(1, models.RoleAdmin)
+
+	mockRepo.On("GetByID", mock.Anything, 1).Return(&models.User{ID: 1, Role: models.RoleAdmin}, nil)
+	mockRepo.On("SetBanned", mock.Anything, 99, true).Return(false, nil)
+
+	w := performUserRequest(router, "POST", "/users/99/ban", adminToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockRepo.AssertExpectations(t)
+}
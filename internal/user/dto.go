@@ -1,7 +1,17 @@
 // dto.go 
 package user
 
-import "errors"
+import (
+    "errors"
+    "fmt"
+    "strings"
+)
+
+// Profile text limits, counted in characters.
+const (
+    MaxFullNameLength = 100
+    MaxBioLength      = 1000
+)
 
 // UpdateUserDTO binds JSON for PUT /users/:id.
 // All fields are optional; only non‐zero (non‐empty) fields will be updated.
@@ -13,11 +23,42 @@ type UpdateUserDTO struct {
     Bio      *string `json:"bio,omitempty"`
 }
 
-// Validate ensures at least one field is present.
+// Validate ensures at least one field is present and that the profile text
+// fields are within their limits (after trimming).
 func (dto *UpdateUserDTO) Validate() error {
     if dto.Username == nil && dto.Email == nil &&
        dto.Password == nil && dto.FullName == nil && dto.Bio == nil {
         return errors.New("at least one field must be provided")
     }
+    var fullName, bio string
+    if dto.FullName != nil {
+        fullName = *dto.FullName
+    }
+    if dto.Bio != nil {
+        bio = *dto.Bio
+    }
+    if err := ValidateProfileText(&fullName, &bio); err != nil {
+        return err
+    }
+    if dto.FullName != nil {
+        *dto.FullName = fullName
+    }
+    if dto.Bio != nil {
+        *dto.Bio = bio
+    }
+    return nil
+}
+
+// ValidateProfileText trims fullName and bio in place and checks them
+// against MaxFullNameLength and MaxBioLength. Registration shares it.
+func ValidateProfileText(fullName, bio *string) error {
+    *fullName = strings.TrimSpace(*fullName)
+    *bio = strings.TrimSpace(*bio)
+    if n := len([]rune(*fullName)); n > MaxFullNameLength {
+        return fmt.Errorf("full_name must be at most %d characters (got %d)", MaxFullNameLength, n)
+    }
+    if n := len([]rune(*bio)); n > MaxBioLength {
+        return fmt.Errorf("bio must be at most %d characters (got %d)", MaxBioLength, n)
+    }
     return nil
 }
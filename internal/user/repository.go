@@ -5,9 +5,11 @@ package user
 import (
     "context"
     "database/sql"
+    "errors"
     "time"
 
     "go-discussion-app/models"
+    "go-discussion-app/pkg/slowquery"
 )
 
 type UserRepository interface {
@@ -16,25 +18,38 @@ type UserRepository interface {
     GetByEmail(ctx context.Context, email string) (*models.User, error)
     Update(ctx context.Context, u *models.User) (sql.Result, error)
     Delete(ctx context.Context, id int) (sql.Result, error)
+    DeleteReassigningContent(ctx context.Context, id int) error
+    SetBanned(ctx context.Context, id int, banned bool) (bool, error)
 }
 
+// DeletedUserUsername names the placeholder account (seeded by migration 013)
+// that inherits content when DELETED_USER_CONTENT=reassign.
+const DeletedUserUsername = "deleted-user"
+
+// ErrNoDeletedUserAccount is returned when the placeholder account is missing.
+var ErrNoDeletedUserAccount = errors.New("deleted-user placeholder account not found")
+
 type userRepo struct {
-    db *sql.DB
+    db *slowquery.DB
 }
 
 func NewRepository(db *sql.DB) UserRepository {
-    return &userRepo{db: db}
+    return &userRepo{db: slowquery.Wrap(db)}
 }
 
 func (r *userRepo) Create(ctx context.Context, u *models.User) (int, error) {
     const q = `
       INSERT INTO users
-        (username, email, password_hash, full_name, bio, created_at, updated_at)
-      VALUES ($1,$2,$3,$4,$5,$6,$7)
+        (username, email, password_hash, full_name, bio, role, created_at, updated_at)
+      VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
       RETURNING id;`
+    role := u.Role
+    if role == "" {
+        role = models.RoleMember
+    }
     var id int
     err := r.db.QueryRowContext(ctx, q,
-        u.Username, u.Email, u.PasswordHash, u.FullName, u.Bio,
+        u.Username, u.Email, u.PasswordHash, u.FullName, u.Bio, role,
         u.CreatedAt, u.UpdatedAt,
     ).Scan(&id)
     return id, err
@@ -42,13 +57,13 @@ func (r *userRepo) Create(ctx context.Context, u *models.User) (int, error) {
 
 func (r *userRepo) GetByID(ctx context.Context, id int) (*models.User, error) {
     const q = `
-      SELECT id, username, email, password_hash, full_name, bio, created_at, updated_at
+      SELECT id, username, email, password_hash, full_name, bio, role, is_banned, email_verified_at, created_at, updated_at
       FROM users WHERE id=$1;`
     row := r.db.QueryRowContext(ctx, q, id)
     var u models.User
     if err := row.Scan(
         &u.ID, &u.Username, &u.Email, &u.PasswordHash,
-        &u.FullName, &u.Bio, &u.CreatedAt, &u.UpdatedAt,
+        &u.FullName, &u.Bio, &u.Role, &u.IsBanned, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt,
     ); err != nil {
         if err == sql.ErrNoRows {
             return nil, nil
@@ -58,15 +73,16 @@ func (r *userRepo) GetByID(ctx context.Context, id int) (*models.User, error) {
     return &u, nil
 }
 
+// GetByEmail looks a user up by email, ignoring case.
 func (r *userRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
     const q = `
-      SELECT id, username, email, password_hash, full_name, bio, created_at, updated_at
-      FROM users WHERE email=$1;`
+      SELECT id, username, email, password_hash, full_name, bio, role, is_banned, email_verified_at, created_at, updated_at
+      FROM users WHERE lower(email)=lower($1);`
     row := r.db.QueryRowContext(ctx, q, email)
     var u models.User
     if err := row.Scan(
         &u.ID, &u.Username, &u.Email, &u.PasswordHash,
-        &u.FullName, &u.Bio, &u.CreatedAt, &u.UpdatedAt,
+        &u.FullName, &u.Bio, &u.Role, &u.IsBanned, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt,
     ); err != nil {
         if err == sql.ErrNoRows {
             return nil, nil
@@ -91,3 +107,50 @@ func (r *userRepo) Delete(ctx context.Context, id int) (sql.Result, error) {
     const q = `DELETE FROM users WHERE id=$1;`
     return r.db.ExecContext(ctx, q, id)
 }
+
+// DeleteReassigningContent moves the user's discussions and comments to the
+// placeholder account and then deletes the user, all in one transaction.
+func (r *userRepo) DeleteReassigningContent(ctx context.Context, id int) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+
+    var placeholderID int
+    err = tx.QueryRowContext(ctx,
+        `SELECT id FROM users WHERE username = $1;`, DeletedUserUsername,
+    ).Scan(&placeholderID)
+    if err != nil {
+        tx.Rollback()
+        if err == sql.ErrNoRows {
+            return ErrNoDeletedUserAccount
+        }
+        return err
+    }
+
+    for _, q := range []string{
+        `UPDATE discussions SET user_id = $1 WHERE user_id = $2;`,
+        `UPDATE comments SET user_id = $1 WHERE user_id = $2;`,
+    } {
+        if _, err := tx.ExecContext(ctx, q, placeholderID, id); err != nil {
+            tx.Rollback()
+            return err
+        }
+    }
+    if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1;`, id); err != nil {
+        tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
+
+// SetBanned flips the is_banned flag. It reports false if no such user exists.
+func (r *userRepo) SetBanned(ctx context.Context, id int, banned bool) (bool, error) {
+    const q = `UPDATE users SET is_banned=$1, updated_at=$2 WHERE id=$3;`
+    res, err := r.db.ExecContext(ctx, q, banned, time.Now().UTC(), id)
+    if err != nil {
+        return false, err
+    }
+    n, err := res.RowsAffected()
+    return n > 0, err
+}
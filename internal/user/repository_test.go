@@ -0,0 +1,71 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMockRepo(t *testing.T) (UserRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewRepository(db), mock
+}
+
+func TestDeleteReassigningContent_MovesContentThenDeletes(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users WHERE username = \$1`).
+		WithArgs(DeletedUserUsername).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(99))
+	mock.ExpectExec(`UPDATE discussions SET user_id = \$1 WHERE user_id = \$2`).
+		WithArgs(99, 5).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`UPDATE comments SET user_id = \$1 WHERE user_id = \$2`).
+		WithArgs(99, 5).
+		WillReturnResult(sqlmock.NewResult(0, 8))
+	mock.ExpectExec(`DELETE FROM users WHERE id = \$1`).
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.DeleteReassigningContent(context.Background(), 5))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteReassigningContent_NoPlaceholderRollsBack(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users WHERE username = \$1`).
+		WithArgs(DeletedUserUsername).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	err := repo.DeleteReassigningContent(context.Background(), 5)
+	assert.ErrorIs(t, err, ErrNoDeletedUserAccount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteReassigningContent_UpdateFailureRollsBack(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users WHERE username = \$1`).
+		WithArgs(DeletedUserUsername).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(99))
+	mock.ExpectExec(`UPDATE discussions SET user_id`).
+		WithArgs(99, 5).
+		WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, repo.DeleteReassigningContent(context.Background(), 5), assert.AnError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -5,16 +5,23 @@ import (
     "database/sql"
 
     "github.com/gin-gonic/gin"
+    "go-discussion-app/config"
 )
 
 // RegisterRoutes mounts user/profile endpoints under the protected group.
-func RegisterRoutes(rg *gin.RouterGroup, dbConn *sql.DB) {
+// requireAdmin guards the moderation routes; it is passed in because the
+// auth package (which provides it) already depends on this one.
+func RegisterRoutes(rg *gin.RouterGroup, dbConn *sql.DB, cfg *config.Config, requireAdmin gin.HandlerFunc) {
     repo := NewRepository(dbConn)
-    svc := NewService(repo)
+    svc := NewService(repo, cfg)
     ctr := NewController(svc)
 
     // All these routes require JWT middleware applied by main.go
     rg.GET("/users/:id", ctr.GetProfile)
     rg.PUT("/users/:id", ctr.UpdateProfile)
     rg.DELETE("/users/:id", ctr.DeleteProfile)
+
+    // moderation
+    rg.POST("/users/:id/ban", requireAdmin, ctr.BanUser)
+    rg.POST("/users/:id/unban", requireAdmin, ctr.UnbanUser)
 }
@@ -5,22 +5,32 @@ import (
     "context"
     //"database/sql"
     "errors"
+    "strings"
     "time"
 
     "golang.org/x/crypto/bcrypt"
+    "go-discussion-app/config"
     "go-discussion-app/models"
 )
 
 var (
     ErrUserNotFound = errors.New("user not found")
+    // ErrPlaceholderUser is returned when deleting the deleted-user placeholder itself.
+    ErrPlaceholderUser = errors.New("the deleted-user placeholder cannot be deleted")
 )
 
 type UserService struct {
     repo UserRepository
+    cfg  *config.Config
 }
 
-func NewService(repo UserRepository) *UserService {
-    return &UserService{repo: repo}
+// NewService wires the service. A nil cfg means every knob is off, so
+// account deletion cascades to the user's content.
+func NewService(repo UserRepository, cfg *config.Config) *UserService {
+    if cfg == nil {
+        cfg = &config.Config{}
+    }
+    return &UserService{repo: repo, cfg: cfg}
 }
 
 // GetByID fetches a user by ID.
@@ -50,7 +60,7 @@ func (s *UserService) Update(ctx context.Context, id int, dto *UpdateUserDTO) (*
         existing.Username = *dto.Username
     }
     if dto.Email != nil {
-        existing.Email = *dto.Email
+        existing.Email = strings.ToLower(strings.TrimSpace(*dto.Email))
     }
     if dto.Password != nil {
         hashed, err := bcrypt.GenerateFromPassword([]byte(*dto.Password), bcrypt.DefaultCost)
@@ -73,7 +83,9 @@ func (s *UserService) Update(ctx context.Context, id int, dto *UpdateUserDTO) (*
     return existing, nil
 }
 
-// Delete removes a user by ID.
+// Delete removes a user by ID. Their discussions and comments are deleted
+// with them, or handed to the deleted-user placeholder when
+// cfg.DeletedContentMode is "reassign".
 func (s *UserService) Delete(ctx context.Context, id int) error {
     // Optionally, check existence first:
     u, err := s.repo.GetByID(ctx, id)
@@ -83,6 +95,24 @@ func (s *UserService) Delete(ctx context.Context, id int) error {
     if u == nil {
         return ErrUserNotFound
     }
+    if u.Username == DeletedUserUsername {
+        return ErrPlaceholderUser
+    }
+    if s.cfg.DeletedContentMode == config.DeletedContentReassign {
+        return s.repo.DeleteReassigningContent(ctx, id)
+    }
     _, err = s.repo.Delete(ctx, id)
     return err
 }
+
+// SetBanned bans or unbans a user.
+func (s *UserService) SetBanned(ctx context.Context, id int, banned bool) error {
+    ok, err := s.repo.SetBanned(ctx, id, banned)
+    if err != nil {
+        return err
+    }
+    if !ok {
+        return ErrUserNotFound
+    }
+    return nil
+}
@@ -0,0 +1,14 @@
+// api_token.go 
+package models
+
+import "time"
+
+// APIToken is a long-lived credential for programmatic access.
+type APIToken struct {
+    ID         int        `json:"id" db:"id"`
+    UserID     int        `json:"user_id" db:"user_id"`
+    Name       string     `json:"name" db:"name"`
+    TokenHash  string     `json:"-" db:"token_hash"` // never exposed
+    CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+    LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+}
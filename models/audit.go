@@ -0,0 +1,13 @@
+// audit.go
+package models
+
+import "time"
+
+// AuditEntry records one security-sensitive action: who did what to which target.
+type AuditEntry struct {
+    ID        int       `json:"id" db:"id"`
+    ActorID   int       `json:"actor_id" db:"actor_id"`
+    Action    string    `json:"action" db:"action"`
+    Target    string    `json:"target" db:"target"`
+    CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -5,9 +5,12 @@ import "time"
 
 // Comment represents a user’s comment on a discussion.
 type Comment struct {
-    ID           int       `json:"id" db:"id"`
-    DiscussionID int       `json:"discussion_id" db:"discussion_id"`
-    UserID       int       `json:"user_id" db:"user_id"`
-    Content      string    `json:"content" db:"content"`
-    CreatedAt    time.Time `json:"created_at" db:"created_at"`
+    ID           int        `json:"id" db:"id"`
+    DiscussionID int        `json:"discussion_id" db:"discussion_id"`
+    UserID       int        `json:"user_id" db:"user_id"`
+    ParentID     *int       `json:"parent_id" db:"parent_id"` // nil ⇒ top-level comment
+    Content      string     `json:"content" db:"content"`
+    CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+    UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+    EditedAt     *time.Time `json:"edited_at" db:"edited_at"` // last edit that changed the content; nil ⇒ never edited
 }
@@ -3,6 +3,13 @@ package models
 
 import "time"
 
+// Discussion statuses.
+const (
+    StatusDraft     = "draft"
+    StatusPublished = "published"
+    StatusArchived  = "archived"
+)
+
 // Discussion represents a top-level discussion topic.
 type Discussion struct {
     ID          int        `json:"id" db:"id"`
@@ -10,6 +17,11 @@ type Discussion struct {
     Title       string     `json:"title" db:"title"`
     Content     string     `json:"content" db:"content"`
     ScheduledAt *time.Time `json:"scheduled_at,omitempty" db:"scheduled_at"` // nil ⇒ post immediately
+    Status      string     `json:"status" db:"status"`
     CreatedAt   time.Time  `json:"created_at" db:"created_at"`
     UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+    ViewCount   int        `json:"view_count" db:"view_count"` // times fetched by id; see Repository.IncrementViewCount
+    Language    string     `json:"language" db:"language"` // ISO 639-1 code, "" when unspecified
+    EditedAt    *time.Time `json:"edited_at" db:"edited_at"` // last edit that changed the title or content; nil ⇒ never edited
+    DeletedAt   *time.Time `json:"deleted_at,omitempty" db:"deleted_at"` // set by soft delete; such rows are never read back
 }
@@ -0,0 +1,16 @@
+// revision.go 
+package models
+
+import "time"
+
+// DiscussionRevision records a single edit made to a discussion.
+type DiscussionRevision struct {
+    ID              int       `json:"id" db:"id"`
+    DiscussionID    int       `json:"discussion_id" db:"discussion_id"`
+    EditedBy        int       `json:"edited_by" db:"edited_by"`
+    PreviousTitle   string    `json:"previous_title" db:"previous_title"`
+    PreviousContent string    `json:"previous_content" db:"previous_content"`
+    Title           string    `json:"title" db:"title"`
+    Content         string    `json:"content" db:"content"`
+    CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
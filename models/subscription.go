@@ -10,4 +10,15 @@ type Subscription struct {
     UserID       *int      `json:"user_id,omitempty" db:"user_id"` // nullable; stored as NULL if external email
     Email        string    `json:"email" db:"email"`
     SubscribedAt time.Time `json:"subscribed_at" db:"subscribed_at"`
+    Confirmed    bool      `json:"confirmed" db:"confirmed"` // false until an anonymous subscriber follows the confirm link
+}
+
+// TagSubscription is an email following a tag: it is subscribed to every
+// discussion the tag is attached to from then on.
+type TagSubscription struct {
+    ID           int       `json:"id" db:"id"`
+    TagID        int       `json:"tag_id" db:"tag_id"`
+    UserID       *int      `json:"user_id,omitempty" db:"user_id"`
+    Email        string    `json:"email" db:"email"`
+    SubscribedAt time.Time `json:"subscribed_at" db:"subscribed_at"`
 }
@@ -3,14 +3,23 @@ package models
 
 import "time"
 
+// Roles a user can hold.
+const (
+    RoleMember = "member"
+    RoleAdmin  = "admin"
+)
+
 // User represents a registered user / profile.
 type User struct {
-    ID           int       `json:"id" db:"id"`
-    Username     string    `json:"username" db:"username"`
-    Email        string    `json:"email" db:"email"`
-    PasswordHash string    `json:"-" db:"password_hash"` // omit hash from JSON responses
-    FullName     string    `json:"full_name,omitempty" db:"full_name"`
-    Bio          string    `json:"bio,omitempty" db:"bio"`
-    CreatedAt    time.Time `json:"created_at" db:"created_at"`
-    UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+    ID              int        `json:"id" db:"id"`
+    Username        string     `json:"username" db:"username"`
+    Email           string     `json:"email" db:"email"`
+    PasswordHash    string     `json:"-" db:"password_hash"` // omit hash from JSON responses
+    FullName        string     `json:"full_name,omitempty" db:"full_name"`
+    Bio             string     `json:"bio,omitempty" db:"bio"`
+    Role            string     `json:"role" db:"role"`
+    IsBanned        bool       `json:"is_banned" db:"is_banned"`
+    EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"` // nil until verified
+    CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+    UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
 }
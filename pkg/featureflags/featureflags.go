@@ -0,0 +1,85 @@
+// pkg/featureflags/featureflags.go
+package featureflags
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Feature names an optional part of the API that can be switched off with
+// FEATURE_<NAME>=false. Every feature is on unless disabled.
+type Feature string
+
+const (
+	Search        Feature = "SEARCH"        // GET /discussions/search
+	Subscriptions Feature = "SUBSCRIPTIONS" // subscribe, unsubscribe, notify and the anonymous flow
+	Activity      Feature = "ACTIVITY"      // GET /me/activity
+	Scheduling    Feature = "SCHEDULING"    // POST /discussions/schedule
+)
+
+// All lists every known feature in the order they are reported.
+var All = []Feature{Search, Subscriptions, Activity, Scheduling}
+
+// EnvVar is the environment variable that toggles f, e.g. FEATURE_SEARCH.
+func (f Feature) EnvVar() string {
+	return "FEATURE_" + string(f)
+}
+
+// Flags records which features are switched off. The zero value enables
+// everything, so a Config built by hand gates nothing.
+type Flags struct {
+	disabled map[Feature]bool
+}
+
+// Enabled reports whether f's routes should be registered.
+func (fl Flags) Enabled(f Feature) bool {
+	return !fl.disabled[f]
+}
+
+// With returns a copy of fl with f turned on or off.
+func (fl Flags) With(f Feature, on bool) Flags {
+	out := Flags{disabled: make(map[Feature]bool, len(fl.disabled)+1)}
+	for k, v := range fl.disabled {
+		out.disabled[k] = v
+	}
+	if on {
+		delete(out.disabled, f)
+	} else {
+		out.disabled[f] = true
+	}
+	return out
+}
+
+// FromEnv reads FEATURE_* through getenv (normally os.Getenv). Unset
+// variables leave the feature on; anything strconv.ParseBool rejects is an
+// error so a typo does not silently change the API surface.
+func FromEnv(getenv func(string) string) (Flags, error) {
+	var fl Flags
+	for _, f := range All {
+		v := getenv(f.EnvVar())
+		if v == "" {
+			continue
+		}
+		on, err := strconv.ParseBool(v)
+		if err != nil {
+			return Flags{}, fmt.Errorf("%s must be a boolean, got %q", f.EnvVar(), v)
+		}
+		fl = fl.With(f, on)
+	}
+	return fl, nil
+}
+
+// String lists the disabled features for the startup config summary.
+func (fl Flags) String() string {
+	var off []string
+	for _, f := range All {
+		if !fl.Enabled(f) {
+			off = append(off, strings.ToLower(string(f)))
+		}
+	}
+	if len(off) == 0 {
+		return "none"
+	}
+	return strings.Join(off, ",")
+}
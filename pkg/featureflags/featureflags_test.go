@@ -0,0 +1,39 @@
+package featureflags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(k string) string { return vars[k] }
+}
+
+func TestZeroValueEnablesEverything(t *testing.T) {
+	var fl Flags
+	for _, f := range All {
+		assert.True(t, fl.Enabled(f), f)
+	}
+	assert.Equal(t, "none", fl.String())
+}
+
+func TestFromEnv(t *testing.T) {
+	fl, err := FromEnv(env(map[string]string{"FEATURE_SEARCH": "false", "FEATURE_ACTIVITY": "1"}))
+	assert.NoError(t, err)
+	assert.False(t, fl.Enabled(Search))
+	assert.True(t, fl.Enabled(Activity))
+	assert.True(t, fl.Enabled(Subscriptions))
+	assert.Equal(t, "search", fl.String())
+
+	_, err = FromEnv(env(map[string]string{"FEATURE_SUBSCRIPTIONS": "off"}))
+	assert.ErrorContains(t, err, "FEATURE_SUBSCRIPTIONS")
+}
+
+func TestWithDoesNotMutate(t *testing.T) {
+	base := Flags{}.With(Search, false)
+	both := base.With(Activity, false)
+	assert.True(t, base.Enabled(Activity))
+	assert.False(t, both.Enabled(Search))
+	assert.True(t, both.With(Search, true).Enabled(Search))
+}
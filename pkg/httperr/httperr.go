@@ -0,0 +1,69 @@
+// pkg/httperr/httperr.go
+package httperr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go-discussion-app/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusClientClosedRequest is the non-standard status (from nginx) recorded
+// when the client went away before we could answer.
+const StatusClientClosedRequest = 499
+
+// Kind tells a genuine failure apart from an abandoned request.
+type Kind int
+
+const (
+	// Other is a real failure, e.g. a database error.
+	Other Kind = iota
+	// Canceled means the client disconnected.
+	Canceled
+	// Timeout means the request deadline passed.
+	Timeout
+)
+
+// Classify inspects err and, because drivers do not always wrap the context
+// error (lib/pq reports "canceling statement due to user request"), the
+// state of ctx itself. ctx may be nil.
+func Classify(ctx context.Context, err error) Kind {
+	if err == nil {
+		return Other
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	}
+	if ctx != nil {
+		switch ctx.Err() {
+		case context.Canceled:
+			return Canceled
+		case context.DeadlineExceeded:
+			return Timeout
+		}
+	}
+	return Other
+}
+
+// Abort answers 499 for a cancelled request or 503 for a timed-out one and
+// reports true. Neither is logged as an error. For any other error it writes
+// nothing and returns false, leaving the caller to log it and reply 500.
+func Abort(c *gin.Context, err error) bool {
+	switch Classify(c.Request.Context(), err) {
+	case Canceled:
+		logger.Debugf("request canceled: %s %s", c.Request.Method, c.Request.URL.Path)
+		c.AbortWithStatus(StatusClientClosedRequest)
+		return true
+	case Timeout:
+		logger.Warnf("request timed out: %s %s", c.Request.Method, c.Request.URL.Path)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "request timed out"})
+		return true
+	}
+	return false
+}
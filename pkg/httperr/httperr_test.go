@@ -0,0 +1,57 @@
+package httperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	assert.Equal(t, Canceled, Classify(nil, context.Canceled))
+	assert.Equal(t, Canceled, Classify(nil, fmt.Errorf("query: %w", context.Canceled)))
+	assert.Equal(t, Timeout, Classify(nil, context.DeadlineExceeded))
+	assert.Equal(t, Other, Classify(nil, errors.New("connection refused")))
+	assert.Equal(t, Other, Classify(context.Background(), nil))
+}
+
+func TestClassify_FallsBackToContext(t *testing.T) {
+	// Drivers such as lib/pq report their own error when a query is cancelled.
+	driverErr := errors.New("pq: canceling statement due to user request")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, Canceled, Classify(ctx, driverErr))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	assert.Equal(t, Timeout, Classify(ctx, driverErr))
+
+	assert.Equal(t, Other, Classify(context.Background(), driverErr))
+}
+
+func abortWith(err error) (*httptest.ResponseRecorder, bool) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/x", nil)
+	return w, Abort(c, err)
+}
+
+func TestAbort(t *testing.T) {
+	w, handled := abortWith(context.Canceled)
+	assert.True(t, handled)
+	assert.Equal(t, StatusClientClosedRequest, w.Code)
+
+	w, handled = abortWith(context.DeadlineExceeded)
+	assert.True(t, handled)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	_, handled = abortWith(errors.New("boom"))
+	assert.False(t, handled)
+}
@@ -3,22 +3,31 @@
 package jwtutil
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
 // JWTClaims defines custom claims, embedding StandardClaims.
-// You can add more fields here if you want (e.g. Role, Email, etc.).
+// You can add more fields here if you want (e.g. Email, etc.).
 type JWTClaims struct {
-	UserID int `json:"user_id"`
+	UserID   int    `json:"user_id"`
+	Role     string `json:"role,omitempty"`
+	TokenUse string `json:"token_use,omitempty"` // "refresh" for refresh tokens, empty for access tokens
 	jwt.RegisteredClaims
 }
 
+// tokenUseRefresh is the TokenUse claim of refresh tokens. ValidateToken
+// rejects them so a refresh token can never be used as an access token.
+const tokenUseRefresh = "refresh"
+
 var (
 	// ErrTokenExpired is returned when JWT is expired
 	ErrTokenExpired = errors.New("token has expired")
@@ -53,18 +62,55 @@ func getExpiryDuration() time.Duration {
 	return time.Minute * time.Duration(minutes)
 }
 
-// GenerateToken creates a signed JWT string for the given user ID.
+// refreshExpiry is the configured refresh token lifetime; zero means unset.
+var refreshExpiry atomic.Int64
+
+// SetRefreshExpiry sets how long refresh tokens remain valid. Zero or
+// negative falls back to JWT_REFRESH_EXPIRES_IN.
+func SetRefreshExpiry(d time.Duration) {
+	refreshExpiry.Store(int64(d))
+}
+
+// getRefreshExpiryDuration returns the lifetime set by SetRefreshExpiry, else
+// reads JWT_REFRESH_EXPIRES_IN (minutes) from env, defaulting to 30 days if
+// missing/invalid.
+func getRefreshExpiryDuration() time.Duration {
+	if d := time.Duration(refreshExpiry.Load()); d > 0 {
+		return d
+	}
+	s := os.Getenv("JWT_REFRESH_EXPIRES_IN")
+	minutes, err := strconv.Atoi(s)
+	if err != nil || minutes <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return time.Minute * time.Duration(minutes)
+}
+
+// GenerateToken creates a signed JWT string for the given user ID with no role claim.
 // It uses HS256 algorithm.
 func GenerateToken(userID int) (string, error) {
+	return GenerateTokenWithRole(userID, "")
+}
+
+// GenerateTokenWithRole creates a signed JWT string carrying the user ID and role.
+// Each token gets a random ID (the jti claim) so that it can be revoked on
+// logout before it expires.
+func GenerateTokenWithRole(userID int, role string) (string, error) {
 	key, err := getSigningKey()
 	if err != nil {
 		return "", err
 	}
+	id, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
 
 	expiry := time.Now().Add(getExpiryDuration())
 	claims := JWTClaims{
 		UserID: userID,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
 			ExpiresAt: jwt.NewNumericDate(expiry),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			// You can add Audience, Issuer, Subject here if desired:
@@ -82,8 +128,74 @@ func GenerateToken(userID int) (string, error) {
 	return signedStr, nil
 }
 
+// GenerateRefreshToken creates a long-lived refresh token for userID. The
+// token carries a random ID (the jti claim) which the caller stores so the
+// token can be revoked; expiresAt is when the token stops validating.
+func GenerateRefreshToken(userID int) (token, id string, expiresAt time.Time, err error) {
+	key, err := getSigningKey()
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	if id, err = newTokenID(); err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt = now.Add(getRefreshExpiryDuration())
+	claims := JWTClaims{
+		UserID:   userID,
+		TokenUse: tokenUseRefresh,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("unable to sign token: %w", err)
+	}
+	return token, id, expiresAt, nil
+}
+
+// newTokenID returns a random jti.
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// ValidateRefreshToken parses a token made by GenerateRefreshToken. Access
+// tokens are rejected with ErrTokenInvalid. Whether the token has been
+// revoked is up to the caller, using claims.ID.
+func ValidateRefreshToken(tokenStr string) (*JWTClaims, error) {
+	claims, err := parseToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != tokenUseRefresh || claims.ID == "" {
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
+
 // ValidateToken parses and validates the JWT string. If valid, it returns the claims.
+// Refresh tokens are rejected with ErrTokenInvalid.
 func ValidateToken(tokenStr string) (*JWTClaims, error) {
+	claims, err := parseToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenUse == tokenUseRefresh {
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
+
+// parseToken checks the signature and expiry of tokenStr and returns its claims.
+func parseToken(tokenStr string) (*JWTClaims, error) {
 	key, err := getSigningKey()
 	if err != nil {
 		return nil, err
@@ -0,0 +1,65 @@
+package jwtutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshAndAccessTokensAreNotInterchangeable(t *testing.T) {
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+
+	access, err := GenerateTokenWithRole(3, "member")
+	assert.NoError(t, err)
+	refresh, id, expiresAt, err := GenerateRefreshToken(3)
+	assert.NoError(t, err)
+	assert.Len(t, id, 32)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), expiresAt, time.Minute)
+
+	claims, err := ValidateRefreshToken(refresh)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, claims.UserID)
+		assert.Equal(t, id, claims.ID)
+	}
+	_, err = ValidateToken(refresh)
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+	_, err = ValidateRefreshToken(access)
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+}
+
+func TestGenerateRefreshToken_Expiry(t *testing.T) {
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("JWT_REFRESH_EXPIRES_IN", "90")
+
+	_, _, expiresAt, err := GenerateRefreshToken(1)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(90*time.Minute), expiresAt, time.Minute)
+}
+
+func TestGenerateRefreshToken_ConfiguredExpiryWins(t *testing.T) {
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("JWT_REFRESH_EXPIRES_IN", "90")
+	SetRefreshExpiry(15 * time.Minute)
+	t.Cleanup(func() { SetRefreshExpiry(0) })
+
+	_, _, expiresAt, err := GenerateRefreshToken(1)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), expiresAt, time.Minute)
+}
+
+func TestGenerateToken_CarriesUniqueID(t *testing.T) {
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+
+	a, err := GenerateToken(1)
+	assert.NoError(t, err)
+	b, err := GenerateToken(1)
+	assert.NoError(t, err)
+
+	ca, err := ValidateToken(a)
+	assert.NoError(t, err)
+	cb, err := ValidateToken(b)
+	assert.NoError(t, err)
+	assert.Len(t, ca.ID, 32)
+	assert.NotEqual(t, ca.ID, cb.ID)
+}
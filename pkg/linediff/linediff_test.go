@@ -0,0 +1,41 @@
+package linediff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff_ReplacedLine(t *testing.T) {
+	got := Diff("intro\nold middle\noutro", "intro\nnew middle\noutro")
+	assert.Equal(t, []Line{
+		{Equal, "intro"},
+		{Delete, "old middle"},
+		{Insert, "new middle"},
+		{Equal, "outro"},
+	}, got)
+	assert.True(t, Changed(got))
+}
+
+func TestDiff_InsertAndDelete(t *testing.T) {
+	got := Diff("a\nb\nc\nd", "a\nc\nd\ne")
+	assert.Equal(t, []Line{
+		{Equal, "a"},
+		{Delete, "b"},
+		{Equal, "c"},
+		{Equal, "d"},
+		{Insert, "e"},
+	}, got)
+}
+
+func TestDiff_Unchanged(t *testing.T) {
+	got := Diff("same\r\ntext", "same\ntext")
+	assert.Equal(t, []Line{{Equal, "same"}, {Equal, "text"}}, got)
+	assert.False(t, Changed(got))
+}
+
+func TestDiff_FromAndToEmpty(t *testing.T) {
+	assert.Equal(t, []Line{{Insert, "x"}}, Diff("", "x"))
+	assert.Equal(t, []Line{{Delete, "x"}}, Diff("x", ""))
+	assert.Equal(t, []Line{}, Diff("", ""))
+}
@@ -0,0 +1,95 @@
+// pkg/mailer/attachment.go
+package mailer
+
+import (
+	"encoding/base64"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// Attachment is a file sent along with an email.
+type Attachment struct {
+	Filename    string // name shown to the recipient, e.g. "export.csv"
+	ContentType string // e.g. "text/csv"; empty means application/octet-stream
+	Data        []byte
+}
+
+// base64LineLength is the longest encoded line allowed by RFC 2045.
+const base64LineLength = 76
+
+// SendMailWithAttachments sends a plaintext email with files attached as a
+// multipart/mixed message: the body comes first, then one base64 part per
+// attachment.
+func SendMailWithAttachments(to []string, subject, body string, attachments []Attachment) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	msg, err := cfg.buildMultipartMessage(to, subject, body, attachments)
+	if err != nil {
+		return err
+	}
+	return cfg.deliver(to, msg)
+}
+
+// buildMultipartMessage renders a multipart/mixed message with the same
+// envelope headers as buildMessage.
+func (cfg *Config) buildMultipartMessage(to []string, subject, body string, attachments []Attachment) (string, error) {
+	headers, err := cfg.headers(to, subject)
+	if err != nil {
+		return "", err
+	}
+
+	var parts strings.Builder
+	w := multipart.NewWriter(&parts)
+
+	text, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {`text/plain; charset="utf-8"`},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := text.Write([]byte(body)); err != nil {
+		return "", err
+	}
+
+	for _, a := range attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": a.Filename})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return "", err
+		}
+		if _, err := part.Write([]byte(wrapBase64(a.Data))); err != nil {
+			return "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	headers = append(headers, [2]string{"Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": w.Boundary()})})
+	return writeHeaders(headers) + "\r\n" + parts.String(), nil
+}
+
+// wrapBase64 encodes data as base64 split into CRLF-terminated lines.
+func wrapBase64(data []byte) string {
+	enc := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(enc) > base64LineLength {
+		b.WriteString(enc[:base64LineLength] + "\r\n")
+		enc = enc[base64LineLength:]
+	}
+	b.WriteString(enc)
+	return b.String()
+}
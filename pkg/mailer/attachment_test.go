@@ -0,0 +1,84 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMultipartMessage_AttachmentPart(t *testing.T) {
+	cfg := &Config{From: "noreply@example.com", FromName: "Go Discussions"}
+	data := bytes.Repeat([]byte("id,title\n1,hello\n"), 10) // long enough to wrap
+
+	msg, err := cfg.buildMultipartMessage([]string{"a@example.com"}, "Your export", "See attached.", []Attachment{
+		{Filename: "export.csv", ContentType: "text/csv", Data: data},
+		{Filename: "raw data.bin", Data: []byte{0, 1, 2}},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(msg))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `"Go Discussions" <noreply@example.com>`, m.Header.Get("From"))
+	assert.Equal(t, "Your export", m.Header.Get("Subject"))
+	assert.Equal(t, "1.0", m.Header.Get("MIME-Version"))
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "multipart/mixed", mediaType)
+	r := multipart.NewReader(m.Body, params["boundary"])
+
+	text, err := r.NextPart()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `text/plain; charset="utf-8"`, text.Header.Get("Content-Type"))
+	b, _ := io.ReadAll(text)
+	assert.Equal(t, "See attached.", string(b))
+
+	csv, err := r.NextPart()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `text/csv; name=export.csv`, csv.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=export.csv`, csv.Header.Get("Content-Disposition"))
+	assert.Equal(t, "base64", csv.Header.Get("Content-Transfer-Encoding"))
+	assert.Equal(t, "export.csv", csv.FileName())
+	encoded, _ := io.ReadAll(csv)
+	for _, line := range strings.Split(string(encoded), "\r\n") {
+		assert.LessOrEqual(t, len(line), base64LineLength)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, data, decoded)
+
+	bin, err := r.NextPart()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `application/octet-stream; name="raw data.bin"`, bin.Header.Get("Content-Type"))
+	assert.Equal(t, "raw data.bin", bin.FileName())
+
+	_, err = r.NextPart()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestBuildMultipartMessage_InvalidFrom(t *testing.T) {
+	cfg := &Config{From: "not an address"}
+	_, err := cfg.buildMultipartMessage([]string{"a@example.com"}, "x", "y", nil)
+	assert.Error(t, err)
+}
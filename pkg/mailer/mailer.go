@@ -5,8 +5,11 @@ package mailer
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"mime"
 	"net"
+	"net/mail"
 	"net/smtp"
 	"os"
 	"strings"
@@ -19,16 +22,24 @@ type Config struct {
 	Username string // SMTP auth username (often the full email address)
 	Password string // SMTP auth password (or app password)
 	From     string // From email address (e.g. "noreply@example.com")
+	FromName string // optional display name shown with From (e.g. "Go Discussions")
+	ReplyTo  string // optional Reply-To address
 }
 
+// ErrNotConfigured is returned by the Send functions when a required
+// SMTP environment variable is missing.
+var ErrNotConfigured = errors.New("email not configured")
+
 // loadConfig reads required environment variables into a Config struct.
-// It panics if any required var is missing.
-func loadConfig() *Config {
+// It returns an error wrapping ErrNotConfigured if any required var is missing.
+func loadConfig() (*Config, error) {
 	host := os.Getenv("SMTP_HOST")
 	port := os.Getenv("SMTP_PORT")
 	user := os.Getenv("SMTP_USERNAME")
 	pass := os.Getenv("SMTP_PASSWORD")
 	from := os.Getenv("FROM_EMAIL")
+	fromName := os.Getenv("FROM_NAME")
+	replyTo := os.Getenv("REPLY_TO")
 
 	missing := []string{}
 	if host == "" {
@@ -47,7 +58,7 @@ func loadConfig() *Config {
 		missing = append(missing, "FROM_EMAIL")
 	}
 	if len(missing) > 0 {
-		panic(fmt.Sprintf("missing required environment variables: %s", strings.Join(missing, ", ")))
+		return nil, fmt.Errorf("%w: missing required environment variables: %s", ErrNotConfigured, strings.Join(missing, ", "))
 	}
 
 	return &Config{
@@ -56,7 +67,54 @@ func loadConfig() *Config {
 		Username: user,
 		Password: pass,
 		From:     from,
+		FromName: fromName,
+		ReplyTo:  replyTo,
+	}, nil
+}
+
+// buildMessage renders the headers and body of a message. From carries
+// FromName as its display name and Reply-To is only set when configured.
+// Both addresses are validated so a bad config fails before dialing.
+func (cfg *Config) buildMessage(to []string, subject, contentType, body string) (string, error) {
+	headers, err := cfg.headers(to, subject)
+	if err != nil {
+		return "", err
+	}
+	headers = append(headers, [2]string{"Content-Type", contentType + "; charset=\"utf-8\""})
+	return writeHeaders(headers) + "\r\n" + body, nil
+}
+
+// headers returns the envelope headers shared by every message, up to and
+// including MIME-Version.
+func (cfg *Config) headers(to []string, subject string) ([][2]string, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FROM_EMAIL %q: %w", cfg.From, err)
+	}
+	from.Name = cfg.FromName
+
+	headers := [][2]string{{"From", from.String()}}
+	if cfg.ReplyTo != "" {
+		replyTo, err := mail.ParseAddress(cfg.ReplyTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REPLY_TO %q: %w", cfg.ReplyTo, err)
+		}
+		headers = append(headers, [2]string{"Reply-To", replyTo.String()})
 	}
+	headers = append(headers,
+		[2]string{"To", strings.Join(to, ", ")},
+		[2]string{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		[2]string{"MIME-Version", "1.0"},
+	)
+	return headers, nil
+}
+
+func writeHeaders(headers [][2]string) string {
+	var b strings.Builder
+	for _, h := range headers {
+		fmt.Fprintf(&b, "%s: %s\r\n", h[0], h[1])
+	}
+	return b.String()
 }
 
 // buildAuth returns an smtp.Auth object for PLAIN auth.
@@ -85,78 +143,67 @@ func (cfg *Config) dialTLS() (*smtp.Client, error) {
 	return client, nil
 }
 
-// SendMail sends a plaintext email to one or more recipients.
-// - to: slice of recipient email addresses.
-// - subject: email subject.
-// - body: plaintext body (no HTML).
-func SendMail(to []string, subject, body string) error {
-	cfg := loadConfig()
-	
-	// 1) Build the email headers
-	headers := make(map[string]string)
-	headers["From"] = cfg.From
-	headers["To"] = strings.Join(to, ", ")
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/plain; charset=\"utf-8\""
-	
-	// 2) Concatenate headers and body
-	var msgBuilder strings.Builder
-	for k, v := range headers {
-		fmt.Fprintf(&msgBuilder, "%s: %s\r\n", k, v)
-	}
-	msgBuilder.WriteString("\r\n" + body)
+// Mailer sends a plaintext email, or an HTML one built from a named
+// template, to one or more recipients. Services take a Mailer so tests can
+// inject a fake instead of talking to an SMTP server.
+type Mailer interface {
+	Send(to []string, subject, body string) error
+	SendTemplate(to []string, tmpl string, data any) error
+}
 
-	// 3) Connect to SMTP server
-	addr := net.JoinHostPort(cfg.Host, cfg.Port)
+// Func adapts an ordinary function to the Mailer interface.
+type Func func(to []string, subject, body string) error
 
-	// Use STARTTLS on port 587 (typical). If your provider requires port 465, swap to dialTLS().
-	client, err := smtp.Dial(addr)
+// Send calls f.
+func (f Func) Send(to []string, subject, body string) error {
+	return f(to, subject, body)
+}
+
+// SendTemplate renders the template and calls f with the HTML as the body.
+func (f Func) SendTemplate(to []string, tmpl string, data any) error {
+	subject, body, err := renderMessage(tmpl, data)
 	if err != nil {
-		return fmt.Errorf("smtp dial error: %w", err)
+		return err
 	}
-	defer client.Quit()
+	return f(to, subject, body)
+}
 
-	// 4) StartTLS (if needed)
-	if ok, _ := client.Extension("STARTTLS"); ok {
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: false,
-			ServerName:         cfg.Host,
-		}
-		if err := client.StartTLS(tlsConfig); err != nil {
-			return fmt.Errorf("failed to start TLS: %w", err)
-		}
-	}
+// SMTPMailer is the production Mailer. It reads the SMTP_* and FROM_EMAIL
+// settings on every send and returns ErrNotConfigured when any are missing.
+type SMTPMailer struct{}
 
-	// 5) Authenticate
-	if err := client.Auth(cfg.buildAuth()); err != nil {
-		return fmt.Errorf("smtp auth error: %w", err)
+// Send delivers a plaintext email.
+// - to: slice of recipient email addresses.
+// - subject: email subject.
+// - body: plaintext body (no HTML).
+func (SMTPMailer) Send(to []string, subject, body string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
 	}
 
-	// 6) Set the sender and recipients
-	if err := client.Mail(cfg.From); err != nil {
-		return fmt.Errorf("failed to set MAIL FROM: %w", err)
-	}
-	for _, recipient := range to {
-		if err := client.Rcpt(recipient); err != nil {
-			return fmt.Errorf("failed to add RCPT TO %s: %w", recipient, err)
-		}
+	// 1-2) Build the headers and body
+	msg, err := cfg.buildMessage(to, subject, "text/plain", body)
+	if err != nil {
+		return err
 	}
+	return cfg.deliver(to, msg)
+}
 
-	// 7) Write the message data
-	wc, err := client.Data()
+// SendTemplate renders the named template and delivers it as HTML. A
+// template or data error is returned before SMTP settings are read.
+func (SMTPMailer) SendTemplate(to []string, tmpl string, data any) error {
+	subject, body, err := renderMessage(tmpl, data)
 	if err != nil {
-		return fmt.Errorf("failed to get Data writer: %w", err)
-	}
-	_, err = wc.Write([]byte(msgBuilder.String()))
-	if err != nil {
-		return fmt.Errorf("failed to write message: %w", err)
-	}
-	if err := wc.Close(); err != nil {
-		return fmt.Errorf("failed to close Data writer: %w", err)
+		return err
 	}
+	return SendMailHTML(to, subject, body)
+}
 
-	return nil
+// SendMail sends a plaintext email through SMTPMailer. It predates the
+// Mailer interface and is kept for existing callers.
+func SendMail(to []string, subject, body string) error {
+	return SMTPMailer{}.Send(to, subject, body)
 }
 
 // SendMailHTML sends an HTML email to one or more recipients.
@@ -164,32 +211,35 @@ func SendMail(to []string, subject, body string) error {
 // - subject: email subject.
 // - htmlBody: HTML content; headers will be set accordingly.
 func SendMailHTML(to []string, subject, htmlBody string) error {
-	cfg := loadConfig()
-	
-	// 1) Build email headers
-	headers := make(map[string]string)
-	headers["From"] = cfg.From
-	headers["To"] = strings.Join(to, ", ")
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=\"utf-8\""
-	
-	// 2) Concatenate headers and HTML body
-	var msgBuilder strings.Builder
-	for k, v := range headers {
-		fmt.Fprintf(&msgBuilder, "%s: %s\r\n", k, v)
-	}
-	msgBuilder.WriteString("\r\n" + htmlBody)
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// 1-2) Build the headers and HTML body
+	msg, err := cfg.buildMessage(to, subject, "text/html", htmlBody)
+	if err != nil {
+		return err
+	}
+	return cfg.deliver(to, msg)
+}
+
+// deliver waits for the send rate limit, then hands msg to the SMTP server,
+// upgrading to TLS when the server offers STARTTLS.
+func (cfg *Config) deliver(to []string, msg string) error {
+	limiter.wait()
 
 	// 3) Connect to SMTP server
 	addr := net.JoinHostPort(cfg.Host, cfg.Port)
+
+	// Use STARTTLS on port 587 (typical). If your provider requires port 465, swap to dialTLS().
 	client, err := smtp.Dial(addr)
 	if err != nil {
 		return fmt.Errorf("smtp dial error: %w", err)
 	}
 	defer client.Quit()
 
-	// 4) StartTLS if available
+	// 4) StartTLS (if needed)
 	if ok, _ := client.Extension("STARTTLS"); ok {
 		tlsConfig := &tls.Config{
 			InsecureSkipVerify: false,
@@ -205,7 +255,7 @@ func SendMailHTML(to []string, subject, htmlBody string) error {
 		return fmt.Errorf("smtp auth error: %w", err)
 	}
 
-	// 6) Set MAIL FROM and RCPT TO
+	// 6) Set the sender and recipients
 	if err := client.Mail(cfg.From); err != nil {
 		return fmt.Errorf("failed to set MAIL FROM: %w", err)
 	}
@@ -215,12 +265,12 @@ func SendMailHTML(to []string, subject, htmlBody string) error {
 		}
 	}
 
-	// 7) Write the HTML message data
+	// 7) Write the message data
 	wc, err := client.Data()
 	if err != nil {
 		return fmt.Errorf("failed to get Data writer: %w", err)
 	}
-	_, err = wc.Write([]byte(msgBuilder.String()))
+	_, err = wc.Write([]byte(msg))
 	if err != nil {
 		return fmt.Errorf("failed to write message: %w", err)
 	}
@@ -0,0 +1,103 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// headerLines returns the header block of msg split into lines.
+func headerLines(msg string) []string {
+	head := strings.SplitN(msg, "\r\n\r\n", 2)[0]
+	return strings.Split(head, "\r\n")
+}
+
+func TestBuildMessage_FromNameAndReplyTo(t *testing.T) {
+	cfg := &Config{From: "noreply@example.com", FromName: "Go Discussions", ReplyTo: "support@example.com"}
+
+	msg, err := cfg.buildMessage([]string{"a@example.com", "b@example.com"}, "Hello", "text/plain", "body")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`From: "Go Discussions" <noreply@example.com>`,
+		`Reply-To: <support@example.com>`,
+		`To: a@example.com, b@example.com`,
+		`Subject: Hello`,
+		`MIME-Version: 1.0`,
+		`Content-Type: text/plain; charset="utf-8"`,
+	}, headerLines(msg))
+	assert.True(t, strings.HasSuffix(msg, "\r\n\r\nbody"))
+}
+
+func TestBuildMessage_EncodesSubject(t *testing.T) {
+	cfg := &Config{From: "noreply@example.com"}
+
+	msg, err := cfg.buildMessage([]string{"a@example.com"}, "Héllo\r\nBcc: x@example.com", "text/plain", "body")
+	assert.NoError(t, err)
+	lines := headerLines(msg)
+	assert.Contains(t, lines, "Subject: =?utf-8?q?H=C3=A9llo=0D=0ABcc:_x@example.com?=")
+	assert.NotContains(t, lines, "Bcc: x@example.com")
+}
+
+func TestBuildMessage_BareFromWithoutReplyTo(t *testing.T) {
+	cfg := &Config{From: "noreply@example.com"}
+
+	msg, err := cfg.buildMessage([]string{"a@example.com"}, "Hi", "text/html", "<p>x</p>")
+	assert.NoError(t, err)
+	lines := headerLines(msg)
+	assert.Equal(t, "From: <noreply@example.com>", lines[0])
+	assert.NotContains(t, msg, "Reply-To")
+	assert.Contains(t, lines, `Content-Type: text/html; charset="utf-8"`)
+}
+
+func TestBuildMessage_EncodesNonASCIIName(t *testing.T) {
+	cfg := &Config{From: "noreply@example.com", FromName: "Débats"}
+
+	msg, err := cfg.buildMessage([]string{"a@example.com"}, "Hi", "text/plain", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "From: =?utf-8?q?D=C3=A9bats?= <noreply@example.com>", headerLines(msg)[0])
+}
+
+func TestBuildMessage_InvalidAddresses(t *testing.T) {
+	_, err := (&Config{From: "not-an-address"}).buildMessage(nil, "s", "text/plain", "")
+	assert.ErrorContains(t, err, "invalid FROM_EMAIL")
+
+	_, err = (&Config{From: "noreply@example.com", ReplyTo: "nope"}).buildMessage(nil, "s", "text/plain", "")
+	assert.ErrorContains(t, err, "invalid REPLY_TO")
+}
+
+func TestSendMail_NotConfiguredReturnsError(t *testing.T) {
+	for _, k := range []string{"SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD", "FROM_EMAIL"} {
+		t.Setenv(k, "")
+	}
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+
+	err := SendMail([]string{"a@example.com"}, "s", "b")
+	assert.ErrorIs(t, err, ErrNotConfigured)
+	assert.ErrorContains(t, err, "SMTP_PORT")
+	assert.NotContains(t, err.Error(), "SMTP_HOST")
+
+	assert.ErrorIs(t, SendMailHTML([]string{"a@example.com"}, "s", "b"), ErrNotConfigured)
+}
+
+func TestSMTPMailer_NotConfigured(t *testing.T) {
+	for _, k := range []string{"SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD", "FROM_EMAIL"} {
+		t.Setenv(k, "")
+	}
+	var m Mailer = SMTPMailer{}
+
+	err := m.Send([]string{"a@example.com"}, "Hi", "body")
+	assert.ErrorIs(t, err, ErrNotConfigured)
+	assert.ErrorIs(t, SendMail([]string{"a@example.com"}, "Hi", "body"), ErrNotConfigured)
+}
+
+func TestFunc_ImplementsMailer(t *testing.T) {
+	var got []string
+	var m Mailer = Func(func(to []string, subject, body string) error {
+		got = append(got, to[0]+"|"+subject+"|"+body)
+		return nil
+	})
+
+	assert.NoError(t, m.Send([]string{"a@example.com"}, "Hi", "body"))
+	assert.Equal(t, []string{"a@example.com|Hi|body"}, got)
+}
@@ -0,0 +1,68 @@
+// pkg/mailer/ratelimit.go
+package mailer
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket is a token bucket shared by every send in the process. A send
+// takes one token; when none is left it reserves the next one and sleeps
+// until it is due, so concurrent senders queue up at the configured rate.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second; 0 disables the limit
+	burst  float64 // bucket capacity
+	tokens float64 // may go negative while senders wait for reserved tokens
+	last   time.Time
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+var limiter = &bucket{now: time.Now, sleep: time.Sleep}
+
+// SetRateLimit caps outgoing mail at perSecond messages per second, letting
+// up to burst go out back to back after a quiet spell. Sends beyond the rate
+// block until their turn. perSecond <= 0 removes the limit; burst < 1 is
+// treated as 1.
+func SetRateLimit(perSecond float64, burst int) {
+	limiter.set(perSecond, burst)
+}
+
+func (b *bucket) set(perSecond float64, burst int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if burst < 1 {
+		burst = 1
+	}
+	b.rate = perSecond
+	b.burst = float64(burst)
+	b.tokens = b.burst
+	b.last = b.now()
+}
+
+// wait blocks until the caller may send one message.
+func (b *bucket) wait() {
+	b.mu.Lock()
+	if b.rate <= 0 {
+		b.mu.Unlock()
+		return
+	}
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay > 0 {
+		b.sleep(delay)
+	}
+}
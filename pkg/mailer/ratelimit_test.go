@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBucket returns a bucket on a fake clock. Sleeping advances the clock,
+// and every sleep is recorded.
+func fakeBucket(perSecond float64, burst int) (*bucket, *[]time.Duration) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var slept []time.Duration
+	b := &bucket{
+		now: func() time.Time { return now },
+		sleep: func(d time.Duration) {
+			slept = append(slept, d)
+			now = now.Add(d)
+		},
+	}
+	b.set(perSecond, burst)
+	return b, &slept
+}
+
+func TestBucket_PacesSendsAtRate(t *testing.T) {
+	b, slept := fakeBucket(10, 1)
+
+	start := b.now()
+	for i := 0; i < 5; i++ {
+		b.wait()
+	}
+
+	// The first send uses the initial token; each of the other four waits 100ms.
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond,
+	}, *slept)
+	assert.Equal(t, 400*time.Millisecond, b.now().Sub(start))
+}
+
+func TestBucket_BurstThenPaced(t *testing.T) {
+	b, slept := fakeBucket(2, 3)
+
+	for i := 0; i < 3; i++ {
+		b.wait()
+	}
+	assert.Empty(t, *slept)
+
+	b.wait()
+	assert.Equal(t, []time.Duration{500 * time.Millisecond}, *slept)
+}
+
+func TestBucket_RefillsWhileIdle(t *testing.T) {
+	b, slept := fakeBucket(1, 1)
+
+	b.wait()
+	b.sleep(5 * time.Second) // idle long enough to refill, capped at burst
+	*slept = nil
+
+	b.wait()
+	assert.Empty(t, *slept)
+	b.wait()
+	assert.Equal(t, []time.Duration{time.Second}, *slept)
+}
+
+func TestBucket_DisabledNeverSleeps(t *testing.T) {
+	b, slept := fakeBucket(0, 1)
+
+	for i := 0; i < 100; i++ {
+		b.wait()
+	}
+	assert.Empty(t, *slept)
+}
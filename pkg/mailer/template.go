@@ -0,0 +1,73 @@
+// pkg/mailer/template.go
+package mailer
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// Template names accepted by RenderTemplate and SendTemplate.
+const (
+	TemplateNotification = "notification.html" // data: NotificationData
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// templates holds every file under templates/, parsed once. html/template
+// escapes each value for where it lands, so user text in a title or body
+// cannot inject markup and a javascript: URL is neutralised in href.
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// NotificationData fills TemplateNotification: one subscriber's copy of a
+// discussion update.
+type NotificationData struct {
+	Subject         string
+	Email           string // the recipient, shown next to the unsubscribe link
+	Message         string // the sender's text, already personalised, shown with its line breaks
+	DiscussionTitle string
+	Snippet         string // the start of the discussion's content
+	DiscussionURL   string
+	UnsubscribeURL  string // removes only this recipient from only this discussion
+}
+
+// MailSubject returns the subject line SendTemplate uses.
+func (d NotificationData) MailSubject() string {
+	return d.Subject
+}
+
+// ErrNoSubject is returned by SendTemplate when data has no MailSubject method.
+var ErrNoSubject = errors.New("template data has no MailSubject method")
+
+// RenderTemplate writes the named template, executed with data, to w.
+func RenderTemplate(w io.Writer, name string, data any) error {
+	if templates.Lookup(name) == nil {
+		return fmt.Errorf("unknown email template %q", name)
+	}
+	return templates.ExecuteTemplate(w, name, data)
+}
+
+// renderMessage renders the named template to a string and reads the
+// subject off data.
+func renderMessage(name string, data any) (subject, body string, err error) {
+	s, ok := data.(interface{ MailSubject() string })
+	if !ok {
+		return "", "", ErrNoSubject
+	}
+	var buf strings.Builder
+	if err := RenderTemplate(&buf, name, data); err != nil {
+		return "", "", err
+	}
+	return s.MailSubject(), buf.String(), nil
+}
+
+// SendTemplate sends the named template as an HTML email through
+// SMTPMailer. data supplies the subject through a MailSubject method, as
+// NotificationData does.
+func SendTemplate(to []string, tmpl string, data any) error {
+	return SMTPMailer{}.SendTemplate(to, tmpl, data)
+}
@@ -0,0 +1,80 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleNotification() NotificationData {
+	return NotificationData{
+		Subject:         "New post",
+		Email:           "alice@example.com",
+		Message:         "Line one\nLine two",
+		DiscussionTitle: "Go generics",
+		Snippet:         "Type parameters landed in 1.18",
+		DiscussionURL:   "https://forum.example.com/discussions/7",
+		UnsubscribeURL:  "https://forum.example.com/subscriptions/unsubscribe?discussion_id=7&email=alice%40example.com&token=abc",
+	}
+}
+
+func TestRenderTemplate_Notification(t *testing.T) {
+	var buf strings.Builder
+	assert.NoError(t, RenderTemplate(&buf, TemplateNotification, sampleNotification()))
+	html := buf.String()
+
+	assert.Contains(t, html, "Line one\nLine two")
+	assert.Contains(t, html, ">Go generics</a>")
+	assert.Contains(t, html, "Type parameters landed in 1.18")
+	assert.Contains(t, html, `href="https://forum.example.com/discussions/7"`)
+	// & in the query string is escaped as an HTML attribute should be
+	assert.Contains(t, html, `href="https://forum.example.com/subscriptions/unsubscribe?discussion_id=7&amp;email=alice%40example.com&amp;token=abc"`)
+}
+
+func TestRenderTemplate_EscapesUserText(t *testing.T) {
+	d := sampleNotification()
+	d.DiscussionTitle = `<script>alert("x")</script>`
+	d.Message = `<b>bold</b> & "quoted"`
+	d.Snippet = `<img src=x onerror=alert(1)>`
+	d.DiscussionURL = "javascript:alert(1)"
+
+	var buf strings.Builder
+	assert.NoError(t, RenderTemplate(&buf, TemplateNotification, d))
+	html := buf.String()
+
+	assert.NotContains(t, html, "<script>")
+	assert.NotContains(t, html, "<b>bold</b>")
+	assert.NotContains(t, html, "<img")
+	assert.Contains(t, html, "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;")
+	assert.Contains(t, html, "&lt;b&gt;bold&lt;/b&gt; &amp; &#34;quoted&#34;")
+	assert.NotContains(t, html, `href="javascript:`)
+}
+
+func TestRenderTemplate_Unknown(t *testing.T) {
+	var buf strings.Builder
+	assert.ErrorContains(t, RenderTemplate(&buf, "missing.html", nil), "unknown email template")
+}
+
+func TestFunc_SendTemplateRendersBody(t *testing.T) {
+	var subject, body string
+	var m Mailer = Func(func(to []string, s, b string) error {
+		subject, body = s, b
+		return nil
+	})
+
+	assert.NoError(t, m.SendTemplate([]string{"alice@example.com"}, TemplateNotification, sampleNotification()))
+	assert.Equal(t, "New post", subject)
+	assert.Contains(t, body, "<!DOCTYPE html>")
+	assert.Contains(t, body, "Line one\nLine two")
+
+	assert.ErrorIs(t, m.SendTemplate([]string{"alice@example.com"}, TemplateNotification, struct{}{}), ErrNoSubject)
+}
+
+func TestSendTemplate_NotConfigured(t *testing.T) {
+	for _, k := range []string{"SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD", "FROM_EMAIL"} {
+		t.Setenv(k, "")
+	}
+	err := SendTemplate([]string{"a@example.com"}, TemplateNotification, sampleNotification())
+	assert.ErrorIs(t, err, ErrNotConfigured)
+}
@@ -0,0 +1,29 @@
+// pkg/markdown/markdown.go
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// md renders CommonMark plus GitHub tables, strikethrough and autolinks.
+// Raw HTML in the source is dropped rather than passed through.
+var md = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// policy is the allowlist for user-generated content: formatting, links
+// and images, but no scripts, styles or event handlers. Links get
+// rel="nofollow" so posts are no use for spam.
+var policy = bluemonday.UGCPolicy()
+
+// Render converts discussion or comment content from markdown to HTML that
+// is safe to insert into a page as-is.
+func Render(src string) (string, error) {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
@@ -0,0 +1,41 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender_Formatting(t *testing.T) {
+	html, err := Render("# Title\n\nSome **bold** and `code`.\n\n- one\n- ~~two~~\n")
+	assert.NoError(t, err)
+	assert.Contains(t, html, "<h1>Title</h1>")
+	assert.Contains(t, html, "<strong>bold</strong>")
+	assert.Contains(t, html, "<code>code</code>")
+	assert.Contains(t, html, "<li>one</li>")
+	assert.Contains(t, html, "<del>two</del>")
+}
+
+func TestRender_StripsScriptsAndHandlers(t *testing.T) {
+	cases := []string{
+		"<script>alert(1)</script>",
+		"<img src=x onerror=alert(1)>",
+		"[click](javascript:alert(1))",
+	}
+	for _, src := range cases {
+		html, err := Render(src)
+		assert.NoError(t, err)
+		lower := strings.ToLower(html)
+		assert.NotContains(t, lower, "<script", src)
+		assert.NotContains(t, lower, "onerror", src)
+		assert.NotContains(t, lower, "javascript:", src)
+	}
+}
+
+func TestRender_LinksAreNofollow(t *testing.T) {
+	html, err := Render("see https://example.com")
+	assert.NoError(t, err)
+	assert.Contains(t, html, `href="https://example.com"`)
+	assert.Contains(t, html, `rel="nofollow"`)
+}
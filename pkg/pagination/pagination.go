@@ -0,0 +1,90 @@
+// pkg/pagination/pagination.go
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultLimit is used when the client does not send ?limit=.
+	DefaultLimit = 20
+	// MaxLimit caps ?limit= so a single page stays cheap.
+	MaxLimit = 100
+)
+
+// ErrInvalidParams is returned when limit/offset are not non-negative integers.
+var ErrInvalidParams = errors.New("limit and offset must be non-negative integers")
+
+// ErrOffsetTooLarge is returned when ?offset= is beyond the configured maximum.
+var ErrOffsetTooLarge = errors.New("offset is too large")
+
+// maxOffset is the deepest offset FromQuery accepts. Zero disables the cap.
+var maxOffset atomic.Int64
+
+// SetMaxOffset sets the deepest ?offset= FromQuery accepts. A large offset
+// still makes the database read and discard every skipped row, so deep
+// pages are refused rather than served slowly. Zero or negative disables
+// the cap.
+func SetMaxOffset(n int) {
+	maxOffset.Store(int64(n))
+}
+
+// Params holds the parsed paging window.
+type Params struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// FromQuery reads ?limit= and ?offset= from the request.
+// A missing or zero limit falls back to DefaultLimit and anything above
+// MaxLimit is clamped. Negative or non-numeric values yield ErrInvalidParams,
+// and an offset above the SetMaxOffset cap yields ErrOffsetTooLarge.
+func FromQuery(c *gin.Context) (Params, error) {
+	return FromQueryDefault(c, DefaultLimit)
+}
+
+// FromQueryDefault is FromQuery for endpoints whose page size, when the
+// client sends no ?limit=, is defaultLimit instead of DefaultLimit.
+func FromQueryDefault(c *gin.Context, defaultLimit int) (Params, error) {
+	p := Params{Limit: defaultLimit}
+
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return p, ErrInvalidParams
+		}
+		if n > 0 {
+			p.Limit = n
+		}
+	}
+	if p.Limit > MaxLimit {
+		p.Limit = MaxLimit
+	}
+
+	if s := c.Query("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return p, ErrInvalidParams
+		}
+		if ceiling := maxOffset.Load(); ceiling > 0 && int64(n) > ceiling {
+			return p, fmt.Errorf("%w: the maximum is %d", ErrOffsetTooLarge, ceiling)
+		}
+		p.Offset = n
+	}
+	return p, nil
+}
+
+// NextOffset returns the offset of the page after one that returned n of
+// total rows, or nil when that page was the last.
+func (p Params) NextOffset(n, total int) *int {
+	next := p.Offset + n
+	if n == 0 || next >= total {
+		return nil
+	}
+	return &next
+}
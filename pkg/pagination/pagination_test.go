@@ -0,0 +1,49 @@
+package pagination
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func queryContext(rawQuery string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?"+rawQuery, nil)
+	return c
+}
+
+func TestFromQuery_DefaultsAndClamp(t *testing.T) {
+	p, err := FromQuery(queryContext(""))
+	assert.NoError(t, err)
+	assert.Equal(t, Params{Limit: DefaultLimit}, p)
+
+	p, err = FromQuery(queryContext("limit=500&offset=40"))
+	assert.NoError(t, err)
+	assert.Equal(t, Params{Limit: MaxLimit, Offset: 40}, p)
+
+	for _, q := range []string{"limit=-1", "offset=-1", "offset=x"} {
+		_, err = FromQuery(queryContext(q))
+		assert.ErrorIs(t, err, ErrInvalidParams, q)
+	}
+}
+
+func TestFromQuery_MaxOffset(t *testing.T) {
+	SetMaxOffset(100)
+	t.Cleanup(func() { SetMaxOffset(0) })
+
+	p, err := FromQuery(queryContext("offset=100"))
+	assert.NoError(t, err)
+	assert.Equal(t, 100, p.Offset)
+
+	_, err = FromQuery(queryContext("offset=101"))
+	assert.ErrorIs(t, err, ErrOffsetTooLarge)
+	assert.EqualError(t, err, "offset is too large: the maximum is 100")
+
+	SetMaxOffset(0)
+	p, err = FromQuery(queryContext("offset=1000000"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1000000, p.Offset)
+}
@@ -0,0 +1,72 @@
+// pkg/slowquery/slowquery.go
+package slowquery
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go-discussion-app/pkg/logger"
+)
+
+// threshold is the minimum duration a query must take to be logged.
+// Zero disables logging.
+var threshold atomic.Int64
+
+// SetThreshold sets how long a query may run before it is logged at Warn.
+// A zero or negative value disables slow-query logging.
+func SetThreshold(d time.Duration) {
+	threshold.Store(int64(d))
+}
+
+// DB wraps *sql.DB and times the query methods repositories use.
+// Everything else (BeginTx, Ping, ...) passes straight through.
+type DB struct {
+	*sql.DB
+}
+
+// Wrap returns a timing wrapper around db.
+func Wrap(db *sql.DB) *DB {
+	return &DB{DB: db}
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer observe(query, time.Now())
+	return d.DB.QueryContext(ctx, query, args...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer observe(query, time.Now())
+	return d.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer observe(query, time.Now())
+	return d.DB.ExecContext(ctx, query, args...)
+}
+
+func (d *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.QueryContext(context.Background(), query, args...)
+}
+
+func (d *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return d.QueryRowContext(context.Background(), query, args...)
+}
+
+func (d *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.ExecContext(context.Background(), query, args...)
+}
+
+// observe logs query if it ran longer than the threshold. Only the
+// parameterized SQL is logged, never the argument values.
+func observe(query string, start time.Time) {
+	limit := time.Duration(threshold.Load())
+	if limit <= 0 {
+		return
+	}
+	if elapsed := time.Since(start); elapsed >= limit {
+		logger.Warnf("slow query (%s): %s", elapsed.Round(time.Millisecond), strings.Join(strings.Fields(query), " "))
+	}
+}
@@ -0,0 +1,83 @@
+package slowquery
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"go-discussion-app/pkg/logger"
+)
+
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	out, level := logger.Log.Out, logger.Log.GetLevel()
+	logger.Log.SetOutput(&buf)
+	logger.Log.SetLevel(logrus.InfoLevel)
+	t.Cleanup(func() {
+		logger.Log.SetOutput(out)
+		logger.Log.SetLevel(level)
+		SetThreshold(0)
+	})
+	return &buf
+}
+
+func newMockDB(t *testing.T) (*DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return Wrap(db), mock
+}
+
+func TestSlowQueryIsLoggedAtWarn(t *testing.T) {
+	buf := captureLog(t)
+	SetThreshold(10 * time.Millisecond)
+	db, mock := newMockDB(t)
+
+	mock.ExpectQuery(`SELECT id FROM discussions WHERE user_id = \$1`).
+		WithArgs(42).
+		WillDelayFor(30 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	rows, err := db.Query("SELECT id\n\t  FROM discussions WHERE user_id = $1", 42)
+	assert.NoError(t, err)
+	rows.Close()
+
+	out := buf.String()
+	assert.Contains(t, out, "warning")
+	assert.Contains(t, out, "slow query")
+	assert.Contains(t, out, "SELECT id FROM discussions WHERE user_id = $1")
+	assert.NotContains(t, out, "42", "argument values must not be logged")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFastQueryIsNotLogged(t *testing.T) {
+	buf := captureLog(t)
+	SetThreshold(time.Second)
+	db, mock := newMockDB(t)
+
+	mock.ExpectExec(`DELETE FROM tags`).WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err := db.Exec("DELETE FROM tags")
+	assert.NoError(t, err)
+
+	assert.Empty(t, buf.String())
+}
+
+func TestZeroThresholdDisablesLogging(t *testing.T) {
+	buf := captureLog(t)
+	SetThreshold(0)
+	db, mock := newMockDB(t)
+
+	mock.ExpectExec(`DELETE FROM tags`).
+		WillDelayFor(5 * time.Millisecond).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err := db.Exec("DELETE FROM tags")
+	assert.NoError(t, err)
+
+	assert.Empty(t, buf.String())
+}